@@ -0,0 +1,248 @@
+package gsm
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed data/apn.csv
+var apnTable string
+
+// ErrAPNNotFound возвращается, если для SIM-карты нет записи в справочнике APN
+var ErrAPNNotFound = errors.New("APN not found for SIM")
+
+// APNAuth тип аутентификации PDP контекста (<auth_prot> AT+CGAUTH)
+type APNAuth int
+
+const (
+	APNAuthNone APNAuth = 0 // Без аутентификации
+	APNAuthPAP  APNAuth = 1 // PAP
+	APNAuthCHAP APNAuth = 2 // CHAP
+)
+
+// String возвращает название типа аутентификации
+func (a APNAuth) String() string {
+	switch a {
+	case APNAuthPAP:
+		return "pap"
+	case APNAuthCHAP:
+		return "chap"
+	default:
+		return "none"
+	}
+}
+
+// APNSettings настройки точки доступа
+type APNSettings struct {
+	MCC      string  // Код страны
+	MNC      string  // Код сети
+	SPN      string  // Service Provider Name MVNO (пусто - любой)
+	GID1     string  // Префикс GID1 MVNO в hex (пусто - любой)
+	Name     string  // Описание ("MegaFon Internet")
+	APN      string  // Имя точки доступа
+	Username string  // Имя пользователя
+	Password string  // Пароль
+	Auth     APNAuth // Тип аутентификации
+	PDPType  PDPType // Тип PDP контекста
+}
+
+// apnDB встроенный справочник и пользовательские записи (имеют приоритет)
+var apnDB struct {
+	once      sync.Once
+	mu        sync.RWMutex
+	builtin   []APNSettings
+	overrides []APNSettings
+}
+
+// loadAPNDB разбирает встроенную таблицу
+func loadAPNDB() {
+	entries, err := parseAPNTable(strings.NewReader(apnTable))
+	if err != nil {
+		panic(fmt.Sprintf("gsm: invalid embedded APN table: %v", err))
+	}
+	apnDB.builtin = entries
+}
+
+// parseAPNTable читает CSV с заголовком mcc,mnc,spn,gid1,name,apn,username,password,auth,type
+func parseAPNTable(r io.Reader) ([]APNSettings, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 10
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []APNSettings
+	for i, row := range records {
+		if i == 0 && strings.EqualFold(row[0], "mcc") {
+			continue
+		}
+		auth := APNAuthNone
+		switch strings.ToLower(row[8]) {
+		case "", "none":
+		case "pap":
+			auth = APNAuthPAP
+		case "chap":
+			auth = APNAuthCHAP
+		default:
+			return nil, fmt.Errorf("line %d: unknown auth %q", i+1, row[8])
+		}
+		pdpType := PDPType(strings.ToUpper(row[9]))
+		if pdpType == "" {
+			pdpType = PDPTypeIP
+		}
+		entries = append(entries, APNSettings{
+			MCC:      row[0],
+			MNC:      row[1],
+			SPN:      row[2],
+			GID1:     strings.ToUpper(row[3]),
+			Name:     row[4],
+			APN:      row[5],
+			Username: row[6],
+			Password: row[7],
+			Auth:     auth,
+			PDPType:  pdpType,
+		})
+	}
+	return entries, nil
+}
+
+// LoadAPNOverrides загружает пользовательский файл APN в формате встроенной таблицы
+// (CSV: mcc,mnc,spn,gid1,name,apn,username,password,auth,type; строки с # - комментарии).
+// Записи файла проверяются раньше встроенных и заменяют ранее загруженные.
+func LoadAPNOverrides(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open APN file: %w", err)
+	}
+	defer file.Close()
+
+	entries, err := parseAPNTable(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse APN file %s: %w", path, err)
+	}
+	apnDB.mu.Lock()
+	apnDB.overrides = entries
+	apnDB.mu.Unlock()
+	return nil
+}
+
+// AddAPN добавляет пользовательскую запись APN (проверяется раньше встроенных)
+func AddAPN(settings APNSettings) {
+	if settings.PDPType == "" {
+		settings.PDPType = PDPTypeIP
+	}
+	apnDB.mu.Lock()
+	apnDB.overrides = append(apnDB.overrides, settings)
+	apnDB.mu.Unlock()
+}
+
+// LookupAPN подбирает APN для SIM-карты. Запись MVNO (SPN или префикс GID1) выбирается раньше
+// общей записи оператора; записи с другим SPN/GID1 пропускаются.
+func LookupAPN(sim SIMIdentity) (APNSettings, bool) {
+	apnDB.once.Do(loadAPNDB)
+	apnDB.mu.RLock()
+	overrides := apnDB.overrides
+	apnDB.mu.RUnlock()
+
+	if settings, ok := matchAPN(overrides, sim); ok {
+		return settings, true
+	}
+	return matchAPN(apnDB.builtin, sim)
+}
+
+// matchAPN выбирает запись с наибольшим совпадением (SPN - 2, GID1 - 1)
+func matchAPN(entries []APNSettings, sim SIMIdentity) (APNSettings, bool) {
+	best, bestScore := APNSettings{}, -1
+	for _, entry := range entries {
+		if entry.MCC != sim.MCC || entry.MNC != sim.MNC {
+			continue
+		}
+		score := 0
+		if entry.SPN != "" {
+			if !strings.EqualFold(entry.SPN, sim.SPN) {
+				continue
+			}
+			score += 2
+		}
+		if entry.GID1 != "" {
+			if !strings.HasPrefix(strings.ToUpper(sim.GID1), entry.GID1) {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = entry, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// AutoConfigureAPN определяет SIM-карту (IMSI, SPN, GID1), подбирает APN по справочнику
+// и записывает его в PDP контекст 1. Возвращает примененные настройки.
+func (m *Modem) AutoConfigureAPN() (*APNSettings, error) {
+	sim, err := m.GetSIMIdentity()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure APN: %w", err)
+	}
+	settings, ok := LookupAPN(*sim)
+	if !ok {
+		return nil, fmt.Errorf("failed to configure APN: %w: %s (SPN %q, GID1 %q)",
+			ErrAPNNotFound, sim.Numeric(), sim.SPN, sim.GID1)
+	}
+	if err := m.ConfigureAPN(1, settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// ConfigureAPN записывает точку доступа в PDP контекст (AT+CGDCONT) и данные аутентификации
+// (AT+CGAUTH; для модемов без нее - Huawei AT^AUTHDATA или Quectel AT+QICSGP)
+func (m *Modem) ConfigureAPN(cid int, settings APNSettings) error {
+	pdpType := settings.PDPType
+	if pdpType == "" {
+		pdpType = PDPTypeIP
+	}
+	if err := m.DefinePDPContext(cid, pdpType, settings.APN); err != nil {
+		return err
+	}
+
+	auth := settings.Auth
+	if settings.Username == "" && settings.Password == "" {
+		auth = APNAuthNone
+	}
+	err := m.SetPDPAuth(cid, auth, settings.Username, settings.Password)
+	if err == nil || auth == APNAuthNone {
+		// Модем без AT+CGAUTH: сбрасывать аутентификацию не нужно
+		return nil
+	}
+
+	var cmd string
+	switch m.Vendor() {
+	case VendorHuawei:
+		cmd = fmt.Sprintf(`AT^AUTHDATA=%d,%d,"","%s","%s"`, cid, auth, settings.Password, settings.Username)
+	case VendorQuectel:
+		// <context_type>: 1 - IPv4, 2 - IPv6, 3 - IPv4v6
+		contextType := map[PDPType]int{PDPTypeIP: 1, PDPTypeIPv6: 2, PDPTypeIPv4v6: 3}[pdpType]
+		if contextType == 0 {
+			contextType = 1
+		}
+		cmd = fmt.Sprintf(`AT+QICSGP=%d,%d,"%s","%s","%s",%d`,
+			cid, contextType, settings.APN, settings.Username, settings.Password, auth)
+	default:
+		return err
+	}
+	if _, err := m.execCommand(cmd, time.Second*5); err != nil {
+		return fmt.Errorf("failed to set APN authentication: %w", err)
+	}
+	return nil
+}
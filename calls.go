@@ -0,0 +1,490 @@
+package gsm
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// callInfoSettle - время ожидания +CLIP/+CNAP после первого RING перед событием о начале вызова
+	callInfoSettle = 300 * time.Millisecond
+	// callRingTimeout - если RING не повторяется дольше этого времени, вызов считается пропущенным
+	callRingTimeout = 8 * time.Second
+)
+
+// CallEndReason причина завершения вызова
+type CallEndReason string
+
+const (
+	CallEndNoCarrier CallEndReason = "NO CARRIER" // Соединение разорвано после ответа
+	CallEndBusy      CallEndReason = "BUSY"       // Абонент занят
+	CallEndNoAnswer  CallEndReason = "NO ANSWER"  // Абонент не ответил
+	CallEndRejected  CallEndReason = "REJECTED"   // Входящий вызов отклонен нами (HangUp до ответа)
+	CallEndMissed    CallEndReason = "MISSED"     // Входящий вызов пропущен (звонящий положил трубку)
+	CallEndHangup    CallEndReason = "HANGUP"     // Вызов завершен нами после ответа
+)
+
+// IncomingCall представляет входящий вызов, собранный из RING/+CRING/+CLIP/+CNAP
+type IncomingCall struct {
+	ID           int           // Стабильный идентификатор вызова в пределах сессии модема
+	Number       string        // Номер звонящего из +CLIP (пусто, если скрыт)
+	NumberType   int           // Тип адреса: 129=национальный, 145=международный
+	Validity     int           // Валидность CLI: 0=номер есть, 1=скрыт абонентом, 2=недоступен
+	Name         string        // Имя звонящего из +CNAP
+	NameValidity int           // Валидность имени: 0=есть, 1=скрыто, 2=недоступно
+	CallType     string        // Тип вызова из +CRING: "VOICE", "DATA", "FAX" и т.д.
+	Rings        int           // Количество полученных RING
+	Answered     bool          // Был ли вызов принят
+	StartedAt    time.Time     // Время первого RING
+	AnsweredAt   time.Time     // Время ответа на вызов
+	EndedAt      time.Time     // Время завершения вызова
+	EndReason    CallEndReason // Причина завершения
+}
+
+// callTracker объединяет индикации входящего вызова в один объект
+type callTracker struct {
+	mu        sync.Mutex
+	emit      func(Event)
+	seq       int
+	current   *IncomingCall
+	announced bool
+	timer     *time.Timer
+	outgoing  []*Call
+
+	settle      time.Duration // Ожидание +CLIP/+CNAP (callInfoSettle)
+	ringTimeout time.Duration // Пауза между RING, после которой вызов пропущен (callRingTimeout)
+}
+
+// newCallTracker создает трекер входящих вызовов
+func newCallTracker(emit func(Event)) *callTracker {
+	return &callTracker{emit: emit, settle: callInfoSettle, ringTimeout: callRingTimeout}
+}
+
+// handleLine обрабатывает строку от модема, возвращает true если строка относится к вызову
+func (t *callTracker) handleLine(line string) bool {
+	switch {
+	case line == "RING":
+		t.ring("")
+		return true
+
+	case strings.HasPrefix(line, "+CRING:"):
+		// +CRING: VOICE
+		t.ring(strings.TrimSpace(line[7:]))
+		return true
+
+	case strings.HasPrefix(line, "+CLIP:"):
+		// +CLIP: "+79991234567",145,"",,"",0
+		fields, _ := parseInfoLine(line, "+CLIP:")
+		t.update(func(call *IncomingCall) {
+			call.Number = fieldAt(fields, 0).Value
+			call.NumberType = fieldAt(fields, 1).IntOr(0)
+			call.Validity = fieldAt(fields, 5).IntOr(0)
+		})
+		return true
+
+	case strings.HasPrefix(line, "+CNAP:"):
+		// +CNAP: "Ivan Petrov",0
+		fields, _ := parseInfoLine(line, "+CNAP:")
+		t.update(func(call *IncomingCall) {
+			call.Name = fieldAt(fields, 0).Value
+			call.NameValidity = fieldAt(fields, 1).IntOr(0)
+		})
+		return true
+
+	case strings.HasPrefix(line, "+CLCC:"):
+		// Unsolicited +CLCC (AT+CLCC=1)
+		if status := parseCLCC(line); status != nil && status.Direction == CallOutgoing {
+			t.outgoingURC(status.Index, func(call *Call) {
+				t.updateOutgoing(call, *status)
+			})
+		}
+		return true
+
+	case strings.HasPrefix(line, "^ORIG:"):
+		// Huawei: ^ORIG:1,0 - начат набор
+		t.outgoingURC(huaweiCallID(line), func(call *Call) {
+			t.setOutgoingState(call, CallStateDialing)
+		})
+		return true
+
+	case strings.HasPrefix(line, "^CONF:"):
+		// Huawei: ^CONF:1 - у абонента идут гудки
+		t.outgoingURC(huaweiCallID(line), func(call *Call) {
+			t.setOutgoingState(call, CallStateAlerting)
+		})
+		return true
+
+	case strings.HasPrefix(line, "^CONN:"):
+		// Huawei: ^CONN:1,0 - абонент ответил
+		t.outgoingURC(huaweiCallID(line), func(call *Call) {
+			t.setOutgoingState(call, CallStateActive)
+		})
+		return true
+
+	case strings.HasPrefix(line, "^CEND:"):
+		// Huawei: ^CEND:1,25,104,16 - <id>,<duration>,<end_status>,<cc_cause>
+		fields, _ := parseInfoLine(line, "^CEND:")
+		cause := ""
+		if len(fields) >= 4 {
+			cause = "cc_cause " + fields[3].Value
+		}
+		t.outgoingURC(huaweiCallID(line), func(call *Call) {
+			reason := CallEndNoAnswer
+			if call.Duration() > 0 {
+				reason = CallEndNoCarrier
+			}
+			t.endOutgoing(call, reason, cause)
+		})
+		return true
+
+	case line == "NO CARRIER" || line == "BUSY" || line == "NO ANSWER":
+		if t.remoteEnd(CallEndReason(line)) {
+			return true
+		}
+		return t.outgoingEnd(CallEndReason(line))
+	}
+
+	return false
+}
+
+// huaweiCallID извлекает идентификатор вызова из ^ORIG/^CONF/^CONN/^CEND
+func huaweiCallID(line string) int {
+	fields, _ := ParseFields(line[strings.Index(line, ":")+1:])
+	return fieldAt(fields, 0).IntOr(0)
+}
+
+// addOutgoing начинает отслеживание исходящего вызова
+func (t *callTracker) addOutgoing(call *Call) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seq++
+	call.id = t.seq
+	t.outgoing = append(t.outgoing, call)
+}
+
+// findOutgoing ищет исходящий вызов по индексу модема, привязывая индекс к вызову без него
+// (вызывается под блокировкой)
+func (t *callTracker) findOutgoing(index int) *Call {
+	for _, call := range t.outgoing {
+		if call.Index() == index {
+			return call
+		}
+	}
+	for _, call := range t.outgoing {
+		call.mu.Lock()
+		unassigned := call.index == 0
+		if unassigned {
+			call.index = index
+		}
+		call.mu.Unlock()
+		if unassigned {
+			return call
+		}
+	}
+	return nil
+}
+
+// outgoingURC применяет URC к исходящему вызову и отключает для него опрос AT+CLCC
+func (t *callTracker) outgoingURC(index int, apply func(call *Call)) {
+	t.mu.Lock()
+	call := t.findOutgoing(index)
+	t.mu.Unlock()
+
+	if call == nil {
+		return
+	}
+	call.mu.Lock()
+	call.urcDriven = true
+	call.mu.Unlock()
+	apply(call)
+}
+
+// matchOutgoing ищет вызов в результате AT+CLCC
+func (t *callTracker) matchOutgoing(call *Call, calls []CallStatus) *CallStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	index := call.Index()
+	for i := range calls {
+		if calls[i].Direction != CallOutgoing {
+			continue
+		}
+		if index == 0 {
+			if t.findOutgoing(calls[i].Index) == call {
+				return &calls[i]
+			}
+			continue
+		}
+		if calls[i].Index == index {
+			return &calls[i]
+		}
+	}
+	return nil
+}
+
+// updateOutgoing применяет состояние из +CLCC
+func (t *callTracker) updateOutgoing(call *Call, status CallStatus) {
+	if status.State == CallStateEnded {
+		reason := CallEndNoAnswer
+		if call.Duration() > 0 {
+			reason = CallEndNoCarrier
+		}
+		t.endOutgoing(call, reason, "")
+		return
+	}
+	t.setOutgoingState(call, status.State)
+}
+
+// setOutgoingState меняет состояние исходящего вызова и отправляет событие
+func (t *callTracker) setOutgoingState(call *Call, state CallState) {
+	if !call.setState(state) {
+		return
+	}
+	t.emit(outgoingEvent(EventCallStateChange, call))
+}
+
+// outgoingEnd завершает активный исходящий вызов по NO CARRIER/BUSY/NO ANSWER
+func (t *callTracker) outgoingEnd(reason CallEndReason) bool {
+	t.mu.Lock()
+	var call *Call
+	if len(t.outgoing) > 0 {
+		call = t.outgoing[0]
+	}
+	t.mu.Unlock()
+
+	if call == nil {
+		return false
+	}
+	t.endOutgoing(call, reason, "")
+	return true
+}
+
+// endOutgoing завершает исходящий вызов и прекращает его отслеживание
+func (t *callTracker) endOutgoing(call *Call, reason CallEndReason, cause string) {
+	t.mu.Lock()
+	for i, c := range t.outgoing {
+		if c == call {
+			t.outgoing = append(t.outgoing[:i], t.outgoing[i+1:]...)
+			break
+		}
+	}
+	t.mu.Unlock()
+
+	if call.finish(reason, cause) {
+		t.emit(outgoingEvent(EventCallEnded, call))
+	}
+}
+
+// outgoingEvent формирует событие исходящего вызова
+func outgoingEvent(eventType EventType, call *Call) Event {
+	event := Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"callId":    call.ID(),
+			"direction": CallOutgoing,
+			"number":    call.Number(),
+			"state":     call.State(),
+			"call":      call,
+		},
+	}
+	if eventType == EventCallEnded {
+		event.Data["reason"] = string(call.EndReason())
+		event.Data["cause"] = call.EndCause()
+		event.Data["duration"] = call.Duration()
+	}
+	return event
+}
+
+// ring обрабатывает RING/+CRING
+func (t *callTracker) ring(callType string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	call := t.ensureCall()
+	call.Rings++
+	if callType != "" {
+		call.CallType = callType
+	}
+
+	if t.announced {
+		t.emit(t.callEvent(EventCallRinging, call))
+	}
+	t.resetTimer()
+}
+
+// update применяет изменение к текущему вызову
+func (t *callTracker) update(apply func(call *IncomingCall)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	call := t.ensureCall()
+	apply(call)
+	if t.announced {
+		return
+	}
+	t.resetTimer()
+}
+
+// ensureCall возвращает текущий вызов или создает новый (вызывается под блокировкой)
+func (t *callTracker) ensureCall() *IncomingCall {
+	if t.current == nil {
+		t.seq++
+		t.current = &IncomingCall{
+			ID:        t.seq,
+			StartedAt: time.Now(),
+		}
+		t.announced = false
+	}
+	return t.current
+}
+
+// resetTimer перезапускает таймер ожидания (вызывается под блокировкой)
+func (t *callTracker) resetTimer() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+
+	call := t.current
+	if !t.announced {
+		// Даем время дойти +CLIP/+CNAP, после чего сообщаем о начале вызова
+		t.timer = time.AfterFunc(t.settle, func() { t.announce(call) })
+		return
+	}
+	t.timer = time.AfterFunc(t.ringTimeout, func() { t.ringTimedOut(call) })
+}
+
+// announce отправляет событие о начале вызова
+func (t *callTracker) announce(call *IncomingCall) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current != call || t.announced {
+		return
+	}
+	t.announced = true
+	t.emit(t.callEvent(EventIncomingCall, call))
+	if !call.Answered {
+		t.timer = time.AfterFunc(t.ringTimeout, func() { t.ringTimedOut(call) })
+	}
+}
+
+// ringTimedOut завершает вызов, если RING перестал приходить
+func (t *callTracker) ringTimedOut(call *IncomingCall) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current != call || call.Answered {
+		return
+	}
+	t.finish(CallEndMissed)
+}
+
+// remoteEnd обрабатывает NO CARRIER/BUSY/NO ANSWER
+func (t *callTracker) remoteEnd(reason CallEndReason) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current == nil {
+		return false
+	}
+	if !t.current.Answered {
+		// Звонящий положил трубку до ответа
+		reason = CallEndMissed
+	}
+	t.finish(reason)
+	return true
+}
+
+// answered отмечает текущий вызов как принятый
+func (t *callTracker) answered() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current == nil {
+		return
+	}
+	t.current.Answered = true
+	t.current.AnsweredAt = time.Now()
+	if t.timer != nil && t.announced {
+		t.timer.Stop()
+	}
+}
+
+// hungUp завершает текущий вызов после локального ATH
+func (t *callTracker) hungUp() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current == nil {
+		return
+	}
+	if t.current.Answered {
+		t.finish(CallEndHangup)
+	} else {
+		t.finish(CallEndRejected)
+	}
+}
+
+// finish завершает текущий вызов и отправляет событие (вызывается под блокировкой)
+func (t *callTracker) finish(reason CallEndReason) {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+
+	call := t.current
+	call.EndedAt = time.Now()
+	call.EndReason = reason
+	if !t.announced {
+		// Вызов закончился раньше, чем мы успели о нем сообщить
+		t.emit(t.callEvent(EventIncomingCall, call))
+	}
+	t.emit(t.callEvent(EventCallEnded, call))
+
+	t.current = nil
+	t.announced = false
+}
+
+// callEvent формирует событие с копией состояния вызова
+func (t *callTracker) callEvent(eventType EventType, call *IncomingCall) Event {
+	event := Event{
+		Type:      eventType,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"callId":    call.ID,
+			"direction": CallIncoming,
+			"rings":     call.Rings,
+			"call":      *call,
+		},
+	}
+	if call.Number != "" {
+		event.Data["number"] = call.Number
+		event.Data["numberType"] = call.NumberType
+	}
+	if call.Name != "" {
+		event.Data["name"] = call.Name
+	}
+	if call.CallType != "" {
+		event.Data["callType"] = call.CallType
+	}
+	if eventType == EventCallEnded {
+		event.Data["reason"] = string(call.EndReason)
+		event.Data["missed"] = call.EndReason == CallEndMissed
+	}
+	return event
+}
+
+// GetIncomingCall возвращает копию текущего входящего вызова или nil
+func (m *Modem) GetIncomingCall() *IncomingCall {
+	m.calls.mu.Lock()
+	defer m.calls.mu.Unlock()
+
+	if m.calls.current == nil {
+		return nil
+	}
+	call := *m.calls.current
+	return &call
+}
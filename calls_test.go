@@ -0,0 +1,203 @@
+package gsm
+
+import (
+	"testing"
+	"time"
+)
+
+const (
+	testCallSettle      = 30 * time.Millisecond
+	testCallRingTimeout = 150 * time.Millisecond
+)
+
+// newTestCallTracker создает трекер с короткими тайм-аутами и канал его событий
+func newTestCallTracker() (*callTracker, chan Event) {
+	events := make(chan Event, 16)
+	t := newCallTracker(func(e Event) { events <- e })
+	t.settle, t.ringTimeout = testCallSettle, testCallRingTimeout
+	return t, events
+}
+
+// feed передает трекеру строки модема
+func feed(t *testing.T, tracker *callTracker, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !tracker.handleLine(line) {
+			t.Fatalf("handleLine(%q) = false", line)
+		}
+	}
+}
+
+// nextCallEvent ждет событие указанного типа
+func nextCallEvent(t *testing.T, events chan Event, want EventType, wait time.Duration) IncomingCall {
+	t.Helper()
+	select {
+	case e := <-events:
+		if e.Type != want {
+			t.Fatalf("event %s, want %s (%v)", e.Type, want, e.Data)
+		}
+		return e.Data["call"].(IncomingCall)
+	case <-time.After(wait):
+		t.Fatalf("no %s event within %v", want, wait)
+	}
+	return IncomingCall{}
+}
+
+// currentCall возвращает текущий вызов трекера
+func currentCall(tracker *callTracker) *IncomingCall {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	return tracker.current
+}
+
+// noCallEvent проверяет, что событий нет в течение wait
+func noCallEvent(t *testing.T, events chan Event, wait time.Duration) {
+	t.Helper()
+	select {
+	case e := <-events:
+		t.Fatalf("unexpected event %s (%v)", e.Type, e.Data)
+	case <-time.After(wait):
+	}
+}
+
+func TestCallInfoMerged(t *testing.T) {
+	tracker, events := newTestCallTracker()
+	feed(t, tracker,
+		"RING",
+		`+CLIP: "+79991234567",145,"",,"",0`,
+		`+CNAP: "Ivan Petrov",0`,
+		"+CRING: VOICE",
+	)
+	// До окончания ожидания +CLIP/+CNAP о вызове не сообщается
+	noCallEvent(t, events, testCallSettle/2)
+
+	call := nextCallEvent(t, events, EventIncomingCall, time.Second)
+	if call.Number != "+79991234567" || call.NumberType != 145 || call.Name != "Ivan Petrov" ||
+		call.CallType != "VOICE" || call.Rings != 2 || call.ID != 1 {
+		t.Errorf("call = %+v", call)
+	}
+	if got := currentCall(tracker); got == nil || got.ID != call.ID {
+		t.Errorf("current call = %+v, want ID %d", got, call.ID)
+	}
+}
+
+func TestCallInfoExtendsSettle(t *testing.T) {
+	tracker, events := newTestCallTracker()
+	feed(t, tracker, "RING")
+	time.Sleep(testCallSettle * 2 / 3)
+	// +CLIP перезапускает ожидание, событие приходит уже с номером
+	feed(t, tracker, `+CLIP: "89161234567",129,"",,"",0`)
+	time.Sleep(testCallSettle * 2 / 3)
+	call := nextCallEvent(t, events, EventIncomingCall, time.Second)
+	if call.Number != "89161234567" || call.NumberType != 129 {
+		t.Errorf("call = %+v, want number from +CLIP", call)
+	}
+}
+
+func TestCallRinging(t *testing.T) {
+	tracker, events := newTestCallTracker()
+	feed(t, tracker, "RING")
+	nextCallEvent(t, events, EventIncomingCall, time.Second)
+
+	// Повторные RING продлевают вызов дольше тайм-аута
+	for i := 0; i < 3; i++ {
+		time.Sleep(testCallRingTimeout / 2)
+		feed(t, tracker, "RING")
+		if call := nextCallEvent(t, events, EventCallRinging, time.Second); call.Rings != i+2 {
+			t.Errorf("rings = %d, want %d", call.Rings, i+2)
+		}
+	}
+	// +CLIP после объявления не перезапускает тайм-аут и не порождает событий
+	feed(t, tracker, `+CLIP: "+79991234567",145,"",,"",0`)
+	call := nextCallEvent(t, events, EventCallEnded, testCallRingTimeout*3)
+	if call.EndReason != CallEndMissed || call.Rings != 4 {
+		t.Errorf("ended call = %+v, want missed after 4 rings", call)
+	}
+}
+
+func TestCallMissedByTimeout(t *testing.T) {
+	tracker, events := newTestCallTracker()
+	feed(t, tracker, "+CRING: VOICE")
+	nextCallEvent(t, events, EventIncomingCall, time.Second)
+
+	start := time.Now()
+	select {
+	case e := <-events:
+		if e.Type != EventCallEnded || e.Data["missed"] != true || e.Data["reason"] != string(CallEndMissed) {
+			t.Fatalf("event %s %v, want missed call", e.Type, e.Data)
+		}
+	case <-time.After(testCallRingTimeout * 3):
+		t.Fatal("call is not finished after ring timeout")
+	}
+	if elapsed := time.Since(start); elapsed < testCallRingTimeout*2/3 {
+		t.Errorf("call finished after %v, before ring timeout %v", elapsed, testCallRingTimeout)
+	}
+	if currentCall(tracker) != nil {
+		t.Error("current call is not cleared")
+	}
+
+	// Следующий вызов получает новый идентификатор
+	feed(t, tracker, "RING")
+	if call := nextCallEvent(t, events, EventIncomingCall, time.Second); call.ID != 2 || call.Rings != 1 {
+		t.Errorf("next call = %+v, want ID 2", call)
+	}
+}
+
+func TestCallEndedBeforeAnnounce(t *testing.T) {
+	tracker, events := newTestCallTracker()
+	feed(t, tracker, "RING", `+CLIP: "+79991234567",145,"",,"",0`, "NO CARRIER")
+
+	// Звонящий положил трубку до события о вызове: сообщаются оба события
+	call := nextCallEvent(t, events, EventIncomingCall, time.Second)
+	if call.EndReason != CallEndMissed || call.Number != "+79991234567" {
+		t.Errorf("call = %+v", call)
+	}
+	nextCallEvent(t, events, EventCallEnded, time.Second)
+	// Отложенное объявление уже завершенного вызова не отправляется
+	noCallEvent(t, events, testCallSettle*2)
+}
+
+func TestCallAnswered(t *testing.T) {
+	tracker, events := newTestCallTracker()
+	feed(t, tracker, "RING")
+	nextCallEvent(t, events, EventIncomingCall, time.Second)
+
+	tracker.answered()
+	// После ответа RING не приходят, но вызов не пропущен
+	noCallEvent(t, events, testCallRingTimeout*2)
+
+	feed(t, tracker, "NO CARRIER")
+	call := nextCallEvent(t, events, EventCallEnded, time.Second)
+	if !call.Answered || call.EndReason != CallEndNoCarrier || call.AnsweredAt.IsZero() {
+		t.Errorf("ended call = %+v, want answered call ended by NO CARRIER", call)
+	}
+}
+
+func TestCallHungUp(t *testing.T) {
+	tracker, events := newTestCallTracker()
+	feed(t, tracker, "RING")
+	nextCallEvent(t, events, EventIncomingCall, time.Second)
+	tracker.hungUp()
+	if call := nextCallEvent(t, events, EventCallEnded, time.Second); call.EndReason != CallEndRejected {
+		t.Errorf("reason = %s, want %s", call.EndReason, CallEndRejected)
+	}
+
+	feed(t, tracker, "RING")
+	nextCallEvent(t, events, EventIncomingCall, time.Second)
+	tracker.answered()
+	tracker.hungUp()
+	if call := nextCallEvent(t, events, EventCallEnded, time.Second); call.EndReason != CallEndHangup {
+		t.Errorf("reason = %s, want %s", call.EndReason, CallEndHangup)
+	}
+	noCallEvent(t, events, testCallRingTimeout*2)
+}
+
+func TestCallUnrelatedLines(t *testing.T) {
+	tracker, events := newTestCallTracker()
+	for _, line := range []string{"OK", "+CMTI: \"SM\",3", "NO CARRIER", "BUSY"} {
+		if tracker.handleLine(line) {
+			t.Errorf("handleLine(%q) = true without a call", line)
+		}
+	}
+	noCallEvent(t, events, testCallSettle*2)
+}
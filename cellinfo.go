@@ -0,0 +1,610 @@
+package gsm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CellInfo параметры обслуживающей или соседней соты.
+// Неизвестные числовые значения равны -1, неизвестные уровни сигнала - NaN.
+type CellInfo struct {
+	Serving    bool             // Обслуживающая сота (false - соседняя)
+	AccessTech AccessTechnology // Технология доступа
+	MCC        string           // Код страны
+	MNC        string           // Код сети
+	AreaCode   int64            // LAC (GSM/UMTS) или TAC (LTE/NR)
+	CellID     int64            // Идентификатор соты (для LTE - 28-битный ECI)
+	ARFCN      int              // ARFCN / UARFCN / EARFCN / NR-ARFCN
+	PCI        int              // PCI (LTE/NR), PSC (UMTS) или BSIC (GSM)
+	Band       string           // Диапазон в формате модема ("LTE BAND 3", "EGSM 900")
+	RSSI       float64          // дБм
+	RSCP       float64          // UMTS, дБм
+	EcIo       float64          // UMTS, дБ
+	RSRP       float64          // LTE/NR, дБм
+	RSRQ       float64          // LTE/NR, дБ
+	SINR       float64          // LTE/NR, дБ
+}
+
+// newCellInfo создает описание соты без значений
+func newCellInfo(serving bool, tech AccessTechnology) CellInfo {
+	nan := math.NaN()
+	return CellInfo{
+		Serving:    serving,
+		AccessTech: tech,
+		AreaCode:   -1,
+		CellID:     -1,
+		ARFCN:      -1,
+		PCI:        -1,
+		RSSI:       nan,
+		RSCP:       nan,
+		EcIo:       nan,
+		RSRP:       nan,
+		RSRQ:       nan,
+		SINR:       nan,
+	}
+}
+
+// cellHex возвращает шестнадцатеричное значение поля или -1
+func cellHex(f Field) int64 {
+	if v, err := f.Hex(); err == nil {
+		return v
+	}
+	return -1
+}
+
+// cellFloat возвращает числовое значение поля, деленное на scale, или NaN
+func cellFloat(f Field, scale float64) float64 {
+	if v, err := strconv.ParseFloat(f.Value, 64); err == nil {
+		return v / scale
+	}
+	return math.NaN()
+}
+
+// GetServingCell возвращает параметры обслуживающей соты. Используется vendor-команда
+// (Quectel AT+QENG, SIMCom AT+CPSI/AT+CENG, u-blox AT+UCGED, Huawei AT^MONSC),
+// а при ее отсутствии - AT+COPS и AT+CREG/AT+CEREG.
+func (m *Modem) GetServingCell() (*CellInfo, error) {
+	cells, err := m.servingCells()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get serving cell: %w", err)
+	}
+	return &cells[0], nil
+}
+
+// GetNeighbourCells возвращает соседние соты (ErrNotSupported, если модем их не сообщает)
+func (m *Modem) GetNeighbourCells() ([]CellInfo, error) {
+	var cells []CellInfo
+	var err error
+	switch m.Vendor() {
+	case VendorQuectel:
+		cells, err = m.quectelCells(`AT+QENG="neighbourcell"`)
+	case VendorSIMCom:
+		cells, err = m.simcomCENG()
+	case VendorHuawei:
+		cells, err = m.huaweiCells("AT^MONNC", "^MONNC:", false)
+	default:
+		err = ErrNotSupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get neighbour cells: %w", err)
+	}
+
+	var result []CellInfo
+	for _, cell := range cells {
+		if !cell.Serving {
+			result = append(result, cell)
+		}
+	}
+	return result, nil
+}
+
+// GetCellInfo возвращает обслуживающие соты (при EN-DC их две: LTE и NR) и соседние соты
+func (m *Modem) GetCellInfo() ([]CellInfo, error) {
+	cells, err := m.servingCells()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cell info: %w", err)
+	}
+	neighbours, err := m.GetNeighbourCells()
+	if err != nil && !errors.Is(err, ErrNotSupported) {
+		return nil, err
+	}
+	return append(cells, neighbours...), nil
+}
+
+// servingCells выбирает источник данных по производителю
+func (m *Modem) servingCells() ([]CellInfo, error) {
+	var cells []CellInfo
+	err := ErrNotSupported
+	switch m.Vendor() {
+	case VendorQuectel:
+		cells, err = m.quectelCells(`AT+QENG="servingcell"`)
+	case VendorSIMCom:
+		if cells, err = m.simcomCPSI(); err != nil {
+			cells, err = m.simcomCENG()
+		}
+	case VendorUblox:
+		cells, err = m.ubloxUCGED()
+	case VendorHuawei:
+		cells, err = m.huaweiCells("AT^MONSC", "^MONSC:", true)
+	}
+
+	var serving []CellInfo
+	for _, cell := range cells {
+		if cell.Serving {
+			serving = append(serving, cell)
+		}
+	}
+	if err == nil && len(serving) > 0 {
+		return serving, nil
+	}
+
+	// Стандартные команды 27.007
+	cell, err := m.genericServingCell()
+	if err != nil {
+		return nil, err
+	}
+	return []CellInfo{*cell}, nil
+}
+
+// genericServingCell собирает данные соты из AT+COPS (MCC/MNC) и AT+CEREG/AT+CREG (LAC, Cell ID)
+func (m *Modem) genericServingCell() (*CellInfo, error) {
+	var reg *RegistrationInfo
+	for _, domain := range []RegistrationDomain{Registration5GS, RegistrationEPS, RegistrationGPRS, RegistrationCS} {
+		info, err := m.GetRegistration(domain)
+		if err == nil && info.Registered() && info.CellID >= 0 {
+			reg = info
+			break
+		}
+	}
+	if reg == nil {
+		return nil, fmt.Errorf("cell location is not reported (not registered or reporting disabled)")
+	}
+
+	cell := newCellInfo(true, reg.AccessTech)
+	cell.AreaCode = reg.AreaCode
+	cell.CellID = reg.CellID
+
+	if numeric, err := m.currentOperatorNumeric(); err == nil && len(numeric) >= 5 {
+		cell.MCC = numeric[:3]
+		cell.MNC = numeric[3:]
+	}
+	return &cell, nil
+}
+
+// quectelCells разбирает AT+QENG="servingcell" и AT+QENG="neighbourcell"
+func (m *Modem) quectelCells(cmd string) ([]CellInfo, error) {
+	resp, err := m.execCommand(cmd, time.Second*3)
+	if err != nil {
+		return nil, err
+	}
+	var cells []CellInfo
+	for _, fields := range parseInfoLines(resp, "+QENG:") {
+		if cell, ok := parseQENG(fields); ok {
+			cells = append(cells, cell)
+		}
+	}
+	return cells, nil
+}
+
+// parseQENG разбирает строку +QENG (при EN-DC обслуживающие соты идут отдельными строками без "servingcell")
+func parseQENG(fields []Field) (CellInfo, bool) {
+	kind := strings.ToLower(fieldAt(fields, 0).Value)
+	switch {
+	case kind == "servingcell":
+		// "servingcell",<state>,<rat>,...
+		if len(fields) < 4 {
+			return CellInfo{}, false
+		}
+		return parseQENGServing(fields[2].Value, fields[3:])
+	case strings.HasPrefix(kind, "neighbourcell"):
+		return parseQENGNeighbour(fieldAt(fields, 1).Value, fields)
+	default:
+		// EN-DC: "LTE",<is_tdd>,... и "NR5G-NSA",<MCC>,...
+		if len(fields) < 2 {
+			return CellInfo{}, false
+		}
+		return parseQENGServing(kind, fields[1:])
+	}
+}
+
+// parseQENGServing разбирает параметры обслуживающей соты Quectel после <rat>
+func parseQENGServing(rat string, d []Field) (CellInfo, bool) {
+	switch strings.ToUpper(rat) {
+	case "GSM":
+		// <mcc>,<mnc>,<lac>,<cellid>,<bsic>,<arfcn>,<band>,...
+		cell := newCellInfo(true, AccessTechGSM)
+		cell.MCC, cell.MNC = fieldAt(d, 0).Value, fieldAt(d, 1).Value
+		cell.AreaCode, cell.CellID = cellHex(fieldAt(d, 2)), cellHex(fieldAt(d, 3))
+		cell.PCI, cell.ARFCN = fieldAt(d, 4).IntOr(-1), fieldAt(d, 5).IntOr(-1)
+		cell.Band = fieldAt(d, 6).Value
+		return cell, true
+
+	case "WCDMA":
+		// <mcc>,<mnc>,<lac>,<cellid>,<uarfcn>,<psc>,<rac>,<rscp>,<ecio>,...
+		cell := newCellInfo(true, AccessTechUMTS)
+		cell.MCC, cell.MNC = fieldAt(d, 0).Value, fieldAt(d, 1).Value
+		cell.AreaCode, cell.CellID = cellHex(fieldAt(d, 2)), cellHex(fieldAt(d, 3))
+		cell.ARFCN, cell.PCI = fieldAt(d, 4).IntOr(-1), fieldAt(d, 5).IntOr(-1)
+		cell.RSCP, cell.EcIo = cellFloat(fieldAt(d, 7), 1), cellFloat(fieldAt(d, 8), 1)
+		return cell, true
+
+	case "LTE", "CAT-M", "CAT-NB":
+		// <is_tdd>,<MCC>,<MNC>,<cellID>,<PCID>,<earfcn>,<band>,<ul_bw>,<dl_bw>,<TAC>,<RSRP>,<RSRQ>,<RSSI>,<SINR>,...
+		tech := AccessTechLTE
+		switch strings.ToUpper(rat) {
+		case "CAT-M":
+			tech = AccessTechLTECatM
+		case "CAT-NB":
+			tech = AccessTechNBIoT
+		}
+		cell := newCellInfo(true, tech)
+		cell.MCC, cell.MNC = fieldAt(d, 1).Value, fieldAt(d, 2).Value
+		cell.CellID = cellHex(fieldAt(d, 3))
+		cell.PCI, cell.ARFCN = fieldAt(d, 4).IntOr(-1), fieldAt(d, 5).IntOr(-1)
+		cell.Band = fieldAt(d, 6).Value
+		cell.AreaCode = cellHex(fieldAt(d, 9))
+		cell.RSRP, cell.RSRQ, cell.RSSI = cellFloat(fieldAt(d, 10), 1), cellFloat(fieldAt(d, 11), 1), cellFloat(fieldAt(d, 12), 1)
+		// SINR в единицах 1/5 дБ со смещением -20
+		cell.SINR = cellFloat(fieldAt(d, 13), 5) - 20
+		return cell, true
+
+	case "NR5G-SA":
+		// <duplex>,<MCC>,<MNC>,<cellID>,<PCID>,<TAC>,<ARFCN>,<band>,<bw>,<RSRP>,<RSRQ>,<SINR>,...
+		cell := newCellInfo(true, AccessTechNR5GC)
+		cell.MCC, cell.MNC = fieldAt(d, 1).Value, fieldAt(d, 2).Value
+		cell.CellID, cell.PCI = cellHex(fieldAt(d, 3)), fieldAt(d, 4).IntOr(-1)
+		cell.AreaCode, cell.ARFCN = cellHex(fieldAt(d, 5)), fieldAt(d, 6).IntOr(-1)
+		cell.Band = fieldAt(d, 7).Value
+		cell.RSRP, cell.RSRQ, cell.SINR = cellFloat(fieldAt(d, 9), 1), cellFloat(fieldAt(d, 10), 1), cellFloat(fieldAt(d, 11), 1)
+		return cell, true
+
+	case "NR5G-NSA":
+		// <MCC>,<MNC>,<PCID>,<RSRP>,<SINR>,<RSRQ>,<ARFCN>,<band>
+		cell := newCellInfo(true, AccessTechENDC)
+		cell.MCC, cell.MNC = fieldAt(d, 0).Value, fieldAt(d, 1).Value
+		cell.PCI = fieldAt(d, 2).IntOr(-1)
+		cell.RSRP, cell.SINR, cell.RSRQ = cellFloat(fieldAt(d, 3), 1), cellFloat(fieldAt(d, 4), 1), cellFloat(fieldAt(d, 5), 1)
+		cell.ARFCN, cell.Band = fieldAt(d, 6).IntOr(-1), fieldAt(d, 7).Value
+		return cell, true
+	}
+	// "SEARCH", "LIMSRV", "NOCONN" без параметров соты
+	return CellInfo{}, false
+}
+
+// parseQENGNeighbour разбирает строку соседней соты Quectel
+func parseQENGNeighbour(rat string, f []Field) (CellInfo, bool) {
+	switch strings.ToUpper(rat) {
+	case "LTE":
+		// "neighbourcell intra","LTE",<earfcn>,<PCID>,<RSRQ>,<RSRP>,<RSSI>,<SINR>,...
+		cell := newCellInfo(false, AccessTechLTE)
+		cell.ARFCN, cell.PCI = fieldAt(f, 2).IntOr(-1), fieldAt(f, 3).IntOr(-1)
+		cell.RSRQ, cell.RSRP, cell.RSSI = cellFloat(fieldAt(f, 4), 1), cellFloat(fieldAt(f, 5), 1), cellFloat(fieldAt(f, 6), 1)
+		return cell, true
+	case "WCDMA":
+		// "neighbourcell","WCDMA",<uarfcn>,<resel_priority>,<thresh_high>,<thresh_low>,<PSC>,<RSCP>,<ecno>,...
+		cell := newCellInfo(false, AccessTechUMTS)
+		cell.ARFCN, cell.PCI = fieldAt(f, 2).IntOr(-1), fieldAt(f, 6).IntOr(-1)
+		cell.RSCP, cell.EcIo = cellFloat(fieldAt(f, 7), 1), cellFloat(fieldAt(f, 8), 1)
+		return cell, true
+	case "GSM":
+		// "neighbourcell","GSM",<arfcn>,<resel_priority>,<thresh_high>,<thresh_low>,<ncc_permitted>,<band>,<bsic>,<rssi>,...
+		cell := newCellInfo(false, AccessTechGSM)
+		cell.ARFCN, cell.Band = fieldAt(f, 2).IntOr(-1), fieldAt(f, 7).Value
+		cell.PCI, cell.RSSI = fieldAt(f, 8).IntOr(-1), cellFloat(fieldAt(f, 9), 1)
+		return cell, true
+	}
+	return CellInfo{}, false
+}
+
+// simcomCPSI разбирает AT+CPSI? (SIM7xxx)
+func (m *Modem) simcomCPSI() ([]CellInfo, error) {
+	resp, err := m.execCommand("AT+CPSI?", time.Second*2)
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range parseInfoLines(resp, "+CPSI:") {
+		if cell, ok := parseCPSICell(fields); ok {
+			return []CellInfo{cell}, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected response format: %s", resp)
+}
+
+// parseCPSICell разбирает +CPSI: <mode>,<status>,<MCC>-<MNC>,<LAC/TAC>,<CellID>,...
+func parseCPSICell(fields []Field) (CellInfo, bool) {
+	signal := parseCPSI(fields)
+	if signal == nil || signal.AccessTech == AccessTechUnknown {
+		return CellInfo{}, false
+	}
+
+	cell := newCellInfo(true, signal.AccessTech)
+	cell.RSSI, cell.RSCP, cell.EcIo = signal.RSSI, signal.RSCP, signal.EcIo
+	cell.RSRP, cell.RSRQ, cell.SINR = signal.RSRP, signal.RSRQ, signal.SINR
+	if plmn := strings.SplitN(fieldAt(fields, 2).Value, "-", 2); len(plmn) == 2 {
+		cell.MCC, cell.MNC = plmn[0], plmn[1]
+	}
+	cell.AreaCode = cellHex(fieldAt(fields, 3))
+	if id, err := fieldAt(fields, 4).Int(); err == nil {
+		cell.CellID = int64(id)
+	}
+
+	switch signal.AccessTech {
+	case AccessTechGSM:
+		// <ARFCN> <Band>: "27 EGSM 900"
+		if parts := strings.SplitN(fieldAt(fields, 5).Value, " ", 2); len(parts) == 2 {
+			cell.ARFCN, _ = strconv.Atoi(parts[0])
+			cell.Band = parts[1]
+		}
+	case AccessTechUMTS:
+		cell.Band = fieldAt(fields, 5).Value
+		cell.ARFCN, cell.PCI = fieldAt(fields, 6).IntOr(-1), fieldAt(fields, 7).IntOr(-1)
+	default:
+		// LTE/NR: <PCellID>,<Band>,<EARFCN/ARFCN>
+		cell.PCI, cell.Band = fieldAt(fields, 5).IntOr(-1), fieldAt(fields, 6).Value
+		cell.ARFCN = fieldAt(fields, 7).IntOr(-1)
+	}
+	return cell, true
+}
+
+// simcomCENG разбирает AT+CENG? (SIM800/SIM900, только GSM). Если инженерный режим выключен,
+// он включается на время запроса, после чего восстанавливается прежний режим.
+func (m *Modem) simcomCENG() ([]CellInfo, error) {
+	resp, err := m.execCommand("AT+CENG?", time.Second*3)
+	if err != nil {
+		return nil, err
+	}
+	if mode, ncell := cengMode(resp); mode != "1" || ncell != "0" {
+		if _, err := m.execCommand("AT+CENG=1,0", time.Second*2); err != nil {
+			return nil, err
+		}
+		defer m.execCommand(fmt.Sprintf("AT+CENG=%s,%s", mode, ncell), time.Second*2)
+		if resp, err = m.execCommand("AT+CENG?", time.Second*3); err != nil {
+			return nil, err
+		}
+	}
+	return parseCENG(resp), nil
+}
+
+// cengMode возвращает режим из заголовка +CENG: <mode>,<Ncell> (по умолчанию 0,0)
+func cengMode(resp string) (mode, ncell string) {
+	mode, ncell = "0", "0"
+	for _, fields := range parseInfoLines(resp, "+CENG:") {
+		if fieldAt(fields, 1).Quoted {
+			continue
+		}
+		if v := fieldAt(fields, 0).Value; v != "" {
+			mode = v
+		}
+		if v := fieldAt(fields, 1).Value; v != "" {
+			ncell = v
+		}
+		break
+	}
+	return mode, ncell
+}
+
+// parseCENG разбирает строки сот ответа AT+CENG?
+func parseCENG(resp string) []CellInfo {
+	var cells []CellInfo
+	for _, fields := range parseInfoLines(resp, "+CENG:") {
+		// Заголовок +CENG: <mode>,<Ncell> пропускаем, данные соты - строка в кавычках
+		if len(fields) < 2 || !fields[1].Quoted {
+			continue
+		}
+		data, err := ParseFields(fields[1].Value)
+		if err != nil {
+			continue
+		}
+		serving := fields[0].Value == "0"
+		cell := newCellInfo(serving, AccessTechGSM)
+		cell.ARFCN = fieldAt(data, 0).IntOr(-1)
+		if rxl := fieldAt(data, 1).IntOr(-1); rxl >= 0 && rxl <= 63 {
+			cell.RSSI = float64(rxl - 111)
+		}
+		if serving {
+			// <arfcn>,<rxl>,<rxq>,<mcc>,<mnc>,<bsic>,<cellid>,<rla>,<txp>,<lac>,<TA>
+			cell.MCC, cell.MNC = fieldAt(data, 3).Value, fieldAt(data, 4).Value
+			cell.PCI = fieldAt(data, 5).IntOr(-1)
+			cell.CellID, cell.AreaCode = cellHex(fieldAt(data, 6)), cellHex(fieldAt(data, 9))
+		} else {
+			// <arfcn>,<rxl>,<bsic>,<cellid>,<mcc>,<mnc>,<lac>
+			cell.PCI = fieldAt(data, 2).IntOr(-1)
+			cell.CellID = cellHex(fieldAt(data, 3))
+			cell.MCC, cell.MNC = fieldAt(data, 4).Value, fieldAt(data, 5).Value
+			cell.AreaCode = cellHex(fieldAt(data, 6))
+		}
+		cells = append(cells, cell)
+	}
+	return cells
+}
+
+// ubloxUCGED разбирает AT+UCGED? (u-blox): после строки +UCGED: <mode> идут строки без префикса.
+// Данные выдаются только в режимах 2 и 5, поэтому при другом режиме на время запроса включается
+// режим 2, после чего восстанавливается прежний.
+func (m *Modem) ubloxUCGED() ([]CellInfo, error) {
+	resp, err := m.execCommand("AT+UCGED?", time.Second*3)
+	if err != nil {
+		return nil, err
+	}
+	mode, lines := splitUCGED(resp)
+	if mode != "2" && mode != "5" {
+		if _, err := m.execCommand("AT+UCGED=2", time.Second*2); err != nil {
+			return nil, fmt.Errorf("failed to enable AT+UCGED mode 2 (current mode %q): %w", mode, err)
+		}
+		if mode == "" {
+			mode = "0"
+		}
+		defer m.execCommand("AT+UCGED="+mode, time.Second*2)
+		if resp, err = m.execCommand("AT+UCGED?", time.Second*3); err != nil {
+			return nil, err
+		}
+		_, lines = splitUCGED(resp)
+	}
+	return parseUCGED(lines, resp)
+}
+
+// splitUCGED возвращает режим из строки +UCGED: <mode> и следующие за ней строки без префикса
+func splitUCGED(resp string) (mode string, lines [][]Field) {
+	started := false
+	for _, line := range strings.Split(resp, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "+UCGED:"):
+			started = true
+			if fields, err := ParseFields(line[len("+UCGED:"):]); err == nil {
+				mode = fieldAt(fields, 0).Value
+			}
+		case started && line != "" && line != "OK":
+			if fields, err := ParseFields(line); err == nil {
+				lines = append(lines, fields)
+			}
+		}
+	}
+	return mode, lines
+}
+
+// parseUCGED разбирает строки ответа AT+UCGED? в режиме 2/5
+func parseUCGED(lines [][]Field, resp string) ([]CellInfo, error) {
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("unexpected response format: %s", resp)
+	}
+
+	// <rat>,<svc>,<MCC>,<MNC>
+	header, data := lines[0], lines[1]
+	var cell CellInfo
+	switch fieldAt(header, 0).Value {
+	case "2":
+		// <arfcn>,<band1900>,<GcellId>,<BSIC>,<Glac>,<Grac>,<rxlev>,...
+		cell = newCellInfo(true, AccessTechGSM)
+		cell.ARFCN = fieldAt(data, 0).IntOr(-1)
+		cell.CellID, cell.PCI = cellHex(fieldAt(data, 2)), fieldAt(data, 3).IntOr(-1)
+		cell.AreaCode = cellHex(fieldAt(data, 4))
+	case "3":
+		// <dl_uarfcn>,<ul_uarfcn>,<band>,<UcellId>,<uLAC>,...
+		cell = newCellInfo(true, AccessTechUMTS)
+		cell.ARFCN, cell.Band = fieldAt(data, 0).IntOr(-1), fieldAt(data, 2).Value
+		cell.CellID, cell.AreaCode = cellHex(fieldAt(data, 3)), cellHex(fieldAt(data, 4))
+	default:
+		// LTE: <earfcn>,<Lband>,<ul_BW>,<dl_BW>,<tac>,<LcellId>,<P-CID>,<mTmsi>,<mmeGrId>,<mmeCode>,<rsrp>,<rsrq>,...
+		cell = newCellInfo(true, AccessTechLTE)
+		switch fieldAt(header, 0).Value {
+		case "6":
+			cell.AccessTech = AccessTechLTECatM
+		case "7":
+			cell.AccessTech = AccessTechNBIoT
+		}
+		cell.ARFCN, cell.Band = fieldAt(data, 0).IntOr(-1), fieldAt(data, 1).Value
+		cell.AreaCode, cell.CellID = cellHex(fieldAt(data, 4)), cellHex(fieldAt(data, 5))
+		cell.PCI = fieldAt(data, 6).IntOr(-1)
+		// Значения в шкале +CESQ (0-97 / 0-34) или уже в дБм/дБ (отрицательные)
+		if rsrp := cellFloat(fieldAt(data, 10), 1); rsrp >= 0 && rsrp <= 97 {
+			cell.RSRP = rsrp - 141
+		} else if rsrp < 0 {
+			cell.RSRP = rsrp
+		}
+		if rsrq := cellFloat(fieldAt(data, 11), 1); rsrq >= 0 && rsrq <= 34 {
+			cell.RSRQ = rsrq*0.5 - 20
+		} else if rsrq < 0 {
+			cell.RSRQ = rsrq
+		}
+	}
+	cell.MCC, cell.MNC = fieldAt(header, 2).Value, fieldAt(header, 3).Value
+	return []CellInfo{cell}, nil
+}
+
+// huaweiCells разбирает AT^MONSC (обслуживающая сота) и AT^MONNC (соседние соты)
+func (m *Modem) huaweiCells(cmd, prefix string, serving bool) ([]CellInfo, error) {
+	resp, err := m.execCommand(cmd, time.Second*3)
+	if err != nil {
+		return nil, err
+	}
+	var cells []CellInfo
+	for _, fields := range parseInfoLines(resp, prefix) {
+		var cell CellInfo
+		var ok bool
+		if serving {
+			cell, ok = parseMONSC(fields)
+		} else {
+			cell, ok = parseMONNC(fields)
+		}
+		if ok {
+			cells = append(cells, cell)
+		}
+	}
+	return cells, nil
+}
+
+// parseMONSC разбирает ^MONSC: <RAT>,<MCC>,<MNC>,... (уровни сигнала в дБм/дБ)
+func parseMONSC(f []Field) (CellInfo, bool) {
+	var cell CellInfo
+	switch strings.ToUpper(fieldAt(f, 0).Value) {
+	case "GSM":
+		// GSM,<MCC>,<MNC>,<BAND>,<ARFCN>,<BSIC>,<Cell_ID>,<LAC>,<RXLEV>,<RxQuality>,<TA>
+		cell = newCellInfo(true, AccessTechGSM)
+		cell.Band, cell.ARFCN = fieldAt(f, 3).Value, fieldAt(f, 4).IntOr(-1)
+		cell.PCI = fieldAt(f, 5).IntOr(-1)
+		cell.CellID, cell.AreaCode = cellHex(fieldAt(f, 6)), cellHex(fieldAt(f, 7))
+		cell.RSSI = cellFloat(fieldAt(f, 8), 1)
+	case "WCDMA":
+		// WCDMA,<MCC>,<MNC>,<ARFCN>,<PSC>,<Cell_ID>,<LAC>,<RSCP>,<RXLEV>,<EC/N0>,<DRX>,<URA>
+		cell = newCellInfo(true, AccessTechUMTS)
+		cell.ARFCN, cell.PCI = fieldAt(f, 3).IntOr(-1), fieldAt(f, 4).IntOr(-1)
+		cell.CellID, cell.AreaCode = cellHex(fieldAt(f, 5)), cellHex(fieldAt(f, 6))
+		cell.RSCP, cell.RSSI, cell.EcIo = cellFloat(fieldAt(f, 7), 1), cellFloat(fieldAt(f, 8), 1), cellFloat(fieldAt(f, 9), 1)
+	case "LTE":
+		// LTE,<MCC>,<MNC>,<ARFCN>,<Cell_ID>,<PCI>,<TAC>,<RSRP>,<RSRQ>,<RXLEV>
+		cell = newCellInfo(true, AccessTechLTE)
+		cell.ARFCN = fieldAt(f, 3).IntOr(-1)
+		cell.CellID, cell.PCI = cellHex(fieldAt(f, 4)), fieldAt(f, 5).IntOr(-1)
+		cell.AreaCode = cellHex(fieldAt(f, 6))
+		cell.RSRP, cell.RSRQ, cell.RSSI = cellFloat(fieldAt(f, 7), 1), cellFloat(fieldAt(f, 8), 1), cellFloat(fieldAt(f, 9), 1)
+	case "NR":
+		// NR,<MCC>,<MNC>,<ARFCN>,<SCS>,<Cell_ID>,<PCI>,<TAC>,<SSB_RSRP>,<SSB_RSRQ>,<SSB_SINR>
+		cell = newCellInfo(true, AccessTechNR5GC)
+		cell.ARFCN = fieldAt(f, 3).IntOr(-1)
+		cell.CellID, cell.PCI = cellHex(fieldAt(f, 5)), fieldAt(f, 6).IntOr(-1)
+		cell.AreaCode = cellHex(fieldAt(f, 7))
+		cell.RSRP, cell.RSRQ, cell.SINR = cellFloat(fieldAt(f, 8), 1), cellFloat(fieldAt(f, 9), 1), cellFloat(fieldAt(f, 10), 1)
+	default:
+		return CellInfo{}, false
+	}
+	cell.MCC, cell.MNC = fieldAt(f, 1).Value, fieldAt(f, 2).Value
+	return cell, true
+}
+
+// parseMONNC разбирает ^MONNC: <RAT>,... (уровни сигнала в дБм/дБ)
+func parseMONNC(f []Field) (CellInfo, bool) {
+	var cell CellInfo
+	switch strings.ToUpper(fieldAt(f, 0).Value) {
+	case "GSM":
+		// GSM,<BAND>,<ARFCN>,<BSIC>,<Cell_ID>,<LAC>,<RXLEV>
+		cell = newCellInfo(false, AccessTechGSM)
+		cell.Band, cell.ARFCN = fieldAt(f, 1).Value, fieldAt(f, 2).IntOr(-1)
+		cell.PCI = fieldAt(f, 3).IntOr(-1)
+		cell.CellID, cell.AreaCode = cellHex(fieldAt(f, 4)), cellHex(fieldAt(f, 5))
+		cell.RSSI = cellFloat(fieldAt(f, 6), 1)
+	case "WCDMA":
+		// WCDMA,<ARFCN>,<PSC>,<RSCP>,<EC/N0>
+		cell = newCellInfo(false, AccessTechUMTS)
+		cell.ARFCN, cell.PCI = fieldAt(f, 1).IntOr(-1), fieldAt(f, 2).IntOr(-1)
+		cell.RSCP, cell.EcIo = cellFloat(fieldAt(f, 3), 1), cellFloat(fieldAt(f, 4), 1)
+	case "LTE":
+		// LTE,<ARFCN>,<PCI>,<RSRP>,<RSRQ>,<RXLEV>
+		cell = newCellInfo(false, AccessTechLTE)
+		cell.ARFCN, cell.PCI = fieldAt(f, 1).IntOr(-1), fieldAt(f, 2).IntOr(-1)
+		cell.RSRP, cell.RSRQ, cell.RSSI = cellFloat(fieldAt(f, 3), 1), cellFloat(fieldAt(f, 4), 1), cellFloat(fieldAt(f, 5), 1)
+	case "NR":
+		// NR,<ARFCN>,<PCI>,<SSB_RSRP>,<SSB_RSRQ>,<SSB_SINR>
+		cell = newCellInfo(false, AccessTechNR5GC)
+		cell.ARFCN, cell.PCI = fieldAt(f, 1).IntOr(-1), fieldAt(f, 2).IntOr(-1)
+		cell.RSRP, cell.RSRQ, cell.SINR = cellFloat(fieldAt(f, 3), 1), cellFloat(fieldAt(f, 4), 1), cellFloat(fieldAt(f, 5), 1)
+	default:
+		// NONE
+		return CellInfo{}, false
+	}
+	return cell, true
+}
@@ -2,7 +2,6 @@ package gsm
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -17,20 +16,42 @@ const (
 	NetworkRegistrationDenied                      // 3 - регистрация отклонена
 	NetworkUnknown                                 // 4 - неизвестный статус
 	NetworkRegisteredRoaming                       // 5 - зарегистрирован в роуминге
+
+	NetworkRegisteredSMSOnlyHome             // 6 - зарегистрирован только для SMS, домашняя сеть (LTE)
+	NetworkRegisteredSMSOnlyRoaming          // 7 - зарегистрирован только для SMS, роуминг (LTE)
+	NetworkEmergencyOnly                     // 8 - только экстренные вызовы
+	NetworkRegisteredCSFBNotPreferredHome    // 9 - зарегистрирован, CSFB не предпочтителен, домашняя сеть
+	NetworkRegisteredCSFBNotPreferredRoaming // 10 - зарегистрирован, CSFB не предпочтителен, роуминг
 )
 
 // SignalQuality представляет качество сигнала
 type SignalQuality struct {
-	RSSI int // Received Signal Strength Indicator (0-31, 99=неизвестно). 0-9=слабый, 10-14=средний, 15-19=хороший, 20-31=отличный
-	BER  int // Bit Error Rate (0-7, 99=неизвестно). 0=без ошибок, 7=максимум ошибок
+	RSSI int `at:"0"` // Received Signal Strength Indicator (0-31, 99=неизвестно). 0-9=слабый, 10-14=средний, 15-19=хороший, 20-31=отличный
+	BER  int `at:"1"` // Bit Error Rate (0-7, 99=неизвестно). 0=без ошибок, 7=максимум ошибок
+}
+
+// ExtendedInfo сводная информация о модеме, SIM-карте и сети
+type ExtendedInfo struct {
+	Manufacturer  string        // Производитель
+	Model         string        // Модель
+	Revision      string        // Версия прошивки
+	IMEI          string        // IMEI
+	NetworkStatus NetworkStatus // Статус регистрации в сети
+	SignalRSSI    int           // Уровень сигнала (0-31, 99=неизвестно)
+	SignalBER     int           // Уровень ошибок (0-7, 99=неизвестно)
+	Operator      string        // Название текущего оператора
+	SIMStatus     PinStatus     // Статус SIM-карты
 }
 
 // OperatorInfo содержит информацию об операторе
 type OperatorInfo struct {
-	Status    string // "0"=неизвестно, "1"=доступен, "2"=текущий, "3"=запрещен
-	LongName  string // Полное название оператора (например: "MegaFon")
-	ShortName string // Короткое название оператора
-	Numeric   string // Числовой код оператора MCC+MNC (например: "25002" = Россия + МегаФон)
+	Status     string // "0"=неизвестно, "1"=доступен, "2"=текущий, "3"=запрещен
+	LongName   string // Полное название оператора (например: "MegaFon")
+	ShortName  string // Короткое название оператора
+	Numeric    string // Числовой код оператора MCC+MNC (например: "25002" = Россия + МегаФон)
+	Brand      string // Торговая марка из справочника операторов
+	Country    string // Страна по MCC
+	CountryISO string // Код страны ISO 3166-1 alpha-2 ("ru")
 }
 
 // ModemMode представляет режим работы модема
@@ -69,20 +90,50 @@ func parseATResponse(response, prefix string) (string, error) {
 	return "", fmt.Errorf("prefix %s not found in response", prefix)
 }
 
-// parseATResponseValues парсит ответ и разделяет по запятой
+// parseATResponseValues парсит ответ и разделяет значения (кавычки снимаются)
 func parseATResponseValues(response, prefix string) ([]string, error) {
 	data, err := parseATResponse(response, prefix)
 	if err != nil {
 		return nil, err
 	}
 
-	// Разделяем по запятой и убираем пробелы
-	parts := strings.Split(data, ",")
-	for i := range parts {
-		parts[i] = strings.TrimSpace(parts[i])
+	fields, err := ParseFields(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s response: %w", prefix, err)
+	}
+
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		if field.IsList {
+			values[i] = field.String()
+		} else {
+			values[i] = field.Value
+		}
+	}
+	return values, nil
+}
+
+// SetCharset устанавливает кодировку строк TE (AT+CSCS): "GSM", "IRA", "UCS2", "HEX", "8859-1"
+func (m *Modem) SetCharset(charset string) error {
+	if _, err := m.execCommand(fmt.Sprintf("AT+CSCS=\"%s\"", charset), time.Second); err != nil {
+		return fmt.Errorf("failed to set %s encoding: %w", charset, err)
 	}
+	m.rememberCharset(charset)
+	return nil
+}
+
+// Charset возвращает кодировку, установленную через SetCharset (пусто, если не устанавливалась)
+func (m *Modem) Charset() string {
+	m.optMu.Lock()
+	defer m.optMu.Unlock()
+	return m.charset
+}
 
-	return parts, nil
+// rememberCharset запоминает текущую кодировку для декодирования ответов
+func (m *Modem) rememberCharset(charset string) {
+	m.optMu.Lock()
+	defer m.optMu.Unlock()
+	m.charset = charset
 }
 
 // TestConnection проверяет связь с модемом
@@ -133,57 +184,36 @@ func (m *Modem) GetIMEI() (string, error) {
 	return extractResponse(resp), nil
 }
 
-// GetNetworkStatus возвращает статус регистрации в сети GSM
-func (m *Modem) GetNetworkStatus() (NetworkStatus, error) {
-	resp, err := m.SendCommand("AT+CREG?", time.Second*2)
+// GetIMSI возвращает IMSI SIM-карты (MCC + MNC + номер абонента)
+func (m *Modem) GetIMSI() (string, error) {
+	resp, err := m.execCommand("AT+CIMI", time.Second*2)
 	if err != nil {
-		return NetworkUnknown, fmt.Errorf("failed to get network status: %w", err)
+		return "", fmt.Errorf("failed to get IMSI: %w", err)
 	}
+	imsi := extractResponse(resp)
+	if len(imsi) < 6 {
+		return "", fmt.Errorf("unexpected IMSI response: %s", resp)
+	}
+	return imsi, nil
+}
 
-	// Парсим ответ вида +CREG: 0,1
-	lines := strings.Split(resp, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "+CREG:") {
-			// Убираем префикс "+CREG: "
-			data := strings.TrimSpace(line[6:])
-			// Разделяем по запятой
-			parts := strings.Split(data, ",")
-			if len(parts) >= 2 {
-				// Второй параметр - статус регистрации
-				status, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-				if err == nil {
-					return NetworkStatus(status), nil
-				}
-			}
-		}
+// GetNetworkStatus возвращает статус регистрации в сети: CS домен (AT+CREG?), а если модем
+// в нем не зарегистрирован - LTE/5G (AT+CEREG?, AT+C5GREG?)
+func (m *Modem) GetNetworkStatus() (NetworkStatus, error) {
+	status, err := m.bestRegistration(RegistrationCS, RegistrationEPS, Registration5GS)
+	if err != nil {
+		return NetworkUnknown, fmt.Errorf("failed to get network status: %w", err)
 	}
-	return NetworkUnknown, fmt.Errorf("unexpected response format: %s", resp)
+	return status, nil
 }
 
-// GetGPRSStatus возвращает статус регистрации в GPRS
+// GetGPRSStatus возвращает статус регистрации в пакетной сети (AT+CGREG?, затем AT+CEREG? и AT+C5GREG?)
 func (m *Modem) GetGPRSStatus() (NetworkStatus, error) {
-	resp, err := m.SendCommand("AT+CGREG?", time.Second*2)
+	status, err := m.bestRegistration(RegistrationGPRS, RegistrationEPS, Registration5GS)
 	if err != nil {
 		return NetworkUnknown, fmt.Errorf("failed to get GPRS status: %w", err)
 	}
-
-	// Парсим ответ вида +CGREG: 0,1
-	lines := strings.Split(resp, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "+CGREG:") {
-			data := strings.TrimSpace(line[7:])
-			parts := strings.Split(data, ",")
-			if len(parts) >= 2 {
-				status, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-				if err == nil {
-					return NetworkStatus(status), nil
-				}
-			}
-		}
-	}
-	return NetworkUnknown, fmt.Errorf("unexpected response format: %s", resp)
+	return status, nil
 }
 
 // GetCurrentOperator возвращает текущего оператора
@@ -193,30 +223,75 @@ func (m *Modem) GetCurrentOperator() (*OperatorInfo, error) {
 		return nil, fmt.Errorf("failed to get current operator: %w", err)
 	}
 
-	// Парсим ответ вида +COPS: 0,0,"MegaFon",2
-	lines := strings.Split(resp, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "+COPS:") {
-			// Убираем префикс "+COPS: "
-			data := strings.TrimSpace(line[6:])
-			// Разделяем по запятой, учитывая кавычки
-			parts := strings.Split(data, ",")
-			if len(parts) >= 3 {
-				operator := &OperatorInfo{
-					LongName: strings.Trim(parts[2], "\""),
-				}
-				// Если есть числовой код
-				if len(parts) >= 4 {
-					operator.Numeric = strings.Trim(parts[3], "\"")
-				}
-				return operator, nil
+	// Парсим ответ вида +COPS: <mode>,<format>,"<oper>",<AcT>
+	for _, fields := range parseInfoLines(resp, "+COPS:") {
+		if len(fields) < 3 {
+			continue
+		}
+		operator := &OperatorInfo{Status: "2"}
+		// Формат имени: 0=длинное, 1=короткое, 2=числовой код
+		switch fields[1].Value {
+		case "1":
+			operator.ShortName = fields[2].Value
+		case "2":
+			operator.Numeric = fields[2].Value
+		default:
+			operator.LongName = fields[2].Value
+		}
+		if operator.Numeric == "" {
+			// Страна и бренд определяются по MCC+MNC
+			if numeric, err := m.currentOperatorNumeric(); err == nil {
+				operator.Numeric = numeric
 			}
 		}
+		m.enrichOperator(operator)
+		return operator, nil
 	}
 	return nil, fmt.Errorf("no operator found or unexpected response: %s", resp)
 }
 
+// currentOperatorNumeric возвращает MCC+MNC текущего оператора. Если модем сообщает имя
+// в другом формате, формат AT+COPS временно переключается на числовой и затем восстанавливается.
+func (m *Modem) currentOperatorNumeric() (string, error) {
+	query := func() ([]Field, error) {
+		resp, err := m.execCommand("AT+COPS?", time.Second*3)
+		if err != nil {
+			return nil, err
+		}
+		// +COPS: <mode>[,<format>,"<oper>"[,<AcT>]]
+		for _, fields := range parseInfoLines(resp, "+COPS:") {
+			if len(fields) >= 3 {
+				return fields, nil
+			}
+		}
+		return nil, fmt.Errorf("not registered")
+	}
+
+	fields, err := query()
+	if err != nil {
+		return "", err
+	}
+	format := fields[1].Value
+	if format == "" {
+		format = "0"
+	}
+	if format == "2" {
+		return fields[2].Value, nil
+	}
+
+	if _, err := m.execCommand("AT+COPS=3,2", time.Second*2); err != nil {
+		return "", err
+	}
+	defer m.execCommand(fmt.Sprintf("AT+COPS=3,%s", format), time.Second*2)
+	if fields, err = query(); err != nil {
+		return "", err
+	}
+	if fieldAt(fields, 1).Value != "2" {
+		return "", fmt.Errorf("numeric operator format is not supported")
+	}
+	return fields[2].Value, nil
+}
+
 // ScanOperators ищет доступных операторов
 func (m *Modem) ScanOperators() ([]OperatorInfo, error) {
 	// Это может занять до 3 минут
@@ -227,27 +302,20 @@ func (m *Modem) ScanOperators() ([]OperatorInfo, error) {
 
 	var operators []OperatorInfo
 
-	// Парсим ответ вида +COPS: (2,"MegaFon","MegaFon","25002",0),...
-	if idx := strings.Index(resp, "+COPS:"); idx != -1 {
-		resp = resp[idx+6:]
-		// Удаляем лишние символы
-		resp = strings.TrimSpace(resp)
-		resp = strings.Trim(resp, "()")
-
-		// Разделяем операторов
-		opStrings := strings.Split(resp, "),(")
-		for _, opStr := range opStrings {
-			opStr = strings.Trim(opStr, "()")
-			parts := strings.Split(opStr, ",")
-			if len(parts) >= 4 {
-				op := OperatorInfo{
-					Status:    strings.TrimSpace(parts[0]),
-					LongName:  strings.Trim(parts[1], "\""),
-					ShortName: strings.Trim(parts[2], "\""),
-					Numeric:   strings.Trim(parts[3], "\""),
-				}
-				operators = append(operators, op)
+	// Парсим ответ вида +COPS: (2,"MegaFon","MegaFon","25002",2),(1,"MTS","MTS","25001",0),,(0-4),(0-2)
+	for _, fields := range parseInfoLines(resp, "+COPS:") {
+		for _, field := range fields {
+			// После списка операторов идут диапазоны поддерживаемых <mode> и <format>
+			if !field.IsList || len(field.List) < 4 || !field.List[1].Quoted {
+				continue
 			}
+			operators = append(operators, OperatorInfo{
+				Status:    field.List[0].Value,
+				LongName:  field.List[1].Value,
+				ShortName: field.List[2].Value,
+				Numeric:   field.List[3].Value,
+			})
+			m.enrichOperator(&operators[len(operators)-1])
 		}
 	}
 
@@ -275,33 +343,12 @@ func (m *Modem) SetAutomaticOperatorSelection() error {
 
 // GetSignalQuality возвращает качество сигнала
 func (m *Modem) GetSignalQuality() (*SignalQuality, error) {
-	resp, err := m.SendCommand("AT+CSQ", time.Second*2)
+	// Парсим ответ вида +CSQ: 20,0
+	signal, err := QueryTimeout[SignalQuality](m, "AT+CSQ", "+CSQ", time.Second*2)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get signal quality: %w", err)
 	}
-
-	// Парсим ответ вида +CSQ: 20,0
-	lines := strings.Split(resp, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "+CSQ:") {
-			// Убираем префикс "+CSQ: "
-			data := strings.TrimSpace(line[5:])
-			// Разделяем по запятой
-			parts := strings.Split(data, ",")
-			if len(parts) >= 2 {
-				rssi, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
-				ber, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
-				if err1 == nil && err2 == nil {
-					return &SignalQuality{
-						RSSI: rssi,
-						BER:  ber,
-					}, nil
-				}
-			}
-		}
-	}
-	return nil, fmt.Errorf("unexpected response format: %s", resp)
+	return &signal, nil
 }
 
 // GetSIMStatus проверяет статус SIM-карты
@@ -312,14 +359,8 @@ func (m *Modem) GetSIMStatus() (PinStatus, error) {
 	}
 
 	// Парсим ответ вида +CPIN: READY
-	lines := strings.Split(resp, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "+CPIN:") {
-			// Убираем префикс "+CPIN: "
-			status := strings.TrimSpace(line[6:])
-			return PinStatus(status), nil
-		}
+	for _, fields := range parseInfoLines(resp, "+CPIN:") {
+		return PinStatus(fieldAt(fields, 0).Value), nil
 	}
 	return "", fmt.Errorf("unexpected response format: %s", resp)
 }
@@ -334,6 +375,16 @@ func (m *Modem) EnterPIN(pin string) error {
 	return nil
 }
 
+// UnblockPIN разблокирует SIM-карту PUK-кодом и устанавливает новый PIN
+func (m *Modem) UnblockPIN(puk, newPIN string) error {
+	cmd := fmt.Sprintf("AT+CPIN=\"%s\",\"%s\"", puk, newPIN)
+	_, err := m.execCommand(cmd, time.Second*5)
+	if err != nil {
+		return fmt.Errorf("failed to unblock PIN: %w", err)
+	}
+	return nil
+}
+
 // GetSIMNumber пытается получить номер телефона SIM-карты
 func (m *Modem) GetSIMNumber() (string, error) {
 	resp, err := m.SendCommand("AT+CNUM", time.Second*2)
@@ -342,16 +393,9 @@ func (m *Modem) GetSIMNumber() (string, error) {
 	}
 
 	// Парсим ответ вида +CNUM: "","79991234567",145
-	if strings.Contains(resp, "+CNUM:") {
-		parts := strings.Split(resp, ":")
-		if len(parts) >= 2 {
-			values := strings.Split(strings.TrimSpace(parts[1]), ",")
-			if len(values) >= 2 {
-				number := strings.Trim(values[1], "\"")
-				if number != "" {
-					return number, nil
-				}
-			}
+	for _, fields := range parseInfoLines(resp, "+CNUM:") {
+		if number := fieldAt(fields, 1).Value; number != "" {
+			return number, nil
 		}
 	}
 	return "", fmt.Errorf("phone number not stored on SIM")
@@ -395,22 +439,16 @@ func (m *Modem) GetModemMode() (ModemMode, error) {
 	}
 
 	// Парсим ответ вида +CFUN: 1
-	lines := strings.Split(resp, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "+CFUN:") {
-			// Убираем префикс "+CFUN: "
-			modeStr := strings.TrimSpace(line[6:])
-			mode, err := strconv.Atoi(modeStr)
-			if err == nil {
-				switch mode {
-				case 0:
-					return ModemModeLowPower, nil
-				case 1:
-					return ModemModeOnline, nil
-				case 4:
-					return ModemModeOffline, nil
-				}
+	for _, fields := range parseInfoLines(resp, "+CFUN:") {
+		mode, err := fieldAt(fields, 0).Int()
+		if err == nil {
+			switch mode {
+			case 0:
+				return ModemModeLowPower, nil
+			case 1:
+				return ModemModeOnline, nil
+			case 4:
+				return ModemModeOffline, nil
 			}
 		}
 	}
@@ -418,46 +456,56 @@ func (m *Modem) GetModemMode() (ModemMode, error) {
 }
 
 // GetExtendedInfo возвращает расширенную информацию о модеме
-func (m *Modem) GetExtendedInfo() (map[string]string, error) {
-	info := make(map[string]string)
+// (недоступные значения остаются пустыми или "неизвестными")
+func (m *Modem) GetExtendedInfo() (*ExtendedInfo, error) {
+	info := &ExtendedInfo{
+		NetworkStatus: NetworkUnknown,
+		SignalRSSI:    99,
+		SignalBER:     99,
+	}
 
 	// Получаем все доступные данные
 	if manufacturer, err := m.GetManufacturer(); err == nil {
-		info["Manufacturer"] = manufacturer
+		info.Manufacturer = manufacturer
 	}
 
 	if model, err := m.GetModel(); err == nil {
-		info["Model"] = model
+		info.Model = model
 	}
 
 	if revision, err := m.GetRevision(); err == nil {
-		info["Revision"] = revision
+		info.Revision = revision
 	}
 
 	if imei, err := m.GetIMEI(); err == nil {
-		info["IMEI"] = imei
+		info.IMEI = imei
 	}
 
 	if status, err := m.GetNetworkStatus(); err == nil {
-		info["NetworkStatus"] = networkStatusToString(status)
+		info.NetworkStatus = status
 	}
 
 	if signal, err := m.GetSignalQuality(); err == nil {
-		info["SignalRSSI"] = fmt.Sprintf("%d", signal.RSSI)
-		info["SignalBER"] = fmt.Sprintf("%d", signal.BER)
+		info.SignalRSSI = signal.RSSI
+		info.SignalBER = signal.BER
 	}
 
 	if operator, err := m.GetCurrentOperator(); err == nil {
-		info["Operator"] = operator.LongName
+		info.Operator = operator.LongName
 	}
 
 	if simStatus, err := m.GetSIMStatus(); err == nil {
-		info["SIMStatus"] = string(simStatus)
+		info.SIMStatus = simStatus
 	}
 
 	return info, nil
 }
 
+// String возвращает текстовое описание статуса регистрации
+func (s NetworkStatus) String() string {
+	return networkStatusToString(s)
+}
+
 // networkStatusToString преобразует NetworkStatus в строку
 func networkStatusToString(status NetworkStatus) string {
 	switch status {
@@ -473,6 +521,16 @@ func networkStatusToString(status NetworkStatus) string {
 		return "Unknown"
 	case NetworkRegisteredRoaming:
 		return "Registered (roaming)"
+	case NetworkRegisteredSMSOnlyHome:
+		return "Registered for SMS only (home)"
+	case NetworkRegisteredSMSOnlyRoaming:
+		return "Registered for SMS only (roaming)"
+	case NetworkEmergencyOnly:
+		return "Emergency services only"
+	case NetworkRegisteredCSFBNotPreferredHome:
+		return "Registered, CSFB not preferred (home)"
+	case NetworkRegisteredCSFBNotPreferredRoaming:
+		return "Registered, CSFB not preferred (roaming)"
 	default:
 		return "Unknown"
 	}
@@ -0,0 +1,353 @@
+package gsm
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultConnectivityHold = 10 * time.Second // время, в течение которого новое состояние должно держаться
+	defaultConnectivityPoll = time.Minute      // период опроса регистрации на случай пропущенных URC
+	defaultMaxOutages       = 100              // сколько последних отключений хранить
+)
+
+// ConnectivityState состояние подключения к сети
+type ConnectivityState int
+
+const (
+	ConnectivityUnknown ConnectivityState = iota // Нет данных
+	ConnectivityOffline                          // Нет регистрации или нет сигнала
+	ConnectivityOnline                           // Зарегистрирован в домашней сети
+	ConnectivityRoaming                          // Зарегистрирован в роуминге
+)
+
+// String возвращает название состояния
+func (s ConnectivityState) String() string {
+	switch s {
+	case ConnectivityOffline:
+		return "offline"
+	case ConnectivityOnline:
+		return "online"
+	case ConnectivityRoaming:
+		return "roaming"
+	default:
+		return "unknown"
+	}
+}
+
+// Connected проверяет, что модем в сети (домашней или в роуминге)
+func (s ConnectivityState) Connected() bool {
+	return s == ConnectivityOnline || s == ConnectivityRoaming
+}
+
+// Outage период отсутствия связи
+type Outage struct {
+	Start time.Time // Потеря связи
+	End   time.Time // Восстановление связи (нулевое значение - отключение продолжается)
+}
+
+// Duration возвращает длительность отключения (для текущего - до настоящего момента)
+func (o Outage) Duration() time.Duration {
+	if o.End.IsZero() {
+		return time.Since(o.Start)
+	}
+	return o.End.Sub(o.Start)
+}
+
+// ConnectivityConfig настройки монитора подключения
+type ConnectivityConfig struct {
+	HoldTime     time.Duration // Сколько новое состояние должно держаться до события (по умолчанию 10 секунд)
+	PollInterval time.Duration // Период опроса регистрации (по умолчанию 1 минута, <0 - не опрашивать)
+	MaxOutages   int           // Сколько последних отключений хранить (по умолчанию 100)
+}
+
+// ConnectivityMonitor отслеживает подключение к сети с подавлением кратковременных изменений
+// и ведет историю отключений. Данные берутся из URC регистрации и уровня сигнала
+// (требуется StartEventListener) и периодического опроса.
+type ConnectivityMonitor struct {
+	modem  *Modem
+	config ConnectivityConfig
+	stop   chan struct{}
+
+	mu           sync.Mutex
+	started      time.Time
+	domains      map[RegistrationDomain]NetworkStatus
+	signalLost   bool // Последний известный уровень сигнала - 0 делений
+	state        ConnectivityState
+	since        time.Time
+	pending      ConnectivityState
+	pendingSince time.Time
+	timer        *time.Timer
+	outages      []Outage
+	current      *Outage
+	downtime     time.Duration
+}
+
+// ErrMonitorRunning возвращается при повторном запуске монитора подключения
+var ErrMonitorRunning = errors.New("connectivity monitor is already running")
+
+// StartConnectivityMonitor запускает монитор подключения
+func (m *Modem) StartConnectivityMonitor(config ConnectivityConfig) (*ConnectivityMonitor, error) {
+	if config.HoldTime <= 0 {
+		config.HoldTime = defaultConnectivityHold
+	}
+	if config.PollInterval == 0 {
+		config.PollInterval = defaultConnectivityPoll
+	}
+	if config.MaxOutages <= 0 {
+		config.MaxOutages = defaultMaxOutages
+	}
+
+	cm := &ConnectivityMonitor{
+		modem:   m,
+		config:  config,
+		stop:    make(chan struct{}),
+		started: time.Now(),
+		domains: make(map[RegistrationDomain]NetworkStatus),
+		pending: ConnectivityUnknown,
+	}
+
+	m.optMu.Lock()
+	if m.connectivity != nil {
+		m.optMu.Unlock()
+		return nil, ErrMonitorRunning
+	}
+	m.connectivity = cm
+	m.optMu.Unlock()
+
+	// Начальное состояние фиксируется сразу, без ожидания
+	cm.poll(true)
+	if config.PollInterval > 0 {
+		go cm.pollLoop()
+	}
+	return cm, nil
+}
+
+// ConnectivityMonitor возвращает запущенный монитор подключения или nil
+func (m *Modem) ConnectivityMonitor() *ConnectivityMonitor {
+	m.optMu.Lock()
+	defer m.optMu.Unlock()
+	return m.connectivity
+}
+
+// Stop останавливает монитор
+func (cm *ConnectivityMonitor) Stop() {
+	m := cm.modem
+	m.optMu.Lock()
+	if m.connectivity == cm {
+		m.connectivity = nil
+	}
+	m.optMu.Unlock()
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	select {
+	case <-cm.stop:
+		return
+	default:
+	}
+	close(cm.stop)
+	if cm.timer != nil {
+		cm.timer.Stop()
+	}
+}
+
+// State возвращает текущее (подтвержденное) состояние и время перехода в него
+func (cm *ConnectivityMonitor) State() (ConnectivityState, time.Time) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.state, cm.since
+}
+
+// Outages возвращает историю отключений, включая текущее (с нулевым End)
+func (cm *ConnectivityMonitor) Outages() []Outage {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	result := append([]Outage(nil), cm.outages...)
+	if cm.current != nil {
+		result = append(result, *cm.current)
+	}
+	return result
+}
+
+// Downtime возвращает суммарное время без связи с момента запуска монитора
+func (cm *ConnectivityMonitor) Downtime() time.Duration {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.downtimeLocked()
+}
+
+// Availability возвращает долю времени в сети с момента запуска монитора (0..1)
+func (cm *ConnectivityMonitor) Availability() float64 {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	elapsed := time.Since(cm.started)
+	if elapsed <= 0 {
+		return 1
+	}
+	return math.Max(0, 1-float64(cm.downtimeLocked())/float64(elapsed))
+}
+
+// downtimeLocked суммирует завершенные отключения и текущее
+func (cm *ConnectivityMonitor) downtimeLocked() time.Duration {
+	total := cm.downtime
+	if cm.current != nil {
+		total += cm.current.Duration()
+	}
+	return total
+}
+
+// pollLoop периодически опрашивает регистрацию
+func (cm *ConnectivityMonitor) pollLoop() {
+	ticker := time.NewTicker(cm.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cm.stop:
+			return
+		case <-ticker.C:
+			cm.poll(false)
+		}
+	}
+}
+
+// poll запрашивает регистрацию во всех доменах
+func (cm *ConnectivityMonitor) poll(initial bool) {
+	registrations, err := cm.modem.GetRegistrations()
+	if err != nil {
+		return
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	for _, reg := range registrations {
+		cm.domains[reg.Domain] = reg.Status
+	}
+	if initial {
+		cm.commitLocked(cm.rawStateLocked(), time.Now())
+		return
+	}
+	cm.evaluateLocked()
+}
+
+// observeRegistration учитывает URC регистрации (вызывается обработчиком событий)
+func (cm *ConnectivityMonitor) observeRegistration(info *RegistrationInfo) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.domains[info.Domain] = info.Status
+	cm.evaluateLocked()
+}
+
+// observeSignal учитывает изменение уровня сигнала. Неизвестный уровень (NaN, например
+// +CSQ: 99) не несет информации и пропускается. Пропадание сигнала само по себе не переводит
+// монитор в Offline: оно лишь запускает внеочередной опрос регистрации, и отключение
+// фиксируется, только если регистрация тоже потеряна.
+func (cm *ConnectivityMonitor) observeSignal(level float64, bars int) {
+	if math.IsNaN(level) {
+		return
+	}
+	cm.mu.Lock()
+	lost := bars == 0 && !cm.signalLost
+	cm.signalLost = bars == 0
+	cm.mu.Unlock()
+	if !lost {
+		return
+	}
+	select {
+	case <-cm.stop:
+	default:
+		go cm.poll(false)
+	}
+}
+
+// rawStateLocked вычисляет состояние по регистрации без подавления дребезга
+func (cm *ConnectivityMonitor) rawStateLocked() ConnectivityState {
+	if len(cm.domains) == 0 {
+		return ConnectivityUnknown
+	}
+	state := ConnectivityOffline
+	for _, status := range cm.domains {
+		switch status {
+		case NetworkRegisteredHome, NetworkRegisteredSMSOnlyHome, NetworkRegisteredCSFBNotPreferredHome:
+			return ConnectivityOnline
+		case NetworkRegisteredRoaming, NetworkRegisteredSMSOnlyRoaming, NetworkRegisteredCSFBNotPreferredRoaming:
+			state = ConnectivityRoaming
+		}
+	}
+	return state
+}
+
+// evaluateLocked запускает или отменяет ожидание перехода в новое состояние
+func (cm *ConnectivityMonitor) evaluateLocked() {
+	raw := cm.rawStateLocked()
+	if raw == cm.pending {
+		return
+	}
+
+	if cm.timer != nil {
+		cm.timer.Stop()
+		cm.timer = nil
+	}
+	cm.pending = raw
+	cm.pendingSince = time.Now()
+	if raw == cm.state {
+		// Кратковременное изменение вернулось к прежнему состоянию
+		return
+	}
+
+	target := raw
+	cm.timer = time.AfterFunc(cm.config.HoldTime, func() {
+		cm.mu.Lock()
+		defer cm.mu.Unlock()
+		if cm.pending == target && cm.state != target {
+			select {
+			case <-cm.stop:
+				return
+			default:
+			}
+			cm.commitLocked(target, cm.pendingSince)
+		}
+	})
+}
+
+// commitLocked фиксирует переход, обновляет историю отключений и отправляет событие
+func (cm *ConnectivityMonitor) commitLocked(state ConnectivityState, at time.Time) {
+	previous := cm.state
+	cm.state = state
+	cm.since = at
+	cm.pending = state
+
+	data := map[string]interface{}{
+		"state":    state,
+		"previous": previous,
+		"since":    at,
+	}
+
+	// Время отключения считается с момента фактической потери связи, а не после ожидания
+	switch {
+	case !state.Connected() && state != ConnectivityUnknown && cm.current == nil:
+		cm.current = &Outage{Start: at}
+	case state.Connected() && cm.current != nil:
+		cm.current.End = at
+		outage := *cm.current
+		cm.downtime += outage.Duration()
+		cm.outages = append(cm.outages, outage)
+		if len(cm.outages) > cm.config.MaxOutages {
+			cm.outages = cm.outages[len(cm.outages)-cm.config.MaxOutages:]
+		}
+		cm.current = nil
+		data["outage"] = outage
+	}
+	data["downtime"] = cm.downtimeLocked()
+
+	if previous == state {
+		return
+	}
+	cm.modem.emitEvent(Event{
+		Type:      EventConnectivity,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+}
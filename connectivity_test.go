@@ -0,0 +1,43 @@
+package gsm
+
+import (
+	"math"
+	"testing"
+)
+
+// newTestConnectivity создает монитор без модема и фоновых опросов
+func newTestConnectivity(statuses map[RegistrationDomain]NetworkStatus) *ConnectivityMonitor {
+	return &ConnectivityMonitor{
+		stop:    make(chan struct{}),
+		domains: statuses,
+		pending: ConnectivityUnknown,
+	}
+}
+
+func TestConnectivityUnknownSignal(t *testing.T) {
+	// +CSQ: 99 / ^RSSI: 99 при регистрации в сети не переводит монитор в Offline
+	cm := newTestConnectivity(map[RegistrationDomain]NetworkStatus{RegistrationEPS: NetworkRegisteredHome})
+	cm.observeSignal(math.NaN(), 0)
+	if cm.signalLost {
+		t.Error("unknown signal level marked as lost")
+	}
+	if state := cm.rawStateLocked(); state != ConnectivityOnline {
+		t.Errorf("state = %v, want online", state)
+	}
+}
+
+func TestConnectivitySignalLost(t *testing.T) {
+	domain := RegistrationEPS
+	cm := newTestConnectivity(map[RegistrationDomain]NetworkStatus{domain: NetworkRegisteredRoaming})
+	cm.signalLost = true
+	if state := cm.rawStateLocked(); state != ConnectivityRoaming {
+		t.Errorf("state with registration = %v, want roaming", state)
+	}
+	cm.domains[domain] = NetworkSearching
+	if state := cm.rawStateLocked(); state != ConnectivityOffline {
+		t.Errorf("state without registration = %v, want offline", state)
+	}
+	if state := newTestConnectivity(map[RegistrationDomain]NetworkStatus{}).rawStateLocked(); state != ConnectivityUnknown {
+		t.Errorf("state without data = %v, want unknown", state)
+	}
+}
@@ -0,0 +1,311 @@
+package gsm
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const dataUsageTimeout = 5 * time.Second
+
+// Источники счетчиков трафика (DataUsage.Source)
+const (
+	DataUsageHuawei  = "DSFLOWQRY" // Huawei AT^DSFLOWQRY: текущая сессия и накопленные значения
+	DataUsageQuectel = "QGDCNT"    // Quectel AT+QGDCNT: байты с последнего сброса
+	DataUsageSIMCom  = "CGDCNT"    // SIMCom AT+CGDCNT: байты по контекстам с последнего сброса
+	DataUsageGeneric = "CGPADDR"   // Только состояние контекста по AT+CGPADDR, без счетчиков
+)
+
+// DataUsage статистика сессии передачи данных
+type DataUsage struct {
+	Source        string        // Команда-источник, см. DataUsage*
+	CID           int           // Идентификатор PDP контекста
+	Active        bool          // Контексту назначен адрес
+	Counters      bool          // Модем сообщает счетчики байт (false для DataUsageGeneric)
+	Shared        bool          // Счетчики общие для всех контекстов (Huawei, Quectel), а не только CID
+	Duration      time.Duration // Длительность текущей сессии
+	BytesSent     uint64        // Отправлено: в текущей сессии (Huawei) или с последнего сброса
+	BytesReceived uint64        // Получено: в текущей сессии (Huawei) или с последнего сброса
+	TotalDuration time.Duration // Huawei: суммарное время сессий с последнего сброса
+	TotalSent     uint64        // Отправлено с последнего сброса (для Huawei - за все сессии)
+	TotalReceived uint64        // Получено с последнего сброса (для Huawei - за все сессии)
+	TxRate        float64       // Скорость передачи, байт/с (по разнице с предыдущим запросом)
+	RxRate        float64       // Скорость приема, байт/с (по разнице с предыдущим запросом)
+	Timestamp     time.Time     // Время запроса
+}
+
+// usageTracker хранит время начала сессий и предыдущие значения счетчиков для расчета скорости
+type usageTracker struct {
+	mu      sync.Mutex
+	started map[int]time.Time // Момент, когда контекст впервые замечен активным
+	last    map[int]DataUsage
+}
+
+// newUsageTracker создает пустой трекер
+func newUsageTracker() *usageTracker {
+	return &usageTracker{started: make(map[int]time.Time), last: make(map[int]DataUsage)}
+}
+
+// GetDataUsage возвращает счетчики трафика и длительность сессии контекста cid.
+// Используется AT^DSFLOWQRY (Huawei), AT+QGDCNT (Quectel) или AT+CGDCNT (SIMCom);
+// для остальных модемов сообщаются только состояние и длительность по AT+CGPADDR.
+// Счетчики Huawei и Quectel общие для всех контекстов: CID в ответе указывает запрошенный
+// контекст, а не источник байт, такие ответы помечаются DataUsage.Shared. Длительность без AT^DSFLOWQRY
+// отсчитывается с момента, когда библиотека впервые увидела контекст активным.
+// Скорость рассчитывается по разнице с предыдущим вызовом и при первом вызове равна нулю.
+func (m *Modem) GetDataUsage(cid int) (*DataUsage, error) {
+	addresses, err := m.GetPDPAddresses(cid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data usage: %w", err)
+	}
+	usage := &DataUsage{
+		Source:    DataUsageGeneric,
+		CID:       cid,
+		Active:    len(addresses) > 0,
+		Timestamp: time.Now(),
+	}
+
+	switch m.Vendor() {
+	case VendorHuawei:
+		err = m.huaweiDataUsage(usage)
+	case VendorQuectel:
+		err = m.quectelDataUsage(usage)
+	case VendorSIMCom:
+		err = m.simcomDataUsage(usage)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get data usage: %w", err)
+	}
+
+	m.usage.update(usage)
+	return usage, nil
+}
+
+// update дополняет статистику длительностью сессии и скоростью
+func (t *usageTracker) update(usage *DataUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !usage.Active {
+		delete(t.started, usage.CID)
+	} else if _, ok := t.started[usage.CID]; !ok {
+		t.started[usage.CID] = usage.Timestamp
+	}
+	if usage.Active && usage.Source != DataUsageHuawei {
+		usage.Duration = usage.Timestamp.Sub(t.started[usage.CID])
+	}
+
+	if !usage.Counters {
+		return
+	}
+	if prev, ok := t.last[usage.CID]; ok && prev.Source == usage.Source {
+		elapsed := usage.Timestamp.Sub(prev.Timestamp).Seconds()
+		// Уменьшение счетчика означает сброс, скорость в этом интервале неизвестна
+		if elapsed > 0 && usage.TotalSent >= prev.TotalSent && usage.TotalReceived >= prev.TotalReceived {
+			usage.TxRate = float64(usage.TotalSent-prev.TotalSent) / elapsed
+			usage.RxRate = float64(usage.TotalReceived-prev.TotalReceived) / elapsed
+		}
+	}
+	t.last[usage.CID] = *usage
+}
+
+// reset забывает предыдущие значения счетчиков и перезапускает отсчет длительности сессий
+func (t *usageTracker) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for cid := range t.started {
+		t.started[cid] = now
+	}
+	t.last = make(map[int]DataUsage)
+}
+
+// huaweiDataUsage читает ^DSFLOWQRY: <last_ds_time>,<last_tx_flow>,<last_rx_flow>,
+// <total_ds_time>,<total_tx_flow>,<total_rx_flow> (все значения в hex, время в секундах)
+func (m *Modem) huaweiDataUsage(usage *DataUsage) error {
+	resp, err := m.execCommand("AT^DSFLOWQRY", dataUsageTimeout)
+	if err != nil {
+		return err
+	}
+	lines := parseInfoLines(resp, "^DSFLOWQRY:")
+	if len(lines) == 0 || len(lines[0]) < 6 {
+		return fmt.Errorf("%w: ^DSFLOWQRY", ErrNoResponse)
+	}
+	var values [6]uint64
+	for i := range values {
+		v, err := lines[0][i].Hex()
+		if err != nil {
+			return fmt.Errorf("invalid ^DSFLOWQRY value %q: %w", lines[0][i].Value, err)
+		}
+		values[i] = uint64(v)
+	}
+	usage.Source = DataUsageHuawei
+	usage.Counters = true
+	usage.Shared = true
+	usage.Duration = time.Duration(values[0]) * time.Second
+	usage.BytesSent, usage.BytesReceived = values[1], values[2]
+	usage.TotalDuration = time.Duration(values[3]) * time.Second
+	usage.TotalSent, usage.TotalReceived = values[4], values[5]
+	return nil
+}
+
+// quectelDataUsage читает +QGDCNT: <bytes_sent>,<bytes_recv>
+func (m *Modem) quectelDataUsage(usage *DataUsage) error {
+	resp, err := m.execCommand("AT+QGDCNT?", dataUsageTimeout)
+	if err != nil {
+		return err
+	}
+	lines := parseInfoLines(resp, "+QGDCNT:")
+	if len(lines) == 0 || len(lines[0]) < 2 {
+		return fmt.Errorf("%w: +QGDCNT", ErrNoResponse)
+	}
+	sent, err := parseCounter(lines[0][0])
+	if err != nil {
+		return err
+	}
+	received, err := parseCounter(lines[0][1])
+	if err != nil {
+		return err
+	}
+	usage.Source = DataUsageQuectel
+	usage.Shared = true
+	usage.setCounters(sent, received)
+	return nil
+}
+
+// simcomDataUsage читает +CGDCNT: <cid>,<bytes_sent>,<bytes_recv> (строка на контекст)
+func (m *Modem) simcomDataUsage(usage *DataUsage) error {
+	resp, err := m.execCommand("AT+CGDCNT?", dataUsageTimeout)
+	if err != nil {
+		return err
+	}
+	for _, fields := range parseInfoLines(resp, "+CGDCNT:") {
+		if len(fields) < 3 || fieldAt(fields, 0).IntOr(-1) != usage.CID {
+			continue
+		}
+		sent, err := parseCounter(fields[1])
+		if err != nil {
+			return err
+		}
+		received, err := parseCounter(fields[2])
+		if err != nil {
+			return err
+		}
+		usage.Source = DataUsageSIMCom
+		usage.setCounters(sent, received)
+		return nil
+	}
+	// Контекст еще не передавал данные
+	usage.Source = DataUsageSIMCom
+	usage.setCounters(0, 0)
+	return nil
+}
+
+// setCounters задает счетчики модемов, которые считают байты с последнего сброса
+func (u *DataUsage) setCounters(sent, received uint64) {
+	u.Counters = true
+	u.BytesSent, u.BytesReceived = sent, received
+	u.TotalSent, u.TotalReceived = sent, received
+}
+
+// parseCounter разбирает десятичный счетчик байт
+func parseCounter(field Field) (uint64, error) {
+	v, err := strconv.ParseUint(field.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid counter %q: %w", field.Value, err)
+	}
+	return v, nil
+}
+
+// ResetDataUsage обнуляет счетчики трафика модема: AT^DSFLOWCLR (Huawei), AT+QGDCNT=0 (Quectel)
+// или AT+CGDCNT=0 (SIMCom). Для остальных модемов перезапускается только отсчет длительности сессии.
+func (m *Modem) ResetDataUsage() error {
+	var cmd string
+	switch m.Vendor() {
+	case VendorHuawei:
+		cmd = "AT^DSFLOWCLR"
+	case VendorQuectel:
+		cmd = "AT+QGDCNT=0"
+	case VendorSIMCom:
+		cmd = "AT+CGDCNT=0"
+	}
+	if cmd != "" {
+		if _, err := m.execCommand(cmd, dataUsageTimeout); err != nil {
+			return fmt.Errorf("failed to reset data usage: %w", err)
+		}
+	}
+	m.usage.reset()
+	return nil
+}
+
+// DataTotals накопленный трафик SIM-карты
+type DataTotals struct {
+	Sent     uint64        // Отправлено байт
+	Received uint64        // Получено байт
+	Duration time.Duration // Время в активной сессии
+}
+
+// DataUsageMeter накапливает трафик по SIM-картам (ключ - обычно ICCID, см. Modem.GetICCID).
+// Счетчики модема обнуляются при перезагрузке и ResetDataUsage, поэтому метр суммирует
+// приращения между вызовами Update, а уменьшение счетчика считает сбросом. Один метр
+// обслуживает один модем; накопленные значения сохраняет и восстанавливает приложение.
+type DataUsageMeter struct {
+	mu     sync.Mutex
+	totals map[string]DataTotals
+	last   *DataUsage
+}
+
+// NewDataUsageMeter создает пустой метр
+func NewDataUsageMeter() *DataUsageMeter {
+	return &DataUsageMeter{totals: make(map[string]DataTotals)}
+}
+
+// Update добавляет к SIM-карте sim трафик с предыдущего вызова и возвращает ее итог.
+// Трафик интервала, в котором сменилась SIM-карта, относится к новой карте.
+func (u *DataUsageMeter) Update(sim string, usage *DataUsage) DataTotals {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	totals := u.totals[sim]
+	if prev := u.last; prev != nil && prev.Counters && usage.Counters && prev.Source == usage.Source {
+		totals.Sent += counterDelta(prev.TotalSent, usage.TotalSent)
+		totals.Received += counterDelta(prev.TotalReceived, usage.TotalReceived)
+	}
+	if prev := u.last; prev != nil && prev.Active && usage.Active && usage.Timestamp.After(prev.Timestamp) {
+		totals.Duration += usage.Timestamp.Sub(prev.Timestamp)
+	}
+	u.totals[sim] = totals
+	last := *usage
+	u.last = &last
+	return totals
+}
+
+// counterDelta возвращает приращение счетчика; после сброса счет идет от нуля
+func counterDelta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// Totals возвращает накопленный трафик SIM-карты
+func (u *DataUsageMeter) Totals(sim string) DataTotals {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.totals[sim]
+}
+
+// Restore задает накопленный трафик SIM-карты (например, после перезапуска приложения)
+func (u *DataUsageMeter) Restore(sim string, totals DataTotals) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.totals[sim] = totals
+}
+
+// Reset обнуляет накопленный трафик SIM-карты (например, в начале расчетного периода)
+func (u *DataUsageMeter) Reset(sim string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	delete(u.totals, sim)
+}
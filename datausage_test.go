@@ -0,0 +1,168 @@
+package gsm
+
+import (
+	"testing"
+	"time"
+)
+
+// usageAt снимок счетчиков с модема, считающего байты с последнего сброса
+func usageAt(start time.Time, sec int, sent, received uint64) *DataUsage {
+	u := &DataUsage{Source: DataUsageQuectel, CID: 1, Active: true, Shared: true, Timestamp: start.Add(time.Duration(sec) * time.Second)}
+	u.setCounters(sent, received)
+	return u
+}
+
+func TestCounterDelta(t *testing.T) {
+	tests := []struct {
+		prev, cur, want uint64
+	}{
+		{0, 0, 0},
+		{100, 150, 50},
+		{100, 100, 0},
+		// Сброс: счет идет от нуля
+		{1000, 30, 30},
+		{1000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := counterDelta(tt.prev, tt.cur); got != tt.want {
+			t.Errorf("counterDelta(%d, %d) = %d, want %d", tt.prev, tt.cur, got, tt.want)
+		}
+	}
+}
+
+func TestDataUsageMeter(t *testing.T) {
+	start := time.Now()
+	meter := NewDataUsageMeter()
+	meter.Restore("sim1", DataTotals{Sent: 10, Received: 20, Duration: time.Hour})
+
+	// Первый снимок только запоминается
+	if got := meter.Update("sim1", usageAt(start, 0, 500, 700)); got != (DataTotals{10, 20, time.Hour}) {
+		t.Errorf("first Update = %+v", got)
+	}
+	got := meter.Update("sim1", usageAt(start, 60, 600, 1000))
+	if want := (DataTotals{110, 320, time.Hour + time.Minute}); got != want {
+		t.Errorf("Update = %+v, want %+v", got, want)
+	}
+
+	// Модем перезагрузился: счетчики начались с нуля
+	got = meter.Update("sim1", usageAt(start, 120, 40, 50))
+	if want := (DataTotals{150, 370, time.Hour + 2*time.Minute}); got != want {
+		t.Errorf("Update after counter reset = %+v, want %+v", got, want)
+	}
+
+	// Смена SIM-карты: трафик интервала относится к новой карте
+	got = meter.Update("sim2", usageAt(start, 180, 140, 80))
+	if want := (DataTotals{100, 30, time.Minute}); got != want {
+		t.Errorf("Update for new SIM = %+v, want %+v", got, want)
+	}
+	if got := meter.Totals("sim1"); got != (DataTotals{150, 370, time.Hour + 2*time.Minute}) {
+		t.Errorf("Totals(sim1) after SIM change = %+v", got)
+	}
+
+	// Смена источника: счетчики несравнимы, байты интервала не учитываются
+	other := usageAt(start, 240, 5000, 5000)
+	other.Source, other.Shared = DataUsageSIMCom, false
+	got = meter.Update("sim2", other)
+	if want := (DataTotals{100, 30, 2 * time.Minute}); got != want {
+		t.Errorf("Update after source change = %+v, want %+v", got, want)
+	}
+
+	// Без счетчиков и в неактивной сессии растет только то, что известно
+	generic := &DataUsage{Source: DataUsageSIMCom, CID: 1, Active: true, Timestamp: start.Add(300 * time.Second)}
+	got = meter.Update("sim2", generic)
+	if want := (DataTotals{100, 30, 3 * time.Minute}); got != want {
+		t.Errorf("Update without counters = %+v, want %+v", got, want)
+	}
+	inactive := usageAt(start, 360, 0, 0)
+	inactive.Active = false
+	if got := meter.Update("sim2", inactive); got.Duration != 3*time.Minute {
+		t.Errorf("Update of inactive session = %+v", got)
+	}
+
+	meter.Reset("sim2")
+	if got := meter.Totals("sim2"); got != (DataTotals{}) {
+		t.Errorf("Totals after Reset = %+v", got)
+	}
+}
+
+func TestUsageTrackerRates(t *testing.T) {
+	start := time.Now()
+	tracker := newUsageTracker()
+
+	first := usageAt(start, 0, 1000, 2000)
+	tracker.update(first)
+	if first.TxRate != 0 || first.RxRate != 0 || first.Duration != 0 {
+		t.Errorf("first update = %+v", first)
+	}
+
+	second := usageAt(start, 10, 2000, 4000)
+	tracker.update(second)
+	if second.TxRate != 100 || second.RxRate != 200 || second.Duration != 10*time.Second {
+		t.Errorf("TxRate = %v, RxRate = %v, Duration = %v, want 100, 200, 10s", second.TxRate, second.RxRate, second.Duration)
+	}
+
+	// Уменьшение счетчика - сброс, скорость интервала неизвестна
+	reset := usageAt(start, 20, 100, 5000)
+	tracker.update(reset)
+	if reset.TxRate != 0 || reset.RxRate != 0 {
+		t.Errorf("rates after counter reset = %v, %v", reset.TxRate, reset.RxRate)
+	}
+	next := usageAt(start, 30, 600, 6000)
+	tracker.update(next)
+	if next.TxRate != 50 || next.RxRate != 100 {
+		t.Errorf("rates after reset = %v, %v, want 50, 100", next.TxRate, next.RxRate)
+	}
+
+	// Смена источника: предыдущие значения не используются
+	other := usageAt(start, 40, 10000, 10000)
+	other.Source = DataUsageSIMCom
+	tracker.update(other)
+	if other.TxRate != 0 || other.RxRate != 0 {
+		t.Errorf("rates after source change = %v, %v", other.TxRate, other.RxRate)
+	}
+
+	// Другой контекст считается отдельно
+	cid2 := usageAt(start, 40, 1, 1)
+	cid2.CID = 2
+	tracker.update(cid2)
+	if cid2.TxRate != 0 || cid2.Duration != 0 {
+		t.Errorf("cid 2 = %+v", cid2)
+	}
+
+	// После ResetDataUsage скорость считается заново
+	tracker.reset()
+	after := usageAt(start, 50, 0, 0)
+	tracker.update(after)
+	if after.TxRate != 0 || after.RxRate != 0 {
+		t.Errorf("update after reset = %+v", after)
+	}
+}
+
+func TestUsageTrackerDuration(t *testing.T) {
+	start := time.Now()
+	tracker := newUsageTracker()
+
+	tracker.update(usageAt(start, 0, 0, 0))
+	inactive := usageAt(start, 30, 0, 0)
+	inactive.Active = false
+	tracker.update(inactive)
+	if inactive.Duration != 0 {
+		t.Errorf("inactive duration = %v", inactive.Duration)
+	}
+
+	// Новая сессия отсчитывается с момента, когда контекст снова стал активным
+	tracker.update(usageAt(start, 60, 0, 0))
+	active := usageAt(start, 90, 0, 0)
+	tracker.update(active)
+	if active.Duration != 30*time.Second {
+		t.Errorf("duration = %v, want 30s", active.Duration)
+	}
+
+	// Huawei сообщает длительность сам
+	huawei := &DataUsage{Source: DataUsageHuawei, CID: 1, Active: true, Counters: true, Shared: true,
+		Duration: 5 * time.Minute, Timestamp: start.Add(120 * time.Second)}
+	tracker.update(huawei)
+	if huawei.Duration != 5*time.Minute {
+		t.Errorf("Huawei duration = %v, want 5m", huawei.Duration)
+	}
+}
@@ -0,0 +1,33 @@
+package gsm
+
+// Device общие возможности модема, не зависящие от протокола управления.
+// Реализуется AT-модемом (Modem), клиентами QMI и MBIM (пакеты qmi, mbim) и HiLink (пакет hilink).
+type Device interface {
+	GetManufacturer() (string, error)
+	GetModel() (string, error)
+	GetRevision() (string, error)
+	GetIMEI() (string, error)
+	GetIMSI() (string, error)
+	GetSIMStatus() (PinStatus, error)
+	EnterPIN(pin string) error
+	GetSignalQuality() (*SignalQuality, error)
+	GetSignalReport() (*SignalReport, error)
+	GetNetworkStatus() (NetworkStatus, error)
+	GetCurrentOperator() (*OperatorInfo, error)
+	SendSMS(number, text string) error
+	ReadSMS(index int) (*SMS, error)
+	ListSMS(status string) ([]*SMS, error)
+	DeleteSMS(index int) error
+	GetEventChannel() (<-chan Event, error)
+	Close() error
+}
+
+var _ Device = (*Modem)(nil)
+
+// USSDDevice устройство с поддержкой USSD запросов (Modem, hilink.Client)
+type USSDDevice interface {
+	Device
+	SendUSSD(code string) (string, error)
+}
+
+var _ USSDDevice = (*Modem)(nil)
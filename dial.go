@@ -0,0 +1,357 @@
+package gsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// callPollInterval - период опроса AT+CLCC, если модем не присылает unsolicited +CLCC
+const callPollInterval = time.Second
+
+// callFallbackPollInterval - период опроса AT+CLCC, когда состояние приходит в URC: опрос
+// завершает вызов, если ^CEND или NO CARRIER потерялся (например, при перезапуске обработчика событий)
+const callFallbackPollInterval = 10 * time.Second
+
+// ErrCallEnded возвращается при ожидании состояния вызова, который уже завершился
+var ErrCallEnded = errors.New("call ended")
+
+// CallState представляет состояние вызова (значения совпадают с <stat> из +CLCC)
+type CallState int
+
+const (
+	CallStateActive   CallState = iota // 0 - разговор
+	CallStateHeld                      // 1 - удерживается
+	CallStateDialing                   // 2 - набор номера (исходящий)
+	CallStateAlerting                  // 3 - вызов абонента (исходящий, идут гудки)
+	CallStateIncoming                  // 4 - входящий вызов
+	CallStateWaiting                   // 5 - ожидающий вызов (входящий)
+	CallStateEnded                     // 6 - вызов завершен (некоторые модемы присылают +CLCC с stat=6)
+)
+
+// String возвращает название состояния вызова
+func (s CallState) String() string {
+	if s == CallStateEnded {
+		return "ended"
+	}
+	return mapCallState(strconv.Itoa(int(s)))
+}
+
+// CallDirection направление вызова
+type CallDirection int
+
+const (
+	CallOutgoing CallDirection = iota // 0 - исходящий (MO)
+	CallIncoming                      // 1 - входящий (MT)
+)
+
+// CallStatus представляет одну строку ответа +CLCC
+type CallStatus struct {
+	Index      int           // Индекс вызова (используется в AT+CHLD)
+	Direction  CallDirection // Направление вызова
+	State      CallState     // Состояние вызова
+	Mode       int           // Режим: 0=голос, 1=данные, 2=факс
+	Multiparty bool          // Участвует ли вызов в конференции
+	Number     string        // Номер абонента
+	NumberType int           // Тип адреса: 129=национальный, 145=международный
+}
+
+// Call представляет исходящий вызов, отслеживаемый от набора до завершения
+type Call struct {
+	modem *Modem
+
+	mu          sync.Mutex
+	id          int
+	index       int
+	number      string
+	state       CallState
+	urcDriven   bool
+	dialedAt    time.Time
+	connectedAt time.Time
+	endedAt     time.Time
+	endReason   CallEndReason
+	endCause    string
+	changed     chan struct{}
+	done        chan struct{}
+}
+
+// ID возвращает идентификатор вызова (общий счетчик со входящими вызовами)
+func (c *Call) ID() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.id
+}
+
+// Number возвращает набранный номер
+func (c *Call) Number() string {
+	return c.number
+}
+
+// Index возвращает индекс вызова в модеме (0, если модем еще не сообщил его)
+func (c *Call) Index() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.index
+}
+
+// State возвращает текущее состояние вызова
+func (c *Call) State() CallState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// Duration возвращает длительность разговора (с момента ответа абонента)
+func (c *Call) Duration() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.connectedAt.IsZero() {
+		return 0
+	}
+	if c.endedAt.IsZero() {
+		return time.Since(c.connectedAt)
+	}
+	return c.endedAt.Sub(c.connectedAt)
+}
+
+// EndReason возвращает причину завершения (пусто, пока вызов не завершен)
+func (c *Call) EndReason() CallEndReason {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.endReason
+}
+
+// EndCause возвращает подробную причину завершения от сети (cc_cause или ответ AT+CEER)
+func (c *Call) EndCause() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.endCause
+}
+
+// Done возвращает канал, который закрывается при завершении вызова
+func (c *Call) Done() <-chan struct{} {
+	return c.done
+}
+
+// Wait ждет завершения вызова
+func (c *Call) Wait(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitState ждет, пока вызов перейдет в указанное состояние
+func (c *Call) WaitState(ctx context.Context, state CallState) error {
+	for {
+		c.mu.Lock()
+		current, changed := c.state, c.changed
+		c.mu.Unlock()
+
+		if current == state {
+			return nil
+		}
+		if current == CallStateEnded {
+			return ErrCallEnded
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Hangup завершает вызов
+func (c *Call) Hangup() error {
+	if c.State() == CallStateEnded {
+		return nil
+	}
+
+	// Если известен индекс - завершаем только этот вызов, иначе все
+	cmd := "ATH"
+	if index := c.Index(); index > 0 {
+		cmd = fmt.Sprintf("AT+CHLD=1%d", index)
+	}
+	_, err := c.modem.execCommand(cmd, time.Second*5)
+	if errors.Is(err, ErrCommandFailed) && cmd != "ATH" {
+		_, err = c.modem.execCommand("ATH", time.Second*5)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to hang up call: %w", err)
+	}
+
+	c.modem.calls.endOutgoing(c, CallEndHangup, "")
+	return nil
+}
+
+// setState меняет состояние вызова, возвращает true если оно изменилось
+func (c *Call) setState(state CallState) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == state || c.state == CallStateEnded {
+		return false
+	}
+	if state == CallStateActive && c.connectedAt.IsZero() {
+		c.connectedAt = time.Now()
+	}
+	c.state = state
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return true
+}
+
+// finish переводит вызов в завершенное состояние, возвращает false если он уже завершен
+func (c *Call) finish(reason CallEndReason, cause string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == CallStateEnded {
+		return false
+	}
+	c.state = CallStateEnded
+	c.endedAt = time.Now()
+	c.endReason = reason
+	c.endCause = cause
+	close(c.changed)
+	c.changed = make(chan struct{})
+	close(c.done)
+	return true
+}
+
+// Dial совершает исходящий голосовой вызов и возвращает объект для отслеживания его состояния.
+// Отмена ctx до завершения вызова приводит к его сбросу.
+func (m *Modem) Dial(ctx context.Context, number string) (*Call, error) {
+	call := &Call{
+		modem:    m,
+		number:   number,
+		state:    CallStateDialing,
+		dialedAt: time.Now(),
+		changed:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	m.calls.addOutgoing(call)
+
+	resp, err := m.execCommand(fmt.Sprintf("ATD%s;", number), time.Second*5)
+	if err != nil {
+		m.calls.endOutgoing(call, CallEndNoCarrier, err.Error())
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+
+	// Некоторые модемы сразу отвечают BUSY/NO CARRIER вместо OK
+	for _, reason := range []CallEndReason{CallEndBusy, CallEndNoAnswer, CallEndNoCarrier} {
+		if strings.Contains(resp, string(reason)) {
+			m.calls.endOutgoing(call, reason, "")
+			return call, nil
+		}
+	}
+
+	go m.watchCall(ctx, call)
+	return call, nil
+}
+
+// watchCall опрашивает AT+CLCC, пока вызов не завершится. Если модем присылает URC,
+// опрос редкий и только страхует от потерянного ^CEND/NO CARRIER.
+func (m *Modem) watchCall(ctx context.Context, call *Call) {
+	ticker := time.NewTicker(callPollInterval)
+	defer ticker.Stop()
+
+	seen := false
+	lastPoll := time.Now()
+	for {
+		select {
+		case <-call.done:
+			return
+
+		case <-ctx.Done():
+			call.Hangup()
+			return
+
+		case <-ticker.C:
+			call.mu.Lock()
+			urcDriven := call.urcDriven
+			call.mu.Unlock()
+			if urcDriven {
+				// Состояние обновляется через +CLCC/^CONN/^CEND, модем уже сообщил о вызове
+				seen = true
+				if time.Since(lastPoll) < callFallbackPollInterval {
+					continue
+				}
+			}
+
+			lastPoll = time.Now()
+			calls, err := m.ListCalls()
+			if err != nil {
+				continue
+			}
+
+			status := m.calls.matchOutgoing(call, calls)
+			if status != nil {
+				seen = true
+				m.calls.updateOutgoing(call, *status)
+				continue
+			}
+
+			// Вызов пропал из списка - значит он завершен
+			if seen || time.Since(call.dialedAt) > callPollInterval*3 {
+				reason := CallEndNoAnswer
+				if call.Duration() > 0 {
+					reason = CallEndNoCarrier
+				}
+				cause, _ := m.GetLastFailureReason()
+				m.calls.endOutgoing(call, reason, cause)
+				return
+			}
+		}
+	}
+}
+
+// ListCalls возвращает список текущих вызовов (AT+CLCC)
+func (m *Modem) ListCalls() ([]CallStatus, error) {
+	resp, err := m.SendCommand("AT+CLCC", time.Second*2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calls: %w", err)
+	}
+
+	var calls []CallStatus
+	for _, line := range strings.Split(resp, "\n") {
+		line = strings.TrimSpace(line)
+		if status := parseCLCC(line); status != nil {
+			calls = append(calls, *status)
+		}
+	}
+	return calls, nil
+}
+
+// parseCLCC парсит строку +CLCC
+func parseCLCC(line string) *CallStatus {
+	// +CLCC: 1,0,2,0,0,"+79991234567",145
+	fields, ok := parseInfoLine(line, "+CLCC:")
+	if !ok || len(fields) < 5 {
+		return nil
+	}
+
+	index, err := fields[0].Int()
+	if err != nil {
+		return nil
+	}
+
+	return &CallStatus{
+		Index:      index,
+		Direction:  CallDirection(fields[1].IntOr(0)),
+		State:      CallState(fields[2].IntOr(0)),
+		Mode:       fields[3].IntOr(0),
+		Multiparty: fields[4].Value == "1",
+		Number:     fieldAt(fields, 5).Value,
+		NumberType: fieldAt(fields, 6).IntOr(0),
+	}
+}
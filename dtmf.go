@@ -0,0 +1,315 @@
+package gsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDTMFTimeout возвращается, если абонент не ввел ни одной цифры
+var ErrDTMFTimeout = errors.New("timeout waiting for DTMF digits")
+
+// DigitOptions параметры сбора DTMF цифр
+type DigitOptions struct {
+	MaxDigits         int           // Максимум цифр (0 - до терминатора или таймаута)
+	Terminator        string        // Символ окончания ввода (обычно "#"), в результат не попадает
+	FirstDigitTimeout time.Duration // Ожидание первой цифры (по умолчанию 10 секунд)
+	InterDigitTimeout time.Duration // Ожидание каждой следующей цифры (по умолчанию 3 секунды)
+}
+
+// IVRConfig параметры простого голосового меню
+type IVRConfig struct {
+	Prompt      string       // Файл приглашения в файловой системе модема (пусто - без приглашения)
+	Digits      DigitOptions // Параметры сбора цифр
+	Retries     int          // Сколько раз повторить приглашение, если ничего не введено
+	HangupAfter bool         // Завершить вызов после успешного ввода
+}
+
+// dtmfCollector передает распознанные DTMF цифры ожидающему их коду
+type dtmfCollector struct {
+	mu     sync.Mutex
+	digits chan string
+	ended  chan struct{}
+}
+
+// start начинает сбор цифр
+func (c *dtmfCollector) start() (<-chan string, <-chan struct{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.digits != nil {
+		return nil, nil, fmt.Errorf("DTMF collection is already in progress")
+	}
+	c.digits = make(chan string, 32)
+	c.ended = make(chan struct{})
+	return c.digits, c.ended, nil
+}
+
+// stop завершает сбор цифр
+func (c *dtmfCollector) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.digits = nil
+	c.ended = nil
+}
+
+// deliver передает цифру, если идет сбор
+func (c *dtmfCollector) deliver(digit string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.digits == nil {
+		return
+	}
+	select {
+	case c.digits <- digit:
+	default:
+	}
+}
+
+// callEnded сообщает о завершении вызова во время сбора
+func (c *dtmfCollector) callEnded() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ended != nil {
+		close(c.ended)
+		c.ended = nil
+	}
+}
+
+// isDTMFDigit проверяет, допустим ли символ для DTMF
+func isDTMFDigit(r rune) bool {
+	return (r >= '0' && r <= '9') || r == '*' || r == '#' || (r >= 'A' && r <= 'D')
+}
+
+// parseDTMF распознает URC с принятой DTMF цифрой
+func parseDTMF(line string) (string, bool) {
+	var data string
+	switch {
+	case strings.HasPrefix(line, "+DTMF:"):
+		// SIMCom (AT+DDET=1): +DTMF: 5
+		data = line[6:]
+	case strings.HasPrefix(line, "+RXDTMF:"):
+		// SIMCom: +RXDTMF: 5
+		data = line[8:]
+	case strings.HasPrefix(line, "+QTONEDET:"):
+		// Quectel: +QTONEDET: 53 (ASCII код символа)
+		code, err := strconv.Atoi(strings.TrimSpace(line[10:]))
+		if err != nil || !isDTMFDigit(rune(code)) {
+			return "", false
+		}
+		return string(rune(code)), true
+	default:
+		return "", false
+	}
+
+	if idx := strings.Index(data, ","); idx != -1 {
+		data = data[:idx]
+	}
+	digit := strings.Trim(data, " \"")
+	if len(digit) != 1 || !isDTMFDigit(rune(digit[0])) {
+		return "", false
+	}
+	return digit, true
+}
+
+// SendDTMF отправляет DTMF цифры в текущий вызов (AT+VTS)
+func (m *Modem) SendDTMF(digits string, duration time.Duration) error {
+	for _, r := range digits {
+		if !isDTMFDigit(r) {
+			return fmt.Errorf("invalid DTMF digit: %q", r)
+		}
+	}
+
+	if duration > 0 {
+		// AT+VTD задает длительность тона в десятых долях секунды
+		tenths := int(duration / (100 * time.Millisecond))
+		if tenths < 1 {
+			tenths = 1
+		}
+		if _, err := m.SendCommand(fmt.Sprintf("AT+VTD=%d", tenths), time.Second); err != nil {
+			return fmt.Errorf("failed to set DTMF duration: %w", err)
+		}
+	}
+
+	for _, r := range digits {
+		if _, err := m.execCommand(fmt.Sprintf("AT+VTS=%c", r), time.Second*2+duration); err != nil {
+			return fmt.Errorf("failed to send DTMF %c: %w", r, err)
+		}
+	}
+	return nil
+}
+
+// EnableDTMFDetection включает распознавание DTMF от удаленного абонента
+func (m *Modem) EnableDTMFDetection(enable bool) error {
+	flag := 0
+	if enable {
+		flag = 1
+	}
+
+	var cmd string
+	switch m.Vendor() {
+	case VendorQuectel:
+		cmd = fmt.Sprintf("AT+QTONEDET=%d", flag)
+	case VendorSIMCom:
+		cmd = fmt.Sprintf("AT+DDET=%d", flag)
+	default:
+		return ErrNotSupported
+	}
+
+	if _, err := m.execCommand(cmd, time.Second*2); err != nil {
+		return fmt.Errorf("failed to set DTMF detection: %w", err)
+	}
+	return nil
+}
+
+// PlayAudio воспроизводит файл из памяти модема удаленному абоненту
+func (m *Modem) PlayAudio(file string) error {
+	var cmd string
+	switch m.Vendor() {
+	case VendorQuectel:
+		// <control>,<file>,<repeat>,<ul_play>,<dl_play> - играем только в сторону абонента
+		cmd = fmt.Sprintf("AT+QPSND=1,\"%s\",0,1,0", file)
+	case VendorSIMCom:
+		// 4 - воспроизведение, канал 0, громкость 90
+		cmd = fmt.Sprintf("AT+CREC=4,\"%s\",0,90", file)
+	default:
+		return ErrNotSupported
+	}
+
+	if _, err := m.execCommand(cmd, time.Second*5); err != nil {
+		return fmt.Errorf("failed to play audio: %w", err)
+	}
+	return nil
+}
+
+// StopAudio останавливает воспроизведение
+func (m *Modem) StopAudio() error {
+	var cmd string
+	switch m.Vendor() {
+	case VendorQuectel:
+		cmd = "AT+QPSND=0"
+	case VendorSIMCom:
+		cmd = "AT+CREC=5"
+	default:
+		return ErrNotSupported
+	}
+
+	if _, err := m.SendCommand(cmd, time.Second*2); err != nil {
+		return fmt.Errorf("failed to stop audio: %w", err)
+	}
+	return nil
+}
+
+// CollectDigits собирает DTMF цифры, введенные абонентом (требуется запущенный обработчик событий)
+func (m *Modem) CollectDigits(ctx context.Context, opts DigitOptions) (string, error) {
+	if !m.IsEventListenerRunning() {
+		return "", fmt.Errorf("event listener is not running, call StartEventListener() first")
+	}
+
+	digits, ended, err := m.dtmf.start()
+	if err != nil {
+		return "", err
+	}
+	defer m.dtmf.stop()
+
+	return collectDigits(ctx, digits, ended, opts)
+}
+
+// collectDigits читает цифры из канала с учетом таймаутов и терминатора
+func collectDigits(ctx context.Context, digits <-chan string, ended <-chan struct{}, opts DigitOptions) (string, error) {
+	firstTimeout := opts.FirstDigitTimeout
+	if firstTimeout <= 0 {
+		firstTimeout = 10 * time.Second
+	}
+	interTimeout := opts.InterDigitTimeout
+	if interTimeout <= 0 {
+		interTimeout = 3 * time.Second
+	}
+
+	var result strings.Builder
+	timer := time.NewTimer(firstTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case digit := <-digits:
+			if opts.Terminator != "" && digit == opts.Terminator {
+				return result.String(), nil
+			}
+			result.WriteString(digit)
+			if opts.MaxDigits > 0 && result.Len() >= opts.MaxDigits {
+				return result.String(), nil
+			}
+			timer.Reset(interTimeout)
+
+		case <-timer.C:
+			if result.Len() == 0 {
+				return "", ErrDTMFTimeout
+			}
+			return result.String(), nil
+
+		case <-ended:
+			return result.String(), ErrCallEnded
+
+		case <-ctx.Done():
+			return result.String(), ctx.Err()
+		}
+	}
+}
+
+// RunIVR отвечает на входящий вызов, проигрывает приглашение и собирает введенные цифры
+func (m *Modem) RunIVR(ctx context.Context, cfg IVRConfig) (string, error) {
+	if !m.IsEventListenerRunning() {
+		return "", fmt.Errorf("event listener is not running, call StartEventListener() first")
+	}
+
+	// Если модем не умеет DTMF URC, цифры все равно могут прийти как +DTMF
+	if err := m.EnableDTMFDetection(true); err != nil && !errors.Is(err, ErrNotSupported) {
+		return "", err
+	}
+
+	digits, ended, err := m.dtmf.start()
+	if err != nil {
+		return "", err
+	}
+	defer m.dtmf.stop()
+
+	if err := m.AnswerCall(); err != nil {
+		return "", err
+	}
+
+	for attempt := 0; attempt <= cfg.Retries; attempt++ {
+		if cfg.Prompt != "" {
+			if err := m.PlayAudio(cfg.Prompt); err != nil {
+				return "", err
+			}
+		}
+
+		input, err := collectDigits(ctx, digits, ended, cfg.Digits)
+		if cfg.Prompt != "" {
+			m.StopAudio()
+		}
+
+		if errors.Is(err, ErrDTMFTimeout) {
+			continue
+		}
+		if err != nil {
+			return input, err
+		}
+
+		if cfg.HangupAfter {
+			if err := m.HangUp(); err != nil {
+				return input, err
+			}
+		}
+		return input, nil
+	}
+
+	return "", ErrDTMFTimeout
+}
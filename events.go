@@ -13,12 +13,17 @@ type EventType string
 const (
 	EventNewSMS            EventType = "NEW_SMS"
 	EventIncomingCall      EventType = "INCOMING_CALL"
+	EventCallRinging       EventType = "CALL_RINGING"
 	EventCallEnded         EventType = "CALL_ENDED"
+	EventCallStateChange   EventType = "CALL_STATE_CHANGE"
 	EventNetworkChange     EventType = "NETWORK_CHANGE"
 	EventSignalChange      EventType = "SIGNAL_CHANGE"
 	EventUSSD              EventType = "USSD"
 	EventModemError        EventType = "MODEM_ERROR"
 	EventSMSDeliveryReport EventType = "SMS_DELIVERY_REPORT"
+	EventDTMF              EventType = "DTMF"
+	EventSupplementary     EventType = "SUPPLEMENTARY_SERVICE"
+	EventConnectivity      EventType = "CONNECTIVITY_CHANGE"
 )
 
 // Event представляет событие от модема
@@ -38,8 +43,8 @@ func (m *Modem) StartEventListener() error {
 		return fmt.Errorf("event listener is already running")
 	}
 
-	// Настраиваем уведомления о новых SMS
-	if err := m.EnableNewSMSNotification(); err != nil {
+	// Настраиваем уведомления о новых SMS (блокировка уже захвачена, поэтому без SendCommand)
+	if _, err := m.sendCommand("AT+CNMI=2,1,0,0,0", time.Second*2); err != nil {
 		return fmt.Errorf("failed to enable SMS notifications: %w", err)
 	}
 
@@ -48,15 +53,40 @@ func (m *Modem) StartEventListener() error {
 		return fmt.Errorf("failed to enable caller ID: %w", err)
 	}
 
+	// Расширенный формат RING (+CRING: VOICE) и имя звонящего - поддерживаются не всеми модемами
+	m.sendCommand("AT+CRC=1", time.Second)
+	m.sendCommand("AT+CNAP=1", time.Second)
+
+	// Unsolicited +CLCC при изменении состояния вызовов
+	m.sendCommand("AT+CLCC=1", time.Second)
+
+	// Уведомления о дополнительных услугах (+CSSI/+CSSU)
+	m.sendCommand("AT+CSSN=1,1", time.Second)
+
 	// Включаем уведомления о изменении регистрации в сети
 	if _, err := m.sendCommand("AT+CREG=2", time.Second); err != nil {
 		return fmt.Errorf("failed to enable network registration updates: %w", err)
 	}
 
+	// Регистрация в пакетных сетях GPRS/LTE/5G - поддерживаются не всеми модемами
+	m.sendCommand("AT+CGREG=2", time.Second)
+	if resp, err := m.sendCommand("AT+CEREG=4", time.Second); err != nil || findErrorLine(resp) != "" {
+		// Модем без PSM - только статус и местоположение
+		m.sendCommand("AT+CEREG=2", time.Second)
+	}
+	m.sendCommand("AT+C5GREG=2", time.Second)
+
+	// Уведомления об уровне сигнала, а если модем их не поддерживает - периодический опрос
+	signalURC := m.enableSignalEvents()
+
 	// Запускаем горутину для чтения событий
 	m.eventsEnabled = true
+	m.startPortReader()
 	m.stopEventsCh = make(chan struct{})
-	go m.eventListenerLoop()
+	go m.eventListenerLoop(m.stopEventsCh)
+	if !signalURC {
+		go m.signalPollLoop(m.stopEventsCh)
+	}
 
 	return nil
 }
@@ -73,10 +103,19 @@ func (m *Modem) StopEventListener() error {
 	// Останавливаем горутину
 	close(m.stopEventsCh)
 	m.eventsEnabled = false
+	m.stopPortReader()
 
 	// Отключаем уведомления
 	m.sendCommand("AT+CLIP=0", time.Second)
+	m.sendCommand("AT+CRC=0", time.Second)
+	m.sendCommand("AT+CNAP=0", time.Second)
+	m.sendCommand("AT+CLCC=0", time.Second)
+	m.sendCommand("AT+CSSN=0,0", time.Second)
 	m.sendCommand("AT+CREG=0", time.Second)
+	m.sendCommand("AT+CGREG=0", time.Second)
+	m.sendCommand("AT+CEREG=0", time.Second)
+	m.sendCommand("AT+C5GREG=0", time.Second)
+	m.disableSignalEvents()
 	m.sendCommand("AT+CNMI=0,0,0,0,0", time.Second)
 
 	return nil
@@ -90,22 +129,26 @@ func (m *Modem) IsEventListenerRunning() bool {
 }
 
 // eventListenerLoop основной цикл обработки событий
-func (m *Modem) eventListenerLoop() {
+func (m *Modem) eventListenerLoop(stop chan struct{}) {
 	buf := make([]byte, 1024)
 	var lineBuffer strings.Builder
 
 	for {
 		select {
-		case <-m.stopEventsCh:
+		case <-stop:
 			return
 		default:
-			// Проверяем, включены ли события
+			// Данные порта забираются под m.mu, пока не выполняется команда (см. portReader).
+			// Строки разбираются после освобождения блокировки - обработчики сами отправляют команды.
+			m.mu.Lock()
 			if !m.eventsEnabled {
+				m.mu.Unlock()
 				return
 			}
 
-			// Читаем данные (с таймаутом из конфига)
-			n, err := m.port.Read(buf)
+			// Читаем данные (не дольше portPollInterval)
+			n, err := m.readEvents(buf)
+			m.mu.Unlock()
 			if err != nil {
 				// Небольшая пауза при ошибке чтения
 				time.Sleep(100 * time.Millisecond)
@@ -124,15 +167,7 @@ func (m *Modem) eventListenerLoop() {
 				for i := 0; i < len(lines)-1; i++ {
 					line := strings.TrimSpace(lines[i])
 					if line != "" {
-						// Обрабатываем событие
-						event := m.parseEvent(line)
-						if event != nil {
-							select {
-							case m.eventChan <- *event:
-							default:
-								// Канал полон, пропускаем событие
-							}
-						}
+						m.handleLine(line)
 					}
 				}
 
@@ -144,6 +179,110 @@ func (m *Modem) eventListenerLoop() {
 	}
 }
 
+// handleLine обрабатывает одну строку от модема
+func (m *Modem) handleLine(line string) {
+	// Строка в кавычках может продолжаться на следующих строках (многострочные USSD меню)
+	joined := 0
+	if m.pendingLine != "" {
+		pending := m.pendingLine
+		joined = m.pendingCount + 1
+		m.pendingLine, m.pendingCount = "", 0
+		if isFinalResult(line) {
+			// Текст так и не закрылся (кавычка внутри текста): отдаем что есть
+			m.dispatchLine(pending)
+			joined = 0
+		} else {
+			line = pending + "\n" + line
+		}
+	}
+	if strings.HasPrefix(line, "+CUSD:") && !cusdComplete(line) &&
+		joined < maxPendingLines && len(line) < maxPendingBytes {
+		m.pendingLine, m.pendingCount = line, joined
+		return
+	}
+	m.dispatchLine(line)
+}
+
+// cusdComplete проверяет, что +CUSD закончен: кавычки парные или строка завершается ",<dcs>"
+func cusdComplete(line string) bool {
+	if strings.Count(line, "\"")%2 == 0 {
+		return true
+	}
+	end := strings.LastIndex(line, "\"")
+	rest := strings.TrimSpace(line[end+1:])
+	if !strings.HasPrefix(rest, ",") {
+		return false
+	}
+	_, err := strconv.Atoi(strings.TrimSpace(rest[1:]))
+	return err == nil
+}
+
+// isFinalResult проверяет, что строка - финальный результат команды
+func isFinalResult(line string) bool {
+	switch line {
+	case "OK", "ERROR", "NO CARRIER", "BUSY", "NO ANSWER", "NO DIALTONE":
+		return true
+	}
+	return strings.HasPrefix(line, "+CME ERROR") || strings.HasPrefix(line, "+CMS ERROR")
+}
+
+// dispatchLine передает собранную строку обработчикам
+func (m *Modem) dispatchLine(line string) {
+
+	// Уведомления сокетов встроенного TCP/IP стека
+	if m.sockets.handleURC(line) {
+		return
+	}
+
+	// Результаты асинхронных команд (HTTP клиент модема)
+	if m.urcs.deliver(line) {
+		return
+	}
+
+	// USSD ответы направляются в активную сессию
+	if strings.HasPrefix(line, "+CUSD:") {
+		m.handleUSSD(line)
+		return
+	}
+
+	// DTMF цифры от удаленного абонента
+	if digit, ok := parseDTMF(line); ok {
+		m.dtmf.deliver(digit)
+		m.emitEvent(Event{
+			Type:      EventDTMF,
+			Timestamp: time.Now(),
+			Data:      map[string]interface{}{"digit": digit},
+		})
+		return
+	}
+	if line == "NO CARRIER" {
+		m.dtmf.callEnded()
+	}
+
+	// Уровень сигнала (+CIEV, ^RSSI, ^HCSQ, +QIND: "csq")
+	if m.handleSignalLine(line) {
+		return
+	}
+
+	// Индикации входящего вызова собираются в один объект
+	if m.calls.handleLine(line) {
+		return
+	}
+
+	if event := m.parseEvent(line); event != nil {
+		m.emitEvent(*event)
+	}
+}
+
+// emitEvent отправляет событие в канал без блокировки
+func (m *Modem) emitEvent(event Event) {
+	select {
+	case m.eventChan <- event:
+	default:
+		// Канал полон, пропускаем событие
+	}
+}
+
 // parseEvent парсит строку события
 func (m *Modem) parseEvent(line string) *Event {
 	event := &Event{
@@ -155,58 +294,66 @@ func (m *Modem) parseEvent(line string) *Event {
 	if strings.HasPrefix(line, "+CMTI:") {
 		// +CMTI: "SM",1
 		event.Type = EventNewSMS
-		parts := strings.Split(line[6:], ",")
-		if len(parts) >= 2 {
-			event.Data["storage"] = strings.Trim(parts[0], " \"")
-			if index, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil {
+		fields, _ := parseInfoLine(line, "+CMTI:")
+		if len(fields) >= 2 {
+			event.Data["storage"] = fields[0].Value
+			if index, err := fields[1].Int(); err == nil {
 				event.Data["index"] = index
 			}
 		}
 		return event
 	}
 
-	// Входящий звонок
-	if strings.HasPrefix(line, "RING") || strings.HasPrefix(line, "+CRING:") {
-		event.Type = EventIncomingCall
-		return event
-	}
-
-	// Информация о звонящем
-	if strings.HasPrefix(line, "+CLIP:") {
-		// +CLIP: "+79991234567",145,"",,"",0
-		event.Type = EventIncomingCall
-		parts := strings.Split(line[6:], ",")
-		if len(parts) >= 1 {
-			event.Data["number"] = strings.Trim(parts[0], " \"")
-		}
-		return event
-	}
-
-	// Изменение регистрации в сети
-	if strings.HasPrefix(line, "+CREG:") {
-		// +CREG: 2,1,"1234","5678"
+	// Изменение регистрации в сети (+CREG/+CGREG/+CEREG/+C5GREG)
+	if domain, fields, ok := registrationURC(line); ok {
+		// +CEREG: 1,"1A2B","01A2B3C4",7
 		event.Type = EventNetworkChange
-		parts := strings.Split(line[6:], ",")
-		if len(parts) >= 2 {
-			if status, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil {
-				event.Data["status"] = NetworkStatus(status)
-				event.Data["statusText"] = networkStatusToString(NetworkStatus(status))
-			}
+		info := parseRegistration(domain, fields)
+		event.Data["domain"] = domain
+		event.Data["status"] = info.Status
+		event.Data["statusText"] = networkStatusToString(info.Status)
+		event.Data["registration"] = info
+		if cm := m.ConnectivityMonitor(); cm != nil {
+			cm.observeRegistration(info)
+		}
+		if len(fields) >= 3 {
+			event.Data["lac"] = fields[1].Value
+			event.Data["cellId"] = fields[2].Value
 		}
-		if len(parts) >= 4 {
-			event.Data["lac"] = strings.Trim(parts[2], " \"")
-			event.Data["cellId"] = strings.Trim(parts[3], " \"")
+		if info.AccessTech != AccessTechUnknown {
+			event.Data["accessTech"] = info.AccessTech
+		}
+		if info.CellID >= 0 {
+			// MCC/MNC, ARFCN и уровни сигнала - через GetServingCell
+			cell := newCellInfo(true, info.AccessTech)
+			cell.AreaCode, cell.CellID = info.AreaCode, info.CellID
+			event.Data["cell"] = &cell
 		}
 		return event
 	}
 
-	// USSD ответ
-	if strings.HasPrefix(line, "+CUSD:") {
-		// +CUSD: 0,"Balance: 100.50 RUB",15
-		event.Type = EventUSSD
-		parts := strings.Split(line[6:], ",")
-		if len(parts) >= 2 {
-			event.Data["message"] = strings.Trim(parts[1], " \"")
+	// Уведомления о дополнительных услугах
+	if strings.HasPrefix(line, "+CSSI:") || strings.HasPrefix(line, "+CSSU:") {
+		// +CSSI: 2 (исходящий вызов), +CSSU: 0,,"+79991234567",145 (входящий вызов)
+		event.Type = EventSupplementary
+		fields, _ := ParseFields(line[6:])
+		code, err := fieldAt(fields, 0).Int()
+		if err != nil {
+			return nil
+		}
+		event.Data["code"] = code
+		if strings.HasPrefix(line, "+CSSI:") {
+			event.Data["direction"] = CallOutgoing
+			event.Data["description"] = mapCSSICode(code)
+		} else {
+			event.Data["direction"] = CallIncoming
+			event.Data["description"] = mapCSSUCode(code)
+			if len(fields) >= 3 {
+				event.Data["number"] = fields[2].Value
+			}
+		}
+		if index := fieldAt(fields, 1); !index.IsEmpty() {
+			event.Data["index"] = index.Value
 		}
 		return event
 	}
@@ -218,7 +365,7 @@ func (m *Modem) parseEvent(line string) *Event {
 		return event
 	}
 
-	// Завершение вызова
+	// Завершение исходящего вызова (входящие обрабатывает callTracker)
 	if strings.Contains(line, "NO CARRIER") || strings.Contains(line, "BUSY") || strings.Contains(line, "NO ANSWER") {
 		event.Type = EventCallEnded
 		event.Data["reason"] = line
@@ -252,43 +399,6 @@ func (m *Modem) WaitForEvent(eventType EventType, timeout time.Duration) (*Event
 	}
 }
 
-// SendUSSD отправляет USSD запрос
-func (m *Modem) SendUSSD(code string) (string, error) {
-	// Устанавливаем кодировку для USSD
-	if _, err := m.SendCommand("AT+CSCS=\"GSM\"", time.Second); err != nil {
-		return "", fmt.Errorf("failed to set encoding: %w", err)
-	}
-
-	// Отправляем USSD запрос
-	cmd := fmt.Sprintf("AT+CUSD=1,\"%s\",15", code)
-	resp, err := m.SendCommand(cmd, time.Second*30)
-	if err != nil {
-		return "", fmt.Errorf("failed to send USSD: %w", err)
-	}
-
-	// Ждем USSD ответ через события
-	event, err := m.WaitForEvent(EventUSSD, time.Second*30)
-	if err != nil {
-		// Пробуем извлечь из прямого ответа
-		if strings.Contains(resp, "+CUSD:") {
-			parts := strings.Split(resp, ":")
-			if len(parts) >= 2 {
-				values := strings.Split(parts[1], ",")
-				if len(values) >= 2 {
-					return strings.Trim(values[1], " \""), nil
-				}
-			}
-		}
-		return "", fmt.Errorf("failed to get USSD response: %w", err)
-	}
-
-	if msg, ok := event.Data["message"].(string); ok {
-		return msg, nil
-	}
-
-	return "", fmt.Errorf("invalid USSD response format")
-}
-
 // MakeCall совершает звонок
 func (m *Modem) MakeCall(number string) error {
 	cmd := fmt.Sprintf("ATD%s;", number)
@@ -305,6 +415,7 @@ func (m *Modem) HangUp() error {
 	if err != nil {
 		return fmt.Errorf("failed to hang up: %w", err)
 	}
+	m.calls.hungUp()
 	return nil
 }
 
@@ -314,6 +425,7 @@ func (m *Modem) AnswerCall() error {
 	if err != nil {
 		return fmt.Errorf("failed to answer call: %w", err)
 	}
+	m.calls.answered()
 	return nil
 }
 
@@ -339,26 +451,18 @@ func (m *Modem) GetCallStatus() ([]map[string]string, error) {
 	}
 
 	var calls []map[string]string
-	lines := strings.Split(resp, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "+CLCC:") {
-			// +CLCC: 1,0,2,0,0,"+79991234567",145
-			call := make(map[string]string)
-			parts := strings.Split(line[6:], ",")
-
-			if len(parts) >= 6 {
-				call["id"] = strings.TrimSpace(parts[0])
-				call["direction"] = mapCallDirection(parts[1])
-				call["state"] = mapCallState(parts[2])
-				call["mode"] = mapCallMode(parts[3])
-				call["multiparty"] = parts[4]
-				call["number"] = strings.Trim(parts[5], " \"")
-			}
-
-			calls = append(calls, call)
+	// +CLCC: 1,0,2,0,0,"+79991234567",145
+	for _, fields := range parseInfoLines(resp, "+CLCC:") {
+		call := make(map[string]string)
+		if len(fields) >= 6 {
+			call["id"] = fields[0].Value
+			call["direction"] = mapCallDirection(fields[1].Value)
+			call["state"] = mapCallState(fields[2].Value)
+			call["mode"] = mapCallMode(fields[3].Value)
+			call["multiparty"] = fields[4].Value
+			call["number"] = fields[5].Value
 		}
+		calls = append(calls, call)
 	}
 
 	return calls, nil
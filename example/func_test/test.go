@@ -75,7 +75,7 @@ func main() {
 }
 
 func runTests(modem *gsm.Modem, testPhone, pin string) {
-	fmt.Println("\n=== Running Modem Tests ===\n")
+	fmt.Print("\n=== Running Modem Tests ===\n\n")
 
 	// Test 1: Connection test
 	fmt.Print("1. Testing connection... ")
@@ -178,10 +178,10 @@ func runTests(modem *gsm.Modem, testPhone, pin string) {
 	if err != nil {
 		fmt.Printf("   ✗ Failed: %v\n", err)
 	} else {
-		fmt.Printf("   Read storage: %s (%s/%s used)\n",
-			storage["ReadStorage"], storage["ReadUsed"], storage["ReadTotal"])
-		fmt.Printf("   Write storage: %s (%s/%s used)\n",
-			storage["WriteStorage"], storage["WriteUsed"], storage["WriteTotal"])
+		fmt.Printf("   Read storage: %s (%d/%d used)\n",
+			storage.ReadStorage, storage.ReadUsed, storage.ReadTotal)
+		fmt.Printf("   Write storage: %s (%d/%d used)\n",
+			storage.WriteStorage, storage.WriteUsed, storage.WriteTotal)
 	}
 
 	// Test 8: List SMS
@@ -48,9 +48,14 @@ func main() {
 	if err != nil {
 		log.Printf("Ошибка при получении информации: %v", err)
 	} else {
-		for key, value := range info {
-			fmt.Printf("%s: %s\n", key, value)
-		}
+		fmt.Printf("Производитель: %s\n", info.Manufacturer)
+		fmt.Printf("Модель: %s\n", info.Model)
+		fmt.Printf("Прошивка: %s\n", info.Revision)
+		fmt.Printf("IMEI: %s\n", info.IMEI)
+		fmt.Printf("Сеть: %s\n", info.NetworkStatus)
+		fmt.Printf("Оператор: %s\n", info.Operator)
+		fmt.Printf("Сигнал: %d/31\n", info.SignalRSSI)
+		fmt.Printf("SIM: %s\n", info.SIMStatus)
 	}
 
 	// Пример 3: Проверка SIM-карты
@@ -84,7 +89,7 @@ func main() {
 	if err != nil {
 		log.Printf("Ошибка при получении оператора: %v", err)
 	} else {
-		fmt.Printf("Оператор: %s\n", operator.LongName)
+		fmt.Printf("Оператор: %s (%s, %s)\n", operator.LongName, operator.Numeric, operator.Country)
 	}
 
 	// Получаем качество сигнала
@@ -103,7 +108,7 @@ func main() {
 	if err != nil {
 		log.Printf("Ошибка при получении информации о хранилище: %v", err)
 	} else {
-		fmt.Printf("SMS в памяти: %s/%s\n", storageInfo["ReadUsed"], storageInfo["ReadTotal"])
+		fmt.Printf("SMS в памяти: %d/%d\n", storageInfo.ReadUsed, storageInfo.ReadTotal)
 	}
 
 	// Чтение всех SMS
@@ -166,7 +171,11 @@ func main() {
 
 // handleEvents обрабатывает события от модема
 func handleEvents(modem *gsm.Modem) {
-	eventChan := modem.GetEventChannel()
+	eventChan, err := modem.GetEventChannel()
+	if err != nil {
+		log.Printf("Ошибка при получении канала событий: %v", err)
+		return
+	}
 
 	for event := range eventChan {
 		fmt.Printf("\n[СОБЫТИЕ] %s в %s\n", event.Type, event.Timestamp.Format("15:04:05"))
@@ -188,9 +197,16 @@ func handleEvents(modem *gsm.Modem) {
 
 		case gsm.EventIncomingCall:
 			if number, ok := event.Data["number"].(string); ok {
-				fmt.Printf("Входящий звонок от: %s\n", number)
+				fmt.Printf("Входящий звонок #%d от: %s\n", event.Data["callId"], number)
+			} else {
+				fmt.Printf("Входящий звонок #%d!\n", event.Data["callId"])
+			}
+
+		case gsm.EventCallEnded:
+			if missed, ok := event.Data["missed"].(bool); ok && missed {
+				fmt.Printf("Пропущенный звонок #%d от: %v\n", event.Data["callId"], event.Data["number"])
 			} else {
-				fmt.Println("Входящий звонок!")
+				fmt.Printf("Звонок завершен: %v\n", event.Data["reason"])
 			}
 
 		case gsm.EventNetworkChange:
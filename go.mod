@@ -6,4 +6,4 @@ toolchain go1.24.3
 
 require github.com/tarm/serial v0.0.0-20180830185346-98f6abe2eb07
 
-require golang.org/x/sys v0.34.0 // indirect
+require golang.org/x/sys v0.34.0
@@ -0,0 +1,149 @@
+package gsm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// gsm7Alphabet базовый алфавит GSM 03.38 (индекс = код септета)
+var gsm7Alphabet = []rune(
+	"@£$¥èéùìòÇ\nØø\rÅå" +
+		"Δ_ΦΓΛΩΠΨΣΘΞ\x1bÆæßÉ" +
+		" !\"#¤%&'()*+,-./" +
+		"0123456789:;<=>?" +
+		"¡ABCDEFGHIJKLMNO" +
+		"PQRSTUVWXYZÄÖÑÜ§" +
+		"¿abcdefghijklmno" +
+		"pqrstuvwxyzäöñüà")
+
+// gsm7Extension таблица расширения GSM 03.38 (после символа ESC 0x1B)
+var gsm7Extension = map[byte]rune{
+	0x0A: '\f',
+	0x14: '^',
+	0x28: '{',
+	0x29: '}',
+	0x2F: '\\',
+	0x3C: '[',
+	0x3D: '~',
+	0x3E: ']',
+	0x40: '|',
+	0x65: '€',
+}
+
+// DecodeGSM7 декодирует неупакованные септеты GSM 03.38 в строку
+func DecodeGSM7(septets []byte) string {
+	var result strings.Builder
+	for i := 0; i < len(septets); i++ {
+		c := septets[i] & 0x7F
+		if c == 0x1B && i+1 < len(septets) {
+			i++
+			if r, ok := gsm7Extension[septets[i]&0x7F]; ok {
+				result.WriteRune(r)
+			} else {
+				result.WriteRune(' ')
+			}
+			continue
+		}
+		result.WriteRune(gsm7Alphabet[c])
+	}
+	return result.String()
+}
+
+// EncodeGSM7 кодирует строку в септеты GSM 03.38, возвращает ошибку для символов вне алфавита
+func EncodeGSM7(text string) ([]byte, error) {
+	var septets []byte
+	for _, r := range text {
+		if code := gsm7Code(r); code >= 0 {
+			septets = append(septets, byte(code))
+			continue
+		}
+		found := false
+		for code, ext := range gsm7Extension {
+			if ext == r {
+				septets = append(septets, 0x1B, code)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("character %q is not in GSM 7-bit alphabet", r)
+		}
+	}
+	return septets, nil
+}
+
+// gsm7Code возвращает код символа в базовом алфавите или -1
+func gsm7Code(r rune) int {
+	for i, c := range gsm7Alphabet {
+		if c == r && i != 0x1B {
+			return i
+		}
+	}
+	return -1
+}
+
+// PackSeptets упаковывает 7-битные септеты в октеты
+func PackSeptets(septets []byte) []byte {
+	packed := make([]byte, 0, (len(septets)*7+7)/8)
+	var acc uint16
+	bits := 0
+	for _, s := range septets {
+		acc |= uint16(s&0x7F) << bits
+		bits += 7
+		for bits >= 8 {
+			packed = append(packed, byte(acc))
+			acc >>= 8
+			bits -= 8
+		}
+	}
+	if bits > 0 {
+		packed = append(packed, byte(acc))
+	}
+	return packed
+}
+
+// UnpackSeptets распаковывает октеты в 7-битные септеты
+func UnpackSeptets(packed []byte) []byte {
+	septets := make([]byte, 0, len(packed)*8/7)
+	var acc uint16
+	bits := 0
+	for _, b := range packed {
+		acc |= uint16(b) << bits
+		bits += 8
+		for bits >= 7 {
+			septets = append(septets, byte(acc&0x7F))
+			acc >>= 7
+			bits -= 7
+		}
+	}
+	return septets
+}
+
+// EncodeUSSDPacked кодирует USSD строку в упакованный 7-битный hex (требуется модемам Huawei)
+func EncodeUSSDPacked(text string) (string, error) {
+	septets, err := EncodeGSM7(text)
+	if err != nil {
+		return "", err
+	}
+	// Если последние 7 бит заполнения образуют лишний септет, 23.038 требует CR вместо @
+	if len(septets)%8 == 7 {
+		septets = append(septets, '\r')
+	}
+	return strings.ToUpper(hex.EncodeToString(PackSeptets(septets))), nil
+}
+
+// DecodeUSSDPacked декодирует упакованный 7-битный hex
+func DecodeUSSDPacked(hexStr string) (string, error) {
+	data, err := hex.DecodeString(strings.ReplaceAll(hexStr, " ", ""))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode hex: %w", err)
+	}
+
+	septets := UnpackSeptets(data)
+	// Если длина кратна 7 октетам, последний септет может быть заполнением (CR или @)
+	if n := len(septets); n > 0 && len(data)%7 == 0 && (septets[n-1] == 0x00 || septets[n-1] == '\r') {
+		septets = septets[:n-1]
+	}
+	return DecodeGSM7(septets), nil
+}
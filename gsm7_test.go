@@ -0,0 +1,95 @@
+package gsm
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestPackSeptets(t *testing.T) {
+	tests := []struct {
+		text   string
+		packed string
+	}{
+		{"hellohello", "E8329BFD4697D9EC37"},
+		{"*100#", "AA180C3602"},
+		{"A", "41"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		septets, err := EncodeGSM7(tt.text)
+		if err != nil {
+			t.Fatalf("EncodeGSM7(%q): %v", tt.text, err)
+		}
+		packed := strings.ToUpper(hex.EncodeToString(PackSeptets(septets)))
+		if packed != tt.packed {
+			t.Errorf("PackSeptets(%q) = %s, want %s", tt.text, packed, tt.packed)
+		}
+		if got := UnpackSeptets(PackSeptets(septets)); !bytes.Equal(got, septets) {
+			t.Errorf("UnpackSeptets(PackSeptets(%q)) = %v, want %v", tt.text, got, septets)
+		}
+	}
+}
+
+func TestGSM7Extension(t *testing.T) {
+	text := "Price: 10€ [a|b] {x} ~^\\"
+	septets, err := EncodeGSM7(text)
+	if err != nil {
+		t.Fatalf("EncodeGSM7: %v", err)
+	}
+	if len(septets) != len([]rune(text))+9 {
+		t.Errorf("EncodeGSM7 produced %d septets, want 9 escapes", len(septets))
+	}
+	if got := DecodeGSM7(septets); got != text {
+		t.Errorf("DecodeGSM7 = %q, want %q", got, text)
+	}
+	if _, err := EncodeGSM7("Привет"); err == nil {
+		t.Error("EncodeGSM7 accepted Cyrillic text")
+	}
+}
+
+func TestUSSDPackedRoundTrip(t *testing.T) {
+	tests := []struct {
+		text   string
+		packed string
+	}{
+		{"*100#", "AA180C3602"},
+		// 7 символов: 7 бит заполнения в последнем октете - CR, а не @
+		{"1234567", "31D98C56B3DD1A"},
+		// 8 символов занимают те же 7 октетов без заполнения
+		{"12345678", "31D98C56B3DD70"},
+		{"*102*1#", "AA184CA68A8D1A"},
+	}
+	for _, tt := range tests {
+		packed, err := EncodeUSSDPacked(tt.text)
+		if err != nil {
+			t.Fatalf("EncodeUSSDPacked(%q): %v", tt.text, err)
+		}
+		if packed != tt.packed {
+			t.Errorf("EncodeUSSDPacked(%q) = %s, want %s", tt.text, packed, tt.packed)
+		}
+		decoded, err := DecodeUSSDPacked(packed)
+		if err != nil {
+			t.Fatalf("DecodeUSSDPacked(%s): %v", packed, err)
+		}
+		if decoded != tt.text {
+			t.Errorf("DecodeUSSDPacked(%s) = %q, want %q", packed, decoded, tt.text)
+		}
+	}
+}
+
+func TestDecodeUSSDPackedPadding(t *testing.T) {
+	// Заполнение нулями (@) в 7 октетах тоже отбрасывается
+	septets, _ := EncodeGSM7("1234567")
+	packed := hex.EncodeToString(PackSeptets(septets))
+	if decoded, err := DecodeUSSDPacked(packed); err != nil || decoded != "1234567" {
+		t.Errorf("DecodeUSSDPacked(%s) = %q, %v, want 1234567", packed, decoded, err)
+	}
+	if decoded, err := DecodeUSSDPacked("AA 18 0C 36 02"); err != nil || decoded != "*100#" {
+		t.Errorf("DecodeUSSDPacked with spaces = %q, %v", decoded, err)
+	}
+	if _, err := DecodeUSSDPacked("AA1"); err == nil {
+		t.Error("DecodeUSSDPacked accepted odd-length hex")
+	}
+}
@@ -0,0 +1,425 @@
+package hilink
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// Состояния подключения (ConnectionStatus)
+const (
+	ConnectionConnecting    = 900
+	ConnectionConnected     = 901
+	ConnectionDisconnected  = 902
+	ConnectionDisconnecting = 903
+)
+
+// Состояния SIM карты (/api/pin/status, SimState)
+const (
+	simStateNoSIM    = 255
+	simStateReady    = 257
+	simStatePIN      = 260
+	simStatePUK      = 261
+	pinOperateVerify = 0
+)
+
+// DeviceInfo ответ /api/device/information
+type DeviceInfo struct {
+	DeviceName      string `xml:"DeviceName"`
+	SerialNumber    string `xml:"SerialNumber"`
+	IMEI            string `xml:"Imei"`
+	IMSI            string `xml:"Imsi"`
+	ICCID           string `xml:"Iccid"`
+	MSISDN          string `xml:"Msisdn"`
+	HardwareVersion string `xml:"HardwareVersion"`
+	SoftwareVersion string `xml:"SoftwareVersion"`
+	WebUIVersion    string `xml:"WebUIVersion"`
+	MACAddress      string `xml:"MacAddress1"`
+	ProductFamily   string `xml:"ProductFamily"`
+	Classify        string `xml:"Classify"`
+	WorkMode        string `xml:"workmode"`
+}
+
+// Status ответ /api/monitoring/status
+type Status struct {
+	ConnectionStatus   int    `xml:"ConnectionStatus"`   // 900-903, см. Connection*
+	SignalIcon         int    `xml:"SignalIcon"`         // Уровень сигнала 0-5
+	CurrentNetworkType int    `xml:"CurrentNetworkType"` // Тип сети по классификации Huawei
+	ServiceStatus      int    `xml:"ServiceStatus"`      // 2 - обслуживание доступно
+	RoamingStatus      int    `xml:"RoamingStatus"`      // 1 - роуминг
+	SimStatus          int    `xml:"SimStatus"`          // 1 - SIM карта доступна
+	WanIPAddress       string `xml:"WanIPAddress"`
+	PrimaryDNS         string `xml:"PrimaryDns"`
+	SecondaryDNS       string `xml:"SecondaryDns"`
+}
+
+// GetDeviceInfo возвращает сведения об устройстве
+func (c *Client) GetDeviceInfo() (*DeviceInfo, error) {
+	var info DeviceInfo
+	if err := c.get("/api/device/information", &info); err != nil {
+		return nil, fmt.Errorf("failed to get device information: %w", err)
+	}
+	return &info, nil
+}
+
+// GetManufacturer возвращает производителя: API HiLink есть только у модемов Huawei
+func (c *Client) GetManufacturer() (string, error) {
+	return "Huawei", nil
+}
+
+// GetModel возвращает модель модема
+func (c *Client) GetModel() (string, error) {
+	info, err := c.GetDeviceInfo()
+	if err != nil {
+		return "", err
+	}
+	return info.DeviceName, nil
+}
+
+// GetRevision возвращает версию прошивки
+func (c *Client) GetRevision() (string, error) {
+	info, err := c.GetDeviceInfo()
+	if err != nil {
+		return "", err
+	}
+	return info.SoftwareVersion, nil
+}
+
+// GetIMEI возвращает IMEI модема
+func (c *Client) GetIMEI() (string, error) {
+	info, err := c.GetDeviceInfo()
+	if err != nil {
+		return "", err
+	}
+	return info.IMEI, nil
+}
+
+// GetIMSI возвращает IMSI SIM карты
+func (c *Client) GetIMSI() (string, error) {
+	info, err := c.GetDeviceInfo()
+	if err != nil {
+		return "", err
+	}
+	if info.IMSI == "" {
+		return "", fmt.Errorf("failed to get IMSI: %w", gsm.ErrNotSupported)
+	}
+	return info.IMSI, nil
+}
+
+// GetICCID возвращает ICCID SIM карты
+func (c *Client) GetICCID() (string, error) {
+	info, err := c.GetDeviceInfo()
+	if err != nil {
+		return "", err
+	}
+	return info.ICCID, nil
+}
+
+// GetSIMNumber возвращает номер телефона, записанный на SIM карте
+func (c *Client) GetSIMNumber() (string, error) {
+	info, err := c.GetDeviceInfo()
+	if err != nil {
+		return "", err
+	}
+	return info.MSISDN, nil
+}
+
+// GetStatus возвращает состояние подключения и регистрации
+func (c *Client) GetStatus() (*Status, error) {
+	var status Status
+	if err := c.get("/api/monitoring/status", &status); err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+	return &status, nil
+}
+
+// networkStatus переводит ServiceStatus и RoamingStatus в статус регистрации
+func (s *Status) networkStatus() gsm.NetworkStatus {
+	switch s.ServiceStatus {
+	case 2:
+		if s.RoamingStatus == 1 {
+			return gsm.NetworkRegisteredRoaming
+		}
+		return gsm.NetworkRegisteredHome
+	case 1, 3:
+		return gsm.NetworkEmergencyOnly
+	case 0:
+		return gsm.NetworkSearching
+	}
+	return gsm.NetworkUnknown
+}
+
+// accessTech переводит CurrentNetworkType в технологию доступа
+func (s *Status) accessTech() gsm.AccessTechnology {
+	switch s.CurrentNetworkType {
+	case 1, 2:
+		return gsm.AccessTechGSM
+	case 3:
+		return gsm.AccessTechEDGE
+	case 4, 8, 41:
+		return gsm.AccessTechUMTS
+	case 5, 42:
+		return gsm.AccessTechHSDPA
+	case 6, 43:
+		return gsm.AccessTechHSUPA
+	case 7, 9, 44, 45, 46:
+		return gsm.AccessTechHSPA
+	case 19, 101, 1011:
+		return gsm.AccessTechLTE
+	case 111:
+		return gsm.AccessTechENDC
+	}
+	return gsm.AccessTechUnknown
+}
+
+// GetNetworkStatus возвращает статус регистрации в сети
+func (c *Client) GetNetworkStatus() (gsm.NetworkStatus, error) {
+	status, err := c.GetStatus()
+	if err != nil {
+		return gsm.NetworkUnknown, err
+	}
+	return status.networkStatus(), nil
+}
+
+// GetCurrentOperator возвращает оператора, в сети которого зарегистрирован модем
+func (c *Client) GetCurrentOperator() (*gsm.OperatorInfo, error) {
+	var plmn struct {
+		State     string `xml:"State"`
+		FullName  string `xml:"FullName"`
+		ShortName string `xml:"ShortName"`
+		Numeric   string `xml:"Numeric"`
+	}
+	if err := c.get("/api/net/current-plmn", &plmn); err != nil {
+		return nil, fmt.Errorf("failed to get current operator: %w", err)
+	}
+	if plmn.Numeric == "" {
+		return nil, fmt.Errorf("failed to get current operator: not registered")
+	}
+	op := &gsm.OperatorInfo{
+		Status:    "2",
+		LongName:  plmn.FullName,
+		ShortName: plmn.ShortName,
+		Numeric:   plmn.Numeric,
+	}
+	enrichOperator(op)
+	return op, nil
+}
+
+// enrichOperator дополняет информацию об операторе страной и названиями из справочника
+func enrichOperator(op *gsm.OperatorInfo) {
+	plmn, ok := gsm.LookupOperator(op.Numeric)
+	op.Country, op.CountryISO = plmn.Country, plmn.ISO
+	if !ok {
+		return
+	}
+	op.Brand = plmn.Brand
+	if op.LongName == "" {
+		op.LongName = plmn.Brand
+	}
+	if op.ShortName == "" {
+		op.ShortName = plmn.Brand
+	}
+}
+
+// signalInfo ответ /api/device/signal: значения вида "-67dBm", ">=-51dBm", "12dB"
+type signalInfo struct {
+	RSSI string `xml:"rssi"`
+	RSRP string `xml:"rsrp"`
+	RSRQ string `xml:"rsrq"`
+	SINR string `xml:"sinr"`
+	RSCP string `xml:"rscp"`
+	EcIo string `xml:"ecio"`
+	Mode int    `xml:"mode"` // 0 - GSM, 2 - WCDMA, 7 - LTE
+}
+
+// parseLevel разбирает значение уровня сигнала, NaN если значение не сообщается
+func parseLevel(value string) float64 {
+	value = strings.TrimSpace(value)
+	value = strings.TrimLeft(value, "<>=")
+	value = strings.TrimSuffix(value, "dBm")
+	value = strings.TrimSuffix(value, "dB")
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return math.NaN()
+	}
+	return v
+}
+
+// getSignal запрашивает /api/device/signal
+func (c *Client) getSignal() (*signalInfo, error) {
+	var info signalInfo
+	if err := c.get("/api/device/signal", &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// GetSignalQuality возвращает уровень сигнала в шкале AT+CSQ
+func (c *Client) GetSignalQuality() (*gsm.SignalQuality, error) {
+	info, err := c.getSignal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signal quality: %w", err)
+	}
+	signal := &gsm.SignalQuality{RSSI: 99, BER: 99}
+	if rssi := parseLevel(info.RSSI); !math.IsNaN(rssi) {
+		signal.RSSI = gsm.DBmToCSQ(rssi)
+	}
+	return signal, nil
+}
+
+// GetSignalReport возвращает метрики сигнала текущей технологии доступа
+func (c *Client) GetSignalReport() (*gsm.SignalReport, error) {
+	info, err := c.getSignal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signal report: %w", err)
+	}
+	report := gsm.NewSignalReport("HiLink")
+	report.RSSI = parseLevel(info.RSSI)
+	switch info.Mode {
+	case 0:
+		report.AccessTech = gsm.AccessTechGSM
+	case 2:
+		report.AccessTech = gsm.AccessTechUMTS
+		report.RSCP = parseLevel(info.RSCP)
+		report.EcIo = parseLevel(info.EcIo)
+	case 7:
+		report.AccessTech = gsm.AccessTechLTE
+		report.RSRP = parseLevel(info.RSRP)
+		report.RSRQ = parseLevel(info.RSRQ)
+		report.SINR = parseLevel(info.SINR)
+	}
+	report.Normalize()
+	return report, nil
+}
+
+// GetSIMStatus возвращает состояние PIN-кода SIM карты
+func (c *Client) GetSIMStatus() (gsm.PinStatus, error) {
+	var pin struct {
+		SimState int `xml:"SimState"`
+	}
+	if err := c.get("/api/pin/status", &pin); err != nil {
+		return "", fmt.Errorf("failed to get SIM status: %w", err)
+	}
+	switch pin.SimState {
+	case simStateReady:
+		return gsm.PinReady, nil
+	case simStatePIN:
+		return gsm.PinRequired, nil
+	case simStatePUK:
+		return gsm.PukRequired, nil
+	case simStateNoSIM:
+		return "", fmt.Errorf("failed to get SIM status: SIM not inserted")
+	}
+	return "", fmt.Errorf("failed to get SIM status: unknown SIM state %d", pin.SimState)
+}
+
+// EnterPIN вводит PIN-код SIM карты
+func (c *Client) EnterPIN(pin string) error {
+	req := struct {
+		XMLName     xml.Name `xml:"request"`
+		OperateType int      `xml:"OperateType"`
+		CurrentPin  string   `xml:"CurrentPin"`
+		NewPin      string   `xml:"NewPin"`
+		PukCode     string   `xml:"PukCode"`
+	}{OperateType: pinOperateVerify, CurrentPin: pin}
+	if err := c.post("/api/pin/operate", &req, nil); err != nil {
+		return fmt.Errorf("failed to enter PIN: %w", err)
+	}
+	return nil
+}
+
+// emitEvent отправляет событие, не блокируясь на полном канале
+func (c *Client) emitEvent(event gsm.Event) {
+	select {
+	case c.events <- event:
+	default:
+		// Канал полон, пропускаем событие
+	}
+}
+
+// GetEventChannel возвращает канал событий: EventNewSMS и EventNetworkChange.
+// HiLink не присылает уведомлений, поэтому при первом вызове запускается опрос
+// с периодом Config.PollInterval.
+func (c *Client) GetEventChannel() (<-chan gsm.Event, error) {
+	c.eventsOnce.Do(func() {
+		go c.pollEvents()
+	})
+	return c.events, nil
+}
+
+// pollEvents опрашивает состояние сети и входящие сообщения
+func (c *Client) pollEvents() {
+	ticker := time.NewTicker(c.config.PollInterval)
+	defer ticker.Stop()
+
+	var lastStatus gsm.NetworkStatus = -1
+	seen := make(map[int]bool)
+	// Сообщения, непрочитанные на момент запуска, событий не порождают
+	if messages, err := c.listBox(boxInbox, true); err == nil {
+		for _, sms := range messages {
+			seen[sms.Index] = true
+		}
+	}
+
+	for {
+		if status, err := c.GetStatus(); err == nil {
+			if ns := status.networkStatus(); ns != lastStatus {
+				if lastStatus != -1 {
+					c.emitEvent(gsm.Event{
+						Type:      gsm.EventNetworkChange,
+						Timestamp: time.Now(),
+						Data: map[string]interface{}{
+							"status":     ns,
+							"accessTech": status.accessTech(),
+						},
+					})
+				}
+				lastStatus = ns
+			}
+		}
+
+		if c.hasUnreadSMS() {
+			if messages, err := c.listBox(boxInbox, true); err == nil {
+				// Индексы удаленных сообщений используются повторно, поэтому
+				// запоминаются только непрочитанные на текущий момент
+				unread := make(map[int]bool, len(messages))
+				for _, sms := range messages {
+					unread[sms.Index] = true
+					if seen[sms.Index] {
+						continue
+					}
+					c.emitEvent(gsm.Event{
+						Type:      gsm.EventNewSMS,
+						Timestamp: time.Now(),
+						Data:      map[string]interface{}{"storage": string(gsm.StorageAny), "index": sms.Index, "sms": sms},
+					})
+				}
+				seen = unread
+			}
+		}
+
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hasUnreadSMS проверяет счетчик непрочитанных сообщений (/api/monitoring/check-notifications)
+func (c *Client) hasUnreadSMS() bool {
+	var notifications struct {
+		UnreadMessage  int `xml:"UnreadMessage"`
+		SmsStorageFull int `xml:"SmsStorageFull"`
+	}
+	if err := c.get("/api/monitoring/check-notifications", &notifications); err != nil {
+		// Уведомления поддерживаются не всеми прошивками, проверяем список
+		return true
+	}
+	return notifications.UnreadMessage > 0
+}
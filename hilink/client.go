@@ -0,0 +1,446 @@
+// Package hilink реализует клиент HTTP API модемов Huawei в прошивке HiLink (E3372h, E8372,
+// роутеры серии B). У таких модемов нет AT порта: управление идет через веб-интерфейс
+// на 192.168.8.1. Client реализует интерфейс gsm.USSDDevice.
+package hilink
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+const (
+	defaultURL          = "http://192.168.8.1"
+	defaultTimeout      = 10 * time.Second
+	defaultPollInterval = 5 * time.Second
+	maxEventQueueLength = 100
+	tokenHeader         = "__RequestVerificationToken"
+)
+
+// Коды ошибок API
+const (
+	ErrorUnknown           = 100001
+	ErrorNotSupported      = 100002
+	ErrorNoRights          = 100003 // Требуется вход
+	ErrorSystemBusy        = 100004
+	ErrorFormat            = 100005
+	ErrorParameter         = 100006
+	ErrorWrongUsername     = 108001
+	ErrorWrongPassword     = 108002
+	ErrorAlreadyLoggedIn   = 108003
+	ErrorWrongCredentials  = 108006
+	ErrorTooManyAttempts   = 108007
+	ErrorUSSDProcessing    = 111019
+	ErrorUSSDTimeout       = 111020
+	ErrorSMSSystemBusy     = 113018
+	ErrorWrongToken        = 125001
+	ErrorWrongSession      = 125002
+	ErrorWrongSessionToken = 125003
+	ErrorPINWrong          = 103002
+	ErrorSIMNotInserted    = 103011
+	ErrorSMSStorageFull    = 113053
+	ErrorNetworkNotReady   = 112005
+	ErrorDeviceNotReady    = 100008
+	ErrorVoiceBusy         = 111021
+	ErrorUSSDNetworkError  = 111022
+)
+
+// errorNames описания известных кодов ошибок
+var errorNames = map[int]string{
+	ErrorUnknown:           "unknown error",
+	ErrorNotSupported:      "not supported",
+	ErrorNoRights:          "login required",
+	ErrorSystemBusy:        "system busy",
+	ErrorFormat:            "format error",
+	ErrorParameter:         "parameter error",
+	ErrorWrongUsername:     "wrong username",
+	ErrorWrongPassword:     "wrong password",
+	ErrorAlreadyLoggedIn:   "already logged in",
+	ErrorWrongCredentials:  "wrong password",
+	ErrorTooManyAttempts:   "too many login attempts",
+	ErrorUSSDProcessing:    "USSD request in progress",
+	ErrorUSSDTimeout:       "USSD timeout",
+	ErrorSMSSystemBusy:     "SMS system busy",
+	ErrorWrongToken:        "wrong verification token",
+	ErrorWrongSession:      "wrong session",
+	ErrorWrongSessionToken: "wrong session token",
+	ErrorPINWrong:          "wrong PIN",
+	ErrorSIMNotInserted:    "SIM not inserted",
+	ErrorSMSStorageFull:    "SMS storage full",
+	ErrorNetworkNotReady:   "network not ready",
+	ErrorDeviceNotReady:    "device not ready",
+	ErrorVoiceBusy:         "voice call in progress",
+	ErrorUSSDNetworkError:  "USSD network error",
+}
+
+// Error ошибка, возвращенная API (<error><code>...</code></error>)
+type Error struct {
+	Path    string
+	Code    int
+	Message string
+}
+
+// Error возвращает текст ошибки
+func (e *Error) Error() string {
+	name, ok := errorNames[e.Code]
+	if !ok {
+		name = e.Message
+	}
+	if name == "" {
+		return fmt.Sprintf("HiLink %s: error %d", e.Path, e.Code)
+	}
+	return fmt.Sprintf("HiLink %s: error %d (%s)", e.Path, e.Code, name)
+}
+
+// IsError проверяет, что err - ошибка API с указанным кодом
+func IsError(err error, code int) bool {
+	var herr *Error
+	return errors.As(err, &herr) && herr.Code == code
+}
+
+// Config параметры клиента HiLink
+type Config struct {
+	URL          string        // Адрес веб-интерфейса (по умолчанию http://192.168.8.1)
+	Username     string        // Имя пользователя (по умолчанию admin, если задан пароль)
+	Password     string        // Пароль веб-интерфейса (пусто - без входа)
+	Timeout      time.Duration // Тайм-аут HTTP запроса (по умолчанию 10 секунд)
+	PollInterval time.Duration // Период опроса для канала событий (по умолчанию 5 секунд)
+	HTTPClient   *http.Client  // HTTP клиент (по умолчанию создается с Timeout)
+}
+
+// Client клиент HTTP API HiLink. Запросы выполняются по одному: каждый POST расходует
+// токен CSRF, новый модем возвращает в заголовке ответа.
+type Client struct {
+	config Config
+	http   *http.Client
+
+	mu       sync.Mutex
+	session  string   // Cookie SessionID
+	tokens   []string // Неизрасходованные токены __RequestVerificationToken
+	loggedIn bool
+
+	eventsOnce sync.Once
+	events     chan gsm.Event
+	stop       chan struct{}
+	closeOnce  sync.Once
+}
+
+// Open подключается к веб-интерфейсу модема и, если задан пароль, выполняет вход
+func Open(config Config) (*Client, error) {
+	if config.URL == "" {
+		config.URL = defaultURL
+	}
+	config.URL = strings.TrimRight(config.URL, "/")
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultPollInterval
+	}
+	if config.Password != "" && config.Username == "" {
+		config.Username = "admin"
+	}
+	c := &Client{
+		config: config,
+		http:   config.HTTPClient,
+		events: make(chan gsm.Event, maxEventQueueLength),
+		stop:   make(chan struct{}),
+	}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: config.Timeout}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.refreshToken(); err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", config.URL, err)
+	}
+	if config.Password != "" {
+		if err := c.login(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// sesTokInfo ответ /api/webserver/SesTokInfo
+type sesTokInfo struct {
+	SesInfo string `xml:"SesInfo"`
+	TokInfo string `xml:"TokInfo"`
+}
+
+// refreshToken получает cookie сессии и токен CSRF (вызывающий держит c.mu)
+func (c *Client) refreshToken() error {
+	var info sesTokInfo
+	body, err := c.roundTrip(http.MethodGet, "/api/webserver/SesTokInfo", nil)
+	if err == nil {
+		err = decodeResponse("/api/webserver/SesTokInfo", body, &info)
+	}
+	if err != nil {
+		// Старые прошивки выдают только токен, cookie сессии приходит в Set-Cookie
+		var token struct {
+			Token string `xml:"token"`
+		}
+		body, tokErr := c.roundTrip(http.MethodGet, "/api/webserver/token", nil)
+		if tokErr != nil {
+			return err
+		}
+		if decodeResponse("/api/webserver/token", body, &token) != nil || token.Token == "" {
+			return err
+		}
+		// Действителен хвост токена длиной 32 символа
+		if len(token.Token) > 32 {
+			token.Token = token.Token[len(token.Token)-32:]
+		}
+		c.tokens = []string{token.Token}
+		return nil
+	}
+	if session := strings.TrimPrefix(info.SesInfo, "SessionID="); session != "" {
+		c.session = session
+	}
+	c.tokens = []string{info.TokInfo}
+	return nil
+}
+
+// roundTrip выполняет HTTP запрос с cookie сессии и токеном, обновляет их из ответа
+// (вызывающий держит c.mu)
+func (c *Client) roundTrip(method, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, c.config.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Requested-With", "XMLHttpRequest")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
+	}
+	if c.session != "" {
+		req.AddCookie(&http.Cookie{Name: "SessionID", Value: c.session})
+	}
+	if len(c.tokens) > 0 {
+		req.Header.Set(tokenHeader, c.tokens[0])
+		if method == http.MethodPost {
+			c.tokens = c.tokens[1:]
+		}
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// После входа модем выдает сразу несколько токенов через '#'
+	if header := resp.Header.Get(tokenHeader); header != "" {
+		c.tokens = c.tokens[:0]
+		for _, token := range strings.Split(header, "#") {
+			if token != "" {
+				c.tokens = append(c.tokens, token)
+			}
+		}
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "SessionID" && cookie.Value != "" {
+			c.session = cookie.Value
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HiLink %s: HTTP %s", path, resp.Status)
+	}
+	return data, nil
+}
+
+// decodeResponse разбирает <response> в out или возвращает *Error для <error>
+func decodeResponse(path string, data []byte, out interface{}) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return fmt.Errorf("HiLink %s: invalid response: %w", path, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "error":
+			var apiErr struct {
+				Code    int    `xml:"code"`
+				Message string `xml:"message"`
+			}
+			if err := decoder.DecodeElement(&apiErr, &start); err != nil {
+				return fmt.Errorf("HiLink %s: invalid error response: %w", path, err)
+			}
+			return &Error{Path: path, Code: apiErr.Code, Message: apiErr.Message}
+		case "response":
+			if out == nil {
+				return nil
+			}
+			if err := decoder.DecodeElement(out, &start); err != nil {
+				return fmt.Errorf("HiLink %s: invalid response: %w", path, err)
+			}
+			return nil
+		default:
+			return fmt.Errorf("HiLink %s: unexpected element <%s>", path, start.Name.Local)
+		}
+	}
+}
+
+// encodeRequest кодирует тело запроса <request>
+func encodeRequest(in interface{}) ([]byte, error) {
+	body, err := xml.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// call выполняет запрос к API. При устаревшем токене или сессии токен обновляется,
+// при ошибке доступа выполняется вход, и запрос повторяется один раз.
+func (c *Client) call(method, path string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = encodeRequest(in); err != nil {
+			return fmt.Errorf("HiLink %s: %w", path, err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for attempt := 0; ; attempt++ {
+		if len(c.tokens) == 0 {
+			if err := c.refreshToken(); err != nil {
+				return err
+			}
+		}
+		data, err := c.roundTrip(method, path, body)
+		if err == nil {
+			err = decodeResponse(path, data, out)
+		}
+		if err == nil || attempt > 0 {
+			return err
+		}
+
+		switch {
+		case IsError(err, ErrorWrongToken), IsError(err, ErrorWrongSession), IsError(err, ErrorWrongSessionToken):
+			c.session, c.tokens, c.loggedIn = "", nil, false
+			if err := c.refreshToken(); err != nil {
+				return err
+			}
+			if c.config.Password != "" {
+				if err := c.login(); err != nil {
+					return err
+				}
+			}
+		case IsError(err, ErrorNoRights) && c.config.Password != "":
+			if err := c.login(); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+}
+
+// get выполняет GET запрос к API
+func (c *Client) get(path string, out interface{}) error {
+	return c.call(http.MethodGet, path, nil, out)
+}
+
+// post выполняет POST запрос к API
+func (c *Client) post(path string, in, out interface{}) error {
+	return c.call(http.MethodPost, path, in, out)
+}
+
+// loginRequest тело /api/user/login
+type loginRequest struct {
+	XMLName      xml.Name `xml:"request"`
+	Username     string   `xml:"Username"`
+	Password     string   `xml:"Password"`
+	PasswordType int      `xml:"password_type"`
+}
+
+// login выполняет вход в веб-интерфейс (вызывающий держит c.mu)
+func (c *Client) login() error {
+	var state struct {
+		State        int `xml:"State"`
+		PasswordType int `xml:"password_type"`
+	}
+	data, err := c.roundTrip(http.MethodGet, "/api/user/state-login", nil)
+	if err == nil {
+		err = decodeResponse("/api/user/state-login", data, &state)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get login state: %w", err)
+	}
+	if state.State == 0 {
+		c.loggedIn = true
+		return nil
+	}
+
+	if len(c.tokens) == 0 {
+		if err := c.refreshToken(); err != nil {
+			return err
+		}
+	}
+	req := loginRequest{Username: c.config.Username, PasswordType: state.PasswordType}
+	if state.PasswordType == 4 {
+		// base64(sha256(user + base64(sha256(password)) + token)), sha256 в hex
+		req.Password = hashPassword(c.config.Username + hashPassword(c.config.Password) + c.tokens[0])
+	} else {
+		req.Password = base64.StdEncoding.EncodeToString([]byte(c.config.Password))
+	}
+	body, err := encodeRequest(req)
+	if err != nil {
+		return err
+	}
+	data, err = c.roundTrip(http.MethodPost, "/api/user/login", body)
+	if err == nil {
+		err = decodeResponse("/api/user/login", data, nil)
+	}
+	if err != nil && !IsError(err, ErrorAlreadyLoggedIn) {
+		return fmt.Errorf("failed to log in: %w", err)
+	}
+	c.loggedIn = true
+	return nil
+}
+
+// hashPassword кодирует значение как base64 от hex-строки SHA-256
+func hashPassword(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return base64.StdEncoding.EncodeToString([]byte(hex.EncodeToString(sum[:])))
+}
+
+// Close останавливает опрос событий и завершает сессию веб-интерфейса
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.mu.Lock()
+		loggedIn := c.loggedIn
+		c.mu.Unlock()
+		if loggedIn {
+			c.post("/api/user/logout", &struct {
+				XMLName xml.Name `xml:"request"`
+				Logout  int      `xml:"Logout"`
+			}{Logout: 1}, nil)
+		}
+	})
+	return nil
+}
+
+var _ gsm.USSDDevice = (*Client)(nil)
@@ -0,0 +1,238 @@
+package hilink
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// apiHandler обработчик запроса к API: возвращает содержимое <response> или код ошибки
+type apiHandler func(body []byte) (string, int)
+
+// fakeRouter веб-интерфейс HiLink: сессии, одноразовые токены CSRF и вход с
+// password_type 4; остальные запросы обслуживают обработчики api
+type fakeRouter struct {
+	t        *testing.T
+	user     string
+	password string
+
+	mu        sync.Mutex
+	session   string
+	sessions  int
+	tokens    map[string]bool // Выданные и неизрасходованные токены
+	nextToken int
+	loggedIn  bool
+	logins    int
+	fetches   int      // Запросы SesTokInfo
+	rejected  int      // POST с израсходованным или чужим токеном
+	paths     []string // Запросы к api (кроме служебных)
+	api       map[string]apiHandler
+}
+
+// newFakeRouter запускает веб-интерфейс и открывает клиента с входом
+func newFakeRouter(t *testing.T, api map[string]apiHandler) (*fakeRouter, *Client) {
+	t.Helper()
+	f := &fakeRouter{t: t, user: "admin", password: "secret", tokens: make(map[string]bool), api: api}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	c, err := Open(Config{URL: server.URL + "/", Password: f.password})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return f, c
+}
+
+// issue выдает новый токен (под f.mu)
+func (f *fakeRouter) issue() string {
+	f.nextToken++
+	token := fmt.Sprintf("%032d", f.nextToken)
+	f.tokens[token] = true
+	return token
+}
+
+// expire завершает сессию, как модем по тайм-ауту бездействия
+func (f *fakeRouter) expire() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.session, f.loggedIn = "", false
+	f.tokens = make(map[string]bool)
+}
+
+// counters возвращает число входов, запросов токена и отвергнутых токенов
+func (f *fakeRouter) counters() (logins, fetches, rejected int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.logins, f.fetches, f.rejected
+}
+
+// ServeHTTP отвечает на запрос к веб-интерфейсу
+func (f *fakeRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	respond := func(inner string) {
+		fmt.Fprintf(w, "%s<response>%s</response>", xml.Header, inner)
+	}
+	fail := func(code int) {
+		fmt.Fprintf(w, "%s<error><code>%d</code><message></message></error>", xml.Header, code)
+	}
+
+	if r.URL.Path == "/api/webserver/SesTokInfo" {
+		f.fetches++
+		if f.session == "" {
+			f.sessions++
+			f.session = fmt.Sprintf("session%d", f.sessions)
+		}
+		respond(fmt.Sprintf("<SesInfo>SessionID=%s</SesInfo><TokInfo>%s</TokInfo>", f.session, f.issue()))
+		return
+	}
+	if cookie, err := r.Cookie("SessionID"); err != nil || f.session == "" || cookie.Value != f.session {
+		fail(ErrorWrongSession)
+		return
+	}
+	if r.Method == http.MethodPost {
+		token := r.Header.Get(tokenHeader)
+		if !f.tokens[token] {
+			f.rejected++
+			fail(ErrorWrongSessionToken)
+			return
+		}
+		delete(f.tokens, token)
+
+		if r.URL.Path == "/api/user/login" {
+			f.login(w, body, token, respond, fail)
+			return
+		}
+	}
+
+	switch r.URL.Path {
+	case "/api/user/state-login":
+		state := -1
+		if f.loggedIn {
+			state = 0
+		}
+		respond(fmt.Sprintf("<State>%d</State><Username></Username><password_type>4</password_type>", state))
+		return
+	case "/api/user/logout":
+		f.loggedIn = false
+		respond("OK")
+		return
+	}
+
+	if !f.loggedIn {
+		fail(ErrorNoRights)
+		return
+	}
+	f.paths = append(f.paths, r.URL.Path)
+	handle, ok := f.api[r.URL.Path]
+	if !ok {
+		fail(ErrorNotSupported)
+		return
+	}
+	// Обработчик может обращаться к api и вызывается без f.mu
+	f.mu.Unlock()
+	inner, code := handle(body)
+	f.mu.Lock()
+	if code != 0 {
+		fail(code)
+		return
+	}
+	respond(inner)
+}
+
+// login проверяет пароль password_type 4 и выдает пачку токенов (под f.mu)
+func (f *fakeRouter) login(w http.ResponseWriter, body []byte, token string, respond func(string), fail func(int)) {
+	var req struct {
+		Username     string `xml:"Username"`
+		Password     string `xml:"Password"`
+		PasswordType int    `xml:"password_type"`
+	}
+	if err := xml.Unmarshal(body, &req); err != nil {
+		f.t.Errorf("malformed login request %q: %v", body, err)
+		fail(ErrorFormat)
+		return
+	}
+	// base64(hex(sha256(user + base64(hex(sha256(password))) + token)))
+	encode := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return base64.StdEncoding.EncodeToString([]byte(hex.EncodeToString(sum[:])))
+	}
+	if req.Username != f.user || req.PasswordType != 4 || req.Password != encode(f.user+encode(f.password)+token) {
+		fail(ErrorWrongCredentials)
+		return
+	}
+	f.loggedIn = true
+	f.logins++
+	w.Header().Set(tokenHeader, strings.Join([]string{f.issue(), f.issue(), f.issue()}, "#"))
+	respond("OK")
+}
+
+func TestLogin(t *testing.T) {
+	f, _ := newFakeRouter(t, nil)
+	if logins, _, rejected := f.counters(); logins != 1 || rejected != 0 {
+		t.Errorf("logins = %d, rejected tokens = %d, want 1 login", logins, rejected)
+	}
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	f := &fakeRouter{t: t, user: "admin", password: "secret", tokens: make(map[string]bool)}
+	server := httptest.NewServer(f)
+	defer server.Close()
+
+	_, err := Open(Config{URL: server.URL, Password: "wrong"})
+	if !IsError(err, ErrorWrongCredentials) {
+		t.Errorf("Open: %v, want wrong credentials", err)
+	}
+}
+
+func TestTokenRotation(t *testing.T) {
+	f, c := newFakeRouter(t, map[string]apiHandler{
+		"/api/sms/delete-sms": func([]byte) (string, int) { return "OK", 0 },
+	})
+	_, fetchesAfterLogin, _ := f.counters()
+
+	// После входа выдано три токена; четвертый POST получает токен через SesTokInfo
+	for i := 1; i <= 4; i++ {
+		if err := c.DeleteSMS(40000 + i); err != nil {
+			t.Fatalf("DeleteSMS #%d: %v", i, err)
+		}
+	}
+	logins, fetches, rejected := f.counters()
+	if rejected != 0 {
+		t.Errorf("%d POST requests reused a token", rejected)
+	}
+	if fetches != fetchesAfterLogin+1 || logins != 1 {
+		t.Errorf("token fetches = %d, logins = %d, want %d fetches and 1 login", fetches, logins, fetchesAfterLogin+1)
+	}
+}
+
+func TestSessionExpired(t *testing.T) {
+	f, c := newFakeRouter(t, map[string]apiHandler{
+		"/api/device/information": func([]byte) (string, int) {
+			return "<DeviceName>E3372h-320</DeviceName><Imei>861234567890123</Imei>", 0
+		},
+	})
+
+	f.expire()
+	imei, err := c.GetIMEI()
+	if err != nil {
+		t.Fatalf("GetIMEI: %v", err)
+	}
+	if imei != "861234567890123" {
+		t.Errorf("IMEI = %q", imei)
+	}
+	if logins, _, _ := f.counters(); logins != 2 {
+		t.Errorf("logins = %d, want re-login after error %d", logins, ErrorWrongSession)
+	}
+}
@@ -0,0 +1,247 @@
+package hilink
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// Папки сообщений (BoxType)
+const (
+	boxInbox  = 1
+	boxOutbox = 2
+	boxDraft  = 3
+)
+
+const (
+	smsPageSize         = 50 // Максимальный ReadCount, который принимают прошивки
+	smsDateLayout       = "2006-01-02 15:04:05"
+	smsSendTimeout      = 60 * time.Second
+	smsSendPollInterval = time.Second
+	smsStatUnread       = 0
+)
+
+// smsListRequest тело /api/sms/sms-list
+type smsListRequest struct {
+	XMLName         xml.Name `xml:"request"`
+	PageIndex       int      `xml:"PageIndex"`
+	ReadCount       int      `xml:"ReadCount"`
+	BoxType         int      `xml:"BoxType"`
+	SortType        int      `xml:"SortType"`
+	Ascending       int      `xml:"Ascending"`
+	UnreadPreferred int      `xml:"UnreadPreferred"`
+}
+
+// smsMessage сообщение в ответе /api/sms/sms-list
+type smsMessage struct {
+	Smstat  int    `xml:"Smstat"` // 0 - непрочитанное, 1 - прочитанное
+	Index   int    `xml:"Index"`
+	Phone   string `xml:"Phone"`
+	Content string `xml:"Content"`
+	Date    string `xml:"Date"`
+}
+
+// smsIndexRequest тело запросов с индексом сообщения (delete-sms, set-read)
+type smsIndexRequest struct {
+	XMLName xml.Name `xml:"request"`
+	Index   int      `xml:"Index"`
+}
+
+// toSMS переводит сообщение HiLink в gsm.SMS со статусом в формате AT+CMGL
+func (m *smsMessage) toSMS(box int) *gsm.SMS {
+	sms := &gsm.SMS{Index: m.Index, Text: m.Content}
+	// Модем хранит дату без часового пояса, в локальном времени
+	if t, err := time.ParseInLocation(smsDateLayout, m.Date, time.Local); err == nil {
+		sms.Time = t
+	}
+	switch box {
+	case boxInbox:
+		sms.Sender = m.Phone
+		sms.Status = "REC READ"
+		if m.Smstat == smsStatUnread {
+			sms.Status = "REC UNREAD"
+		}
+	case boxOutbox:
+		sms.Receiver = m.Phone
+		sms.Status = "STO SENT"
+	case boxDraft:
+		sms.Receiver = m.Phone
+		sms.Status = "STO UNSENT"
+	}
+	return sms
+}
+
+// listBox возвращает сообщения папки постранично
+func (c *Client) listBox(box int, unreadOnly bool) ([]*gsm.SMS, error) {
+	var messages []*gsm.SMS
+	for page := 1; ; page++ {
+		var resp struct {
+			Count    int          `xml:"Count"`
+			Messages []smsMessage `xml:"Messages>Message"`
+		}
+		req := smsListRequest{PageIndex: page, ReadCount: smsPageSize, BoxType: box}
+		if unreadOnly {
+			req.UnreadPreferred = 1
+		}
+		if err := c.post("/api/sms/sms-list", &req, &resp); err != nil {
+			return nil, err
+		}
+		for i := range resp.Messages {
+			if unreadOnly && resp.Messages[i].Smstat != smsStatUnread {
+				continue
+			}
+			messages = append(messages, resp.Messages[i].toSMS(box))
+		}
+		if len(resp.Messages) < smsPageSize {
+			return messages, nil
+		}
+	}
+}
+
+// ListSMS возвращает сообщения с указанным статусом AT+CMGL ("REC UNREAD", "ALL" или "")
+func (c *Client) ListSMS(status string) ([]*gsm.SMS, error) {
+	var (
+		boxes  []int
+		filter string
+	)
+	switch status {
+	case "", "ALL":
+		boxes = []int{boxInbox, boxOutbox, boxDraft}
+	case "REC UNREAD", "REC READ":
+		boxes, filter = []int{boxInbox}, status
+	case "STO SENT":
+		boxes = []int{boxOutbox}
+	case "STO UNSENT":
+		boxes = []int{boxDraft}
+	default:
+		return nil, fmt.Errorf("unsupported SMS status %q", status)
+	}
+
+	var messages []*gsm.SMS
+	for _, box := range boxes {
+		list, err := c.listBox(box, filter == "REC UNREAD")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list SMS: %w", err)
+		}
+		for _, sms := range list {
+			if filter == "" || sms.Status == filter {
+				messages = append(messages, sms)
+			}
+		}
+	}
+	return messages, nil
+}
+
+// ReadSMS читает SMS по индексу и помечает входящее сообщение прочитанным
+func (c *Client) ReadSMS(index int) (*gsm.SMS, error) {
+	// Отдельного запроса чтения в API нет, сообщение ищется в папках
+	for _, box := range []int{boxInbox, boxOutbox, boxDraft} {
+		list, err := c.listBox(box, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SMS %d: %w", index, err)
+		}
+		for _, sms := range list {
+			if sms.Index != index {
+				continue
+			}
+			if sms.Status == "REC UNREAD" {
+				if err := c.MarkSMSAsRead(index); err != nil {
+					return nil, err
+				}
+			}
+			return sms, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to read SMS %d: no message", index)
+}
+
+// MarkSMSAsRead помечает сообщение прочитанным
+func (c *Client) MarkSMSAsRead(index int) error {
+	if err := c.post("/api/sms/set-read", &smsIndexRequest{Index: index}, nil); err != nil {
+		return fmt.Errorf("failed to mark SMS %d as read: %w", index, err)
+	}
+	return nil
+}
+
+// CountUnreadSMS возвращает количество непрочитанных сообщений
+func (c *Client) CountUnreadSMS() (int, error) {
+	var count struct {
+		LocalUnread int `xml:"LocalUnread"`
+		SimUnread   int `xml:"SimUnread"`
+	}
+	if err := c.get("/api/sms/sms-count", &count); err != nil {
+		return 0, fmt.Errorf("failed to count unread SMS: %w", err)
+	}
+	return count.LocalUnread + count.SimUnread, nil
+}
+
+// DeleteSMS удаляет SMS по индексу
+func (c *Client) DeleteSMS(index int) error {
+	if err := c.post("/api/sms/delete-sms", &smsIndexRequest{Index: index}, nil); err != nil {
+		return fmt.Errorf("failed to delete SMS %d: %w", index, err)
+	}
+	return nil
+}
+
+// DeleteAllSMS удаляет все SMS
+func (c *Client) DeleteAllSMS() error {
+	messages, err := c.ListSMS("ALL")
+	if err != nil {
+		return fmt.Errorf("failed to delete all SMS: %w", err)
+	}
+	for _, sms := range messages {
+		if err := c.DeleteSMS(sms.Index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SendSMS отправляет SMS сообщение и ждет результата отправки
+func (c *Client) SendSMS(number, text string) error {
+	req := struct {
+		XMLName  xml.Name `xml:"request"`
+		Index    int      `xml:"Index"`
+		Phones   []string `xml:"Phones>Phone"`
+		Sca      string   `xml:"Sca"`
+		Content  string   `xml:"Content"`
+		Length   int      `xml:"Length"`
+		Reserved int      `xml:"Reserved"`
+		Date     string   `xml:"Date"`
+	}{
+		Index:    -1,
+		Phones:   []string{number},
+		Content:  text,
+		Length:   utf8.RuneCountInString(text),
+		Reserved: 1,
+		Date:     time.Now().Format(smsDateLayout),
+	}
+	if err := c.post("/api/sms/send-sms", &req, nil); err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+
+	// Отправка асинхронная: результат по номерам сообщает send-status
+	deadline := time.Now().Add(smsSendTimeout)
+	for {
+		var status struct {
+			SucPhone  string `xml:"SucPhone"`
+			FailPhone string `xml:"FailPhone"`
+		}
+		if err := c.get("/api/sms/send-status", &status); err != nil {
+			return fmt.Errorf("failed to send SMS: %w", err)
+		}
+		switch {
+		case status.FailPhone != "":
+			return fmt.Errorf("failed to send SMS to %s", status.FailPhone)
+		case status.SucPhone != "":
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("failed to send SMS: timeout")
+		}
+		time.Sleep(smsSendPollInterval)
+	}
+}
@@ -0,0 +1,97 @@
+package hilink
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// smsBox обработчик /api/sms/sms-list для папки входящих из count сообщений;
+// непрочитанное - каждое третье
+func smsBox(t *testing.T, count int, pages *[]int, mu *sync.Mutex) apiHandler {
+	return func(body []byte) (string, int) {
+		var req smsListRequest
+		if err := xml.Unmarshal(body, &req); err != nil {
+			t.Errorf("malformed sms-list request %q: %v", body, err)
+			return "", ErrorFormat
+		}
+		if req.ReadCount > smsPageSize || req.ReadCount <= 0 || req.PageIndex < 1 {
+			return "", ErrorParameter
+		}
+		mu.Lock()
+		*pages = append(*pages, req.PageIndex)
+		mu.Unlock()
+		if req.BoxType != boxInbox {
+			return "<Count>0</Count><Messages></Messages>", 0
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "<Count>%d</Count><Messages>", count)
+		for i := (req.PageIndex - 1) * req.ReadCount; i < min(req.PageIndex*req.ReadCount, count); i++ {
+			stat := 1
+			if i%3 == 0 {
+				stat = smsStatUnread
+			}
+			fmt.Fprintf(&b, "<Message><Smstat>%d</Smstat><Index>%d</Index><Phone>+79161234567</Phone>"+
+				"<Content>message %d</Content><Date>2024-05-01 12:00:00</Date></Message>", stat, 40000+i, i)
+		}
+		b.WriteString("</Messages>")
+		return b.String(), 0
+	}
+}
+
+func TestListSMSPaging(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		pages []int
+	)
+	_, c := newFakeRouter(t, map[string]apiHandler{"/api/sms/sms-list": smsBox(t, 120, &pages, &mu)})
+
+	messages, err := c.ListSMS("REC READ")
+	if err != nil {
+		t.Fatalf("ListSMS: %v", err)
+	}
+	if len(messages) != 80 {
+		t.Fatalf("ListSMS returned %d messages, want 80 read", len(messages))
+	}
+	if last := messages[len(messages)-1]; last.Index != 40119 || last.Sender != "+79161234567" || last.Text != "message 119" {
+		t.Errorf("last message = %+v", last)
+	}
+	mu.Lock()
+	if fmt.Sprint(pages) != "[1 2 3]" {
+		t.Errorf("requested pages %v, want [1 2 3]", pages)
+	}
+	mu.Unlock()
+
+	unread, err := c.ListSMS("REC UNREAD")
+	if err != nil {
+		t.Fatalf("ListSMS: %v", err)
+	}
+	if len(unread) != 40 || unread[0].Status != "REC UNREAD" {
+		t.Errorf("ListSMS(REC UNREAD) returned %d messages, want 40 unread", len(unread))
+	}
+}
+
+func TestListSMSFullPage(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		pages []int
+	)
+	_, c := newFakeRouter(t, map[string]apiHandler{"/api/sms/sms-list": smsBox(t, smsPageSize, &pages, &mu)})
+
+	messages, err := c.ListSMS("ALL")
+	if err != nil {
+		t.Fatalf("ListSMS: %v", err)
+	}
+	if len(messages) != smsPageSize {
+		t.Errorf("ListSMS returned %d messages, want %d", len(messages), smsPageSize)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// Входящие: полная страница и пустая; исходящие и черновики: по одной пустой
+	if fmt.Sprint(pages) != "[1 2 1 1]" {
+		t.Errorf("requested pages %v, want [1 2 1 1]", pages)
+	}
+}
@@ -0,0 +1,67 @@
+package hilink
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+const (
+	ussdTimeout      = 30 * time.Second
+	ussdPollInterval = time.Second
+	ussdStatusDone   = 0 // result в /api/ussd/status: ответ получен
+)
+
+// SendUSSD отправляет USSD запрос и возвращает текст ответа сети
+func (c *Client) SendUSSD(code string) (string, error) {
+	req := struct {
+		XMLName  xml.Name `xml:"request"`
+		Content  string   `xml:"content"`
+		CodeType string   `xml:"codeType"`
+		Timeout  string   `xml:"timeout"`
+	}{Content: code, CodeType: "CodeType"}
+
+	err := c.post("/api/ussd/send", &req, nil)
+	if IsError(err, ErrorUSSDProcessing) {
+		// Модем занят незавершенной сессией: закрываем ее и повторяем запрос
+		c.ReleaseUSSD()
+		err = c.post("/api/ussd/send", &req, nil)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to send USSD: %w", err)
+	}
+
+	deadline := time.Now().Add(ussdTimeout)
+	for {
+		var status struct {
+			Result int `xml:"result"`
+		}
+		if err := c.get("/api/ussd/status", &status); err != nil {
+			return "", fmt.Errorf("failed to get USSD status: %w", err)
+		}
+		if status.Result == ussdStatusDone {
+			break
+		}
+		if time.Now().After(deadline) {
+			c.ReleaseUSSD()
+			return "", fmt.Errorf("failed to send USSD: timeout")
+		}
+		time.Sleep(ussdPollInterval)
+	}
+
+	var resp struct {
+		Content string `xml:"content"`
+	}
+	if err := c.get("/api/ussd/get", &resp); err != nil {
+		return "", fmt.Errorf("failed to get USSD response: %w", err)
+	}
+	return resp.Content, nil
+}
+
+// ReleaseUSSD завершает текущую USSD сессию
+func (c *Client) ReleaseUSSD() error {
+	if err := c.get("/api/ussd/release", nil); err != nil {
+		return fmt.Errorf("failed to release USSD session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package hilink
+
+import (
+	"encoding/xml"
+	"sync"
+	"testing"
+)
+
+func TestSendUSSD(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		sends    int
+		releases int
+		polls    int
+	)
+	_, c := newFakeRouter(t, map[string]apiHandler{
+		"/api/ussd/send": func(body []byte) (string, int) {
+			var req struct {
+				Content string `xml:"content"`
+			}
+			if err := xml.Unmarshal(body, &req); err != nil || req.Content != "*100#" {
+				t.Errorf("ussd send request %q", body)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			sends++
+			if sends == 1 && releases == 0 {
+				// Предыдущая сессия не завершена
+				return "", ErrorUSSDProcessing
+			}
+			return "OK", 0
+		},
+		"/api/ussd/release": func([]byte) (string, int) {
+			mu.Lock()
+			defer mu.Unlock()
+			releases++
+			return "OK", 0
+		},
+		"/api/ussd/status": func([]byte) (string, int) {
+			mu.Lock()
+			defer mu.Unlock()
+			polls++
+			if polls == 1 {
+				return "<result>1</result>", 0 // Ответ сети еще не получен
+			}
+			return "<result>0</result>", 0
+		},
+		"/api/ussd/get": func([]byte) (string, int) {
+			return "<content>Balance: 123.45 RUB</content>", 0
+		},
+	})
+
+	resp, err := c.SendUSSD("*100#")
+	if err != nil {
+		t.Fatalf("SendUSSD: %v", err)
+	}
+	if resp != "Balance: 123.45 RUB" {
+		t.Errorf("SendUSSD = %q", resp)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if sends != 2 || releases != 1 || polls != 2 {
+		t.Errorf("sends = %d, releases = %d, status polls = %d, want 2, 1, 2", sends, releases, polls)
+	}
+}
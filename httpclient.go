@@ -0,0 +1,469 @@
+package gsm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultHTTPTimeout = 60 * time.Second
+	httpReadChunk      = 2048                 // размер части ответа AT+HTTPREAD
+	quectelCACertFile  = "UFS:gsm_cacert.pem" // файл корневых сертификатов на модеме Quectel
+)
+
+// qhttpReadEnd окончание ответа AT+QHTTPREAD после данных
+var qhttpReadEnd = regexp.MustCompile(`\r\nOK\r\n\r\n\+QHTTPREAD: (\d+)\r\n$`)
+
+// HTTPConfig настройки HTTP клиента модема
+type HTTPConfig struct {
+	CID                int           // PDP контекст (по умолчанию 1)
+	APN                string        // Точка доступа, если контекст еще не настроен (SIM800: AT+SAPBR)
+	Timeout            time.Duration // Ожидание ответа сервера (по умолчанию 60 секунд)
+	CACert             []byte        // Корневые сертификаты (PEM) для проверки HTTPS; Quectel загружает их в файл модема
+	InsecureSkipVerify bool          // Не проверять сертификат сервера HTTPS
+}
+
+// HTTPTransport http.RoundTripper на встроенном HTTP(S) клиенте модема: Quectel AT+QHTTPURL/QHTTPGET/
+// QHTTPPOST/QHTTPREAD и SIMCom SIM800 AT+HTTPINIT/HTTPACTION/HTTPREAD. Запросы выполняются по одному.
+// Quectel проверяет сертификат сервера HTTPS по HTTPConfig.CACert (без него - по сертификатам,
+// уже настроенным в модеме) и требует верного времени модема. SIM800 сертификаты не проверяет,
+// поэтому HTTPS на нем выполняется только с InsecureSkipVerify.
+type HTTPTransport struct {
+	modem  *Modem
+	config HTTPConfig
+	mu     sync.Mutex // HTTP клиент модема обслуживает один запрос
+}
+
+// NewHTTPTransport активирует PDP контекст для HTTP клиента модема и возвращает RoundTripper
+func (m *Modem) NewHTTPTransport(config HTTPConfig) (*HTTPTransport, error) {
+	if config.CID <= 0 {
+		config.CID = 1
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultHTTPTimeout
+	}
+
+	t := &HTTPTransport{modem: m, config: config}
+	var err error
+	switch m.Vendor() {
+	case VendorQuectel:
+		err = t.setupQuectel()
+	case VendorSIMCom:
+		err = t.setupSIMCom()
+	default:
+		return nil, ErrNotSupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up HTTP client: %w", err)
+	}
+	return t, nil
+}
+
+// setupQuectel активирует контекст и включает передачу заголовков запроса и ответа
+func (t *HTTPTransport) setupQuectel() error {
+	stack := &quectelStack{m: t.modem}
+	if err := stack.activate(DialerConfig{CID: t.config.CID, APN: t.config.APN}); err != nil {
+		return err
+	}
+
+	commands := []string{
+		fmt.Sprintf(`AT+QHTTPCFG="contextid",%d`, t.config.CID),
+		// Запрос передается целиком (строка запроса, заголовки, тело)
+		`AT+QHTTPCFG="requestheader",1`,
+		// AT+QHTTPREAD возвращает строку статуса и заголовки
+		`AT+QHTTPCFG="responseheader",1`,
+		`AT+QHTTPCFG="sslctxid",1`,
+		`AT+QSSLCFG="sslversion",1,4`,
+		`AT+QSSLCFG="ciphersuite",1,0xFFFF`,
+	}
+	// Уровень 0 - без проверки, 1 - проверка сертификата сервера
+	if t.config.InsecureSkipVerify {
+		commands = append(commands, `AT+QSSLCFG="seclevel",1,0`)
+	} else {
+		commands = append(commands, `AT+QSSLCFG="seclevel",1,1`)
+		if len(t.config.CACert) > 0 {
+			if err := t.uploadQuectelCACert(); err != nil {
+				return err
+			}
+			commands = append(commands, fmt.Sprintf(`AT+QSSLCFG="cacert",1,"%s"`, quectelCACertFile))
+		}
+	}
+	for _, cmd := range commands {
+		if _, err := t.modem.execCommand(cmd, time.Second*5); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadQuectelCACert записывает HTTPConfig.CACert в файл модема (AT+QFUPL)
+func (t *HTTPTransport) uploadQuectelCACert() error {
+	m := t.modem
+	// Файл от прошлого запуска мешает загрузке
+	m.execCommand(fmt.Sprintf(`AT+QFDEL="%s"`, quectelCACertFile), time.Second*5)
+
+	cmd := fmt.Sprintf(`AT+QFUPL="%s",%d,10`, quectelCACertFile, len(t.config.CACert))
+	if _, err := m.sendWithPrompt(cmd, "CONNECT", t.config.CACert, []string{"\r\nOK\r\n"}, time.Second*15); err != nil {
+		return fmt.Errorf("failed to upload CA certificate: %w", err)
+	}
+	return nil
+}
+
+// setupSIMCom открывает GPRS bearer профиля 1 (AT+SAPBR)
+func (t *HTTPTransport) setupSIMCom() error {
+	// +SAPBR: <cid>,<status>,"<ip>": 1 - открыт
+	if resp, err := t.modem.execCommand("AT+SAPBR=2,1", time.Second*5); err == nil {
+		for _, fields := range parseInfoLines(resp, "+SAPBR:") {
+			if fieldAt(fields, 1).Value == "1" {
+				return nil
+			}
+		}
+	}
+
+	apn := t.config.APN
+	if apn == "" {
+		if contexts, err := t.modem.GetPDPContexts(); err == nil {
+			for _, ctx := range contexts {
+				if ctx.CID == t.config.CID {
+					apn = ctx.APN
+				}
+			}
+		}
+	}
+	commands := []string{`AT+SAPBR=3,1,"Contype","GPRS"`, fmt.Sprintf(`AT+SAPBR=3,1,"APN","%s"`, apn)}
+	for _, cmd := range commands {
+		if _, err := t.modem.execCommand(cmd, time.Second*5); err != nil {
+			return err
+		}
+	}
+	if _, err := t.modem.execCommand("AT+SAPBR=1,1", time.Second*85); err != nil {
+		return fmt.Errorf("failed to open bearer: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip выполняет HTTP запрос через модем
+func (t *HTTPTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL == nil || (req.URL.Scheme != "http" && req.URL.Scheme != "https") {
+		closeBody(req)
+		return nil, fmt.Errorf("unsupported URL %v", req.URL)
+	}
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	timeout := t.config.Timeout
+	if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+
+	t.mu.Lock()
+	if t.modem.Vendor() == VendorSIMCom {
+		// Блокировка снимается при закрытии тела ответа, которое читается частями
+		resp, err := t.roundTripSIMCom(req, body, timeout)
+		if err != nil {
+			t.modem.execCommand("AT+HTTPTERM", time.Second*5)
+			t.mu.Unlock()
+		}
+		return resp, err
+	}
+	defer t.mu.Unlock()
+	return t.roundTripQuectel(req, body, timeout)
+}
+
+// closeBody закрывает тело запроса, которое RoundTrip обязан закрыть
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// roundTripQuectel: AT+QHTTPURL, AT+QHTTPGET/QHTTPPOST с запросом целиком, AT+QHTTPREAD
+func (t *HTTPTransport) roundTripQuectel(req *http.Request, body []byte, timeout time.Duration) (*http.Response, error) {
+	m := t.modem
+	url := req.URL.String()
+	cmd := fmt.Sprintf("AT+QHTTPURL=%d,80", len(url))
+	if _, err := m.sendWithPrompt(cmd, "CONNECT", []byte(url), []string{"\r\nOK\r\n"}, time.Second*10); err != nil {
+		return nil, fmt.Errorf("failed to set URL: %w", err)
+	}
+
+	// Запрос с заголовками формирует net/http, тело передается с Content-Length
+	out := req.Clone(req.Context())
+	out.ContentLength = int64(len(body))
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.TransferEncoding = nil
+	out.Close = true
+	var raw bytes.Buffer
+	if err := out.Write(&raw); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	seconds := int(timeout / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	prefix := "+QHTTPPOST:"
+	cmd = fmt.Sprintf("AT+QHTTPPOST=%d,60,%d", raw.Len(), seconds)
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		prefix = "+QHTTPGET:"
+		cmd = fmt.Sprintf("AT+QHTTPGET=%d,%d", seconds, raw.Len())
+	}
+
+	ch := m.urcs.expect(prefix)
+	resp, err := m.sendWithPrompt(cmd, "CONNECT", raw.Bytes(), []string{"\r\nOK\r\n"}, time.Second*60)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	m.urcs.scan(resp)
+
+	// +QHTTPGET: <err>[,<httprspcode>[,<content_length>]]
+	urc, err := m.awaitURC(ch, prefix, timeout+5*time.Second, m.urcs.deliver)
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := parseInfoLine(urc, prefix)
+	if code := fieldAt(fields, 0).IntOr(-1); code != 0 {
+		return nil, fmt.Errorf("HTTP request failed: error %d", code)
+	}
+
+	data, err := t.readQuectel(seconds)
+	if err != nil {
+		return nil, err
+	}
+	response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return response, nil
+}
+
+// readQuectel читает ответ AT+QHTTPREAD: CONNECT, данные, OK и +QHTTPREAD: <err>
+func (t *HTTPTransport) readQuectel(seconds int) ([]byte, error) {
+	m := t.modem
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dataMode {
+		return nil, ErrDataMode
+	}
+
+	cmd := fmt.Sprintf("AT+QHTTPREAD=%d", seconds)
+	m.flushPort()
+	if _, err := m.port.Write([]byte(cmd + "\r\n")); err != nil {
+		return nil, fmt.Errorf("failed to write command: %w", err)
+	}
+
+	var response []byte
+	buf := make([]byte, 4096)
+	timeout := time.Duration(seconds+5) * time.Second
+	for start := time.Now(); time.Since(start) < timeout; {
+		n, err := m.readPort(buf)
+		if err != nil || n == 0 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		response = append(response, buf[:n]...)
+
+		connect := bytes.Index(response, []byte("CONNECT\r\n"))
+		if connect < 0 {
+			if line := findErrorLine(string(response)); line != "" && bytes.HasSuffix(response, []byte("\n")) {
+				return nil, fmt.Errorf("failed to read response: %w: %s", ErrCommandFailed, line)
+			}
+			continue
+		}
+		match := qhttpReadEnd.FindSubmatchIndex(response)
+		if match == nil || match[0] < connect {
+			continue
+		}
+
+		debugResponse(cmd, fmt.Sprintf("<%d bytes>", match[0]-connect-9))
+		if code := string(response[match[2]:match[3]]); code != "0" {
+			return nil, fmt.Errorf("failed to read response: error %s", code)
+		}
+		return response[connect+9 : match[0]], nil
+	}
+	return nil, fmt.Errorf("timeout waiting for +QHTTPREAD")
+}
+
+// simcomHTTPMethods коды методов AT+HTTPACTION
+var simcomHTTPMethods = map[string]int{
+	http.MethodGet:    0,
+	http.MethodPost:   1,
+	http.MethodHead:   2,
+	http.MethodDelete: 3,
+}
+
+// roundTripSIMCom: AT+HTTPINIT, AT+HTTPPARA, AT+HTTPDATA, AT+HTTPACTION и чтение частями AT+HTTPREAD
+func (t *HTTPTransport) roundTripSIMCom(req *http.Request, body []byte, timeout time.Duration) (*http.Response, error) {
+	m := t.modem
+	method, ok := simcomHTTPMethods[req.Method]
+	if !ok {
+		return nil, fmt.Errorf("HTTP method %s: %w", req.Method, ErrNotSupported)
+	}
+	if req.URL.Scheme == "https" && !t.config.InsecureSkipVerify {
+		// SIM800 не проверяет сертификат сервера
+		return nil, fmt.Errorf("HTTPS certificate verification: %w", ErrNotSupported)
+	}
+
+	// Сессия могла остаться от прерванного запроса
+	m.execCommand("AT+HTTPTERM", time.Second*5)
+	if _, err := m.execCommand("AT+HTTPINIT", time.Second*5); err != nil {
+		return nil, fmt.Errorf("failed to init HTTP: %w", err)
+	}
+
+	ssl := 0
+	if req.URL.Scheme == "https" {
+		ssl = 1
+	}
+	commands := []string{
+		`AT+HTTPPARA="CID",1`,
+		fmt.Sprintf(`AT+HTTPPARA="URL","%s"`, req.URL.String()),
+		fmt.Sprintf("AT+HTTPSSL=%d", ssl),
+	}
+	var userData []string
+	for name, values := range req.Header {
+		for _, value := range values {
+			if strings.ContainsAny(value, "\"\r\n") {
+				continue
+			}
+			if name == "Content-Type" {
+				commands = append(commands, fmt.Sprintf(`AT+HTTPPARA="CONTENT","%s"`, value))
+				continue
+			}
+			userData = append(userData, name+": "+value)
+		}
+	}
+	if len(userData) > 0 {
+		commands = append(commands, fmt.Sprintf(`AT+HTTPPARA="USERDATA","%s"`, strings.Join(userData, `\r\n`)))
+	}
+	for _, cmd := range commands {
+		if _, err := m.execCommand(cmd, time.Second*5); err != nil {
+			return nil, fmt.Errorf("failed to set HTTP parameters: %w", err)
+		}
+	}
+
+	if len(body) > 0 {
+		cmd := fmt.Sprintf("AT+HTTPDATA=%d,10000", len(body))
+		if _, err := m.sendWithPrompt(cmd, "DOWNLOAD", body, []string{"\r\nOK\r\n"}, time.Second*15); err != nil {
+			return nil, fmt.Errorf("failed to upload request body: %w", err)
+		}
+	}
+
+	ch := m.urcs.expect("+HTTPACTION:")
+	resp, err := m.execCommand(fmt.Sprintf("AT+HTTPACTION=%d", method), time.Second*5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start HTTP request: %w", err)
+	}
+	m.urcs.scan(resp)
+
+	// +HTTPACTION: <method>,<status>,<datalen>; статусы 600+ - ошибки модема (601 - сеть, 603 - DNS)
+	urc, err := m.awaitURC(ch, "+HTTPACTION:", timeout+5*time.Second, m.urcs.deliver)
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := parseInfoLine(urc, "+HTTPACTION:")
+	status := fieldAt(fields, 1).IntOr(0)
+	length := fieldAt(fields, 2).IntOr(0)
+	if status < 100 || status >= 600 {
+		return nil, fmt.Errorf("HTTP request failed: status %d", status)
+	}
+
+	response := &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        t.headersSIMCom(),
+		ContentLength: int64(length),
+		Request:       req,
+	}
+	if req.Method == http.MethodHead {
+		length = 0
+	}
+	response.Body = &simcomHTTPBody{t: t, length: length}
+	return response, nil
+}
+
+// headersSIMCom читает заголовки ответа (AT+HTTPHEAD); без поддержки команды - пустые
+func (t *HTTPTransport) headersSIMCom() http.Header {
+	header := make(http.Header)
+	_, data, err := t.modem.readData("AT+HTTPHEAD", "+HTTPHEAD:", 0, time.Second*10)
+	if err != nil || len(data) == 0 {
+		return header
+	}
+
+	reader := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(data), strings.NewReader("\r\n\r\n"))))
+	if bytes.HasPrefix(data, []byte("HTTP/")) {
+		reader.ReadLine()
+	}
+	mime, _ := reader.ReadMIMEHeader()
+	for name, values := range mime {
+		header[name] = values
+	}
+	return header
+}
+
+// simcomHTTPBody тело ответа, читаемое частями (AT+HTTPREAD=<start>,<size>).
+// Close завершает HTTP сессию и освобождает транспорт для следующего запроса.
+type simcomHTTPBody struct {
+	t         *HTTPTransport
+	offset    int
+	length    int
+	closeOnce sync.Once
+	closed    bool
+}
+
+func (b *simcomHTTPBody) Read(p []byte) (int, error) {
+	if b.closed {
+		return 0, fmt.Errorf("read on closed response body")
+	}
+	if b.offset >= b.length {
+		return 0, io.EOF
+	}
+
+	size := b.length - b.offset
+	if size > len(p) {
+		size = len(p)
+	}
+	if size > httpReadChunk {
+		size = httpReadChunk
+	}
+	// +HTTPREAD: <data_len>
+	cmd := fmt.Sprintf("AT+HTTPREAD=%d,%d", b.offset, size)
+	_, data, err := b.t.modem.readData(cmd, "+HTTPREAD:", 0, time.Second*30)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if len(data) == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, data)
+	b.offset += n
+	return n, nil
+}
+
+func (b *simcomHTTPBody) Close() error {
+	b.closeOnce.Do(func() {
+		b.closed = true
+		b.t.modem.execCommand("AT+HTTPTERM", time.Second*5)
+		b.t.mu.Unlock()
+	})
+	return nil
+}
@@ -0,0 +1,428 @@
+package mbim
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// CID сервиса Basic Connect
+const (
+	cidDeviceCaps            = 1
+	cidSubscriberReadyStatus = 2
+	cidRadioState            = 3
+	cidPIN                   = 4
+	cidRegisterState         = 9
+	cidSignalState           = 11
+	cidConnect               = 12
+	cidIPConfiguration       = 15
+)
+
+// Состояния готовности SIM (MBIM_SUBSCRIBER_READY_STATE)
+const (
+	readyInitialized    = 1
+	readySIMNotInserted = 2
+	readyBadSIM         = 3
+	readyDeviceLocked   = 6
+)
+
+// Типы PIN (MBIM_PIN_TYPE)
+const (
+	pinTypeNone = 0
+	pinTypePIN1 = 2
+	pinTypePUK1 = 11
+)
+
+// Состояния регистрации (MBIM_REGISTER_STATE)
+const (
+	registerDeregistered = 1
+	registerSearching    = 2
+	registerHome         = 3
+	registerRoaming      = 4
+	registerPartner      = 5
+	registerDenied       = 6
+)
+
+// Классы передачи данных (MBIM_DATA_CLASS)
+const (
+	dataClassGPRS  = 0x01
+	dataClassEDGE  = 0x02
+	dataClassUMTS  = 0x04
+	dataClassHSDPA = 0x08
+	dataClassHSUPA = 0x10
+	dataClassLTE   = 0x20
+	dataClass5GNSA = 0x40
+	dataClass5GSA  = 0x80
+)
+
+// ErrSIMNotInserted SIM-карта отсутствует или неисправна
+var ErrSIMNotInserted = errors.New("SIM not inserted")
+
+// DeviceCaps возможности устройства (MBIM_DEVICE_CAPS_INFO)
+type DeviceCaps struct {
+	DeviceType    uint32 // 1 - встроенное, 2 - съемное, 3 - удаленное
+	CellularClass uint32 // 1 - GSM, 2 - CDMA
+	SIMClass      uint32 // 1 - логическая SIM, 2 - съемная
+	DataClass     uint32 // Битовая маска поддерживаемых технологий
+	SMSCaps       uint32 // Битовая маска возможностей SMS
+	ControlCaps   uint32 // Битовая маска возможностей управления
+	MaxSessions   uint32 // Максимум одновременных сессий данных
+	DeviceID      string // IMEI для GSM модемов
+	FirmwareInfo  string // Версия прошивки
+	HardwareInfo  string // Модель или аппаратная ревизия
+}
+
+// GetDeviceCaps возвращает возможности устройства
+func (c *Client) GetDeviceCaps() (*DeviceCaps, error) {
+	resp, err := c.query(ServiceBasicConnect, cidDeviceCaps, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get device caps: %w", err)
+	}
+	r := &infoReader{buf: resp.Data}
+	caps := &DeviceCaps{DeviceType: r.u32(), CellularClass: r.u32()}
+	r.u32() // VoiceClass
+	caps.SIMClass = r.u32()
+	caps.DataClass = r.u32()
+	caps.SMSCaps = r.u32()
+	caps.ControlCaps = r.u32()
+	caps.MaxSessions = r.u32()
+	r.ref() // CustomDataClass
+	caps.DeviceID = r.str()
+	caps.FirmwareInfo = r.str()
+	caps.HardwareInfo = r.str()
+	if !r.ok() {
+		return nil, fmt.Errorf("failed to get device caps: %w", ErrMalformed)
+	}
+	return caps, nil
+}
+
+// GetManufacturer возвращает производителя из дескриптора USB (MBIM его не сообщает)
+func (c *Client) GetManufacturer() (string, error) {
+	if c.manufacturer == "" {
+		return "", fmt.Errorf("failed to get manufacturer: %w", gsm.ErrNotSupported)
+	}
+	return c.manufacturer, nil
+}
+
+// GetModel возвращает модель: HardwareInfo из возможностей устройства или название из дескриптора USB
+func (c *Client) GetModel() (string, error) {
+	caps, err := c.GetDeviceCaps()
+	if err != nil {
+		return "", err
+	}
+	if caps.HardwareInfo == "" && c.product != "" {
+		return c.product, nil
+	}
+	return caps.HardwareInfo, nil
+}
+
+// GetRevision возвращает версию прошивки
+func (c *Client) GetRevision() (string, error) {
+	caps, err := c.GetDeviceCaps()
+	if err != nil {
+		return "", err
+	}
+	return caps.FirmwareInfo, nil
+}
+
+// GetIMEI возвращает IMEI модема
+func (c *Client) GetIMEI() (string, error) {
+	caps, err := c.GetDeviceCaps()
+	if err != nil {
+		return "", err
+	}
+	return caps.DeviceID, nil
+}
+
+// SubscriberStatus состояние SIM-карты (MBIM_SUBSCRIBER_READY_INFO)
+type SubscriberStatus struct {
+	ReadyState uint32   // 0 - не инициализирована, 1 - готова, 2 - нет SIM, 3 - неисправна, 6 - заблокирована
+	IMSI       string   // Идентификатор абонента
+	ICCID      string   // Идентификатор SIM-карты
+	Numbers    []string // Номера телефонов (MSISDN)
+}
+
+// GetSubscriberStatus возвращает состояние SIM-карты
+func (c *Client) GetSubscriberStatus() (*SubscriberStatus, error) {
+	resp, err := c.query(ServiceBasicConnect, cidSubscriberReadyStatus, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscriber status: %w", err)
+	}
+	r := &infoReader{buf: resp.Data}
+	status := &SubscriberStatus{ReadyState: r.u32(), IMSI: r.str(), ICCID: r.str()}
+	r.u32() // ReadyInfo
+	count := int(r.u32())
+	for i := 0; i < count && r.ok(); i++ {
+		status.Numbers = append(status.Numbers, r.str())
+	}
+	if !r.ok() {
+		return nil, fmt.Errorf("failed to get subscriber status: %w", ErrMalformed)
+	}
+	return status, nil
+}
+
+// GetIMSI возвращает IMSI SIM-карты
+func (c *Client) GetIMSI() (string, error) {
+	status, err := c.GetSubscriberStatus()
+	if err != nil {
+		return "", err
+	}
+	if status.IMSI == "" {
+		return "", fmt.Errorf("failed to get IMSI: SIM is not ready (state %d)", status.ReadyState)
+	}
+	return status.IMSI, nil
+}
+
+// GetICCID возвращает ICCID SIM-карты
+func (c *Client) GetICCID() (string, error) {
+	status, err := c.GetSubscriberStatus()
+	if err != nil {
+		return "", err
+	}
+	return status.ICCID, nil
+}
+
+// GetSIMStatus возвращает статус PIN SIM-карты
+func (c *Client) GetSIMStatus() (gsm.PinStatus, error) {
+	status, err := c.GetSubscriberStatus()
+	if err != nil {
+		return "", fmt.Errorf("failed to get SIM status: %w", err)
+	}
+	switch status.ReadyState {
+	case readyInitialized:
+		return gsm.PinReady, nil
+	case readySIMNotInserted, readyBadSIM:
+		return "", fmt.Errorf("failed to get SIM status: %w", ErrSIMNotInserted)
+	case readyDeviceLocked:
+		// Какой код нужен, сообщает запрос MBIM_CID_PIN
+		resp, err := c.query(ServiceBasicConnect, cidPIN, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to get SIM status: %w", err)
+		}
+		r := &infoReader{buf: resp.Data}
+		pinType := r.u32()
+		if !r.ok() {
+			return "", fmt.Errorf("failed to get SIM status: %w", ErrMalformed)
+		}
+		switch pinType {
+		case pinTypeNone:
+			return gsm.PinReady, nil
+		case pinTypePUK1:
+			return gsm.PukRequired, nil
+		default:
+			return gsm.PinRequired, nil
+		}
+	default:
+		return "", fmt.Errorf("failed to get SIM status: SIM is not ready (state %d)", status.ReadyState)
+	}
+}
+
+// EnterPIN вводит PIN1
+func (c *Client) EnterPIN(pin string) error {
+	data := newInfoBuilder(24).
+		u32(pinTypePIN1).
+		u32(0). // Операция: ввод
+		str(pin).
+		str(""). // Новый PIN
+		bytes()
+	if _, err := c.set(ServiceBasicConnect, cidPIN, data); err != nil {
+		return fmt.Errorf("failed to enter PIN: %w", err)
+	}
+	return nil
+}
+
+// GetRadioState возвращает состояние радиомодуля: аппаратный и программный выключатели
+func (c *Client) GetRadioState() (hardware, software bool, err error) {
+	resp, err := c.query(ServiceBasicConnect, cidRadioState, nil)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get radio state: %w", err)
+	}
+	r := &infoReader{buf: resp.Data}
+	hardware, software = r.u32() == 1, r.u32() == 1
+	if !r.ok() {
+		return false, false, fmt.Errorf("failed to get radio state: %w", ErrMalformed)
+	}
+	return hardware, software, nil
+}
+
+// SetRadioState включает или выключает радиомодуль (аналог AT+CFUN=1/4)
+func (c *Client) SetRadioState(on bool) error {
+	state := uint32(0)
+	if on {
+		state = 1
+	}
+	if _, err := c.set(ServiceBasicConnect, cidRadioState, newInfoBuilder(4).u32(state).bytes()); err != nil {
+		return fmt.Errorf("failed to set radio state: %w", err)
+	}
+	return nil
+}
+
+// RegisterState состояние регистрации в сети (MBIM_REGISTRATION_STATE_INFO)
+type RegisterState struct {
+	NetworkError uint32               // Код отказа сети (cause из 24.008)
+	Status       gsm.NetworkStatus    // Статус регистрации в терминах AT+CREG
+	DataClass    uint32               // Доступные технологии передачи данных
+	AccessTech   gsm.AccessTechnology // Лучшая из доступных технологий
+	ProviderID   string               // MCC+MNC
+	ProviderName string               // Название оператора
+	RoamingText  string               // Текст роуминга от оператора
+	Registration uint32               // Флаги регистрации
+}
+
+// GetRegisterState возвращает состояние регистрации в сети
+func (c *Client) GetRegisterState() (*RegisterState, error) {
+	resp, err := c.query(ServiceBasicConnect, cidRegisterState, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get register state: %w", err)
+	}
+	state, err := parseRegisterState(resp.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get register state: %w", err)
+	}
+	return state, nil
+}
+
+// parseRegisterState разбирает ответ или уведомление REGISTER_STATE
+func parseRegisterState(data []byte) (*RegisterState, error) {
+	r := &infoReader{buf: data}
+	state := &RegisterState{NetworkError: r.u32()}
+	registerState := r.u32()
+	r.u32() // RegisterMode
+	state.DataClass = r.u32()
+	r.u32() // CurrentCellularClass
+	state.ProviderID = r.str()
+	state.ProviderName = r.str()
+	state.RoamingText = r.str()
+	state.Registration = r.u32()
+	if !r.ok() {
+		return nil, ErrMalformed
+	}
+
+	switch registerState {
+	case registerDeregistered:
+		state.Status = gsm.NetworkNotRegistered
+	case registerSearching:
+		state.Status = gsm.NetworkSearching
+	case registerHome:
+		state.Status = gsm.NetworkRegisteredHome
+	case registerRoaming, registerPartner:
+		state.Status = gsm.NetworkRegisteredRoaming
+	case registerDenied:
+		state.Status = gsm.NetworkRegistrationDenied
+	default:
+		state.Status = gsm.NetworkUnknown
+	}
+	state.AccessTech = accessTech(state.DataClass)
+	return state, nil
+}
+
+// accessTech выбирает лучшую технологию из маски классов передачи данных
+func accessTech(dataClass uint32) gsm.AccessTechnology {
+	switch {
+	case dataClass&dataClass5GSA != 0:
+		return gsm.AccessTechNR5GC
+	case dataClass&dataClass5GNSA != 0:
+		return gsm.AccessTechENDC
+	case dataClass&dataClassLTE != 0:
+		return gsm.AccessTechLTE
+	case dataClass&(dataClassHSDPA|dataClassHSUPA) == dataClassHSDPA|dataClassHSUPA:
+		return gsm.AccessTechHSPA
+	case dataClass&dataClassHSDPA != 0:
+		return gsm.AccessTechHSDPA
+	case dataClass&dataClassHSUPA != 0:
+		return gsm.AccessTechHSUPA
+	case dataClass&dataClassUMTS != 0:
+		return gsm.AccessTechUMTS
+	case dataClass&dataClassEDGE != 0:
+		return gsm.AccessTechEDGE
+	case dataClass&dataClassGPRS != 0:
+		return gsm.AccessTechGSM
+	default:
+		return gsm.AccessTechUnknown
+	}
+}
+
+// GetNetworkStatus возвращает статус регистрации в сети
+func (c *Client) GetNetworkStatus() (gsm.NetworkStatus, error) {
+	state, err := c.GetRegisterState()
+	if err != nil {
+		return gsm.NetworkUnknown, err
+	}
+	return state.Status, nil
+}
+
+// GetCurrentOperator возвращает текущего оператора
+func (c *Client) GetCurrentOperator() (*gsm.OperatorInfo, error) {
+	state, err := c.GetRegisterState()
+	if err != nil {
+		return nil, err
+	}
+	if state.ProviderID == "" {
+		return nil, fmt.Errorf("no operator found: modem is not registered")
+	}
+	return state.operator(), nil
+}
+
+// operator переводит данные регистрации в OperatorInfo
+func (s *RegisterState) operator() *gsm.OperatorInfo {
+	op := &gsm.OperatorInfo{Status: "2", LongName: s.ProviderName, Numeric: s.ProviderID}
+	plmn, ok := gsm.LookupOperator(op.Numeric)
+	op.Country, op.CountryISO = plmn.Country, plmn.ISO
+	if ok {
+		op.Brand = plmn.Brand
+		if op.LongName == "" {
+			op.LongName = plmn.Brand
+		}
+		op.ShortName = plmn.Brand
+	}
+	return op
+}
+
+// handleRegisterState формирует событие EventNetworkChange из уведомления
+func (c *Client) handleRegisterState(msg *Message) {
+	state, err := parseRegisterState(msg.Data)
+	if err != nil {
+		return
+	}
+	data := map[string]interface{}{
+		"status":     state.Status,
+		"accessTech": state.AccessTech,
+	}
+	if state.ProviderID != "" {
+		data["operator"] = state.operator()
+	}
+	c.emitEvent(gsm.Event{Type: gsm.EventNetworkChange, Timestamp: time.Now(), Data: data})
+}
+
+// GetSignalQuality возвращает уровень сигнала: MBIM использует шкалу AT+CSQ
+func (c *Client) GetSignalQuality() (*gsm.SignalQuality, error) {
+	resp, err := c.query(ServiceBasicConnect, cidSignalState, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signal quality: %w", err)
+	}
+	r := &infoReader{buf: resp.Data}
+	signal := &gsm.SignalQuality{RSSI: int(r.u32()), BER: int(r.u32())}
+	if !r.ok() {
+		return nil, fmt.Errorf("failed to get signal quality: %w", ErrMalformed)
+	}
+	return signal, nil
+}
+
+// GetSignalReport возвращает метрики сигнала. Базовый MBIM сообщает только RSSI.
+func (c *Client) GetSignalReport() (*gsm.SignalReport, error) {
+	signal, err := c.GetSignalQuality()
+	if err != nil {
+		return nil, err
+	}
+	report := gsm.NewSignalReport("MBIM")
+	if state, err := c.GetRegisterState(); err == nil {
+		report.AccessTech = state.AccessTech
+	}
+	if dbm, ok := signal.DBm(); ok {
+		report.RSSI = float64(dbm)
+	}
+	report.Normalize()
+	return report, nil
+}
@@ -0,0 +1,89 @@
+package mbim
+
+import (
+	"testing"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// registerStateInfo собирает MBIM_REGISTRATION_STATE_INFO
+func registerStateInfo(state, dataClass uint32, providerID, providerName string) []byte {
+	return newInfoBuilder(48).
+		u32(0).     // NwError
+		u32(state). // RegisterState
+		u32(1).     // RegisterMode: автоматический
+		u32(dataClass).
+		u32(1). // CurrentCellularClass: GSM
+		str(providerID).
+		str(providerName).
+		str("").
+		u32(0).
+		bytes()
+}
+
+func TestRegisterState(t *testing.T) {
+	f, c := newFakeClient(t,
+		step{service: ServiceBasicConnect, cid: cidRegisterState, resp: registerStateInfo(registerHome, dataClassLTE|dataClassUMTS, "25001", "MTS RUS")},
+		step{service: ServiceBasicConnect, cid: cidRegisterState, resp: registerStateInfo(registerSearching, 0, "", "")},
+	)
+
+	op, err := c.GetCurrentOperator()
+	if err != nil {
+		t.Fatalf("GetCurrentOperator: %v", err)
+	}
+	if op.Numeric != "25001" || op.LongName != "MTS RUS" {
+		t.Errorf("operator = %+v, want 25001 MTS RUS", op)
+	}
+
+	status, err := c.GetNetworkStatus()
+	if err != nil {
+		t.Fatalf("GetNetworkStatus: %v", err)
+	}
+	if status != gsm.NetworkSearching {
+		t.Errorf("status = %v, want searching", status)
+	}
+	f.done()
+}
+
+func TestRegisterStateMalformed(t *testing.T) {
+	data := registerStateInfo(registerHome, dataClassLTE, "25001", "MTS")
+	// Строка указывает за пределы буфера
+	if _, err := parseRegisterState(data[:len(data)-8]); err == nil {
+		t.Error("parseRegisterState accepted truncated buffer")
+	}
+}
+
+func TestRegisterStateIndication(t *testing.T) {
+	f, c := newFakeClient(t)
+	events, err := c.GetEventChannel()
+	if err != nil {
+		t.Fatalf("GetEventChannel: %v", err)
+	}
+
+	// Уведомление другого CID не порождает событие
+	f.indicate(ServiceBasicConnect, cidSignalState, make([]byte, 20))
+	f.indicate(ServiceBasicConnect, cidRegisterState, registerStateInfo(registerRoaming, dataClassLTE, "25002", "MegaFon"))
+
+	select {
+	case event := <-events:
+		if event.Type != gsm.EventNetworkChange {
+			t.Fatalf("event type = %s, want %s", event.Type, gsm.EventNetworkChange)
+		}
+		if event.Data["status"] != gsm.NetworkRegisteredRoaming || event.Data["accessTech"] != gsm.AccessTechLTE {
+			t.Errorf("event data = %v, want roaming LTE", event.Data)
+		}
+		op, ok := event.Data["operator"].(*gsm.OperatorInfo)
+		if !ok || op.Numeric != "25002" || op.LongName != "MegaFon" {
+			t.Errorf("event operator = %+v, want 25002 MegaFon", event.Data["operator"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no EventNetworkChange after register state indication")
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %+v", event)
+	default:
+	}
+	f.done()
+}
@@ -0,0 +1,426 @@
+package mbim
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+const (
+	maxControlTransfer  = 4096 // Максимальный размер управляющего сообщения
+	defaultTimeout      = 10 * time.Second
+	openAttempts        = 5
+	openTimeout         = time.Second
+	closeTimeout        = time.Second
+	fragmentTimeout     = 2 * time.Second // Наибольшая пауза между фрагментами одного сообщения
+	maxEventQueueLength = 100
+)
+
+// ErrClosed клиент закрыт или устройство отключено
+var ErrClosed = errors.New("MBIM client closed")
+
+// errTimeout модем не ответил на сообщение
+var errTimeout = errors.New("timed out")
+
+// Config параметры клиента MBIM
+type Config struct {
+	Device  string        // Устройство управления ("/dev/cdc-wdm0")
+	Timeout time.Duration // Тайм-аут команды (по умолчанию 10 секунд)
+}
+
+// Client клиент MBIM. Соединение с функцией MBIM открывается в Open/NewClient
+// (MBIM_OPEN_MSG) и закрывается в Close.
+type Client struct {
+	rw              io.ReadWriteCloser
+	timeout         time.Duration
+	fragmentTimeout time.Duration
+
+	// Производитель и модель из дескриптора USB: MBIM их не сообщает
+	manufacturer string
+	product      string
+
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	pending     map[uint32]chan *Message
+	fragments   map[fragmentKey]*fragment
+	indications []*indicationHandler
+	nextTx      uint32
+	events      chan gsm.Event
+	eventsOnce  sync.Once
+
+	done      chan struct{}
+	err       error
+	closeOnce sync.Once
+}
+
+// fragmentKey идентифицирует собираемое из фрагментов сообщение
+type fragmentKey struct {
+	msgType     MessageType
+	transaction uint32
+}
+
+// indicationHandler обработчик уведомлений сервиса
+type indicationHandler struct {
+	service UUID
+	cid     uint32
+	handle  func(*Message)
+}
+
+// Open открывает устройство управления MBIM и выполняет MBIM_OPEN
+func Open(config Config) (*Client, error) {
+	if config.Device == "" {
+		config.Device = "/dev/cdc-wdm0"
+	}
+	f, err := os.OpenFile(config.Device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", config.Device, err)
+	}
+	c, err := NewClient(f, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", config.Device, err)
+	}
+	c.manufacturer, c.product = usbDescriptor(config.Device)
+	return c, nil
+}
+
+// usbDescriptor читает производителя и модель USB устройства из sysfs (Linux)
+func usbDescriptor(device string) (string, string) {
+	// /sys/class/usbmisc/cdc-wdmN/device - интерфейс USB, родительский каталог - устройство
+	dir := filepath.Join("/sys/class/usbmisc", filepath.Base(device), "device", "..")
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	return read("manufacturer"), read("product")
+}
+
+// NewClient создает клиент поверх произвольного канала MBIM (например, записи обмена)
+// и открывает соединение. Канал закрывается вместе с клиентом.
+func NewClient(rw io.ReadWriteCloser, config Config) (*Client, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	c := &Client{
+		rw:              rw,
+		timeout:         config.Timeout,
+		fragmentTimeout: fragmentTimeout,
+		pending:         make(map[uint32]chan *Message),
+		fragments:       make(map[fragmentKey]*fragment),
+		events:          make(chan gsm.Event, maxEventQueueLength),
+		done:            make(chan struct{}),
+	}
+	go c.readLoop()
+
+	if err := c.open(); err != nil {
+		c.shutdown(err)
+		rw.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// open выполняет MBIM_OPEN. Сразу после подключения модем может не отвечать, поэтому
+// MBIM_OPEN повторяется; статус MBIM_OPEN_DONE или MBIM_FUNCTION_ERROR - окончательный ответ.
+func (c *Client) open() error {
+	for i := 0; i < openAttempts; i++ {
+		resp, err := c.transact(&Message{Type: MessageOpen}, openTimeout)
+		if errors.Is(err, errTimeout) {
+			continue
+		}
+		if err == nil && resp.Status != StatusSuccess {
+			err = &Error{Status: resp.Status}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to open MBIM function: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("modem does not respond to MBIM open: %w", errTimeout)
+}
+
+// Command отправляет команду сервису и ждет MBIM_COMMAND_DONE. Ошибка из статуса
+// ответа возвращается вместе с ответом.
+func (c *Client) Command(service UUID, cid uint32, command CommandType, data []byte) (*Message, error) {
+	return c.commandTimeout(service, cid, command, data, c.timeout)
+}
+
+// commandTimeout отправляет команду с указанным тайм-аутом
+func (c *Client) commandTimeout(service UUID, cid uint32, command CommandType, data []byte, timeout time.Duration) (*Message, error) {
+	req := &Message{Type: MessageCommand, Service: service, CID: cid, Command: command, Data: data}
+	resp, err := c.transact(req, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != StatusSuccess {
+		return resp, &Error{Service: service, CID: cid, Status: resp.Status}
+	}
+	return resp, nil
+}
+
+// query запрашивает значение CID
+func (c *Client) query(service UUID, cid uint32, data []byte) (*Message, error) {
+	return c.Command(service, cid, CommandQuery, data)
+}
+
+// set устанавливает значение CID
+func (c *Client) set(service UUID, cid uint32, data []byte) (*Message, error) {
+	return c.Command(service, cid, CommandSet, data)
+}
+
+// transact отправляет сообщение и ждет ответ с тем же номером транзакции
+func (c *Client) transact(req *Message, timeout time.Duration) (*Message, error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.nextTx++
+	if c.nextTx == 0 {
+		c.nextTx = 1
+	}
+	req.Transaction = c.nextTx
+	ch := make(chan *Message, 1)
+	c.pending[req.Transaction] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, req.Transaction)
+		c.mu.Unlock()
+	}()
+
+	c.writeMu.Lock()
+	_, err := c.rw.Write(req.Marshal())
+	c.writeMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to send MBIM message: %w", err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-ch:
+		if resp.Type == MessageFunctionError {
+			return nil, ProtocolError(resp.Status)
+		}
+		return resp, nil
+	case <-c.done:
+		return nil, c.Err()
+	case <-timer.C:
+		if req.Type == MessageCommand {
+			return nil, fmt.Errorf("MBIM %s CID %d %w", serviceName(req.Service), req.CID, errTimeout)
+		}
+		return nil, fmt.Errorf("MBIM message 0x%08X %w", uint32(req.Type), errTimeout)
+	}
+}
+
+// onIndication регистрирует обработчик уведомлений и возвращает функцию отмены.
+// Обработчик вызывается из горутины чтения и не должен отправлять команды.
+func (c *Client) onIndication(service UUID, cid uint32, handle func(*Message)) func() {
+	h := &indicationHandler{service, cid, handle}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.indications = append(c.indications, h)
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, registered := range c.indications {
+			if registered == h {
+				c.indications = append(c.indications[:i], c.indications[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// readLoop читает сообщения и распределяет ответы и уведомления
+func (c *Client) readLoop() {
+	r := bufio.NewReader(c.rw)
+	for {
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			c.shutdown(err)
+			return
+		}
+		length := int(binary.LittleEndian.Uint32(header[4:]))
+		if length < headerSize || length > 1<<20 {
+			c.shutdown(fmt.Errorf("%w: message length %d", ErrMalformed, length))
+			return
+		}
+		frame := make([]byte, length)
+		copy(frame, header)
+		if _, err := io.ReadFull(r, frame[headerSize:]); err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		f, err := parseFragment(frame)
+		if err != nil {
+			continue
+		}
+		whole, perr := c.reassemble(f, time.Now())
+		if perr != 0 {
+			c.hostError(f.transaction, perr)
+		}
+		if whole == nil {
+			continue
+		}
+		msg, err := whole.decode()
+		if err != nil {
+			continue
+		}
+		c.dispatch(msg)
+	}
+}
+
+// reassemble накапливает фрагменты и возвращает сообщение целиком после последнего.
+// Фрагмент вне последовательности или после паузы больше fragmentTimeout отбрасывает
+// сообщение; код ошибки нужно сообщить модему в MBIM_HOST_ERROR_MSG.
+func (c *Client) reassemble(f *fragment, now time.Time) (*fragment, ProtocolError) {
+	if f.total <= 1 {
+		return f, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Сообщения, последний фрагмент которых так и не пришел
+	key := fragmentKey{f.msgType, f.transaction}
+	for k, partial := range c.fragments {
+		if k != key && now.Sub(partial.received) > c.fragmentTimeout {
+			delete(c.fragments, k)
+		}
+	}
+
+	if f.current == 0 {
+		f.received = now
+		c.fragments[key] = f
+		return nil, 0
+	}
+	first, ok := c.fragments[key]
+	if !ok || f.current != first.current+1 {
+		delete(c.fragments, key)
+		return nil, ErrorFragmentOutOfSequence
+	}
+	if now.Sub(first.received) > c.fragmentTimeout {
+		delete(c.fragments, key)
+		return nil, ErrorTimeoutFragment
+	}
+	first.current = f.current
+	first.received = now
+	first.payload = append(first.payload, f.payload...)
+	if f.current+1 < f.total {
+		return nil, 0
+	}
+	delete(c.fragments, key)
+	return first, 0
+}
+
+// hostError сообщает модему об ошибке приема сообщения (MBIM_HOST_ERROR_MSG)
+func (c *Client) hostError(transaction uint32, code ProtocolError) {
+	msg := &Message{Type: MessageHostError, Transaction: transaction, Status: Status(code)}
+	c.writeMu.Lock()
+	c.rw.Write(msg.Marshal())
+	c.writeMu.Unlock()
+}
+
+// dispatch передает ответ ожидающему запросу или уведомление обработчикам
+func (c *Client) dispatch(msg *Message) {
+	c.mu.Lock()
+	if msg.Type != MessageIndicate {
+		ch, ok := c.pending[msg.Transaction]
+		c.mu.Unlock()
+		if ok {
+			// Модем может повторить MBIM_COMMAND_DONE (например, после MBIM_HOST_ERROR_MSG),
+			// а ответ на прерванный запрос - прийти после shutdown: в буфере канала место
+			// только для первого ответа, остальные отбрасываются
+			select {
+			case ch <- msg:
+			default:
+			}
+		}
+		return
+	}
+	var handlers []func(*Message)
+	for _, h := range c.indications {
+		if h.service == msg.Service && h.cid == msg.CID {
+			handlers = append(handlers, h.handle)
+		}
+	}
+	c.mu.Unlock()
+	for _, handle := range handlers {
+		handle(msg)
+	}
+}
+
+// shutdown завершает ожидающие запросы ошибкой: они ждут закрытия c.done
+func (c *Client) shutdown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if errors.Is(err, ErrClosed) || errors.Is(err, io.EOF) || errors.Is(err, os.ErrClosed) {
+		c.err = ErrClosed
+	} else {
+		c.err = fmt.Errorf("%w: %w", ErrClosed, err)
+	}
+	close(c.done)
+}
+
+// emitEvent отправляет событие в канал без блокировки
+func (c *Client) emitEvent(event gsm.Event) {
+	select {
+	case c.events <- event:
+	default:
+		// Канал полон, пропускаем событие
+	}
+}
+
+// GetEventChannel возвращает канал событий: EventNewSMS и EventNetworkChange.
+// Модем присылает уведомления всех сервисов сразу после MBIM_OPEN.
+func (c *Client) GetEventChannel() (<-chan gsm.Event, error) {
+	c.eventsOnce.Do(func() {
+		c.onIndication(ServiceSMS, cidSMSRead, c.handleSMSRead)
+		c.onIndication(ServiceSMS, cidSMSMessageStoreStatus, c.handleStoreStatus)
+		c.onIndication(ServiceBasicConnect, cidRegisterState, c.handleRegisterState)
+	})
+	return c.events, nil
+}
+
+// Done закрывается, когда устройство отключено или клиент закрыт
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err возвращает причину завершения работы клиента
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close закрывает соединение с функцией MBIM (MBIM_CLOSE) и устройство
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.transact(&Message{Type: MessageClose}, closeTimeout)
+		c.shutdown(ErrClosed)
+		err = c.rw.Close()
+	})
+	return err
+}
+
+var _ gsm.Device = (*Client)(nil)
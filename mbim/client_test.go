@@ -0,0 +1,605 @@
+package mbim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// step шаг записи обмена: команда клиента и ответ модема
+type step struct {
+	service   UUID
+	cid       uint32
+	command   CommandType
+	check     func(t *testing.T, data []byte) // Проверка информационного буфера команды
+	status    Status                          // Статус MBIM_COMMAND_DONE
+	resp      []byte                          // Информационный буфер ответа
+	fragments int                             // Число фрагментов ответа (0 - один)
+	silent    bool                            // Модем не отвечает
+	twice     bool                            // Модем повторяет ответ
+	fnError   ProtocolError                   // Модем отвечает MBIM_FUNCTION_ERROR_MSG
+}
+
+// openReply ответ модема на MBIM_OPEN
+type openReply struct {
+	status  Status
+	silent  bool
+	fnError ProtocolError
+}
+
+// fakeDevice функция MBIM на другом конце net.Pipe: проигрывает запись обмена, а
+// MBIM_OPEN и MBIM_CLOSE обслуживает сама
+type fakeDevice struct {
+	t    *testing.T
+	conn net.Conn
+
+	writeMu     sync.Mutex
+	mu          sync.Mutex
+	steps       []step
+	openReplies []openReply // Ответы на MBIM_OPEN по порядку, затем - успех
+	opens       int
+	closed      bool
+	silent      chan *Message
+	hostErrors  chan *Message
+}
+
+// newFakeDevice создает функцию MBIM и возвращает канал для клиента
+func newFakeDevice(t *testing.T, steps ...step) (*fakeDevice, net.Conn) {
+	t.Helper()
+	local, remote := net.Pipe()
+	f := &fakeDevice{t: t, conn: remote, steps: steps, silent: make(chan *Message, 4), hostErrors: make(chan *Message, 4)}
+	go f.serve()
+	t.Cleanup(func() { remote.Close() })
+	return f, local
+}
+
+// newFakeClient создает функцию MBIM и открытый клиент поверх нее
+func newFakeClient(t *testing.T, steps ...step) (*fakeDevice, *Client) {
+	t.Helper()
+	f, local := newFakeDevice(t, steps...)
+	c, err := NewClient(local, Config{Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return f, c
+}
+
+// expect добавляет шаги в запись обмена
+func (f *fakeDevice) expect(steps ...step) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.steps = append(f.steps, steps...)
+}
+
+// done проверяет, что запись обмена проиграна целиком
+func (f *fakeDevice) done() {
+	f.t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.steps {
+		f.t.Errorf("command %s CID %d was not sent", serviceName(s.service), s.cid)
+	}
+}
+
+// write отправляет клиенту кадр
+func (f *fakeDevice) write(frame []byte) {
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	f.conn.Write(frame)
+}
+
+// serve читает сообщения клиента и отвечает на них
+func (f *fakeDevice) serve() {
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(f.conn, header); err != nil {
+			return
+		}
+		frame := make([]byte, binary.LittleEndian.Uint32(header[4:]))
+		copy(frame, header)
+		if _, err := io.ReadFull(f.conn, frame[headerSize:]); err != nil {
+			return
+		}
+		msgType := MessageType(binary.LittleEndian.Uint32(frame))
+		tx := binary.LittleEndian.Uint32(frame[8:])
+		switch msgType {
+		case MessageOpen:
+			f.mu.Lock()
+			f.opens++
+			var reply openReply
+			if len(f.openReplies) > 0 {
+				reply, f.openReplies = f.openReplies[0], f.openReplies[1:]
+			}
+			f.mu.Unlock()
+			switch {
+			case reply.silent:
+			case reply.fnError != 0:
+				f.write(statusFrame(MessageFunctionError, tx, Status(reply.fnError)))
+			default:
+				f.write(statusFrame(MessageOpenDone, tx, reply.status))
+			}
+		case MessageClose:
+			f.mu.Lock()
+			f.closed = true
+			f.mu.Unlock()
+			f.write(statusFrame(MessageCloseDone, tx, StatusSuccess))
+		case MessageHostError:
+			f.hostErrors <- &Message{Type: msgType, Transaction: tx, Status: Status(binary.LittleEndian.Uint32(frame[headerSize:]))}
+		case MessageCommand:
+			req, err := parseCommand(frame)
+			if err != nil {
+				f.t.Errorf("client sent malformed command %x: %v", frame, err)
+				continue
+			}
+			f.replay(req)
+		default:
+			f.t.Errorf("client sent message 0x%08X", uint32(msgType))
+		}
+	}
+}
+
+// replay сверяет команду со следующим шагом записи и отвечает
+func (f *fakeDevice) replay(req *Message) {
+	f.mu.Lock()
+	if len(f.steps) == 0 {
+		f.mu.Unlock()
+		f.t.Errorf("unexpected command %s CID %d", serviceName(req.Service), req.CID)
+		f.write(commandDoneFrame(req.Transaction, req.Service, req.CID, StatusNoDeviceSupport, nil, 1)[0])
+		return
+	}
+	s := f.steps[0]
+	f.steps = f.steps[1:]
+	f.mu.Unlock()
+
+	if req.Service != s.service || req.CID != s.cid || req.Command != s.command {
+		f.t.Errorf("command %s CID %d type %d, want %s CID %d type %d",
+			serviceName(req.Service), req.CID, req.Command, serviceName(s.service), s.cid, s.command)
+	}
+	if s.check != nil {
+		s.check(f.t, req.Data)
+	}
+	if s.silent {
+		f.silent <- req
+		return
+	}
+	if s.fnError != 0 {
+		f.write(statusFrame(MessageFunctionError, req.Transaction, Status(s.fnError)))
+		return
+	}
+	for i := 0; i < 1+btoi(s.twice); i++ {
+		for _, frame := range commandDoneFrame(req.Transaction, req.Service, req.CID, s.status, s.resp, s.fragments) {
+			f.write(frame)
+		}
+	}
+}
+
+// btoi переводит bool в 0 или 1
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// parseCommand разбирает MBIM_COMMAND_MSG клиента
+func parseCommand(frame []byte) (*Message, error) {
+	f, err := parseFragment(frame)
+	if err != nil {
+		return nil, err
+	}
+	// Заголовок фрагмента, UUID, CID, тип команды, длина буфера
+	p := f.payload
+	if len(p) < 36 || binary.LittleEndian.Uint32(p) != 1 {
+		return nil, ErrMalformed
+	}
+	p = p[fragmentHeaderSize:]
+	m := &Message{Type: MessageCommand, Transaction: f.transaction}
+	copy(m.Service[:], p)
+	m.CID = binary.LittleEndian.Uint32(p[16:])
+	m.Command = CommandType(binary.LittleEndian.Uint32(p[20:]))
+	n := int(binary.LittleEndian.Uint32(p[24:]))
+	if 28+n != len(p) {
+		return nil, ErrMalformed
+	}
+	m.Data = p[28:]
+	return m, nil
+}
+
+// rawFrame собирает сообщение модема из заголовка и тела
+func rawFrame(msgType MessageType, tx uint32, body []byte) []byte {
+	b := binary.LittleEndian.AppendUint32(nil, uint32(msgType))
+	b = binary.LittleEndian.AppendUint32(b, uint32(headerSize+len(body)))
+	b = binary.LittleEndian.AppendUint32(b, tx)
+	return append(b, body...)
+}
+
+// statusFrame собирает MBIM_OPEN_DONE, MBIM_CLOSE_DONE или MBIM_FUNCTION_ERROR_MSG
+func statusFrame(msgType MessageType, tx uint32, status Status) []byte {
+	return rawFrame(msgType, tx, binary.LittleEndian.AppendUint32(nil, uint32(status)))
+}
+
+// fragmentFrames делит полезную нагрузку на count фрагментов
+func fragmentFrames(msgType MessageType, tx uint32, payload []byte, count int) [][]byte {
+	if count < 1 {
+		count = 1
+	}
+	size := (len(payload) + count - 1) / count
+	var frames [][]byte
+	for i := 0; i < count; i++ {
+		part := payload[min(i*size, len(payload)):min((i+1)*size, len(payload))]
+		body := binary.LittleEndian.AppendUint32(nil, uint32(count))
+		body = binary.LittleEndian.AppendUint32(body, uint32(i))
+		frames = append(frames, rawFrame(msgType, tx, append(body, part...)))
+	}
+	return frames
+}
+
+// commandDoneFrame собирает MBIM_COMMAND_DONE из count фрагментов
+func commandDoneFrame(tx uint32, service UUID, cid uint32, status Status, data []byte, count int) [][]byte {
+	p := append([]byte(nil), service[:]...)
+	p = binary.LittleEndian.AppendUint32(p, cid)
+	p = binary.LittleEndian.AppendUint32(p, uint32(status))
+	p = binary.LittleEndian.AppendUint32(p, uint32(len(data)))
+	return fragmentFrames(MessageCommandDone, tx, append(p, data...), count)
+}
+
+// indicate отправляет клиенту уведомление сервиса
+func (f *fakeDevice) indicate(service UUID, cid uint32, data []byte) {
+	p := append([]byte(nil), service[:]...)
+	p = binary.LittleEndian.AppendUint32(p, cid)
+	p = binary.LittleEndian.AppendUint32(p, uint32(len(data)))
+	p = append(p, data...)
+	f.write(fragmentFrames(MessageIndicate, 0, p, 1)[0])
+}
+
+// signalInfo информационный буфер MBIM_SIGNAL_STATE_INFO (RSSI и BER)
+func signalInfo(rssi, ber uint32) []byte {
+	return binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, rssi), ber)
+}
+
+// expectHostError ждет MBIM_HOST_ERROR_MSG от клиента
+func (f *fakeDevice) expectHostError(tx uint32, code ProtocolError) {
+	f.t.Helper()
+	select {
+	case msg := <-f.hostErrors:
+		if msg.Transaction != tx || ProtocolError(msg.Status) != code {
+			f.t.Errorf("host error %v for transaction %d, want %v for %d", ProtocolError(msg.Status), msg.Transaction, code, tx)
+		}
+	case <-time.After(time.Second):
+		f.t.Errorf("no host error %v for transaction %d", code, tx)
+	}
+}
+
+func TestMarshalCommand(t *testing.T) {
+	msg := &Message{Type: MessageCommand, Transaction: 7, Service: ServiceBasicConnect, CID: cidSignalState, Command: CommandQuery, Data: []byte{1, 2, 3, 4}}
+	parsed, err := parseCommand(msg.Marshal())
+	if err != nil {
+		t.Fatalf("parseCommand: %v", err)
+	}
+	if parsed.Transaction != 7 || parsed.Service != ServiceBasicConnect || parsed.CID != cidSignalState ||
+		parsed.Command != CommandQuery || !bytes.Equal(parsed.Data, msg.Data) {
+		t.Errorf("round trip = %+v, want %+v", parsed, msg)
+	}
+
+	hostErr := (&Message{Type: MessageHostError, Transaction: 9, Status: Status(ErrorTimeoutFragment)}).Marshal()
+	want := rawFrame(MessageHostError, 9, binary.LittleEndian.AppendUint32(nil, uint32(ErrorTimeoutFragment)))
+	if !bytes.Equal(hostErr, want) {
+		t.Errorf("host error = %x, want %x", hostErr, want)
+	}
+}
+
+func TestParseFragment(t *testing.T) {
+	// MBIM_OPEN_DONE не делится на фрагменты
+	f, err := parseFragment(statusFrame(MessageOpenDone, 3, StatusBusy))
+	if err != nil || f.total != 1 {
+		t.Fatalf("parseFragment(OPEN_DONE) = %+v, %v", f, err)
+	}
+	if msg, err := f.decode(); err != nil || msg.Status != StatusBusy || msg.Transaction != 3 {
+		t.Errorf("decode(OPEN_DONE) = %+v, %v", msg, err)
+	}
+
+	done := commandDoneFrame(5, ServiceSMS, cidSMSRead, StatusSuccess, []byte{1, 2, 3, 4}, 2)
+	if f, err := parseFragment(done[1]); err != nil || f.total != 2 || f.current != 1 || f.transaction != 5 {
+		t.Errorf("parseFragment(fragment 2/2) = %+v, %v", f, err)
+	}
+
+	overflow := commandDoneFrame(5, ServiceSMS, cidSMSRead, StatusSuccess, []byte{1, 2, 3, 4}, 1)[0]
+	overflow = overflow[:len(overflow)-2]
+	binary.LittleEndian.PutUint32(overflow[4:], uint32(len(overflow)))
+	for name, frame := range map[string][]byte{
+		"length mismatch":       append(statusFrame(MessageOpenDone, 1, StatusSuccess), 0),
+		"short header":          statusFrame(MessageOpenDone, 1, StatusSuccess)[:8],
+		"short fragment header": rawFrame(MessageCommandDone, 1, []byte{1, 0, 0, 0}),
+	} {
+		if _, err := parseFragment(frame); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: parseFragment = %v, want ErrMalformed", name, err)
+		}
+	}
+	for name, frame := range map[string][]byte{
+		"short status":                rawFrame(MessageFunctionError, 1, []byte{5}),
+		"short command header":        rawFrame(MessageCommandDone, 1, make([]byte, fragmentHeaderSize+20)),
+		"information buffer overflow": overflow,
+	} {
+		f, err := parseFragment(frame)
+		if err == nil {
+			_, err = f.decode()
+		}
+		if !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: decode = %v, want ErrMalformed", name, err)
+		}
+	}
+}
+
+func TestOpenAndClose(t *testing.T) {
+	f, local := newFakeDevice(t)
+	c, err := NewClient(local, Config{})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	f.mu.Lock()
+	opens, closed := f.opens, f.closed
+	f.mu.Unlock()
+	if opens != 1 || !closed {
+		t.Errorf("opens = %d, closed = %v, want 1 open and MBIM_CLOSE", opens, closed)
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Error("Done is not closed after Close")
+	}
+	if _, err := c.GetSignalQuality(); !errors.Is(err, ErrClosed) {
+		t.Errorf("command after Close: %v, want ErrClosed", err)
+	}
+}
+
+func TestOpenDone(t *testing.T) {
+	tests := []struct {
+		name    string
+		replies []openReply
+		opens   int
+		check   func(error) bool
+	}{
+		// Сразу после подключения модем может молчать - MBIM_OPEN повторяется
+		{"success after silence", []openReply{{silent: true}}, 2, func(err error) bool { return err == nil }},
+		// Статус MBIM_OPEN_DONE - окончательный ответ, повтор не поможет
+		{"failure status", []openReply{{status: StatusFailure}}, 1, func(err error) bool { return IsStatus(err, StatusFailure) }},
+		{"busy status", []openReply{{status: StatusBusy}}, 1, func(err error) bool { return IsStatus(err, StatusBusy) }},
+		{"function error", []openReply{{fnError: ErrorMaxTransfer}}, 1, func(err error) bool { return errors.Is(err, ErrorMaxTransfer) }},
+	}
+	for _, tt := range tests {
+		f, local := newFakeDevice(t)
+		f.mu.Lock()
+		f.openReplies = tt.replies
+		f.mu.Unlock()
+		c, err := NewClient(local, Config{})
+		if !tt.check(err) {
+			t.Errorf("%s: NewClient: %v", tt.name, err)
+		}
+		if c != nil {
+			c.Close()
+		}
+		f.mu.Lock()
+		if f.opens != tt.opens {
+			t.Errorf("%s: opens = %d, want %d", tt.name, f.opens, tt.opens)
+		}
+		f.mu.Unlock()
+	}
+}
+
+func TestCommandDoneStatus(t *testing.T) {
+	tests := []struct {
+		status Status
+		text   string
+	}{
+		{StatusNotInitialized, "MBIM Basic Connect CID 11: not initialized"},
+		{StatusSIMNotInserted, "MBIM Basic Connect CID 11: SIM not inserted"},
+		{Status(200), "MBIM Basic Connect CID 11: status 200"},
+	}
+	for _, tt := range tests {
+		f, c := newFakeClient(t, step{service: ServiceBasicConnect, cid: cidSignalState, status: tt.status, resp: signalInfo(1, 2)})
+		// Ответ с ошибкой возвращается вместе с ней
+		resp, err := c.Command(ServiceBasicConnect, cidSignalState, CommandQuery, nil)
+		if !IsStatus(err, tt.status) || err.Error() != tt.text {
+			t.Errorf("Command: %v, want %q", err, tt.text)
+		}
+		if resp == nil || resp.Status != tt.status || !bytes.Equal(resp.Data, signalInfo(1, 2)) {
+			t.Errorf("Command response = %+v", resp)
+		}
+		f.done()
+	}
+}
+
+func TestFunctionError(t *testing.T) {
+	tests := []struct {
+		code ProtocolError
+		text string
+	}{
+		{ErrorNotOpened, "MBIM protocol error: function not opened"},
+		{ErrorDuplicatedTID, "MBIM protocol error: duplicated transaction ID"},
+		{ProtocolError(42), "MBIM protocol error: code 42"},
+	}
+	for _, tt := range tests {
+		f, c := newFakeClient(t, step{service: ServiceBasicConnect, cid: cidSignalState, fnError: tt.code})
+		_, err := c.GetSignalQuality()
+		if !errors.Is(err, tt.code) || !strings.Contains(err.Error(), tt.text) {
+			t.Errorf("GetSignalQuality: %v, want %q", err, tt.text)
+		}
+		f.done()
+	}
+}
+
+func TestFragmentReassembly(t *testing.T) {
+	f, c := newFakeClient(t, step{service: ServiceBasicConnect, cid: cidSignalState, resp: signalInfo(20, 0), fragments: 3})
+	s, err := c.GetSignalQuality()
+	if err != nil {
+		t.Fatalf("GetSignalQuality: %v", err)
+	}
+	if s.RSSI != 20 || s.BER != 0 {
+		t.Errorf("signal = %+v, want RSSI 20 BER 0", s)
+	}
+	f.done()
+}
+
+func TestFragmentOutOfSequence(t *testing.T) {
+	f, c := newFakeClient(t, step{service: ServiceBasicConnect, cid: cidSignalState, silent: true})
+
+	result := make(chan error, 1)
+	go func() {
+		s, err := c.GetSignalQuality()
+		if err == nil && (s.RSSI != 15 || s.BER != 3) {
+			err = errors.New("wrong signal quality")
+		}
+		result <- err
+	}()
+	req := <-f.silent
+
+	// Первый фрагмент, затем третий: сообщение отбрасывается, модем получает
+	// MBIM_HOST_ERROR_MSG, а запрос продолжает ждать повторного ответа
+	frames := commandDoneFrame(req.Transaction, req.Service, req.CID, StatusSuccess, signalInfo(15, 3), 3)
+	f.write(frames[0])
+	f.write(frames[2])
+	f.expectHostError(req.Transaction, ErrorFragmentOutOfSequence)
+	select {
+	case err := <-result:
+		t.Fatalf("GetSignalQuality returned on broken sequence: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	for _, frame := range frames {
+		f.write(frame)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("GetSignalQuality: %v", err)
+	}
+	f.done()
+}
+
+func TestFragmentTimeout(t *testing.T) {
+	f, c := newFakeClient(t, step{service: ServiceBasicConnect, cid: cidSignalState, silent: true})
+	c.mu.Lock()
+	c.fragmentTimeout = 50 * time.Millisecond
+	c.mu.Unlock()
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := c.GetSignalQuality()
+		result <- err
+	}()
+	req := <-f.silent
+
+	// Второй фрагмент опоздал: сообщение отбрасывается с ошибкой TIMEOUT_FRAGMENT
+	frames := commandDoneFrame(req.Transaction, req.Service, req.CID, StatusSuccess, signalInfo(15, 3), 2)
+	f.write(frames[0])
+	time.Sleep(100 * time.Millisecond)
+	f.write(frames[1])
+	f.expectHostError(req.Transaction, ErrorTimeoutFragment)
+	select {
+	case err := <-result:
+		t.Fatalf("GetSignalQuality returned on late fragment: %v", err)
+	default:
+	}
+
+	for _, frame := range frames {
+		f.write(frame)
+	}
+	if err := <-result; err != nil {
+		t.Errorf("GetSignalQuality: %v", err)
+	}
+	c.mu.Lock()
+	if len(c.fragments) != 0 {
+		t.Errorf("fragments left after reassembly: %d", len(c.fragments))
+	}
+	c.mu.Unlock()
+	f.done()
+}
+
+func TestFragmentStaleCleanup(t *testing.T) {
+	c := &Client{fragments: make(map[fragmentKey]*fragment), fragmentTimeout: time.Second}
+	start := time.Now()
+	lost := &fragment{msgType: MessageIndicate, transaction: 0, total: 2, current: 0}
+	if msg, code := c.reassemble(lost, start); msg != nil || code != 0 {
+		t.Fatalf("reassemble(first) = %v, %v", msg, code)
+	}
+
+	// Последний фрагмент уведомления не пришел; следующее сообщение убирает остаток
+	next := &fragment{msgType: MessageCommandDone, transaction: 7, total: 2, current: 0}
+	c.reassemble(next, start.Add(2*time.Second))
+	if _, ok := c.fragments[fragmentKey{MessageIndicate, 0}]; ok {
+		t.Error("stale indication fragment was not removed")
+	}
+	last := &fragment{msgType: MessageCommandDone, transaction: 7, total: 2, current: 1, payload: []byte{1}}
+	if msg, code := c.reassemble(last, start.Add(2500*time.Millisecond)); msg == nil || code != 0 {
+		t.Errorf("reassemble(last) = %v, %v", msg, code)
+	}
+	if len(c.fragments) != 0 {
+		t.Errorf("fragments = %v", c.fragments)
+	}
+}
+
+func TestRepeatedFragmentedResponse(t *testing.T) {
+	// Модем повторяет ответ из нескольких фрагментов; копия не попадает в следующий запрос
+	f, c := newFakeClient(t,
+		step{service: ServiceBasicConnect, cid: cidSignalState, resp: signalInfo(10, 99), fragments: 2, twice: true},
+		step{service: ServiceBasicConnect, cid: cidSignalState, resp: signalInfo(25, 1), fragments: 2},
+	)
+	for i, want := range []int{10, 25} {
+		s, err := c.GetSignalQuality()
+		if err != nil {
+			t.Fatalf("GetSignalQuality #%d: %v", i+1, err)
+		}
+		if s.RSSI != want {
+			t.Errorf("GetSignalQuality #%d: RSSI %d, want %d", i+1, s.RSSI, want)
+		}
+	}
+	f.done()
+}
+
+func TestDisconnectMidFragment(t *testing.T) {
+	const requests = 3
+	f, c := newFakeClient(t)
+	for i := 0; i < requests; i++ {
+		f.expect(step{service: ServiceBasicConnect, cid: cidSignalState, silent: true})
+	}
+
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		go func() {
+			_, err := c.GetSignalQuality()
+			errs <- err
+		}()
+	}
+	var pending []*Message
+	for i := 0; i < requests; i++ {
+		pending = append(pending, <-f.silent)
+	}
+
+	// Модем отключается посреди фрагментированного ответа; запоздалые ответы не должны
+	// приводить к панике
+	req := pending[0]
+	f.write(commandDoneFrame(req.Transaction, req.Service, req.CID, StatusSuccess, signalInfo(1, 1), 2)[0])
+	f.conn.Close()
+	for i := 0; i < requests; i++ {
+		if err := <-errs; !errors.Is(err, ErrClosed) {
+			t.Errorf("pending request: %v, want ErrClosed", err)
+		}
+	}
+	for _, req := range pending {
+		c.dispatch(&Message{Type: MessageCommandDone, Transaction: req.Transaction})
+	}
+	select {
+	case <-c.Done():
+	default:
+		t.Error("Done is not closed after disconnect")
+	}
+	f.done()
+}
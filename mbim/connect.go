@@ -0,0 +1,252 @@
+package mbim
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// Состояния активации контекста (MBIM_ACTIVATION_STATE)
+const (
+	activationActivated   = 1
+	activationDeactivated = 3
+)
+
+// Флаги доступности параметров в MBIM_IP_CONFIGURATION_INFO
+const (
+	ipConfigAddress = 0x01
+	ipConfigGateway = 0x02
+	ipConfigDNS     = 0x04
+	ipConfigMTU     = 0x08
+)
+
+const defaultConnectTimeout = 60 * time.Second
+
+// ErrDataDisconnected сеть разорвала сессию передачи данных
+var ErrDataDisconnected = errors.New("data session disconnected")
+
+// DataConfig параметры сессии передачи данных
+type DataConfig struct {
+	APN       string        // Точка доступа
+	Username  string        // Имя пользователя
+	Password  string        // Пароль
+	Auth      gsm.APNAuth   // Тип аутентификации
+	Type      gsm.PDPType   // IP, IPV6, IPV4V6 (пусто - по умолчанию модема)
+	SessionID uint32        // Номер сессии (0 - основной интерфейс wwan0)
+	Timeout   time.Duration // Тайм-аут подключения (по умолчанию 60 секунд)
+}
+
+// DataSession активная сессия передачи данных. Трафик идет через сетевой интерфейс
+// cdc_mbim (wwan0) в режиме raw IP, адреса для него возвращает Settings.
+type DataSession struct {
+	c      *Client
+	id     uint32
+	apn    string
+	cancel func()
+
+	done      chan struct{}
+	mu        sync.Mutex
+	err       error
+	closeOnce sync.Once
+}
+
+// StartData активирует контекст Интернет (MBIM_CID_CONNECT)
+func (c *Client) StartData(config DataConfig) (*DataSession, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultConnectTimeout
+	}
+	var ipType uint32
+	switch config.Type {
+	case "":
+		ipType = 0
+	case gsm.PDPTypeIP:
+		ipType = 1
+	case gsm.PDPTypeIPv6:
+		ipType = 2
+	case gsm.PDPTypeIPv4v6:
+		ipType = 3
+	default:
+		return nil, fmt.Errorf("unsupported PDP type %q", config.Type)
+	}
+
+	s := &DataSession{c: c, id: config.SessionID, apn: config.APN, done: make(chan struct{})}
+	s.cancel = c.onIndication(ServiceBasicConnect, cidConnect, func(msg *Message) {
+		r := &infoReader{buf: msg.Data}
+		id, state := r.u32(), r.u32()
+		if r.ok() && id == s.id && state == activationDeactivated {
+			s.finish(ErrDataDisconnected)
+		}
+	})
+
+	data := newInfoBuilder(60).
+		u32(config.SessionID).
+		u32(1). // Активировать
+		str(config.APN).
+		str(config.Username).
+		str(config.Password).
+		u32(0). // Без сжатия
+		u32(uint32(config.Auth)).
+		u32(ipType).
+		uuid(ContextInternet).
+		bytes()
+	resp, err := c.commandTimeout(ServiceBasicConnect, cidConnect, CommandSet, data, config.Timeout)
+	if err != nil {
+		s.cancel()
+		if resp != nil {
+			if nwError := connectNetworkError(resp.Data); nwError != 0 {
+				err = fmt.Errorf("%w (network error %d)", err, nwError)
+			}
+		}
+		return nil, fmt.Errorf("failed to start data session: %w", err)
+	}
+	r := &infoReader{buf: resp.Data}
+	r.u32()
+	if state := r.u32(); r.ok() && state != activationActivated {
+		s.cancel()
+		return nil, fmt.Errorf("failed to start data session: activation state %d", state)
+	}
+	return s, nil
+}
+
+// connectNetworkError возвращает NwError из MBIM_CONNECT_INFO
+func connectNetworkError(data []byte) uint32 {
+	// SessionId, ActivationState, VoiceCallState, IPType, ContextType, NwError
+	if len(data) < 36 {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(data[32:])
+}
+
+// Settings возвращает адреса, шлюзы, DNS и MTU сессии (по записи на семейство адресов)
+func (s *DataSession) Settings() ([]gsm.PDPDynamicParams, error) {
+	query := newInfoBuilder(60).u32(s.id)
+	for i := 0; i < 14; i++ {
+		query.u32(0)
+	}
+	resp, err := s.c.query(ServiceBasicConnect, cidIPConfiguration, query.bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IP configuration: %w", err)
+	}
+	params, err := parseIPConfiguration(resp.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IP configuration: %w", err)
+	}
+	for i := range params {
+		params[i].CID = int(s.id)
+		params[i].APN = s.apn
+	}
+	return params, nil
+}
+
+// parseIPConfiguration разбирает MBIM_IP_CONFIGURATION_INFO
+func parseIPConfiguration(data []byte) ([]gsm.PDPDynamicParams, error) {
+	r := &infoReader{buf: data}
+	r.u32() // SessionId
+	v4Available, v6Available := r.u32(), r.u32()
+	v4Count, v4Offset := r.u32(), r.u32()
+	v6Count, v6Offset := r.u32(), r.u32()
+	v4Gateway, v6Gateway := r.u32(), r.u32()
+	v4DNSCount, v4DNSOffset := r.u32(), r.u32()
+	v6DNSCount, v6DNSOffset := r.u32(), r.u32()
+	v4MTU, v6MTU := r.u32(), r.u32()
+	if !r.ok() {
+		return nil, ErrMalformed
+	}
+
+	// at возвращает size байт по смещению или nil при выходе за границу
+	bad := false
+	at := func(offset uint32, size int) []byte {
+		if int(offset)+size > len(data) {
+			bad = true
+			return nil
+		}
+		return data[offset : int(offset)+size]
+	}
+
+	var params []gsm.PDPDynamicParams
+	family := func(available, count, offset, gateway, dnsCount, dnsOffset, mtu uint32, size, bits int) {
+		if available == 0 {
+			return
+		}
+		var p gsm.PDPDynamicParams
+		if available&ipConfigAddress != 0 && count > 0 {
+			// Элемент: длина префикса и адрес; используется первый адрес
+			if e := at(offset, 4+size); e != nil {
+				p.Mask = net.CIDRMask(int(binary.LittleEndian.Uint32(e)), bits)
+				p.Address = net.IP(append([]byte(nil), e[4:]...))
+			}
+		}
+		if available&ipConfigGateway != 0 && gateway != 0 {
+			if e := at(gateway, size); e != nil {
+				p.Gateway = net.IP(append([]byte(nil), e...))
+			}
+		}
+		if available&ipConfigDNS != 0 {
+			for i := uint32(0); i < dnsCount; i++ {
+				if e := at(dnsOffset+i*uint32(size), size); e != nil {
+					p.DNS = append(p.DNS, net.IP(append([]byte(nil), e...)))
+				}
+			}
+		}
+		if available&ipConfigMTU != 0 {
+			p.MTU = int(mtu)
+		}
+		params = append(params, p)
+	}
+	family(v4Available, v4Count, v4Offset, v4Gateway, v4DNSCount, v4DNSOffset, v4MTU, net.IPv4len, 32)
+	family(v6Available, v6Count, v6Offset, v6Gateway, v6DNSCount, v6DNSOffset, v6MTU, net.IPv6len, 128)
+	if bad {
+		return nil, ErrMalformed
+	}
+	return params, nil
+}
+
+// finish завершает сессию с указанной причиной
+func (s *DataSession) finish(err error) {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// Done закрывается, когда сеть разорвала сессию или вызван Stop
+func (s *DataSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err возвращает причину завершения сессии
+func (s *DataSession) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Stop деактивирует контекст
+func (s *DataSession) Stop() error {
+	s.cancel()
+	defer s.finish(ErrDataDisconnected)
+
+	data := newInfoBuilder(60).
+		u32(s.id).
+		u32(0). // Деактивировать
+		str("").
+		str("").
+		str("").
+		u32(0).
+		u32(0).
+		u32(0).
+		uuid(ContextInternet).
+		bytes()
+	_, err := s.c.set(ServiceBasicConnect, cidConnect, data)
+	if err != nil && !IsStatus(err, StatusContextNotActivated) {
+		return fmt.Errorf("failed to stop data session: %w", err)
+	}
+	return nil
+}
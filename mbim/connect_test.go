@@ -0,0 +1,144 @@
+package mbim
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// connectInfo собирает MBIM_CONNECT_INFO
+func connectInfo(session, state, nwError uint32) []byte {
+	return newInfoBuilder(36).
+		u32(session).
+		u32(state).
+		u32(0). // VoiceCallState
+		u32(1). // IPType
+		uuid(ContextInternet).
+		u32(nwError).
+		bytes()
+}
+
+// ipConfigurationInfo собирает MBIM_IP_CONFIGURATION_INFO с адресом IPv4, шлюзом и двумя DNS
+func ipConfigurationInfo(session uint32) []byte {
+	const fixed = 15 * 4
+	b := binary.LittleEndian.AppendUint32(nil, session)
+	for _, v := range []uint32{
+		ipConfigAddress | ipConfigGateway | ipConfigDNS | ipConfigMTU, 0, // Доступность IPv4, IPv6
+		1, fixed, 0, 0, // Адреса IPv4 и IPv6
+		fixed + 8, 0, // Шлюзы
+		2, fixed + 12, 0, 0, // DNS IPv4 и IPv6
+		1500, 0, // MTU
+	} {
+		b = binary.LittleEndian.AppendUint32(b, v)
+	}
+	b = binary.LittleEndian.AppendUint32(b, 30)
+	b = append(b, 10, 64, 1, 2) // Адрес
+	b = append(b, 10, 64, 1, 1) // Шлюз
+	b = append(b, 8, 8, 8, 8, 8, 8, 4, 4)
+	return b
+}
+
+// checkConnect проверяет запрос MBIM_CID_CONNECT
+func checkConnect(activate uint32, apn, user string, auth gsm.APNAuth, ipType uint32) func(*testing.T, []byte) {
+	return func(t *testing.T, data []byte) {
+		t.Helper()
+		r := &infoReader{buf: data}
+		session, command := r.u32(), r.u32()
+		gotAPN, gotUser := r.str(), r.str()
+		r.str()
+		r.u32() // Сжатие
+		gotAuth, gotType := r.u32(), r.u32()
+		context := r.uuid()
+		if !r.ok() {
+			t.Errorf("malformed connect request %x", data)
+			return
+		}
+		if session != 0 || command != activate || gotAPN != apn || gotUser != user ||
+			gsm.APNAuth(gotAuth) != auth || gotType != ipType || context != ContextInternet {
+			t.Errorf("connect request: session %d command %d APN %q user %q auth %d type %d context %s",
+				session, command, gotAPN, gotUser, gotAuth, gotType, context)
+		}
+	}
+}
+
+func TestStartData(t *testing.T) {
+	f, c := newFakeClient(t,
+		step{
+			service: ServiceBasicConnect, cid: cidConnect, command: CommandSet,
+			check: checkConnect(1, "internet.mts.ru", "mts", gsm.APNAuthPAP, 1),
+			resp:  connectInfo(0, activationActivated, 0),
+		},
+		step{service: ServiceBasicConnect, cid: cidIPConfiguration, resp: ipConfigurationInfo(0)},
+	)
+
+	s, err := c.StartData(DataConfig{APN: "internet.mts.ru", Username: "mts", Password: "mts", Auth: gsm.APNAuthPAP, Type: gsm.PDPTypeIP})
+	if err != nil {
+		t.Fatalf("StartData: %v", err)
+	}
+	params, err := s.Settings()
+	if err != nil {
+		t.Fatalf("Settings: %v", err)
+	}
+	if len(params) != 1 {
+		t.Fatalf("Settings returned %d families, want 1", len(params))
+	}
+	p := params[0]
+	if !p.Address.Equal(net.IPv4(10, 64, 1, 2)) || p.Mask.String() != "fffffffc" || !p.Gateway.Equal(net.IPv4(10, 64, 1, 1)) ||
+		len(p.DNS) != 2 || !p.DNS[1].Equal(net.IPv4(8, 8, 4, 4)) || p.MTU != 1500 || p.APN != "internet.mts.ru" {
+		t.Errorf("settings = %+v", p)
+	}
+
+	// Уведомление о деактивации другой сессии игнорируется
+	f.indicate(ServiceBasicConnect, cidConnect, connectInfo(1, activationDeactivated, 0))
+	f.indicate(ServiceBasicConnect, cidConnect, connectInfo(0, activationDeactivated, 36))
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatal("session is not finished after deactivation indication")
+	}
+	if !errors.Is(s.Err(), ErrDataDisconnected) {
+		t.Errorf("Err = %v, want ErrDataDisconnected", s.Err())
+	}
+	f.done()
+}
+
+func TestStartDataNetworkError(t *testing.T) {
+	f, c := newFakeClient(t, step{
+		service: ServiceBasicConnect, cid: cidConnect, command: CommandSet,
+		status: StatusFailure, resp: connectInfo(0, activationDeactivated, 33),
+	})
+	_, err := c.StartData(DataConfig{APN: "bad"})
+	if !IsStatus(err, StatusFailure) || !strings.Contains(err.Error(), "network error 33") {
+		t.Errorf("StartData: %v, want failure with network error 33", err)
+	}
+	f.done()
+}
+
+func TestStopData(t *testing.T) {
+	f, c := newFakeClient(t,
+		step{service: ServiceBasicConnect, cid: cidConnect, command: CommandSet, resp: connectInfo(0, activationActivated, 0)},
+		step{
+			service: ServiceBasicConnect, cid: cidConnect, command: CommandSet,
+			check:  checkConnect(0, "", "", gsm.APNAuthNone, 0),
+			status: StatusContextNotActivated,
+		},
+	)
+	s, err := c.StartData(DataConfig{APN: "internet"})
+	if err != nil {
+		t.Fatalf("StartData: %v", err)
+	}
+	if err := s.Stop(); err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+	select {
+	case <-s.Done():
+	default:
+		t.Error("Done is not closed after Stop")
+	}
+	f.done()
+}
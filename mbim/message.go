@@ -0,0 +1,421 @@
+// Package mbim реализует клиент протокола Mobile Broadband Interface Model для модемов,
+// управляемых через /dev/cdc-wdmN (драйвер cdc_mbim). Поддерживаются сервисы Basic Connect
+// и SMS; Client реализует интерфейс gsm.Device.
+package mbim
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf16"
+)
+
+// MessageType тип управляющего сообщения MBIM
+type MessageType uint32
+
+const (
+	MessageOpen          MessageType = 0x00000001
+	MessageClose         MessageType = 0x00000002
+	MessageCommand       MessageType = 0x00000003
+	MessageHostError     MessageType = 0x00000004
+	MessageOpenDone      MessageType = 0x80000001
+	MessageCloseDone     MessageType = 0x80000002
+	MessageCommandDone   MessageType = 0x80000003
+	MessageFunctionError MessageType = 0x80000004
+	MessageIndicate      MessageType = 0x80000007
+)
+
+// CommandType тип команды: запрос значения или установка
+type CommandType uint32
+
+const (
+	CommandQuery CommandType = 0
+	CommandSet   CommandType = 1
+)
+
+const (
+	headerSize         = 12 // Тип, длина, номер транзакции
+	fragmentHeaderSize = 8  // Всего фрагментов, номер фрагмента
+)
+
+// UUID идентификатор сервиса устройства (байты в сетевом порядке)
+type UUID [16]byte
+
+// String возвращает UUID в каноническом виде
+func (u UUID) String() string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+var (
+	// ServiceBasicConnect сервис Basic Connect: устройство, SIM, регистрация, подключение
+	ServiceBasicConnect = UUID{0xa2, 0x89, 0xcc, 0x33, 0xbc, 0xbb, 0x8b, 0x4f, 0xb6, 0xb0, 0x13, 0x3e, 0xc2, 0xaa, 0xe6, 0xdf}
+	// ServiceSMS сервис SMS
+	ServiceSMS = UUID{0x53, 0x3f, 0xbe, 0xeb, 0x14, 0xfe, 0x44, 0x67, 0x9f, 0x90, 0x33, 0xa2, 0x23, 0xe5, 0x6c, 0x3f}
+	// ContextInternet тип контекста "Интернет" для CONNECT
+	ContextInternet = UUID{0x7e, 0x5e, 0x2a, 0x7e, 0x4e, 0x6f, 0x72, 0x72, 0x73, 0x6b, 0x65, 0x6e, 0x7e, 0x5e, 0x2a, 0x7e}
+)
+
+// ErrMalformed сообщение MBIM не удалось разобрать
+var ErrMalformed = errors.New("malformed MBIM message")
+
+// Message управляющее сообщение MBIM. Для команд, ответов и уведомлений заполнены
+// Service, CID и Data (информационный буфер).
+type Message struct {
+	Type        MessageType
+	Transaction uint32
+	Service     UUID
+	CID         uint32
+	Command     CommandType // Только для MessageCommand
+	Status      Status      // Для MessageOpenDone, MessageCloseDone, MessageCommandDone и ошибок
+	Data        []byte
+}
+
+// Marshal кодирует сообщение одним фрагментом
+func (m *Message) Marshal() []byte {
+	var body []byte
+	switch m.Type {
+	case MessageOpen:
+		body = binary.LittleEndian.AppendUint32(nil, uint32(maxControlTransfer))
+	case MessageCommand:
+		body = binary.LittleEndian.AppendUint32(body, 1) // Всего фрагментов
+		body = binary.LittleEndian.AppendUint32(body, 0) // Номер фрагмента
+		body = append(body, m.Service[:]...)
+		body = binary.LittleEndian.AppendUint32(body, m.CID)
+		body = binary.LittleEndian.AppendUint32(body, uint32(m.Command))
+		body = binary.LittleEndian.AppendUint32(body, uint32(len(m.Data)))
+		body = append(body, m.Data...)
+	case MessageHostError:
+		body = binary.LittleEndian.AppendUint32(nil, uint32(m.Status))
+	}
+
+	b := binary.LittleEndian.AppendUint32(nil, uint32(m.Type))
+	b = binary.LittleEndian.AppendUint32(b, uint32(headerSize+len(body)))
+	b = binary.LittleEndian.AppendUint32(b, m.Transaction)
+	return append(b, body...)
+}
+
+// fragment фрагмент сообщения MBIM_COMMAND_DONE или MBIM_INDICATE_STATUS_MSG
+type fragment struct {
+	msgType     MessageType
+	transaction uint32
+	total       uint32
+	current     uint32
+	payload     []byte    // Данные после заголовка фрагмента
+	received    time.Time // Время последнего фрагмента собираемого сообщения
+}
+
+// parseFragment разбирает заголовок сообщения; для сообщений без фрагментации total = 1
+func parseFragment(b []byte) (*fragment, error) {
+	if len(b) < headerSize || int(binary.LittleEndian.Uint32(b[4:])) != len(b) {
+		return nil, fmt.Errorf("%w: bad header", ErrMalformed)
+	}
+	f := &fragment{
+		msgType:     MessageType(binary.LittleEndian.Uint32(b)),
+		transaction: binary.LittleEndian.Uint32(b[8:]),
+		total:       1,
+		payload:     b[headerSize:],
+	}
+	if f.msgType == MessageCommandDone || f.msgType == MessageIndicate {
+		if len(f.payload) < fragmentHeaderSize {
+			return nil, fmt.Errorf("%w: short fragment header", ErrMalformed)
+		}
+		f.total = binary.LittleEndian.Uint32(f.payload)
+		f.current = binary.LittleEndian.Uint32(f.payload[4:])
+		f.payload = f.payload[fragmentHeaderSize:]
+	}
+	return f, nil
+}
+
+// decode собирает сообщение из полезной нагрузки всех фрагментов
+func (f *fragment) decode() (*Message, error) {
+	m := &Message{Type: f.msgType, Transaction: f.transaction}
+	p := f.payload
+	switch m.Type {
+	case MessageOpenDone, MessageCloseDone, MessageFunctionError:
+		if len(p) < 4 {
+			return nil, fmt.Errorf("%w: short status", ErrMalformed)
+		}
+		m.Status = Status(binary.LittleEndian.Uint32(p))
+	case MessageCommandDone, MessageIndicate:
+		fixed := 16 + 4 + 4 // UUID, CID, длина буфера
+		if m.Type == MessageCommandDone {
+			fixed += 4 // Статус
+		}
+		if len(p) < fixed {
+			return nil, fmt.Errorf("%w: short command header", ErrMalformed)
+		}
+		copy(m.Service[:], p)
+		m.CID = binary.LittleEndian.Uint32(p[16:])
+		p = p[20:]
+		if m.Type == MessageCommandDone {
+			m.Status = Status(binary.LittleEndian.Uint32(p))
+			p = p[4:]
+		}
+		n := int(binary.LittleEndian.Uint32(p))
+		if 4+n > len(p) {
+			return nil, fmt.Errorf("%w: information buffer overflows message", ErrMalformed)
+		}
+		m.Data = p[4 : 4+n]
+	}
+	return m, nil
+}
+
+// Status код состояния MBIM_STATUS
+type Status uint32
+
+const (
+	StatusSuccess                Status = 0
+	StatusBusy                   Status = 1
+	StatusFailure                Status = 2
+	StatusSIMNotInserted         Status = 3
+	StatusBadSIM                 Status = 4
+	StatusPINRequired            Status = 5
+	StatusPINDisabled            Status = 6
+	StatusNotRegistered          Status = 7
+	StatusProvidersNotFound      Status = 8
+	StatusNoDeviceSupport        Status = 9
+	StatusProviderNotVisible     Status = 10
+	StatusDataClassNotAvailable  Status = 11
+	StatusPacketServiceDetached  Status = 12
+	StatusMaxActivatedContexts   Status = 13
+	StatusNotInitialized         Status = 14
+	StatusVoiceCallInProgress    Status = 15
+	StatusContextNotActivated    Status = 16
+	StatusServiceNotActivated    Status = 17
+	StatusInvalidAccessString    Status = 18
+	StatusInvalidUserNamePwd     Status = 19
+	StatusRadioPowerOff          Status = 20
+	StatusInvalidParameters      Status = 21
+	StatusReadFailure            Status = 22
+	StatusWriteFailure           Status = 23
+	StatusOperationNotAllowed    Status = 28
+	StatusInvalidMemoryIndex     Status = 30
+	StatusMemoryFull             Status = 31
+	StatusSMSUnknownSMSCAddress  Status = 100
+	StatusSMSNetworkTimeout      Status = 101
+	StatusSMSEncodingUnsupported Status = 103
+)
+
+// statusNames названия известных кодов состояния
+var statusNames = map[Status]string{
+	StatusBusy:                   "busy",
+	StatusFailure:                "failure",
+	StatusSIMNotInserted:         "SIM not inserted",
+	StatusBadSIM:                 "bad SIM",
+	StatusPINRequired:            "PIN required",
+	StatusPINDisabled:            "PIN disabled",
+	StatusNotRegistered:          "not registered",
+	StatusProvidersNotFound:      "providers not found",
+	StatusNoDeviceSupport:        "no device support",
+	StatusProviderNotVisible:     "provider not visible",
+	StatusDataClassNotAvailable:  "data class not available",
+	StatusPacketServiceDetached:  "packet service detached",
+	StatusMaxActivatedContexts:   "max activated contexts",
+	StatusNotInitialized:         "not initialized",
+	StatusVoiceCallInProgress:    "voice call in progress",
+	StatusContextNotActivated:    "context not activated",
+	StatusServiceNotActivated:    "service not activated",
+	StatusInvalidAccessString:    "invalid access string",
+	StatusInvalidUserNamePwd:     "invalid user name or password",
+	StatusRadioPowerOff:          "radio power off",
+	StatusInvalidParameters:      "invalid parameters",
+	StatusReadFailure:            "read failure",
+	StatusWriteFailure:           "write failure",
+	StatusOperationNotAllowed:    "operation not allowed",
+	StatusInvalidMemoryIndex:     "invalid memory index",
+	StatusMemoryFull:             "memory full",
+	StatusSMSUnknownSMSCAddress:  "unknown SMSC address",
+	StatusSMSNetworkTimeout:      "SMS network timeout",
+	StatusSMSEncodingUnsupported: "SMS encoding not supported",
+}
+
+// ProtocolError код ошибки протокола из MBIM_FUNCTION_ERROR_MSG (модем) или
+// MBIM_HOST_ERROR_MSG (хост)
+type ProtocolError uint32
+
+const (
+	ErrorTimeoutFragment       ProtocolError = 1
+	ErrorFragmentOutOfSequence ProtocolError = 2
+	ErrorLengthMismatch        ProtocolError = 3
+	ErrorDuplicatedTID         ProtocolError = 4
+	ErrorNotOpened             ProtocolError = 5
+	ErrorUnknown               ProtocolError = 6
+	ErrorCancel                ProtocolError = 7
+	ErrorMaxTransfer           ProtocolError = 8
+)
+
+// protocolErrorNames названия кодов ошибок протокола
+var protocolErrorNames = map[ProtocolError]string{
+	ErrorTimeoutFragment:       "fragment timeout",
+	ErrorFragmentOutOfSequence: "fragment out of sequence",
+	ErrorLengthMismatch:        "length mismatch",
+	ErrorDuplicatedTID:         "duplicated transaction ID",
+	ErrorNotOpened:             "function not opened",
+	ErrorUnknown:               "unknown error",
+	ErrorCancel:                "cancelled",
+	ErrorMaxTransfer:           "message exceeds max transfer size",
+}
+
+// Error возвращает текст ошибки
+func (e ProtocolError) Error() string {
+	name, ok := protocolErrorNames[e]
+	if !ok {
+		name = fmt.Sprintf("code %d", uint32(e))
+	}
+	return "MBIM protocol error: " + name
+}
+
+// Error ошибка, возвращенная модемом в ответе на команду
+type Error struct {
+	Service UUID
+	CID     uint32
+	Status  Status
+}
+
+// Error возвращает текст ошибки
+func (e *Error) Error() string {
+	name, ok := statusNames[e.Status]
+	if !ok {
+		name = fmt.Sprintf("status %d", uint32(e.Status))
+	}
+	return fmt.Sprintf("MBIM %s CID %d: %s", serviceName(e.Service), e.CID, name)
+}
+
+// IsStatus проверяет, что err - ошибка MBIM с указанным кодом
+func IsStatus(err error, status Status) bool {
+	var merr *Error
+	return errors.As(err, &merr) && merr.Status == status
+}
+
+// serviceName возвращает короткое название известного сервиса
+func serviceName(u UUID) string {
+	switch u {
+	case ServiceBasicConnect:
+		return "Basic Connect"
+	case ServiceSMS:
+		return "SMS"
+	default:
+		return u.String()
+	}
+}
+
+// infoBuilder собирает информационный буфер: фиксированная часть с полями и парами
+// (смещение, размер), за ней - выровненные по 4 байта переменные данные
+type infoBuilder struct {
+	fixedSize int
+	fixed     []byte
+	data      []byte
+}
+
+// newInfoBuilder создает буфер с фиксированной частью указанного размера
+func newInfoBuilder(fixedSize int) *infoBuilder {
+	return &infoBuilder{fixedSize: fixedSize}
+}
+
+// u32 добавляет поле uint32
+func (b *infoBuilder) u32(v uint32) *infoBuilder {
+	b.fixed = binary.LittleEndian.AppendUint32(b.fixed, v)
+	return b
+}
+
+// uuid добавляет поле UUID
+func (b *infoBuilder) uuid(u UUID) *infoBuilder {
+	b.fixed = append(b.fixed, u[:]...)
+	return b
+}
+
+// ref добавляет пару (смещение, размер) на данные в переменной части
+func (b *infoBuilder) ref(data []byte) *infoBuilder {
+	if len(data) == 0 {
+		return b.u32(0).u32(0)
+	}
+	b.u32(uint32(b.fixedSize + len(b.data))).u32(uint32(len(data)))
+	b.data = append(b.data, data...)
+	for len(b.data)%4 != 0 {
+		b.data = append(b.data, 0)
+	}
+	return b
+}
+
+// str добавляет строку в кодировке UTF-16LE
+func (b *infoBuilder) str(s string) *infoBuilder {
+	return b.ref(encodeString(s))
+}
+
+// bytes возвращает собранный буфер
+func (b *infoBuilder) bytes() []byte {
+	return append(b.fixed, b.data...)
+}
+
+// infoReader читает поля информационного буфера; смещения в парах отсчитываются
+// от начала буфера (структуры)
+type infoReader struct {
+	buf []byte
+	pos int
+	bad bool
+}
+
+// u32 читает поле uint32
+func (r *infoReader) u32() uint32 {
+	if r.pos+4 > len(r.buf) {
+		r.bad = true
+		return 0
+	}
+	v := binary.LittleEndian.Uint32(r.buf[r.pos:])
+	r.pos += 4
+	return v
+}
+
+// uuid читает поле UUID
+func (r *infoReader) uuid() UUID {
+	var u UUID
+	if r.pos+16 > len(r.buf) {
+		r.bad = true
+		return u
+	}
+	copy(u[:], r.buf[r.pos:])
+	r.pos += 16
+	return u
+}
+
+// ref читает пару (смещение, размер) и возвращает данные, на которые она указывает
+func (r *infoReader) ref() []byte {
+	offset, size := int(r.u32()), int(r.u32())
+	if size == 0 {
+		return nil
+	}
+	if offset < 0 || size < 0 || offset+size > len(r.buf) {
+		r.bad = true
+		return nil
+	}
+	return r.buf[offset : offset+size]
+}
+
+// str читает строку UTF-16LE
+func (r *infoReader) str() string {
+	return decodeString(r.ref())
+}
+
+// ok проверяет, что все поля прочитаны без выхода за границу
+func (r *infoReader) ok() bool {
+	return !r.bad
+}
+
+// encodeString кодирует строку в UTF-16LE
+func encodeString(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// decodeString декодирует строку UTF-16LE
+func decodeString(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
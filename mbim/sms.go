@@ -0,0 +1,178 @@
+package mbim
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// CID сервиса SMS
+const (
+	cidSMSRead               = 2
+	cidSMSSend               = 3
+	cidSMSDelete             = 4
+	cidSMSMessageStoreStatus = 5
+)
+
+// Фильтры сообщений (MBIM_SMS_FLAG)
+const (
+	smsFlagAll   = 0
+	smsFlagIndex = 1
+	smsFlagNew   = 2
+	smsFlagOld   = 3
+	smsFlagSent  = 4
+	smsFlagDraft = 5
+)
+
+const (
+	smsFormatPDU         = 0
+	smsStoreNewMessage   = 0x02 // Флаг нового сообщения в MBIM_SMS_STATUS_INFO
+	smsSendTimeout       = 60 * time.Second
+	smsSendPDURecordSize = 8 // PduData
+)
+
+// smsStatuses статусы сообщений в формате AT+CMGL по MBIM_SMS_MESSAGE_STATUS
+var smsStatuses = map[uint32]string{
+	0: "REC UNREAD",
+	1: "REC READ",
+	2: "STO UNSENT",
+	3: "STO SENT",
+}
+
+// smsFlags фильтры MBIM по статусам AT+CMGL
+var smsFlags = map[string]uint32{
+	"":           smsFlagAll,
+	"ALL":        smsFlagAll,
+	"REC UNREAD": smsFlagNew,
+	"REC READ":   smsFlagOld,
+	"STO SENT":   smsFlagSent,
+	"STO UNSENT": smsFlagDraft,
+}
+
+// SendSMS отправляет SMS сообщение
+func (c *Client) SendSMS(number, text string) error {
+	pdu, err := gsm.EncodeSMSSubmitPDU(number, text)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	// За форматом следует MBIM_SMS_SEND_PDU, смещение PDU отсчитывается от его начала
+	record := newInfoBuilder(smsSendPDURecordSize).ref(pdu).bytes()
+	data := append(newInfoBuilder(4).u32(smsFormatPDU).bytes(), record...)
+	if _, err := c.commandTimeout(ServiceSMS, cidSMSSend, CommandSet, data, smsSendTimeout); err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	return nil
+}
+
+// readSMS запрашивает сообщения по фильтру
+func (c *Client) readSMS(flag, index uint32) ([]*gsm.SMS, error) {
+	data := newInfoBuilder(12).u32(smsFormatPDU).u32(flag).u32(index).bytes()
+	resp, err := c.query(ServiceSMS, cidSMSRead, data)
+	if err != nil {
+		return nil, err
+	}
+	return parseSMSRecords(resp.Data)
+}
+
+// parseSMSRecords разбирает MBIM_SMS_READ_INFO: список записей с PDU
+func parseSMSRecords(data []byte) ([]*gsm.SMS, error) {
+	r := &infoReader{buf: data}
+	if format := r.u32(); format != smsFormatPDU {
+		return nil, fmt.Errorf("unsupported SMS format %d", format)
+	}
+	count := int(r.u32())
+	messages := make([]*gsm.SMS, 0, count)
+	for i := 0; i < count && r.ok(); i++ {
+		// Смещение PDU внутри записи отсчитывается от начала записи
+		record := &infoReader{buf: r.ref()}
+		index, status := record.u32(), record.u32()
+		pdu := record.ref()
+		if !record.ok() {
+			return nil, ErrMalformed
+		}
+		sms, err := gsm.DecodeSMSPDU(pdu)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode SMS %d: %w", index, err)
+		}
+		sms.Index = int(index)
+		sms.Status = smsStatuses[status]
+		messages = append(messages, sms)
+	}
+	if !r.ok() {
+		return nil, ErrMalformed
+	}
+	return messages, nil
+}
+
+// ReadSMS читает SMS по индексу; модем помечает входящее сообщение прочитанным
+func (c *Client) ReadSMS(index int) (*gsm.SMS, error) {
+	messages, err := c.readSMS(smsFlagIndex, uint32(index))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SMS %d: %w", index, err)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("failed to read SMS %d: no message", index)
+	}
+	return messages[0], nil
+}
+
+// ListSMS возвращает сообщения с указанным статусом AT+CMGL ("REC UNREAD", "ALL" или "")
+func (c *Client) ListSMS(status string) ([]*gsm.SMS, error) {
+	flag, ok := smsFlags[status]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SMS status %q", status)
+	}
+	messages, err := c.readSMS(flag, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SMS: %w", err)
+	}
+	return messages, nil
+}
+
+// DeleteSMS удаляет SMS по индексу
+func (c *Client) DeleteSMS(index int) error {
+	data := newInfoBuilder(8).u32(smsFlagIndex).u32(uint32(index)).bytes()
+	if _, err := c.set(ServiceSMS, cidSMSDelete, data); err != nil {
+		return fmt.Errorf("failed to delete SMS %d: %w", index, err)
+	}
+	return nil
+}
+
+// DeleteAllSMS удаляет все SMS
+func (c *Client) DeleteAllSMS() error {
+	data := newInfoBuilder(8).u32(smsFlagAll).u32(0).bytes()
+	if _, err := c.set(ServiceSMS, cidSMSDelete, data); err != nil {
+		return fmt.Errorf("failed to delete all SMS: %w", err)
+	}
+	return nil
+}
+
+// handleSMSRead обрабатывает уведомление READ: модем присылает им сообщения, которые
+// не сохраняются в памяти (class 0), поэтому событие содержит само сообщение
+func (c *Client) handleSMSRead(msg *Message) {
+	messages, err := parseSMSRecords(msg.Data)
+	if err != nil {
+		return
+	}
+	for _, sms := range messages {
+		c.emitEvent(gsm.Event{
+			Type:      gsm.EventNewSMS,
+			Timestamp: time.Now(),
+			Data:      map[string]interface{}{"index": sms.Index, "sms": sms},
+		})
+	}
+}
+
+// handleStoreStatus обрабатывает уведомление MESSAGE_STORE_STATUS о новом сообщении
+func (c *Client) handleStoreStatus(msg *Message) {
+	r := &infoReader{buf: msg.Data}
+	flag, index := r.u32(), r.u32()
+	if r.ok() && flag&smsStoreNewMessage != 0 {
+		c.emitEvent(gsm.Event{
+			Type:      gsm.EventNewSMS,
+			Timestamp: time.Now(),
+			Data:      map[string]interface{}{"storage": string(gsm.StorageAny), "index": int(index)},
+		})
+	}
+}
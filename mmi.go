@@ -0,0 +1,441 @@
+package gsm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotMMI возвращается, если строка не является MMI кодом (например, обычный номер телефона)
+var ErrNotMMI = errors.New("not an MMI code")
+
+// MMIProcedure тип процедуры MMI кода (3GPP TS 22.030)
+type MMIProcedure string
+
+const (
+	MMIActivate     MMIProcedure = "*"  // Активация: *SC*SI#
+	MMIDeactivate   MMIProcedure = "#"  // Деактивация: #SC*SI#
+	MMIInterrogate  MMIProcedure = "*#" // Запрос состояния: *#SC*SI#
+	MMIRegister     MMIProcedure = "**" // Регистрация: **SC*SI#
+	MMIErase        MMIProcedure = "##" // Удаление: ##SC*SI#
+	MMIProcedureNil MMIProcedure = ""   // Не процедура дополнительной услуги (USSD)
+)
+
+// MMIKind вид MMI кода
+type MMIKind string
+
+const (
+	MMIKindUSSD       MMIKind = "ussd"       // USSD запрос, отправляется в сеть как есть
+	MMIKindForwarding MMIKind = "forwarding" // Переадресация (21, 67, 61, 62, 002, 004)
+	MMIKindBarring    MMIKind = "barring"    // Запрет вызовов (33, 331, 332, 35, 351, 330, 333, 353)
+	MMIKindWaiting    MMIKind = "waiting"    // Ожидание вызова (43)
+	MMIKindCLIP       MMIKind = "clip"       // Определение номера (30)
+	MMIKindCLIR       MMIKind = "clir"       // Скрытие номера (31)
+	MMIKindPIN        MMIKind = "pin"        // Смена PIN/PIN2 (04, 042) и разблокировка PUK (05)
+	MMIKindPassword   MMIKind = "password"   // Смена пароля запрета вызовов (03)
+	MMIKindIMEI       MMIKind = "imei"       // Показ IMEI (*#06#)
+)
+
+// MMICode разобранный MMI код
+type MMICode struct {
+	Raw         string       // Исходная строка
+	Kind        MMIKind      // Вид кода
+	Procedure   MMIProcedure // Процедура (пусто для USSD)
+	ServiceCode string       // Код услуги (SC), например "21"
+	SI          []string     // Дополнительная информация SIA, SIB, SIC
+}
+
+// MMIResult результат выполнения MMI кода
+type MMIResult struct {
+	Code       *MMICode         // Разобранный код
+	Message    string           // Текстовое описание результата (как на экране телефона)
+	USSD       string           // Ответ сети на USSD запрос
+	IMEI       string           // IMEI модема (*#06#)
+	Forwarding []ForwardingRule // Состояние переадресации (запрос *#21#)
+	Services   []ServiceStatus  // Состояние запрета/ожидания вызова (запрос *#33#, *#43#)
+	Active     bool             // Услуга активна (для запросов)
+	CLIRMode   CLIRMode         // Режим CLIR (*#31#)
+	CLIRStatus CLIRProvisioning // Статус CLIR в сети (*#31#)
+}
+
+// forwardingCodes коды услуг переадресации
+var forwardingCodes = map[string]ForwardingReason{
+	"21":  ForwardUnconditional,
+	"67":  ForwardBusy,
+	"61":  ForwardNoReply,
+	"62":  ForwardNotReachable,
+	"002": ForwardAll,
+	"004": ForwardAllConditional,
+}
+
+// barringCodes коды услуг запрета вызовов
+var barringCodes = map[string]Facility{
+	"33":  BarAllOutgoing,
+	"331": BarOutgoingInternational,
+	"332": BarOutgoingInternationalExH,
+	"35":  BarAllIncoming,
+	"351": BarIncomingRoaming,
+	"330": BarAll,
+	"333": BarAllOutgoingServices,
+	"353": BarAllIncomingServices,
+}
+
+// basicServiceClass преобразует код группы услуг BS (22.030 Annex C) в класс 27.007
+func basicServiceClass(bs string) ServiceClass {
+	switch bs {
+	case "":
+		return ClassDefault
+	case "10":
+		return ClassVoice | ClassFax | ClassSMS
+	case "11":
+		return ClassVoice
+	case "12":
+		return ClassFax | ClassSMS
+	case "13":
+		return ClassFax
+	case "16":
+		return ClassSMS
+	case "19":
+		return ClassVoice | ClassFax
+	case "20":
+		return ClassDataSync | ClassDataAsync
+	case "21", "25":
+		return ClassDataAsync
+	case "22", "24":
+		return ClassDataSync
+	default:
+		return ClassDefault
+	}
+}
+
+// ParseMMI разбирает MMI код по 3GPP TS 22.030
+func ParseMMI(code string) (*MMICode, error) {
+	raw := strings.TrimSpace(code)
+	mmi := &MMICode{Raw: raw}
+
+	if raw == "*#06#" {
+		mmi.Kind = MMIKindIMEI
+		mmi.Procedure = MMIInterrogate
+		mmi.ServiceCode = "06"
+		return mmi, nil
+	}
+
+	if !strings.HasSuffix(raw, "#") {
+		// Короткие коды из 1-2 цифр во время вызова также считаются USSD
+		if len(raw) > 0 && len(raw) <= 2 && isDigits(raw) {
+			mmi.Kind = MMIKindUSSD
+			return mmi, nil
+		}
+		return nil, ErrNotMMI
+	}
+
+	// Определяем процедуру (сначала двухсимвольные префиксы)
+	body := raw
+	for _, proc := range []MMIProcedure{MMIInterrogate, MMIRegister, MMIErase, MMIActivate, MMIDeactivate} {
+		if strings.HasPrefix(body, string(proc)) {
+			mmi.Procedure = proc
+			body = body[len(proc):]
+			break
+		}
+	}
+	body = strings.TrimSuffix(body, "#")
+
+	fields := strings.Split(body, "*")
+	mmi.ServiceCode = fields[0]
+	mmi.SI = fields[1:]
+
+	mmi.Kind = mmiKind(mmi.ServiceCode)
+	if mmi.Procedure == MMIProcedureNil || mmi.Kind == MMIKindUSSD || !isDigits(mmi.ServiceCode) {
+		// Все, что не является известной дополнительной услугой, отправляется как USSD
+		mmi.Kind = MMIKindUSSD
+		mmi.Procedure = MMIProcedureNil
+		mmi.ServiceCode = ""
+		mmi.SI = nil
+	}
+	return mmi, nil
+}
+
+// mmiKind определяет вид кода по коду услуги
+func mmiKind(sc string) MMIKind {
+	if _, ok := forwardingCodes[sc]; ok {
+		return MMIKindForwarding
+	}
+	if _, ok := barringCodes[sc]; ok {
+		return MMIKindBarring
+	}
+	switch sc {
+	case "43":
+		return MMIKindWaiting
+	case "30":
+		return MMIKindCLIP
+	case "31":
+		return MMIKindCLIR
+	case "04", "042", "05":
+		return MMIKindPIN
+	case "03":
+		return MMIKindPassword
+	default:
+		return MMIKindUSSD
+	}
+}
+
+// si возвращает поле дополнительной информации по номеру (0=SIA)
+func (c *MMICode) si(i int) string {
+	if i < len(c.SI) {
+		return c.SI[i]
+	}
+	return ""
+}
+
+// ExecuteMMI выполняет MMI код так же, как это делает телефон:
+// коды дополнительных услуг транслируются в AT команды, остальное отправляется как USSD
+func (m *Modem) ExecuteMMI(code string) (*MMIResult, error) {
+	mmi, err := ParseMMI(code)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MMIResult{Code: mmi}
+	switch mmi.Kind {
+	case MMIKindUSSD:
+		resp, err := m.SendUSSD(mmi.Raw)
+		if err != nil {
+			return nil, err
+		}
+		result.USSD = resp
+		result.Message = resp
+
+	case MMIKindIMEI:
+		imei, err := m.GetIMEI()
+		if err != nil {
+			return nil, err
+		}
+		result.IMEI = imei
+		result.Message = imei
+
+	case MMIKindForwarding:
+		err = m.executeForwardingMMI(mmi, result)
+
+	case MMIKindBarring:
+		err = m.executeBarringMMI(mmi, result)
+
+	case MMIKindWaiting:
+		err = m.executeWaitingMMI(mmi, result)
+
+	case MMIKindCLIP:
+		if mmi.Procedure != MMIInterrogate {
+			return nil, fmt.Errorf("unsupported MMI procedure for CLIP: %s", mmi.Raw)
+		}
+		result.Active, err = m.QueryCLIP()
+		result.Message = serviceMessage(result.Active)
+
+	case MMIKindCLIR:
+		if mmi.Procedure != MMIInterrogate {
+			return nil, fmt.Errorf("unsupported MMI procedure for CLIR: %s", mmi.Raw)
+		}
+		result.CLIRMode, result.CLIRStatus, err = m.GetCLIR()
+		result.Active = result.CLIRStatus == CLIRPermanent || result.CLIRStatus == CLIRTemporaryRestricted
+		result.Message = serviceMessage(result.Active)
+
+	case MMIKindPIN:
+		err = m.executePINMMI(mmi, result)
+
+	case MMIKindPassword:
+		err = m.executePasswordMMI(mmi, result)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// executeForwardingMMI выполняет коды переадресации: SIA=номер, SIB=группа услуг, SIC=время
+func (m *Modem) executeForwardingMMI(mmi *MMICode, result *MMIResult) error {
+	reason := forwardingCodes[mmi.ServiceCode]
+	class := basicServiceClass(mmi.si(1))
+	number := mmi.si(0)
+
+	switch mmi.Procedure {
+	case MMIInterrogate:
+		rules, err := m.QueryCallForwarding(reason, class)
+		if err != nil {
+			return err
+		}
+		result.Forwarding = rules
+		for _, rule := range rules {
+			if rule.Active {
+				result.Active = true
+			}
+		}
+		result.Message = serviceMessage(result.Active)
+		return nil
+
+	case MMIRegister, MMIActivate:
+		if number == "" {
+			// *21# без номера - активация ранее зарегистрированной переадресации
+			if err := m.EnableCallForwarding(reason, class, true); err != nil {
+				return err
+			}
+		} else {
+			noReplyTime := 0
+			fmt.Sscanf(mmi.si(2), "%d", &noReplyTime)
+			if err := m.SetCallForwarding(reason, class, number, noReplyTime); err != nil {
+				return err
+			}
+		}
+		result.Message = "Service was activated"
+
+	case MMIDeactivate:
+		if err := m.EnableCallForwarding(reason, class, false); err != nil {
+			return err
+		}
+		result.Message = "Service was deactivated"
+
+	case MMIErase:
+		if err := m.EraseCallForwarding(reason, class); err != nil {
+			return err
+		}
+		result.Message = "Service was erased"
+	}
+	return nil
+}
+
+// executeBarringMMI выполняет коды запрета вызовов: SIA=пароль, SIB=группа услуг
+func (m *Modem) executeBarringMMI(mmi *MMICode, result *MMIResult) error {
+	facility := barringCodes[mmi.ServiceCode]
+	class := basicServiceClass(mmi.si(1))
+
+	switch mmi.Procedure {
+	case MMIInterrogate:
+		services, err := m.QueryCallBarring(facility, class)
+		if err != nil {
+			return err
+		}
+		result.Services = services
+		result.Active = anyServiceActive(services)
+		result.Message = serviceMessage(result.Active)
+
+	case MMIActivate, MMIRegister:
+		if err := m.SetCallBarring(facility, true, mmi.si(0), class); err != nil {
+			return err
+		}
+		result.Message = "Service was activated"
+
+	case MMIDeactivate, MMIErase:
+		if err := m.SetCallBarring(facility, false, mmi.si(0), class); err != nil {
+			return err
+		}
+		result.Message = "Service was deactivated"
+	}
+	return nil
+}
+
+// executeWaitingMMI выполняет коды ожидания вызова: SIA=группа услуг
+func (m *Modem) executeWaitingMMI(mmi *MMICode, result *MMIResult) error {
+	switch mmi.Procedure {
+	case MMIInterrogate:
+		services, err := m.QueryCallWaiting(basicServiceClass(mmi.si(0)))
+		if err != nil {
+			return err
+		}
+		result.Services = services
+		result.Active = anyServiceActive(services)
+		result.Message = serviceMessage(result.Active)
+
+	case MMIActivate, MMIRegister:
+		if err := m.SetCallWaitingClass(true, basicServiceClass(mmi.si(0))); err != nil {
+			return err
+		}
+		result.Message = "Service was activated"
+
+	case MMIDeactivate, MMIErase:
+		if err := m.SetCallWaitingClass(false, basicServiceClass(mmi.si(0))); err != nil {
+			return err
+		}
+		result.Message = "Service was deactivated"
+	}
+	return nil
+}
+
+// executePINMMI выполняет **04*OLD*NEW*NEW#, **042*OLD*NEW*NEW# и **05*PUK*NEW*NEW#
+func (m *Modem) executePINMMI(mmi *MMICode, result *MMIResult) error {
+	if mmi.Procedure != MMIRegister {
+		return fmt.Errorf("unsupported MMI procedure for PIN: %s", mmi.Raw)
+	}
+	if mmi.si(1) == "" || mmi.si(1) != mmi.si(2) {
+		return fmt.Errorf("new PIN and confirmation do not match")
+	}
+
+	var err error
+	switch mmi.ServiceCode {
+	case "04":
+		err = m.ChangePassword(FacilitySIMPIN, mmi.si(0), mmi.si(1))
+	case "042":
+		err = m.ChangePassword(FacilitySIMPIN2, mmi.si(0), mmi.si(1))
+	case "05":
+		err = m.UnblockPIN(mmi.si(0), mmi.si(1))
+	}
+	if err != nil {
+		return err
+	}
+	result.Message = "PIN code changed"
+	return nil
+}
+
+// executePasswordMMI выполняет **03*SC*OLD*NEW*NEW# (смена пароля запрета вызовов)
+func (m *Modem) executePasswordMMI(mmi *MMICode, result *MMIResult) error {
+	if mmi.Procedure != MMIRegister {
+		return fmt.Errorf("unsupported MMI procedure for password: %s", mmi.Raw)
+	}
+	if mmi.si(2) == "" || mmi.si(2) != mmi.si(3) {
+		return fmt.Errorf("new password and confirmation do not match")
+	}
+
+	// Пустой SC (**03**OLD*NEW*NEW#) означает все запреты
+	facility := BarAll
+	if sc := mmi.si(0); sc != "" {
+		f, ok := barringCodes[sc]
+		if !ok {
+			return fmt.Errorf("unknown barring service code: %s", sc)
+		}
+		facility = f
+	}
+
+	if err := m.ChangePassword(facility, mmi.si(1), mmi.si(2)); err != nil {
+		return err
+	}
+	result.Message = "Password changed"
+	return nil
+}
+
+// anyServiceActive проверяет, активна ли услуга хотя бы для одного класса
+func anyServiceActive(services []ServiceStatus) bool {
+	for _, s := range services {
+		if s.Active {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceMessage возвращает текст результата запроса состояния услуги
+func serviceMessage(active bool) string {
+	if active {
+		return "Service is active"
+	}
+	return "Service is not active"
+}
+
+// isDigits проверяет, что строка состоит только из цифр
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
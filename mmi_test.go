@@ -0,0 +1,76 @@
+package gsm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseMMI(t *testing.T) {
+	tests := []struct {
+		code      string
+		kind      MMIKind
+		procedure MMIProcedure
+		sc        string
+		si        []string
+	}{
+		{"*#06#", MMIKindIMEI, MMIInterrogate, "06", nil},
+		{"**21*+79991234567#", MMIKindForwarding, MMIRegister, "21", []string{"+79991234567"}},
+		{"**61*+79991234567**20#", MMIKindForwarding, MMIRegister, "61", []string{"+79991234567", "", "20"}},
+		{"*#21#", MMIKindForwarding, MMIInterrogate, "21", []string{}},
+		{"##002#", MMIKindForwarding, MMIErase, "002", []string{}},
+		{"*33*0000#", MMIKindBarring, MMIActivate, "33", []string{"0000"}},
+		{"#331*0000*11#", MMIKindBarring, MMIDeactivate, "331", []string{"0000", "11"}},
+		{"*43*11#", MMIKindWaiting, MMIActivate, "43", []string{"11"}},
+		{"*#43#", MMIKindWaiting, MMIInterrogate, "43", []string{}},
+		{"*#30#", MMIKindCLIP, MMIInterrogate, "30", []string{}},
+		{"#31#", MMIKindCLIR, MMIDeactivate, "31", []string{}},
+		{"**04*1111*2222*2222#", MMIKindPIN, MMIRegister, "04", []string{"1111", "2222", "2222"}},
+		{"**05*12345678*1111*1111#", MMIKindPIN, MMIRegister, "05", []string{"12345678", "1111", "1111"}},
+		{"**03*330*0000*1234*1234#", MMIKindPassword, MMIRegister, "03", []string{"330", "0000", "1234", "1234"}},
+		// USSD: неизвестный код услуги, код без процедуры и короткие коды
+		{"*100#", MMIKindUSSD, MMIProcedureNil, "", nil},
+		{" *102*1# ", MMIKindUSSD, MMIProcedureNil, "", nil},
+		{"#100#", MMIKindUSSD, MMIProcedureNil, "", nil},
+		{"*#*1#", MMIKindUSSD, MMIProcedureNil, "", nil},
+		{"1", MMIKindUSSD, MMIProcedureNil, "", nil},
+		{"12", MMIKindUSSD, MMIProcedureNil, "", nil},
+	}
+	for _, tt := range tests {
+		mmi, err := ParseMMI(tt.code)
+		if err != nil {
+			t.Errorf("ParseMMI(%q): %v", tt.code, err)
+			continue
+		}
+		if mmi.Kind != tt.kind || mmi.Procedure != tt.procedure || mmi.ServiceCode != tt.sc || !reflect.DeepEqual(mmi.SI, tt.si) {
+			t.Errorf("ParseMMI(%q) = kind %q procedure %q SC %q SI %q, want %q %q %q %q",
+				tt.code, mmi.Kind, mmi.Procedure, mmi.ServiceCode, mmi.SI, tt.kind, tt.procedure, tt.sc, tt.si)
+		}
+	}
+}
+
+func TestParseMMINotMMI(t *testing.T) {
+	for _, code := range []string{"", "123", "+79991234567", "89991234567", "*100", "ab"} {
+		if mmi, err := ParseMMI(code); !errors.Is(err, ErrNotMMI) {
+			t.Errorf("ParseMMI(%q) = %+v, %v, want ErrNotMMI", code, mmi, err)
+		}
+	}
+}
+
+func TestBasicServiceClass(t *testing.T) {
+	tests := map[string]ServiceClass{
+		"":   ClassDefault,
+		"11": ClassVoice,
+		"13": ClassFax,
+		"16": ClassSMS,
+		"10": ClassVoice | ClassFax | ClassSMS,
+		"20": ClassDataSync | ClassDataAsync,
+		"25": ClassDataAsync,
+		"99": ClassDefault,
+	}
+	for bs, want := range tests {
+		if got := basicServiceClass(bs); got != want {
+			t.Errorf("basicServiceClass(%q) = %d, want %d", bs, got, want)
+		}
+	}
+}
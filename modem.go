@@ -11,6 +11,15 @@ import (
 	"github.com/tarm/serial"
 )
 
+// ErrCommandFailed возвращается, если модем ответил ERROR, +CME ERROR или +CMS ERROR
+var ErrCommandFailed = errors.New("command failed")
+
+// Ограничения склейки многострочного +CUSD: при превышении строка обрабатывается как есть
+const (
+	maxPendingLines = 16
+	maxPendingBytes = 4096
+)
+
 // Modem представляет GSM модем
 type Modem struct {
 	port          *serial.Port
@@ -19,6 +28,24 @@ type Modem struct {
 	eventChan     chan Event
 	stopEventsCh  chan struct{}
 	eventsEnabled bool
+	dataMode      bool       // Порт занят PPP соединением, защищено mu
+	optMu         sync.Mutex // Защищает настройки, которые читает обработчик событий
+	vendor        Vendor
+	charset       string
+	ussdPacked    bool
+	calls         *callTracker
+	dtmf          *dtmfCollector
+	ussd          *ussdRouter
+	signal        *signalMonitor
+	connectivity  *ConnectivityMonitor // Защищено optMu
+	operatorNames map[string]string    // Имена операторов из AT+COPN, защищено optMu
+	sockets       *socketManager       // Сокеты встроенного TCP/IP стека
+	usage         *usageTracker        // Предыдущие значения счетчиков трафика
+	urcs          urcWaiters           // Ожидаемые URC асинхронных команд
+	pendingLine   string               // Незаконченный многострочный +CUSD
+	pendingCount  int                  // Количество строк, присоединенных к pendingLine
+	reader        *portReader          // Читатель порта при запущенном обработчике событий, защищено mu
+	unread        []byte               // Прочитанные, но еще не разобранные данные порта, защищено mu
 }
 
 // ModemInfo содержит информацию о модеме
@@ -170,6 +197,12 @@ func New(port string, baudRate int) (*Modem, error) {
 		stopEventsCh:  make(chan struct{}),
 		eventsEnabled: false,
 	}
+	m.calls = newCallTracker(m.emitEvent)
+	m.dtmf = &dtmfCollector{}
+	m.ussd = &ussdRouter{}
+	m.signal = newSignalMonitor()
+	m.sockets = newSocketManager()
+	m.usage = newUsageTracker()
 
 	// Инициализация модема
 	if err := m.initialize(); err != nil {
@@ -177,6 +210,14 @@ func New(port string, baudRate int) (*Modem, error) {
 		return nil, fmt.Errorf("failed to initialize modem: %w", err)
 	}
 
+	// Определяем производителя для vendor-специфичных команд
+	if manufacturer, err := m.GetManufacturer(); err == nil {
+		m.vendor = DetectVendor(manufacturer)
+		// Huawei принимает и возвращает USSD в виде упакованного 7-битного hex
+		m.ussdPacked = m.vendor == VendorHuawei
+		m.detectUSSDMode()
+	}
+
 	return m, nil
 }
 
@@ -215,6 +256,11 @@ func (m *Modem) Close() error {
 		close(m.stopEventsCh)
 		m.eventsEnabled = false
 	}
+	if m.reader != nil {
+		// Чтение прервется закрытием порта
+		close(m.reader.stop)
+		m.reader = nil
+	}
 
 	if m.port != nil {
 		return m.port.Close()
@@ -236,10 +282,37 @@ func (m *Modem) SendCommand(cmd string, timeout time.Duration) (string, error) {
 	return response, err
 }
 
+// execCommand отправляет AT команду и возвращает ошибку, если модем ответил ERROR
+func (m *Modem) execCommand(cmd string, timeout time.Duration) (string, error) {
+	response, err := m.SendCommand(cmd, timeout)
+	if err != nil {
+		return response, err
+	}
+	if line := findErrorLine(response); line != "" {
+		return response, fmt.Errorf("%w: %s", ErrCommandFailed, line)
+	}
+	return response, nil
+}
+
+// findErrorLine возвращает строку с ошибкой из ответа модема или пустую строку
+func findErrorLine(response string) string {
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "ERROR" || strings.HasPrefix(line, "+CME ERROR") || strings.HasPrefix(line, "+CMS ERROR") {
+			return line
+		}
+	}
+	return ""
+}
+
 // sendCommand внутренний метод для отправки команд (без блокировки)
 func (m *Modem) sendCommand(cmd string, timeout time.Duration) (string, error) {
+	if m.dataMode {
+		return "", ErrDataMode
+	}
+
 	// Очищаем буфер перед отправкой
-	m.port.Flush()
+	m.flushPort()
 
 	// Отправляем команду
 	_, err := m.port.Write([]byte(cmd + "\r\n"))
@@ -259,7 +332,7 @@ func (m *Modem) readResponse(timeout time.Duration) (string, error) {
 
 	for time.Since(startTime) < timeout {
 		// Читаем доступные данные
-		n, err := m.port.Read(buf)
+		n, err := m.readPort(buf)
 		if err != nil {
 			// Если уже что-то прочитали и есть финальный ответ, возвращаем
 			if response.Len() > 0 {
@@ -297,6 +370,128 @@ func (m *Modem) readResponse(timeout time.Duration) (string, error) {
 	return response.String(), nil
 }
 
+// waitForURC читает порт, пока не придет незапрошенный ответ с префиксом
+// (используется, когда обработчик событий не запущен)
+func (m *Modem) waitForURC(prefix string, timeout time.Duration) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var response strings.Builder
+	buf := make([]byte, 1024)
+	startTime := time.Now()
+
+	for time.Since(startTime) < timeout {
+		n, err := m.readPort(buf)
+		if err != nil || n == 0 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+
+		response.Write(buf[:n])
+		responseStr := response.String()
+		if idx := strings.Index(responseStr, prefix); idx != -1 {
+			if urc, ok := completeURC(responseStr[idx:]); ok {
+				debugResponse("URC", urc)
+				return urc, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("timeout waiting for %s", prefix)
+}
+
+// awaitURC ждет результат асинхронной команды в канале ch: через обработчик событий, если он
+// запущен, иначе читая порт напрямую и передавая найденные URC с префиксом в handle
+func (m *Modem) awaitURC(ch <-chan string, prefix string, timeout time.Duration, handle func(line string) bool) (string, error) {
+	select {
+	case result := <-ch:
+		return result, nil
+	default:
+	}
+
+	if m.IsEventListenerRunning() {
+		select {
+		case result := <-ch:
+			return result, nil
+		case <-time.After(timeout):
+			return "", fmt.Errorf("timeout waiting for %s", prefix)
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		urc, err := m.waitForURC(prefix, time.Until(deadline))
+		if err != nil {
+			return "", err
+		}
+		handle(strings.TrimSpace(urc))
+		select {
+		case result := <-ch:
+			return result, nil
+		default:
+		}
+	}
+	return "", fmt.Errorf("timeout waiting for %s", prefix)
+}
+
+// urcWaiters ожидание URC с результатом асинхронной команды (+QHTTPGET, +HTTPACTION и т.д.)
+type urcWaiters struct {
+	mu      sync.Mutex
+	waiters map[string]chan string
+}
+
+// expect подготавливает ожидание строки с префиксом
+func (w *urcWaiters) expect(prefix string) chan string {
+	ch := make(chan string, 1)
+	w.mu.Lock()
+	if w.waiters == nil {
+		w.waiters = make(map[string]chan string)
+	}
+	w.waiters[prefix] = ch
+	w.mu.Unlock()
+	return ch
+}
+
+// deliver передает строку ожидающему, возвращает true если она была нужна
+func (w *urcWaiters) deliver(line string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for prefix, ch := range w.waiters {
+		if strings.HasPrefix(line, prefix) {
+			delete(w.waiters, prefix)
+			ch <- line
+			return true
+		}
+	}
+	return false
+}
+
+// scan передает ожидающим URC, попавшие в ответ на команду
+func (w *urcWaiters) scan(response string) {
+	for _, line := range strings.Split(response, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			w.deliver(line)
+		}
+	}
+}
+
+// completeURC возвращает URC целиком, если встретился конец строки вне кавычек
+func completeURC(data string) (string, bool) {
+	inQuotes := false
+	for i, c := range data {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case (c == '\r' || c == '\n') && !inQuotes:
+			return data[:i], true
+		case (c == '\r' || c == '\n') && strings.HasPrefix(data, "+CUSD:") && cusdComplete(data[:i]):
+			// Неэкранированная кавычка в тексте USSD: строка закончилась на ",<dcs>"
+			return data[:i], true
+		}
+	}
+	return "", false
+}
+
 // extractResponse извлекает чистый ответ из AT команды
 func extractResponse(response string) string {
 	lines := strings.Split(response, "\n")
@@ -0,0 +1,584 @@
+package gsm
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// networkModeTimeout - смена режима и диапазонов может занимать несколько секунд (модем перерегистрируется)
+const networkModeTimeout = 15 * time.Second
+
+// NetworkMode предпочтительный режим сети
+type NetworkMode int
+
+const (
+	NetworkModeAuto   NetworkMode = iota // Автоматический выбор
+	NetworkMode2GOnly                    // Только GSM
+	NetworkMode3GOnly                    // Только UMTS
+	NetworkMode4GOnly                    // Только LTE
+)
+
+// String возвращает название режима
+func (n NetworkMode) String() string {
+	switch n {
+	case NetworkModeAuto:
+		return "auto"
+	case NetworkMode2GOnly:
+		return "2G only"
+	case NetworkMode3GOnly:
+		return "3G only"
+	case NetworkMode4GOnly:
+		return "4G only"
+	default:
+		return fmt.Sprintf("unknown (%d)", int(n))
+	}
+}
+
+// Band частотный диапазон
+type Band struct {
+	Tech   AccessTechnology // AccessTechGSM, AccessTechUMTS, AccessTechLTE, AccessTechLTECatM или AccessTechNBIoT
+	Number int              // Номер диапазона (1, 3, 20...), для GSM - частота в МГц (850, 900, 1800, 1900)
+}
+
+// String возвращает название диапазона ("GSM 900", "LTE B20")
+func (b Band) String() string {
+	if b.Tech == AccessTechGSM {
+		return fmt.Sprintf("GSM %d", b.Number)
+	}
+	return fmt.Sprintf("%s B%d", b.Tech, b.Number)
+}
+
+// bandBit соответствие диапазона GSM/UMTS биту маски модема
+type bandBit struct {
+	band Band
+	mask uint64
+}
+
+// huaweiBandBits маска <band> команды AT^SYSCFGEX
+var huaweiBandBits = []bandBit{
+	{Band{AccessTechGSM, 850}, 0x80000},
+	{Band{AccessTechGSM, 900}, 0x300}, // Extended и Primary GSM900
+	{Band{AccessTechGSM, 1800}, 0x80},
+	{Band{AccessTechGSM, 1900}, 0x200000},
+	{Band{AccessTechUMTS, 1}, 0x400000},
+	{Band{AccessTechUMTS, 2}, 0x800000},
+	{Band{AccessTechUMTS, 5}, 0x4000000},
+	{Band{AccessTechUMTS, 8}, 0x2000000000000},
+}
+
+// simcomBandBits маска <mode> команды AT+CNBP (раскладка та же, что у AT^SYSCFGEX)
+var simcomBandBits = []bandBit{
+	{Band{AccessTechGSM, 850}, 0x80000},
+	{Band{AccessTechGSM, 900}, 0x300},
+	{Band{AccessTechGSM, 1800}, 0x80},
+	{Band{AccessTechGSM, 1900}, 0x200000},
+	{Band{AccessTechUMTS, 1}, 0x400000},
+	{Band{AccessTechUMTS, 2}, 0x800000},
+	{Band{AccessTechUMTS, 4}, 0x2000000},
+	{Band{AccessTechUMTS, 5}, 0x4000000},
+	{Band{AccessTechUMTS, 6}, 0x8000000},
+	{Band{AccessTechUMTS, 8}, 0x2000000000000},
+}
+
+// quectelBandBits маска <bandval> команды AT+QCFG="band"
+var quectelBandBits = []bandBit{
+	{Band{AccessTechGSM, 900}, 0x1},
+	{Band{AccessTechGSM, 1800}, 0x2},
+	{Band{AccessTechGSM, 850}, 0x4},
+	{Band{AccessTechGSM, 1900}, 0x8},
+	{Band{AccessTechUMTS, 1}, 0x10},
+	{Band{AccessTechUMTS, 2}, 0x20},
+	{Band{AccessTechUMTS, 5}, 0x40},
+	{Band{AccessTechUMTS, 8}, 0x80},
+	{Band{AccessTechUMTS, 6}, 0x100},
+	{Band{AccessTechUMTS, 4}, 0x200},
+}
+
+// SetNetworkModePreference ограничивает модем одной технологией (или возвращает автоматический выбор).
+// Используется AT^SYSCFGEX (Huawei), AT+QCFG="nwscanmode" (Quectel), AT+CNMP (SIMCom) или AT+URAT (u-blox).
+func (m *Modem) SetNetworkModePreference(mode NetworkMode) error {
+	var err error
+	switch m.Vendor() {
+	case VendorHuawei:
+		err = m.huaweiSetMode(mode)
+	case VendorQuectel:
+		err = m.setModeValue(mode, map[NetworkMode]string{
+			NetworkModeAuto: "0", NetworkMode2GOnly: "1", NetworkMode3GOnly: "2", NetworkMode4GOnly: "3",
+		}, `AT+QCFG="nwscanmode",%s,1`)
+	case VendorSIMCom:
+		err = m.setModeValue(mode, map[NetworkMode]string{
+			NetworkModeAuto: "2", NetworkMode2GOnly: "13", NetworkMode3GOnly: "14", NetworkMode4GOnly: "38",
+		}, "AT+CNMP=%s")
+	case VendorUblox:
+		err = m.ubloxSetMode(mode)
+	default:
+		err = ErrNotSupported
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set network mode %s: %w", mode, err)
+	}
+	return nil
+}
+
+// GetNetworkModePreference возвращает текущий режим сети. Комбинации технологий
+// (например, GSM+LTE) и приоритеты сообщаются как NetworkModeAuto.
+func (m *Modem) GetNetworkModePreference() (NetworkMode, error) {
+	var cmd, prefix string
+	var modes map[string]NetworkMode
+	switch m.Vendor() {
+	case VendorHuawei:
+		cmd, prefix = "AT^SYSCFGEX?", "^SYSCFGEX:"
+		modes = map[string]NetworkMode{"01": NetworkMode2GOnly, "02": NetworkMode3GOnly, "03": NetworkMode4GOnly}
+	case VendorQuectel:
+		cmd, prefix = `AT+QCFG="nwscanmode"`, "+QCFG:"
+		modes = map[string]NetworkMode{"1": NetworkMode2GOnly, "2": NetworkMode3GOnly, "3": NetworkMode4GOnly}
+	case VendorSIMCom:
+		cmd, prefix = "AT+CNMP?", "+CNMP:"
+		modes = map[string]NetworkMode{"13": NetworkMode2GOnly, "14": NetworkMode3GOnly, "38": NetworkMode4GOnly}
+	case VendorUblox:
+		cmd, prefix = "AT+URAT?", "+URAT:"
+		modes = map[string]NetworkMode{"0": NetworkMode2GOnly, "2": NetworkMode3GOnly, "3": NetworkMode4GOnly, "7": NetworkMode4GOnly}
+	default:
+		return NetworkModeAuto, fmt.Errorf("failed to get network mode: %w", ErrNotSupported)
+	}
+
+	resp, err := m.execCommand(cmd, networkModeTimeout)
+	if err != nil {
+		return NetworkModeAuto, fmt.Errorf("failed to get network mode: %w", err)
+	}
+	fields := parseInfoLines(resp, prefix)
+	if len(fields) == 0 {
+		return NetworkModeAuto, fmt.Errorf("failed to get network mode: %w: %s", ErrNoResponse, prefix)
+	}
+	value := fieldAt(fields[0], 0)
+	if m.Vendor() == VendorQuectel {
+		// +QCFG: "nwscanmode",<mode>
+		value = fieldAt(fields[0], 1)
+	}
+	if mode, ok := modes[value.Value]; ok {
+		return mode, nil
+	}
+	return NetworkModeAuto, nil
+}
+
+// setModeValue выполняет команду с vendor-значением режима
+func (m *Modem) setModeValue(mode NetworkMode, values map[NetworkMode]string, format string) error {
+	value, ok := values[mode]
+	if !ok {
+		return ErrNotSupported
+	}
+	_, err := m.execCommand(fmt.Sprintf(format, value), networkModeTimeout)
+	return err
+}
+
+// huaweiSetMode задает порядок поиска сетей AT^SYSCFGEX, для модемов без LTE - AT^SYSCFG
+func (m *Modem) huaweiSetMode(mode NetworkMode) error {
+	// Диапазоны, роуминг и домен услуг не меняются (40000000, 2, 4)
+	err := m.setModeValue(mode, map[NetworkMode]string{
+		NetworkModeAuto: "00", NetworkMode2GOnly: "01", NetworkMode3GOnly: "02", NetworkMode4GOnly: "03",
+	}, `AT^SYSCFGEX="%s",40000000,2,4,40000000,,`)
+	if err == nil || mode == NetworkMode4GOnly {
+		return err
+	}
+	// E173, E1550 и другие 3G-модемы поддерживают только AT^SYSCFG=<mode>,<order>,<band>,<roam>,<srvdomain>
+	if legacy := m.setModeValue(mode, map[NetworkMode]string{
+		NetworkModeAuto: "2", NetworkMode2GOnly: "13", NetworkMode3GOnly: "14",
+	}, "AT^SYSCFG=%s,0,40000000,2,4"); legacy == nil {
+		return nil
+	}
+	return err
+}
+
+// ubloxSetMode задает AT+URAT: сначала значение для модулей с LTE, затем для SARA-U2 (без LTE)
+// и SARA-R4 (LTE Cat-M1)
+func (m *Modem) ubloxSetMode(mode NetworkMode) error {
+	values := map[NetworkMode][]string{
+		NetworkModeAuto:   {"4", "1"},
+		NetworkMode2GOnly: {"0", "9"},
+		NetworkMode3GOnly: {"2"},
+		NetworkMode4GOnly: {"3", "7"},
+	}[mode]
+	if len(values) == 0 {
+		return ErrNotSupported
+	}
+
+	var err error
+	for _, value := range values {
+		if _, err = m.execCommand("AT+URAT="+value, networkModeTimeout); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// SetBandLock разрешает модему работать только в указанных диапазонах. Диапазоны технологий,
+// которые не перечислены, не меняются; вызов без аргументов снимает ограничение.
+// Используется AT^SYSCFGEX (Huawei), AT+QCFG="band" (Quectel), AT+CBANDCFG (SIMCom Cat-M/NB-IoT)
+// или AT+CNBP (SIMCom SIM7500/SIM7600).
+func (m *Modem) SetBandLock(bands ...Band) error {
+	var err error
+	switch m.Vendor() {
+	case VendorHuawei:
+		err = m.huaweiSetBands(bands)
+	case VendorQuectel:
+		err = m.quectelSetBands(bands)
+	case VendorSIMCom:
+		err = m.simcomSetBands(bands)
+	default:
+		err = ErrNotSupported
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set band lock: %w", err)
+	}
+	return nil
+}
+
+// GetBands возвращает разрешенные диапазоны. Без ограничения модем сообщает маску
+// "любой диапазон", поэтому в список попадают все диапазоны, известные библиотеке.
+func (m *Modem) GetBands() ([]Band, error) {
+	var bands []Band
+	var err error
+	switch m.Vendor() {
+	case VendorHuawei:
+		bands, err = m.huaweiBands()
+	case VendorQuectel:
+		bands, err = m.quectelBands()
+	case VendorSIMCom:
+		bands, err = m.simcomBands()
+	default:
+		err = ErrNotSupported
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bands: %w", err)
+	}
+	return bands, nil
+}
+
+// splitBands разделяет диапазоны на GSM/UMTS, LTE (включая Cat-M) и NB-IoT
+func splitBands(bands []Band) (legacy, lte, nbiot []Band, err error) {
+	for _, band := range bands {
+		switch band.Tech {
+		case AccessTechGSM, AccessTechUMTS:
+			legacy = append(legacy, band)
+		case AccessTechLTE, AccessTechLTECatM:
+			lte = append(lte, band)
+		case AccessTechNBIoT:
+			nbiot = append(nbiot, band)
+		default:
+			return nil, nil, nil, fmt.Errorf("%w: band %s", ErrNotSupported, band)
+		}
+	}
+	return legacy, lte, nbiot, nil
+}
+
+// encodeBandBits собирает маску GSM/UMTS диапазонов по таблице модема
+func encodeBandBits(bands []Band, table []bandBit) (uint64, error) {
+	var mask uint64
+	for _, band := range bands {
+		found := false
+		for _, bit := range table {
+			if bit.band == band {
+				mask |= bit.mask
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("%w: band %s", ErrNotSupported, band)
+		}
+	}
+	return mask, nil
+}
+
+// decodeBandBits возвращает диапазоны GSM/UMTS, биты которых установлены в маске
+func decodeBandBits(mask uint64, table []bandBit) []Band {
+	var bands []Band
+	for _, bit := range table {
+		if mask&bit.mask != 0 {
+			bands = append(bands, bit.band)
+		}
+	}
+	return bands
+}
+
+// encodeLTEMask собирает маску LTE: диапазон N - бит N-1
+func encodeLTEMask(bands []Band) (*big.Int, error) {
+	mask := new(big.Int)
+	for _, band := range bands {
+		if band.Number < 1 || band.Number > 256 {
+			return nil, fmt.Errorf("invalid band %s", band)
+		}
+		mask.SetBit(mask, band.Number-1, 1)
+	}
+	return mask, nil
+}
+
+// decodeLTEMask разбирает шестнадцатеричную маску LTE ("0x80084", "7FFFFFFFFFFFFFFF")
+func decodeLTEMask(value string, tech AccessTechnology) []Band {
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X")
+	mask, ok := new(big.Int).SetString(value, 16)
+	if !ok {
+		return nil
+	}
+	var bands []Band
+	for i := 0; i < mask.BitLen(); i++ {
+		if mask.Bit(i) == 1 {
+			bands = append(bands, Band{tech, i + 1})
+		}
+	}
+	return bands
+}
+
+// parseBandBits разбирает шестнадцатеричную маску GSM/UMTS
+func parseBandBits(f Field) uint64 {
+	value := strings.TrimPrefix(strings.TrimPrefix(f.Value, "0x"), "0X")
+	mask, _ := strconv.ParseUint(value, 16, 64)
+	return mask
+}
+
+// huaweiSetBands задает <band> и <lteband> команды AT^SYSCFGEX (40000000 - без изменений)
+func (m *Modem) huaweiSetBands(bands []Band) error {
+	legacy, lte, nbiot, err := splitBands(bands)
+	if err != nil {
+		return err
+	}
+	lte = append(lte, nbiot...)
+
+	band, lteBand := "40000000", "40000000"
+	if len(bands) == 0 {
+		band, lteBand = "3FFFFFFF", "7FFFFFFFFFFFFFFF"
+	}
+	if len(legacy) > 0 {
+		mask, err := encodeBandBits(legacy, huaweiBandBits)
+		if err != nil {
+			return err
+		}
+		band = fmt.Sprintf("%X", mask)
+	}
+	if len(lte) > 0 {
+		mask, err := encodeLTEMask(lte)
+		if err != nil {
+			return err
+		}
+		lteBand = fmt.Sprintf("%X", mask)
+	}
+
+	// "99" - порядок поиска сетей не меняется
+	_, err = m.execCommand(fmt.Sprintf(`AT^SYSCFGEX="99",%s,2,4,%s,,`, band, lteBand), networkModeTimeout)
+	return err
+}
+
+// huaweiBands разбирает ^SYSCFGEX: <acqorder>,<band>,<roam>,<srvdomain>,<lteband>
+func (m *Modem) huaweiBands() ([]Band, error) {
+	resp, err := m.execCommand("AT^SYSCFGEX?", networkModeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range parseInfoLines(resp, "^SYSCFGEX:") {
+		bands := decodeBandBits(parseBandBits(fieldAt(fields, 1)), huaweiBandBits)
+		return append(bands, decodeLTEMask(fieldAt(fields, 4).Value, AccessTechLTE)...), nil
+	}
+	return nil, fmt.Errorf("%w: ^SYSCFGEX:", ErrNoResponse)
+}
+
+// quectelSetBands задает AT+QCFG="band",<bandval>,<ltebandval>,<nbbandval>,1 (0 - без изменений).
+// Для BG95/BG96 второе значение - диапазоны Cat-M1, третье - NB-IoT.
+func (m *Modem) quectelSetBands(bands []Band) error {
+	legacy, lte, nbiot, err := splitBands(bands)
+	if err != nil {
+		return err
+	}
+
+	band, lteBand, nbBand := "0", "0", "0"
+	if len(bands) == 0 {
+		band, lteBand = "0xFFFF", "0x7FFFFFFFFFFFFFFF"
+	}
+	if len(legacy) > 0 {
+		mask, err := encodeBandBits(legacy, quectelBandBits)
+		if err != nil {
+			return err
+		}
+		band = fmt.Sprintf("0x%x", mask)
+	}
+	if len(lte) > 0 {
+		mask, err := encodeLTEMask(lte)
+		if err != nil {
+			return err
+		}
+		lteBand = fmt.Sprintf("0x%x", mask)
+	}
+	if len(nbiot) > 0 {
+		mask, err := encodeLTEMask(nbiot)
+		if err != nil {
+			return err
+		}
+		nbBand = fmt.Sprintf("0x%x", mask)
+	}
+
+	_, err = m.execCommand(fmt.Sprintf(`AT+QCFG="band",%s,%s,%s,1`, band, lteBand, nbBand), networkModeTimeout)
+	return err
+}
+
+// quectelBands разбирает +QCFG: "band",<bandval>,<ltebandval>,<nbbandval>
+func (m *Modem) quectelBands() ([]Band, error) {
+	resp, err := m.execCommand(`AT+QCFG="band"`, networkModeTimeout)
+	if err != nil {
+		return nil, err
+	}
+	for _, fields := range parseInfoLines(resp, "+QCFG:") {
+		if !strings.EqualFold(fieldAt(fields, 0).Value, "band") {
+			continue
+		}
+		bands := decodeBandBits(parseBandBits(fieldAt(fields, 1)), quectelBandBits)
+		bands = append(bands, decodeLTEMask(fieldAt(fields, 2).Value, AccessTechLTE)...)
+		return append(bands, decodeLTEMask(fieldAt(fields, 3).Value, AccessTechNBIoT)...), nil
+	}
+	return nil, fmt.Errorf("%w: +QCFG:", ErrNoResponse)
+}
+
+// simcomBandModes соответствие технологии режиму AT+CBANDCFG
+var simcomBandModes = map[AccessTechnology]string{
+	AccessTechLTECatM: "CAT-M",
+	AccessTechNBIoT:   "NB-IOT",
+}
+
+// simcomSetBands задает диапазоны командой AT+CBANDCFG (SIM7000/SIM7070/SIM7080), а если модем
+// ее не поддерживает - маской AT+CNBP (SIM7500/SIM7600)
+func (m *Modem) simcomSetBands(bands []Band) error {
+	resp, err := m.execCommand("AT+CBANDCFG=?", networkModeTimeout)
+	if err != nil {
+		return m.simcomSetCNBP(bands)
+	}
+	return m.simcomSetCBANDCFG(bands, resp)
+}
+
+// simcomSetCBANDCFG задает AT+CBANDCFG="<mode>",<band>[,<band>...]. Режимы есть только для
+// Cat-M и NB-IoT, поэтому диапазоны LTE и GSM/UMTS не поддерживаются.
+func (m *Modem) simcomSetCBANDCFG(bands []Band, supportedResp string) error {
+	numbers := map[string][]int{}
+	for _, band := range bands {
+		mode, ok := simcomBandModes[band.Tech]
+		if !ok {
+			return fmt.Errorf("%w: band %s (AT+CBANDCFG supports only Cat-M and NB-IoT)", ErrNotSupported, band)
+		}
+		numbers[mode] = append(numbers[mode], band.Number)
+	}
+	if len(bands) == 0 {
+		// Снятие ограничения - все диапазоны из AT+CBANDCFG=?: (CAT-M,NB-IOT),(1,2,3,...)
+		for _, fields := range parseInfoLines(supportedResp, "+CBANDCFG:") {
+			var supported []int
+			for _, f := range fieldAt(fields, 1).List {
+				if n, err := f.Int(); err == nil {
+					supported = append(supported, n)
+				}
+			}
+			for _, mode := range fieldAt(fields, 0).List {
+				numbers[strings.ToUpper(mode.Value)] = supported
+			}
+		}
+	}
+
+	modes := make([]string, 0, len(numbers))
+	for mode := range numbers {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	for _, mode := range modes {
+		list := make([]string, len(numbers[mode]))
+		for i, n := range numbers[mode] {
+			list[i] = strconv.Itoa(n)
+		}
+		cmd := fmt.Sprintf(`AT+CBANDCFG="%s",%s`, mode, strings.Join(list, ","))
+		if _, err := m.execCommand(cmd, networkModeTimeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simcomSetCNBP задает AT+CNBP=<mode>,<lte_mode>. Команда меняет обе маски сразу, поэтому маска
+// технологии без диапазонов в списке берется из текущих настроек.
+func (m *Modem) simcomSetCNBP(bands []Band) error {
+	legacy, lte, nbiot, err := splitBands(bands)
+	if err != nil {
+		return err
+	}
+	if len(nbiot) > 0 {
+		return fmt.Errorf("%w: NB-IoT band lock", ErrNotSupported)
+	}
+	for _, band := range lte {
+		if band.Tech == AccessTechLTECatM {
+			return fmt.Errorf("%w: band %s", ErrNotSupported, band)
+		}
+	}
+
+	band, lteBand := "0xFFFFFFFF7FFFFFFF", "0x7FFFFFFFFFFFFFFF"
+	if len(bands) > 0 {
+		if band, lteBand, err = m.simcomCNBP(); err != nil {
+			return err
+		}
+	}
+	if len(legacy) > 0 {
+		mask, err := encodeBandBits(legacy, simcomBandBits)
+		if err != nil {
+			return err
+		}
+		band = fmt.Sprintf("0x%016X", mask)
+	}
+	if len(lte) > 0 {
+		mask, err := encodeLTEMask(lte)
+		if err != nil {
+			return err
+		}
+		lteBand = fmt.Sprintf("0x%016X", mask)
+	}
+
+	_, err = m.execCommand(fmt.Sprintf("AT+CNBP=%s,%s", band, lteBand), networkModeTimeout)
+	return err
+}
+
+// simcomCNBP возвращает текущие маски +CNBP: <mode>,<lte_mode>[,<tds_mode>]
+func (m *Modem) simcomCNBP() (band, lteBand string, err error) {
+	resp, err := m.execCommand("AT+CNBP?", networkModeTimeout)
+	if err != nil {
+		return "", "", err
+	}
+	for _, fields := range parseInfoLines(resp, "+CNBP:") {
+		return fieldAt(fields, 0).Value, fieldAt(fields, 1).Value, nil
+	}
+	return "", "", fmt.Errorf("%w: +CNBP:", ErrNoResponse)
+}
+
+// simcomBands разбирает строки +CBANDCFG: "<mode>",<band>[,<band>...], а без AT+CBANDCFG - маски AT+CNBP
+func (m *Modem) simcomBands() ([]Band, error) {
+	resp, err := m.execCommand("AT+CBANDCFG?", networkModeTimeout)
+	if err != nil {
+		band, lteBand, err := m.simcomCNBP()
+		if err != nil {
+			return nil, err
+		}
+		bands := decodeBandBits(parseBandBits(Field{Value: band}), simcomBandBits)
+		return append(bands, decodeLTEMask(lteBand, AccessTechLTE)...), nil
+	}
+	var bands []Band
+	for _, fields := range parseInfoLines(resp, "+CBANDCFG:") {
+		tech := AccessTechUnknown
+		for t, mode := range simcomBandModes {
+			if strings.EqualFold(fieldAt(fields, 0).Value, mode) {
+				tech = t
+			}
+		}
+		if tech == AccessTechUnknown {
+			continue
+		}
+		for _, f := range fields[1:] {
+			if n, err := f.Int(); err == nil {
+				bands = append(bands, Band{tech, n})
+			}
+		}
+	}
+	return bands, nil
+}
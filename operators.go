@@ -0,0 +1,178 @@
+package gsm
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed data/mcc.csv
+var mccTable string
+
+//go:embed data/mnc.csv
+var mncTable string
+
+// Country страна по коду MCC
+type Country struct {
+	Name string // Название страны (на английском)
+	ISO  string // Код ISO 3166-1 alpha-2 в нижнем регистре ("ru")
+}
+
+// PLMN запись справочника операторов
+type PLMN struct {
+	MCC      string // Mobile Country Code
+	MNC      string // Mobile Network Code (2 или 3 цифры)
+	Country  string // Название страны
+	ISO      string // Код страны ISO 3166-1 alpha-2
+	Brand    string // Торговая марка ("MegaFon")
+	Operator string // Юридическое название оператора
+}
+
+// Numeric возвращает числовой код MCC+MNC
+func (p PLMN) Numeric() string {
+	return p.MCC + p.MNC
+}
+
+// operatorDB справочник стран и операторов, загружается при первом обращении
+var operatorDB struct {
+	once      sync.Once
+	countries map[string]Country // MCC -> страна
+	operators map[string]PLMN    // MCC+MNC -> оператор
+}
+
+// loadOperatorDB разбирает встроенные таблицы
+func loadOperatorDB() {
+	operatorDB.countries = make(map[string]Country)
+	operatorDB.operators = make(map[string]PLMN)
+
+	for _, row := range readTable(mccTable) {
+		operatorDB.countries[row[0]] = Country{Name: row[2], ISO: row[1]}
+	}
+	for _, row := range readTable(mncTable) {
+		country := operatorDB.countries[row[0]]
+		operatorDB.operators[row[0]+row[1]] = PLMN{
+			MCC:      row[0],
+			MNC:      row[1],
+			Country:  country.Name,
+			ISO:      country.ISO,
+			Brand:    row[2],
+			Operator: row[3],
+		}
+	}
+}
+
+// readTable возвращает строки встроенного CSV без заголовка
+func readTable(data string) [][]string {
+	records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+	if err != nil || len(records) == 0 {
+		panic(fmt.Sprintf("gsm: invalid embedded operator table: %v", err))
+	}
+	return records[1:]
+}
+
+// LookupCountry возвращает страну по MCC (или по числовому коду оператора MCC+MNC)
+func LookupCountry(mcc string) (Country, bool) {
+	operatorDB.once.Do(loadOperatorDB)
+	if len(mcc) > 3 {
+		mcc = mcc[:3]
+	}
+	country, ok := operatorDB.countries[mcc]
+	return country, ok
+}
+
+// LookupOperator ищет оператора по числовому коду MCC+MNC ("25002").
+// Если оператор неизвестен, но известна страна, возвращается запись только со страной и false.
+func LookupOperator(numeric string) (PLMN, bool) {
+	operatorDB.once.Do(loadOperatorDB)
+	numeric = strings.TrimSpace(numeric)
+	if plmn, ok := operatorDB.operators[numeric]; ok {
+		return plmn, true
+	}
+	if len(numeric) < 5 {
+		return PLMN{}, false
+	}
+	plmn := PLMN{MCC: numeric[:3], MNC: numeric[3:]}
+	if country, ok := operatorDB.countries[plmn.MCC]; ok {
+		plmn.Country, plmn.ISO = country.Name, country.ISO
+	}
+	return plmn, false
+}
+
+// CountryOperators возвращает операторов страны по коду ISO ("ru"), отсортированных по MCC+MNC
+func CountryOperators(iso string) []PLMN {
+	operatorDB.once.Do(loadOperatorDB)
+	var result []PLMN
+	for _, plmn := range operatorDB.operators {
+		if strings.EqualFold(plmn.ISO, iso) {
+			result = append(result, plmn)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Numeric() < result[j].Numeric()
+	})
+	return result
+}
+
+// LoadOperatorNames читает список операторов из памяти модема (AT+COPN). Имена используются
+// для операторов, которых нет во встроенном справочнике (LookupOperator у модема, GetCurrentOperator, ScanOperators).
+func (m *Modem) LoadOperatorNames() (map[string]string, error) {
+	// Список может содержать тысячи строк
+	resp, err := m.execCommand("AT+COPN", time.Minute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operator names: %w", err)
+	}
+
+	// +COPN: "25001","MTS RUS"
+	names := make(map[string]string)
+	for _, fields := range parseInfoLines(resp, "+COPN:") {
+		numeric, name := fieldAt(fields, 0).Value, fieldAt(fields, 1).Value
+		if numeric != "" && name != "" {
+			names[numeric] = name
+		}
+	}
+
+	m.optMu.Lock()
+	m.operatorNames = names
+	m.optMu.Unlock()
+	return names, nil
+}
+
+// LookupOperator ищет оператора во встроенном справочнике, затем в списке AT+COPN (см. LoadOperatorNames)
+func (m *Modem) LookupOperator(numeric string) (PLMN, bool) {
+	plmn, ok := LookupOperator(numeric)
+	if ok {
+		return plmn, true
+	}
+
+	m.optMu.Lock()
+	name, found := m.operatorNames[numeric]
+	m.optMu.Unlock()
+	if !found {
+		return plmn, false
+	}
+	plmn.Brand, plmn.Operator = name, name
+	return plmn, true
+}
+
+// enrichOperator дополняет информацию об операторе страной и названиями из справочника
+func (m *Modem) enrichOperator(op *OperatorInfo) {
+	if op.Numeric == "" {
+		return
+	}
+	plmn, ok := m.LookupOperator(op.Numeric)
+	op.Country, op.CountryISO = plmn.Country, plmn.ISO
+	if !ok {
+		return
+	}
+	op.Brand = plmn.Brand
+	if op.LongName == "" {
+		op.LongName = plmn.Brand
+	}
+	if op.ShortName == "" {
+		op.ShortName = plmn.Brand
+	}
+}
@@ -0,0 +1,289 @@
+package gsm
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	attachTimeout   = 75 * time.Second  // AT+CGATT: максимальное время ответа по 27.007
+	activateTimeout = 150 * time.Second // AT+CGACT: максимальное время ответа по 27.007
+)
+
+// PDPType тип PDP контекста
+type PDPType string
+
+const (
+	PDPTypeIP     PDPType = "IP"     // IPv4
+	PDPTypeIPv6   PDPType = "IPV6"   // IPv6
+	PDPTypeIPv4v6 PDPType = "IPV4V6" // IPv4 и IPv6 (dual stack)
+)
+
+// PDPContext определение PDP контекста (+CGDCONT)
+type PDPContext struct {
+	CID     int     `at:"0"` // Идентификатор контекста
+	Type    PDPType `at:"1"` // Тип PDP
+	APN     string  `at:"2"` // Точка доступа
+	Address string  `at:"3"` // Запрошенный адрес (обычно пустой)
+}
+
+// PDPDynamicParams динамические параметры активного контекста (+CGCONTRDP).
+// Для IPV4V6 модем возвращает по записи на каждое семейство адресов.
+type PDPDynamicParams struct {
+	CID      int        // Идентификатор контекста
+	BearerID int        // Идентификатор EPS bearer
+	APN      string     // Точка доступа, назначенная сетью
+	Address  net.IP     // Локальный адрес
+	Mask     net.IPMask // Маска подсети
+	Gateway  net.IP     // Шлюз
+	DNS      []net.IP   // Первичный и вторичный DNS
+	MTU      int        // MTU IPv4 (0 - не сообщается)
+}
+
+// GetPDPContexts возвращает определенные PDP контексты
+func (m *Modem) GetPDPContexts() ([]PDPContext, error) {
+	contexts, err := QueryAllTimeout[PDPContext](m, "AT+CGDCONT?", "+CGDCONT", time.Second*5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PDP contexts: %w", err)
+	}
+	return contexts, nil
+}
+
+// DefinePDPContext задает тип и точку доступа PDP контекста (AT+CGDCONT)
+func (m *Modem) DefinePDPContext(cid int, pdpType PDPType, apn string) error {
+	if pdpType == "" {
+		pdpType = PDPTypeIP
+	}
+	cmd := fmt.Sprintf(`AT+CGDCONT=%d,"%s","%s"`, cid, pdpType, apn)
+	if _, err := m.execCommand(cmd, time.Second*5); err != nil {
+		return fmt.Errorf("failed to define PDP context %d: %w", cid, err)
+	}
+	return nil
+}
+
+// DeletePDPContext удаляет определение PDP контекста
+func (m *Modem) DeletePDPContext(cid int) error {
+	if _, err := m.execCommand(fmt.Sprintf("AT+CGDCONT=%d", cid), time.Second*5); err != nil {
+		return fmt.Errorf("failed to delete PDP context %d: %w", cid, err)
+	}
+	return nil
+}
+
+// SetPDPAuth задает аутентификацию PDP контекста (AT+CGAUTH)
+func (m *Modem) SetPDPAuth(cid int, auth APNAuth, username, password string) error {
+	cmd := fmt.Sprintf(`AT+CGAUTH=%d,%d,"%s","%s"`, cid, auth, username, password)
+	if auth == APNAuthNone {
+		cmd = fmt.Sprintf("AT+CGAUTH=%d,0", cid)
+	}
+	if _, err := m.execCommand(cmd, time.Second*5); err != nil {
+		return fmt.Errorf("failed to set PDP context %d authentication: %w", cid, err)
+	}
+	return nil
+}
+
+// SetPacketAttach подключает (true) или отключает модем от пакетной сети (AT+CGATT)
+func (m *Modem) SetPacketAttach(attach bool) error {
+	state := 0
+	if attach {
+		state = 1
+	}
+	if _, err := m.execCommand(fmt.Sprintf("AT+CGATT=%d", state), attachTimeout); err != nil {
+		return fmt.Errorf("failed to set packet attach: %w", err)
+	}
+	return nil
+}
+
+// IsPacketAttached проверяет подключение к пакетной сети
+func (m *Modem) IsPacketAttached() (bool, error) {
+	resp, err := m.execCommand("AT+CGATT?", time.Second*5)
+	if err != nil {
+		return false, fmt.Errorf("failed to get packet attach state: %w", err)
+	}
+	for _, fields := range parseInfoLines(resp, "+CGATT:") {
+		return fieldAt(fields, 0).Value == "1", nil
+	}
+	return false, fmt.Errorf("unexpected response format: %s", resp)
+}
+
+// ActivatePDPContext активирует PDP контекст (AT+CGACT)
+func (m *Modem) ActivatePDPContext(cid int) error {
+	if _, err := m.execCommand(fmt.Sprintf("AT+CGACT=1,%d", cid), activateTimeout); err != nil {
+		return fmt.Errorf("failed to activate PDP context %d: %w", cid, err)
+	}
+	return nil
+}
+
+// DeactivatePDPContext деактивирует PDP контекст
+func (m *Modem) DeactivatePDPContext(cid int) error {
+	if _, err := m.execCommand(fmt.Sprintf("AT+CGACT=0,%d", cid), activateTimeout); err != nil {
+		return fmt.Errorf("failed to deactivate PDP context %d: %w", cid, err)
+	}
+	return nil
+}
+
+// GetPDPContextStates возвращает состояние контекстов: cid -> активен
+func (m *Modem) GetPDPContextStates() (map[int]bool, error) {
+	resp, err := m.execCommand("AT+CGACT?", time.Second*5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PDP context states: %w", err)
+	}
+
+	// +CGACT: <cid>,<state>
+	states := make(map[int]bool)
+	for _, fields := range parseInfoLines(resp, "+CGACT:") {
+		if cid, err := fieldAt(fields, 0).Int(); err == nil {
+			states[cid] = fieldAt(fields, 1).Value == "1"
+		}
+	}
+	return states, nil
+}
+
+// GetPDPAddresses возвращает адреса, назначенные контексту (AT+CGPADDR)
+func (m *Modem) GetPDPAddresses(cid int) ([]net.IP, error) {
+	resp, err := m.execCommand(fmt.Sprintf("AT+CGPADDR=%d", cid), time.Second*5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PDP context %d addresses: %w", cid, err)
+	}
+
+	// +CGPADDR: <cid>[,<PDP_addr_1>[,<PDP_addr_2>]]
+	var addresses []net.IP
+	for _, fields := range parseInfoLines(resp, "+CGPADDR:") {
+		if fieldAt(fields, 0).IntOr(-1) != cid {
+			continue
+		}
+		for _, field := range fields[1:] {
+			// Некоторые модемы передают оба адреса в одном поле через пробел
+			for _, value := range strings.Fields(field.Value) {
+				if ip, _ := parsePDPAddress(value); ip != nil && !ip.IsUnspecified() {
+					addresses = append(addresses, ip)
+				}
+			}
+		}
+	}
+	return addresses, nil
+}
+
+// GetPDPDynamicParams возвращает адрес, шлюз, DNS и MTU активного контекста (AT+CGCONTRDP)
+func (m *Modem) GetPDPDynamicParams(cid int) ([]PDPDynamicParams, error) {
+	resp, err := m.execCommand(fmt.Sprintf("AT+CGCONTRDP=%d", cid), time.Second*5)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PDP context %d parameters: %w", cid, err)
+	}
+
+	var result []PDPDynamicParams
+	for _, fields := range parseInfoLines(resp, "+CGCONTRDP:") {
+		result = append(result, parseCGCONTRDP(fields))
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("failed to get PDP context %d parameters: %w: +CGCONTRDP:", cid, ErrNoResponse)
+	}
+	return result, nil
+}
+
+// parseCGCONTRDP разбирает +CGCONTRDP: <cid>,<bearer_id>,<apn>,<local_addr and subnet_mask>,<gw_addr>,
+// <DNS_prim_addr>,<DNS_sec_addr>,<P-CSCF_prim_addr>,<P-CSCF_sec_addr>,<IM_CN_Signalling_Flag>,
+// <LIPA_indication>,<IPv4_MTU>
+func parseCGCONTRDP(fields []Field) PDPDynamicParams {
+	params := PDPDynamicParams{
+		CID:      fieldAt(fields, 0).IntOr(-1),
+		BearerID: fieldAt(fields, 1).IntOr(-1),
+		APN:      fieldAt(fields, 2).Value,
+		MTU:      fieldAt(fields, 11).IntOr(0),
+	}
+	params.Address, params.Mask = parsePDPAddress(fieldAt(fields, 3).Value)
+	params.Gateway, _ = parsePDPAddress(fieldAt(fields, 4).Value)
+	for _, i := range []int{5, 6} {
+		if dns, _ := parsePDPAddress(fieldAt(fields, i).Value); dns != nil && !dns.IsUnspecified() {
+			params.DNS = append(params.DNS, dns)
+		}
+	}
+	return params
+}
+
+// parsePDPAddress разбирает адрес (и маску) в форматах 27.007: "a.b.c.d", "a.b.c.d.m1.m2.m3.m4",
+// IPv6 как 16 (или 32 с маской) десятичных октетов через точку, а также "адрес маска" и обычную запись IPv6
+// (при AT+CGPIAF).
+func parsePDPAddress(value string) (net.IP, net.IPMask) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if parts := strings.Fields(value); len(parts) == 2 {
+		ip := net.ParseIP(parts[0])
+		mask, _ := parsePDPAddress(parts[1])
+		if ip == nil {
+			return nil, nil
+		}
+		if v4 := mask.To4(); v4 != nil && ip.To4() != nil {
+			return ip, net.IPMask(v4)
+		}
+		return ip, net.IPMask(mask)
+	}
+	if strings.Contains(value, ":") {
+		if ip, network, err := net.ParseCIDR(value); err == nil {
+			return ip, network.Mask
+		}
+		return net.ParseIP(value), nil
+	}
+
+	parts := strings.Split(value, ".")
+	octets := make([]byte, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || n > 255 {
+			return nil, nil
+		}
+		octets[i] = byte(n)
+	}
+	switch len(octets) {
+	case 4:
+		return net.IP(octets).To16(), nil
+	case 8:
+		return net.IP(octets[:4]).To16(), net.IPMask(octets[4:])
+	case 16:
+		return net.IP(octets), nil
+	case 32:
+		return net.IP(octets[:16]), net.IPMask(octets[16:])
+	}
+	return nil, nil
+}
+
+// ConnectData поднимает сетевой интерфейс модема (NDIS/QMI/MBIM/ECM) для контекста:
+// Huawei AT^NDISDUP, Quectel AT+QNETDEVCTL, для остальных - активация контекста AT+CGACT.
+// Адрес на интерфейсе хоста нужно получить по DHCP или взять из GetPDPDynamicParams.
+func (m *Modem) ConnectData(cid int) error {
+	var cmd string
+	switch m.Vendor() {
+	case VendorHuawei:
+		cmd = fmt.Sprintf("AT^NDISDUP=%d,1", cid)
+	case VendorQuectel:
+		// <op>,<cid>,<urc_en>
+		cmd = fmt.Sprintf("AT+QNETDEVCTL=1,%d,1", cid)
+	default:
+		return m.ActivatePDPContext(cid)
+	}
+	if _, err := m.execCommand(cmd, activateTimeout); err != nil {
+		return fmt.Errorf("failed to connect data on context %d: %w", cid, err)
+	}
+	return nil
+}
+
+// DisconnectData отключает сетевой интерфейс модема для контекста
+func (m *Modem) DisconnectData(cid int) error {
+	var cmd string
+	switch m.Vendor() {
+	case VendorHuawei:
+		cmd = fmt.Sprintf("AT^NDISDUP=%d,0", cid)
+	case VendorQuectel:
+		cmd = fmt.Sprintf("AT+QNETDEVCTL=0,%d,0", cid)
+	default:
+		return m.DeactivatePDPContext(cid)
+	}
+	if _, err := m.execCommand(cmd, activateTimeout); err != nil {
+		return fmt.Errorf("failed to disconnect data on context %d: %w", cid, err)
+	}
+	return nil
+}
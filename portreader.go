@@ -0,0 +1,126 @@
+package gsm
+
+import (
+	"io"
+	"time"
+)
+
+// portPollInterval сколько чтение ждет данные от portReader. Обработчик событий держит m.mu
+// не дольше этого времени, поэтому команды не ждут таймаута чтения последовательного порта.
+const portPollInterval = 50 * time.Millisecond
+
+// portReader единственный читатель порта, пока запущен обработчик событий. Прочитанные данные
+// забирает тот, кто держит m.mu: команда (в том числе двоичные чтения сокетов и HTTP) или
+// обработчик событий между командами, поэтому ответы не попадают в обработчик и наоборот.
+type portReader struct {
+	chunks   chan []byte
+	stop     chan struct{}
+	done     chan struct{}
+	events   []byte // Данные, пришедшие до отправки команды, - URC для обработчика событий (под m.mu)
+	leftover []byte // Фрагмент, прочитанный во время остановки (доступен после done)
+}
+
+// startPortReader запускает чтение порта в отдельной горутине (под m.mu)
+func (m *Modem) startPortReader() {
+	if m.reader != nil {
+		return
+	}
+	r := &portReader{
+		chunks: make(chan []byte),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	m.reader = r
+
+	go func() {
+		defer close(r.done)
+		buf := make([]byte, 1024)
+		for {
+			select {
+			case <-r.stop:
+				return
+			default:
+			}
+
+			n, err := m.port.Read(buf)
+			if n > 0 {
+				chunk := append([]byte(nil), buf[:n]...)
+				select {
+				case r.chunks <- chunk:
+				case <-r.stop:
+					r.leftover = chunk
+					return
+				}
+			}
+			if err != nil {
+				// Последовательный порт возвращает EOF по таймауту чтения
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}()
+}
+
+// stopPortReader останавливает чтение порта и ждет завершения текущего чтения (под m.mu),
+// после чего порт снова читается напрямую - например, PPP соединением
+func (m *Modem) stopPortReader() {
+	r := m.reader
+	if r == nil {
+		return
+	}
+	m.reader = nil
+	close(r.stop)
+	<-r.done
+
+	unread := append(r.events, m.unread...)
+	m.unread = append(unread, r.leftover...)
+}
+
+// readPort читает порт (под m.mu): у portReader, если запущен обработчик событий, иначе напрямую.
+// Если данных нет, возвращает io.EOF, как последовательный порт по таймауту чтения.
+func (m *Modem) readPort(buf []byte) (int, error) {
+	if len(m.unread) == 0 && m.reader != nil {
+		select {
+		case m.unread = <-m.reader.chunks:
+		case <-time.After(portPollInterval):
+			return 0, io.EOF
+		}
+	}
+	if len(m.unread) > 0 {
+		n := copy(buf, m.unread)
+		m.unread = m.unread[n:]
+		return n, nil
+	}
+	return m.port.Read(buf)
+}
+
+// readEvents читает данные для обработчика событий (под m.mu): сначала URC, пришедшие
+// до последней команды, затем порт
+func (m *Modem) readEvents(buf []byte) (int, error) {
+	if r := m.reader; r != nil && len(r.events) > 0 {
+		n := copy(buf, r.events)
+		r.events = r.events[n:]
+		return n, nil
+	}
+	return m.readPort(buf)
+}
+
+// flushPort готовит порт к новой команде (под m.mu). Без обработчика событий непрочитанные
+// данные отбрасываются; при запущенном обработчике они передаются ему - это URC.
+func (m *Modem) flushPort() {
+	r := m.reader
+	if r == nil {
+		m.unread = nil
+		m.port.Flush()
+		return
+	}
+	r.events = append(r.events, m.unread...)
+	m.unread = nil
+	for {
+		select {
+		case chunk := <-r.chunks:
+			r.events = append(r.events, chunk...)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,867 @@
+package gsm
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Опции LCP, IPCP и IPv6CP
+const (
+	lcpOptionMRU      = 1
+	lcpOptionACCM     = 2
+	lcpOptionAuth     = 3
+	lcpOptionMagic    = 5
+	lcpOptionPFC      = 7
+	lcpOptionACFC     = 8
+	ipcpOptionAddress = 3
+	ipcpOptionDNS1    = 129
+	ipcpOptionDNS2    = 131
+	ipv6cpOptionIfID  = 1
+	chapMD5           = 5
+)
+
+const (
+	defaultPPPTimeout      = 30 * time.Second
+	defaultPPPEchoInterval = 30 * time.Second
+	defaultPPPEchoFailures = 4
+	defaultPPPMTU          = 1500
+)
+
+var (
+	// ErrPPPClosed возвращается после разрыва или закрытия PPP соединения
+	ErrPPPClosed = errors.New("PPP link closed")
+	// ErrPPPAuth возвращается, если сеть отклонила имя пользователя или пароль
+	ErrPPPAuth = errors.New("PPP authentication failed")
+	// ErrPacketDropped возвращается WritePacket для пакетов, которые нельзя передать по каналу
+	// (IPv6 без IPv6CP, неизвестная версия IP); канал при этом остается рабочим
+	ErrPacketDropped = errors.New("packet dropped")
+	// ErrDataMode возвращается AT командами, пока порт модема занят PPP соединением
+	ErrDataMode = errors.New("modem is in data mode")
+)
+
+// PacketLink канал передачи IP-пакетов
+type PacketLink interface {
+	ReadPacket(b []byte) (int, error) // Читает следующий IP-пакет
+	WritePacket(b []byte) error       // Отправляет IP-пакет (IPv4 или IPv6)
+	MTU() int                         // Максимальный размер пакета
+	Close() error                     // Закрывает канал
+}
+
+// PPPConfig настройки PPP соединения
+type PPPConfig struct {
+	Username     string        // Имя пользователя PAP/CHAP (обычно любое)
+	Password     string        // Пароль PAP/CHAP
+	APN          string        // Точка доступа: если задана, DialPPP записывает ее в контекст CID
+	CID          int           // PDP контекст для набора ATD*99***<cid># (0 - ATD*99#)
+	IPv6         bool          // Согласовывать IPv6CP
+	Timeout      time.Duration // Время на установку соединения (по умолчанию 30 секунд)
+	EchoInterval time.Duration // Период LCP Echo (по умолчанию 30 секунд, <0 - не проверять)
+	EchoFailures int           // Сколько LCP Echo без ответа означают разрыв (по умолчанию 4)
+}
+
+// PPP клиентское PPP соединение (RFC 1661): LCP, PAP/CHAP, IPCP и IPv6CP.
+// Реализует PacketLink.
+type PPP struct {
+	rw      io.ReadWriter
+	config  PPPConfig
+	writeMu sync.Mutex
+
+	lcp    *controlProtocol
+	ipcp   *controlProtocol
+	ipv6cp *controlProtocol
+
+	mu          sync.Mutex
+	magic       uint32
+	wantMagic   bool
+	wantACCM    bool
+	authProto   uint16
+	peerMRU     int
+	wantAddress bool
+	wantDNS     [2]bool
+	localIP     net.IP
+	peerIP      net.IP
+	dns         [2]net.IP
+	localIfID   [8]byte
+	peerIfID    [8]byte
+	ipv6Up      bool
+
+	authID     byte
+	authResult chan error
+	echoMissed int32
+
+	packets     chan []byte
+	ready       chan struct{}
+	readyOnce   sync.Once
+	done        chan struct{}
+	failOnce    sync.Once
+	err         error
+	readerDone  chan struct{}
+	cleanupDone chan struct{}
+	onClose     func()
+}
+
+// NewPPP устанавливает PPP соединение поверх произвольного потока (порт модема после CONNECT,
+// псевдотерминал и т.д.). Поток не закрывается при закрытии соединения.
+func NewPPP(rw io.ReadWriter, config PPPConfig) (*PPP, error) {
+	p := newPPP(rw, config)
+	if err := p.open(nil); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// DialPPP набирает ATD*99# (или ATD*99***<cid>#) и устанавливает PPP соединение через AT порт.
+// Пока соединение открыто, AT команды на этом порту возвращают ErrDataMode; обработчик событий
+// должен быть остановлен. Close возвращает модем в командный режим.
+func (m *Modem) DialPPP(config PPPConfig) (*PPP, error) {
+	if config.APN != "" {
+		cid := config.CID
+		if cid == 0 {
+			cid = 1
+		}
+		if err := m.DefinePDPContext(cid, PDPTypeIP, config.APN); err != nil {
+			return nil, err
+		}
+	}
+
+	cmd := "ATD*99#"
+	if config.CID > 0 {
+		cmd = fmt.Sprintf("ATD*99***%d#", config.CID)
+	}
+
+	m.mu.Lock()
+	if m.eventsEnabled {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("failed to dial PPP: event listener must be stopped")
+	}
+	if m.dataMode {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("failed to dial PPP: %w", ErrDataMode)
+	}
+	leftover, err := m.dialData(cmd, time.Second*30)
+	if err != nil {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("failed to dial PPP: %w", err)
+	}
+	m.dataMode = true
+	m.mu.Unlock()
+
+	p := newPPP(m.port, config)
+	p.onClose = m.leaveDataMode
+	if err := p.open(leftover); err != nil {
+		return nil, fmt.Errorf("failed to establish PPP: %w", err)
+	}
+	return p, nil
+}
+
+// dialData отправляет команду набора и ждет CONNECT (под m.mu). Возвращает байты,
+// пришедшие после строки CONNECT - это уже начало PPP потока.
+func (m *Modem) dialData(cmd string, timeout time.Duration) ([]byte, error) {
+	m.flushPort()
+	if _, err := m.port.Write([]byte(cmd + "\r\n")); err != nil {
+		return nil, fmt.Errorf("failed to write command: %w", err)
+	}
+
+	var response []byte
+	buf := make([]byte, 1024)
+	for start := time.Now(); time.Since(start) < timeout; {
+		n, err := m.readPort(buf)
+		if err != nil || n == 0 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		response = append(response, buf[:n]...)
+
+		if idx := bytes.Index(response, []byte("CONNECT")); idx >= 0 {
+			if end := bytes.Index(response[idx:], []byte("\r\n")); end >= 0 {
+				debugResponse(cmd, string(response[:idx+end]))
+				return response[idx+end+2:], nil
+			}
+			continue
+		}
+		for _, failure := range []string{"NO CARRIER", "ERROR", "BUSY", "NO DIALTONE", "NO ANSWER"} {
+			if bytes.Contains(response, []byte(failure)) {
+				debugResponse(cmd, string(response))
+				return nil, fmt.Errorf("%w: %s", ErrCommandFailed, bytes.TrimSpace(response))
+			}
+		}
+	}
+	return nil, fmt.Errorf("timeout waiting for CONNECT")
+}
+
+// leaveDataMode возвращает модем в командный режим после завершения PPP
+func (m *Modem) leaveDataMode() {
+	// Escape-последовательность требует паузы до и после "+++"
+	time.Sleep(time.Second)
+	m.port.Write([]byte("+++"))
+	time.Sleep(time.Second)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dataMode = false
+	m.sendCommand("ATH", time.Second*5)
+}
+
+// newPPP создает соединение с настройками по умолчанию
+func newPPP(rw io.ReadWriter, config PPPConfig) *PPP {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultPPPTimeout
+	}
+	if config.EchoInterval == 0 {
+		config.EchoInterval = defaultPPPEchoInterval
+	}
+	if config.EchoFailures <= 0 {
+		config.EchoFailures = defaultPPPEchoFailures
+	}
+
+	p := &PPP{
+		rw:          rw,
+		config:      config,
+		magic:       randomUint32(),
+		wantMagic:   true,
+		wantACCM:    true,
+		peerMRU:     defaultPPPMTU,
+		wantAddress: true,
+		wantDNS:     [2]bool{true, true},
+		localIP:     net.IPv4zero.To4(),
+		authResult:  make(chan error, 1),
+		packets:     make(chan []byte, 128),
+		ready:       make(chan struct{}),
+		done:        make(chan struct{}),
+		readerDone:  make(chan struct{}),
+		cleanupDone: make(chan struct{}),
+	}
+	p.dns = [2]net.IP{net.IPv4zero.To4(), net.IPv4zero.To4()}
+	rand.Read(p.localIfID[:])
+
+	p.lcp = newControlProtocol(p, pppLCP, "LCP")
+	p.lcp.request, p.lcp.nak, p.lcp.reject = p.lcpRequest, p.lcpNak, p.lcpReject
+	p.lcp.check, p.lcp.extra, p.lcp.up = p.lcpCheck, p.lcpExtra, p.networkPhase
+
+	p.ipcp = newControlProtocol(p, pppIPCP, "IPCP")
+	p.ipcp.request, p.ipcp.nak, p.ipcp.reject = p.ipcpRequest, p.ipcpNak, p.ipcpReject
+	p.ipcp.check = p.ipcpCheck
+	p.ipcp.up = func() { p.readyOnce.Do(func() { close(p.ready) }) }
+
+	p.ipv6cp = newControlProtocol(p, pppIPv6CP, "IPv6CP")
+	p.ipv6cp.request, p.ipv6cp.nak, p.ipv6cp.reject = p.ipv6cpRequest, p.ipv6cpNak, p.ipv6cpReject
+	p.ipv6cp.check = p.ipv6cpCheck
+	p.ipv6cp.up = func() {
+		p.mu.Lock()
+		p.ipv6Up = true
+		p.mu.Unlock()
+	}
+	return p
+}
+
+// open запускает чтение и согласование и ждет готовности IPCP
+func (p *PPP) open(initial []byte) error {
+	go p.cleanup()
+	go p.readLoop(initial)
+	p.lcp.start()
+
+	select {
+	case <-p.ready:
+		return nil
+	case <-p.done:
+		p.Close()
+		return p.Err()
+	case <-time.After(p.config.Timeout):
+		p.Close()
+		return fmt.Errorf("PPP negotiation timeout")
+	}
+}
+
+// randomUint32 возвращает случайное ненулевое число (Magic-Number)
+func randomUint32() uint32 {
+	var b [4]byte
+	for {
+		rand.Read(b[:])
+		if v := binary.BigEndian.Uint32(b[:]); v != 0 {
+			return v
+		}
+	}
+}
+
+// readLoop читает поток, собирает кадры и передает их протоколам
+func (p *PPP) readLoop(initial []byte) {
+	defer close(p.readerDone)
+
+	var reader frameReader
+	process := func(data []byte) {
+		for _, raw := range reader.feed(data) {
+			if protocol, info, err := decodeFrame(raw); err == nil {
+				p.dispatch(protocol, info)
+			}
+		}
+		// Модем сообщает о разрыве текстом вне кадров
+		if bytes.HasPrefix(bytes.TrimLeft(reader.buf, "\r\n"), []byte("NO CARRIER")) {
+			p.fail(fmt.Errorf("%w: NO CARRIER", ErrPPPClosed))
+		}
+	}
+	process(initial)
+
+	buf := make([]byte, 4096)
+	for {
+		select {
+		case <-p.done:
+			return
+		default:
+		}
+
+		n, err := p.rw.Read(buf)
+		if n > 0 {
+			process(buf[:n])
+		}
+		if err != nil {
+			// Последовательный порт возвращает EOF по таймауту чтения
+			if errors.Is(err, io.EOF) {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			p.fail(fmt.Errorf("%w: %v", ErrPPPClosed, err))
+			return
+		}
+	}
+}
+
+// dispatch передает кадр протоколу
+func (p *PPP) dispatch(protocol uint16, info []byte) {
+	switch protocol {
+	case pppLCP:
+		p.lcp.handle(info)
+	case pppPAP:
+		p.handlePAP(info)
+	case pppCHAP:
+		p.handleCHAP(info)
+	case pppIPCP:
+		if p.lcp.isOpened() {
+			p.ipcp.handle(info)
+		}
+	case pppIPv6CP:
+		if !p.config.IPv6 {
+			p.protocolReject(protocol, info)
+		} else if p.lcp.isOpened() {
+			p.ipv6cp.handle(info)
+		}
+	case pppIPv4:
+		if p.ipcp.isOpened() {
+			p.deliver(info)
+		}
+	case pppIPv6:
+		if p.ipv6cp.isOpened() {
+			p.deliver(info)
+		}
+	default:
+		if p.lcp.isOpened() {
+			p.protocolReject(protocol, info)
+		}
+	}
+}
+
+// deliver передает IP-пакет читателю (при переполнении очереди пакет отбрасывается)
+func (p *PPP) deliver(packet []byte) {
+	select {
+	case p.packets <- packet:
+	default:
+	}
+}
+
+// protocolReject отвечает LCP Protocol-Reject на неподдерживаемый протокол
+func (p *PPP) protocolReject(protocol uint16, info []byte) {
+	data := binary.BigEndian.AppendUint16(nil, protocol)
+	data = append(data, info...)
+	if max := p.MTU() - 4; len(data) > max {
+		data = data[:max]
+	}
+	p.lcp.send(lcpProtocolReject, p.lcp.nextID(), data)
+}
+
+// writeFrame отправляет кадр
+func (p *PPP) writeFrame(protocol uint16, info []byte) error {
+	frame := encodeFrame(protocol, info)
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	_, err := p.rw.Write(frame)
+	return err
+}
+
+// fail разрывает соединение с указанной причиной
+func (p *PPP) fail(err error) {
+	p.failOnce.Do(func() {
+		p.mu.Lock()
+		p.err = err
+		p.mu.Unlock()
+		close(p.done)
+	})
+}
+
+// cleanup после разрыва дожидается остановки чтения и возвращает порт владельцу.
+// Для произвольного потока (NewPPP) чтение может остаться заблокированным до его закрытия.
+func (p *PPP) cleanup() {
+	defer close(p.cleanupDone)
+	<-p.done
+	if p.onClose == nil {
+		return
+	}
+	select {
+	case <-p.readerDone:
+	case <-time.After(10 * time.Second):
+	}
+	p.onClose()
+}
+
+// lcpRequest собственные опции LCP
+func (p *PPP) lcpRequest() []cpOption {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var options []cpOption
+	if p.wantACCM {
+		// Модем может не экранировать управляющие символы
+		options = append(options, cpOption{lcpOptionACCM, []byte{0, 0, 0, 0}})
+	}
+	if p.wantMagic {
+		options = append(options, cpOption{lcpOptionMagic, binary.BigEndian.AppendUint32(nil, p.magic)})
+	}
+	return options
+}
+
+// lcpNak принимает предложения собеседника по нашим опциям LCP
+func (p *PPP) lcpNak(opt cpOption) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch opt.typ {
+	case lcpOptionMagic:
+		p.magic = randomUint32()
+	case lcpOptionACCM:
+		p.wantACCM = false
+	}
+}
+
+// lcpReject убирает отвергнутые опции LCP
+func (p *PPP) lcpReject(opt cpOption) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch opt.typ {
+	case lcpOptionMagic:
+		p.wantMagic = false
+	case lcpOptionACCM:
+		p.wantACCM = false
+	}
+}
+
+// lcpCheck проверяет опции LCP собеседника
+func (p *PPP) lcpCheck(opt cpOption) (cpVerdict, []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch opt.typ {
+	case lcpOptionMRU:
+		if len(opt.data) != 2 {
+			return cpReject, nil
+		}
+		p.peerMRU = int(binary.BigEndian.Uint16(opt.data))
+		return cpAck, nil
+	case lcpOptionACCM, lcpOptionPFC, lcpOptionACFC:
+		// Отправляемые кадры всегда полные и с экранированием - это допустимо при любых значениях
+		return cpAck, nil
+	case lcpOptionAuth:
+		if len(opt.data) >= 2 {
+			switch binary.BigEndian.Uint16(opt.data) {
+			case pppPAP:
+				p.authProto = pppPAP
+				return cpAck, nil
+			case pppCHAP:
+				if len(opt.data) == 3 && opt.data[2] == chapMD5 {
+					p.authProto = pppCHAP
+					return cpAck, nil
+				}
+			}
+		}
+		// Поддерживается только CHAP-MD5 и PAP
+		return cpNak, []byte{byte(pppCHAP >> 8), byte(pppCHAP & 0xFF), chapMD5}
+	case lcpOptionMagic:
+		if len(opt.data) != 4 {
+			return cpReject, nil
+		}
+		if p.wantMagic && binary.BigEndian.Uint32(opt.data) == p.magic {
+			// Петля или совпадение - предлагаем другое значение
+			return cpNak, binary.BigEndian.AppendUint32(nil, randomUint32())
+		}
+		return cpAck, nil
+	default:
+		return cpReject, nil
+	}
+}
+
+// lcpExtra обрабатывает Echo, Discard и Protocol-Reject
+func (p *PPP) lcpExtra(code, id byte, data []byte) {
+	switch code {
+	case lcpEchoRequest:
+		if p.lcp.isOpened() && len(data) >= 4 {
+			p.mu.Lock()
+			reply := binary.BigEndian.AppendUint32(nil, p.magic)
+			p.mu.Unlock()
+			p.lcp.send(lcpEchoReply, id, append(reply, data[4:]...))
+		}
+	case lcpEchoReply:
+		atomic.StoreInt32(&p.echoMissed, 0)
+	case lcpDiscardRequest:
+	case lcpProtocolReject:
+		if len(data) < 2 {
+			return
+		}
+		switch binary.BigEndian.Uint16(data) {
+		case pppIPv6CP:
+			p.ipv6cp.stop()
+		case pppIPCP:
+			p.fail(fmt.Errorf("%w: peer rejected IPCP", ErrPPPClosed))
+		}
+	default:
+		p.lcp.send(cpCodeReject, p.lcp.nextID(), encodeCP(code, id, data))
+	}
+}
+
+// networkPhase после открытия LCP: аутентификация, IPCP/IPv6CP и проверка канала
+func (p *PPP) networkPhase() {
+	if err := p.authenticate(); err != nil {
+		p.fail(err)
+		return
+	}
+	p.ipcp.start()
+	if p.config.IPv6 {
+		p.ipv6cp.start()
+	}
+	if p.config.EchoInterval > 0 {
+		go p.echoLoop()
+	}
+}
+
+// echoLoop отправляет LCP Echo-Request и разрывает соединение, если собеседник не отвечает
+func (p *PPP) echoLoop() {
+	ticker := time.NewTicker(p.config.EchoInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+		}
+		if int(atomic.AddInt32(&p.echoMissed, 1)) > p.config.EchoFailures {
+			p.fail(fmt.Errorf("%w: no LCP echo reply", ErrPPPClosed))
+			return
+		}
+		p.mu.Lock()
+		magic := binary.BigEndian.AppendUint32(nil, p.magic)
+		p.mu.Unlock()
+		p.lcp.send(lcpEchoRequest, p.lcp.nextID(), magic)
+	}
+}
+
+// authenticate выполняет аутентификацию, которую запросил собеседник
+func (p *PPP) authenticate() error {
+	p.mu.Lock()
+	proto := p.authProto
+	p.mu.Unlock()
+
+	switch proto {
+	case pppPAP:
+		user, pass := []byte(p.config.Username), []byte(p.config.Password)
+		data := append([]byte{byte(len(user))}, user...)
+		data = append(append(data, byte(len(pass))), pass...)
+		for attempt := 0; attempt < cpMaxConfigure; attempt++ {
+			p.authID++
+			p.writeFrame(pppPAP, encodeCP(1, p.authID, data))
+			select {
+			case err := <-p.authResult:
+				return err
+			case <-p.done:
+				return p.Err()
+			case <-time.After(cpRestartTimer):
+			}
+		}
+		return fmt.Errorf("%w: PAP timeout", ErrPPPAuth)
+
+	case pppCHAP:
+		// Собеседник сам присылает Challenge, ответ отправляет handleCHAP
+		select {
+		case err := <-p.authResult:
+			return err
+		case <-p.done:
+			return p.Err()
+		case <-time.After(p.config.Timeout):
+			return fmt.Errorf("%w: CHAP timeout", ErrPPPAuth)
+		}
+	}
+	return nil
+}
+
+// reportAuth передает результат аутентификации
+func (p *PPP) reportAuth(err error) {
+	select {
+	case p.authResult <- err:
+	default:
+	}
+}
+
+// handlePAP обрабатывает Authenticate-Ack/Nak (RFC 1334)
+func (p *PPP) handlePAP(packet []byte) {
+	code, _, data, err := decodeCP(packet)
+	if err != nil {
+		return
+	}
+	message := ""
+	if len(data) > 0 && int(data[0]) <= len(data)-1 {
+		message = string(data[1 : 1+data[0]])
+	}
+	switch code {
+	case 2:
+		p.reportAuth(nil)
+	case 3:
+		p.reportAuth(fmt.Errorf("%w: %s", ErrPPPAuth, message))
+	}
+}
+
+// handleCHAP отвечает на Challenge и принимает Success/Failure (RFC 1994, MD5)
+func (p *PPP) handleCHAP(packet []byte) {
+	code, id, data, err := decodeCP(packet)
+	if err != nil {
+		return
+	}
+	switch code {
+	case 1:
+		if len(data) < 1 || int(data[0]) > len(data)-1 {
+			return
+		}
+		challenge := data[1 : 1+data[0]]
+		hash := md5.New()
+		hash.Write([]byte{id})
+		hash.Write([]byte(p.config.Password))
+		hash.Write(challenge)
+		response := append([]byte{md5.Size}, hash.Sum(nil)...)
+		response = append(response, p.config.Username...)
+		p.writeFrame(pppCHAP, encodeCP(2, id, response))
+	case 3:
+		p.reportAuth(nil)
+	case 4:
+		p.reportAuth(fmt.Errorf("%w: %s", ErrPPPAuth, data))
+	}
+}
+
+// ipcpRequest собственные опции IPCP: адрес и DNS (RFC 1332, RFC 1877)
+func (p *PPP) ipcpRequest() []cpOption {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var options []cpOption
+	if p.wantAddress {
+		options = append(options, cpOption{ipcpOptionAddress, p.localIP.To4()})
+	}
+	for i, typ := range []byte{ipcpOptionDNS1, ipcpOptionDNS2} {
+		if p.wantDNS[i] {
+			options = append(options, cpOption{typ, p.dns[i].To4()})
+		}
+	}
+	return options
+}
+
+// ipcpNak принимает адреса, назначенные собеседником
+func (p *PPP) ipcpNak(opt cpOption) {
+	if len(opt.data) != 4 {
+		return
+	}
+	ip := net.IP(append([]byte(nil), opt.data...))
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch opt.typ {
+	case ipcpOptionAddress:
+		p.localIP = ip
+	case ipcpOptionDNS1:
+		p.dns[0] = ip
+	case ipcpOptionDNS2:
+		p.dns[1] = ip
+	}
+}
+
+// ipcpReject убирает отвергнутые опции IPCP
+func (p *PPP) ipcpReject(opt cpOption) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch opt.typ {
+	case ipcpOptionAddress:
+		p.wantAddress = false
+	case ipcpOptionDNS1:
+		p.wantDNS[0] = false
+	case ipcpOptionDNS2:
+		p.wantDNS[1] = false
+	}
+}
+
+// ipcpCheck принимает адрес собеседника, остальные опции (сжатие заголовков) отвергаются
+func (p *PPP) ipcpCheck(opt cpOption) (cpVerdict, []byte) {
+	if opt.typ != ipcpOptionAddress || len(opt.data) != 4 {
+		return cpReject, nil
+	}
+	p.mu.Lock()
+	p.peerIP = net.IP(append([]byte(nil), opt.data...))
+	p.mu.Unlock()
+	return cpAck, nil
+}
+
+// ipv6cpRequest собственный идентификатор интерфейса (RFC 5072)
+func (p *PPP) ipv6cpRequest() []cpOption {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return []cpOption{{ipv6cpOptionIfID, append([]byte(nil), p.localIfID[:]...)}}
+}
+
+// ipv6cpNak принимает идентификатор, предложенный собеседником
+func (p *PPP) ipv6cpNak(opt cpOption) {
+	if opt.typ == ipv6cpOptionIfID && len(opt.data) == 8 {
+		p.mu.Lock()
+		copy(p.localIfID[:], opt.data)
+		p.mu.Unlock()
+	}
+}
+
+// ipv6cpReject - без идентификатора интерфейса IPv6 невозможен
+func (p *PPP) ipv6cpReject(opt cpOption) {
+	p.ipv6cp.stop()
+}
+
+// ipv6cpCheck проверяет идентификатор собеседника
+func (p *PPP) ipv6cpCheck(opt cpOption) (cpVerdict, []byte) {
+	if opt.typ != ipv6cpOptionIfID || len(opt.data) != 8 {
+		return cpReject, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if bytes.Equal(opt.data, p.localIfID[:]) {
+		var suggestion [8]byte
+		rand.Read(suggestion[:])
+		return cpNak, suggestion[:]
+	}
+	copy(p.peerIfID[:], opt.data)
+	return cpAck, nil
+}
+
+// ReadPacket читает следующий IP-пакет
+func (p *PPP) ReadPacket(b []byte) (int, error) {
+	select {
+	case packet := <-p.packets:
+		n := copy(b, packet)
+		if n < len(packet) {
+			return n, io.ErrShortBuffer
+		}
+		return n, nil
+	case <-p.done:
+		return 0, p.Err()
+	}
+}
+
+// WritePacket отправляет IPv4 или IPv6 пакет
+func (p *PPP) WritePacket(b []byte) error {
+	select {
+	case <-p.done:
+		return p.Err()
+	default:
+	}
+	if len(b) == 0 {
+		return fmt.Errorf("%w: empty packet", ErrPacketDropped)
+	}
+
+	switch b[0] >> 4 {
+	case 4:
+		return p.writeFrame(pppIPv4, b)
+	case 6:
+		p.mu.Lock()
+		up := p.ipv6Up
+		p.mu.Unlock()
+		if !up {
+			return fmt.Errorf("%w: IPv6 is not negotiated", ErrPacketDropped)
+		}
+		return p.writeFrame(pppIPv6, b)
+	default:
+		return fmt.Errorf("%w: unknown IP version %d", ErrPacketDropped, b[0]>>4)
+	}
+}
+
+// MTU возвращает максимальный размер отправляемого пакета (MRU собеседника)
+func (p *PPP) MTU() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.peerMRU
+}
+
+// LocalIP возвращает адрес IPv4, назначенный сетью
+func (p *PPP) LocalIP() net.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.localIP
+}
+
+// PeerIP возвращает адрес IPv4 собеседника (шлюз)
+func (p *PPP) PeerIP() net.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.peerIP
+}
+
+// DNS возвращает адреса DNS, полученные при согласовании IPCP
+func (p *PPP) DNS() []net.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var servers []net.IP
+	for i, ip := range p.dns {
+		if p.wantDNS[i] && !ip.IsUnspecified() {
+			servers = append(servers, ip)
+		}
+	}
+	return servers
+}
+
+// LocalIPv6 возвращает link-local адрес IPv6 (nil, если IPv6CP не согласован).
+// Глобальный адрес назначается сетью через SLAAC поверх канала.
+func (p *PPP) LocalIPv6() net.IP {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.ipv6Up {
+		return nil
+	}
+	ip := net.ParseIP("fe80::")
+	copy(ip[8:], p.localIfID[:])
+	return ip
+}
+
+// Done закрывается при разрыве соединения
+func (p *PPP) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err возвращает причину разрыва соединения
+func (p *PPP) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Close завершает соединение (LCP Terminate) и возвращает модем в командный режим
+func (p *PPP) Close() error {
+	select {
+	case <-p.done:
+	default:
+		if p.lcp.isOpened() {
+			p.lcp.terminate(cpRestartTimer)
+		}
+		p.fail(ErrPPPClosed)
+	}
+	<-p.cleanupDone
+	return nil
+}
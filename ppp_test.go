@@ -0,0 +1,398 @@
+package gsm
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pppPeer сетевая сторона PPP для тестов: подтверждает опции LCP, проверяет PAP/CHAP
+// и назначает адреса IPCP через Configure-Nak
+type pppPeer struct {
+	t        *testing.T
+	conn     net.Conn
+	auth     uint16
+	username string
+	password string
+
+	out     chan []byte
+	packets chan []byte
+
+	mu        sync.Mutex
+	challenge []byte
+	authUser  string
+	authOK    bool
+	naked     bool
+	terminate bool
+}
+
+// newPPPPeer запускает собеседника на одном конце net.Pipe и возвращает другой конец
+func newPPPPeer(t *testing.T, auth uint16, username, password string) (*pppPeer, net.Conn) {
+	client, server := net.Pipe()
+	peer := &pppPeer{
+		t:        t,
+		conn:     server,
+		auth:     auth,
+		username: username,
+		password: password,
+		out:      make(chan []byte, 64),
+		packets:  make(chan []byte, 16),
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	// Запись отдельно от чтения: обе стороны net.Pipe пишут синхронно
+	go func() {
+		for frame := range peer.out {
+			if _, err := server.Write(frame); err != nil {
+				return
+			}
+		}
+	}()
+	go peer.readLoop()
+
+	authOption := binary.BigEndian.AppendUint16(nil, auth)
+	if auth == pppCHAP {
+		authOption = append(authOption, chapMD5)
+	}
+	peer.send(pppLCP, encodeCP(cpConfigureRequest, 1, encodeOptions([]cpOption{
+		{lcpOptionAuth, authOption},
+		{lcpOptionMagic, []byte{0x11, 0x22, 0x33, 0x44}},
+	})))
+	return peer, client
+}
+
+// send ставит кадр в очередь на отправку
+func (s *pppPeer) send(protocol uint16, info []byte) {
+	s.out <- encodeFrame(protocol, info)
+}
+
+// readLoop разбирает кадры клиента и отвечает на них
+func (s *pppPeer) readLoop() {
+	var reader frameReader
+	buf := make([]byte, 4096)
+	for {
+		n, err := s.conn.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, raw := range reader.feed(buf[:n]) {
+			protocol, info, err := decodeFrame(raw)
+			if err != nil {
+				s.t.Errorf("client sent bad frame: %x", raw)
+				continue
+			}
+			s.handle(protocol, info)
+		}
+	}
+}
+
+// handle отвечает на кадр клиента
+func (s *pppPeer) handle(protocol uint16, info []byte) {
+	if protocol == pppIPv4 {
+		s.packets <- info
+		return
+	}
+	code, id, data, err := decodeCP(info)
+	if err != nil {
+		s.t.Errorf("client sent bad packet: %x", info)
+		return
+	}
+
+	switch protocol {
+	case pppLCP:
+		switch code {
+		case cpConfigureRequest:
+			s.send(pppLCP, encodeCP(cpConfigureAck, id, data))
+		case cpConfigureAck:
+			if s.auth == pppCHAP {
+				s.mu.Lock()
+				s.challenge = []byte("0123456789abcdef")
+				challenge := s.challenge
+				s.mu.Unlock()
+				value := append([]byte{byte(len(challenge))}, challenge...)
+				s.send(pppCHAP, encodeCP(1, 7, append(value, "peer"...)))
+			}
+		case cpTerminateRequest:
+			s.mu.Lock()
+			s.terminate = true
+			s.mu.Unlock()
+			s.send(pppLCP, encodeCP(cpTerminateAck, id, nil))
+		}
+
+	case pppPAP:
+		// Authenticate-Request: <len><user><len><password>
+		if code != 1 || len(data) < 2 || int(data[0]) >= len(data)-1 {
+			s.t.Errorf("unexpected PAP packet: %x", info)
+			return
+		}
+		user := string(data[1 : 1+data[0]])
+		rest := data[1+data[0]:]
+		if int(rest[0]) >= len(rest) {
+			s.t.Errorf("unexpected PAP packet: %x", info)
+			return
+		}
+		pass := string(rest[1 : 1+rest[0]])
+		s.authResult(id, user, user == s.username && pass == s.password, pppPAP)
+
+	case pppCHAP:
+		// Response: <len><md5><name>
+		if code != 2 || len(data) < 1+md5.Size || data[0] != md5.Size {
+			s.t.Errorf("unexpected CHAP packet: %x", info)
+			return
+		}
+		s.mu.Lock()
+		challenge := s.challenge
+		s.mu.Unlock()
+		hash := md5.New()
+		hash.Write([]byte{id})
+		hash.Write([]byte(s.password))
+		hash.Write(challenge)
+		ok := bytes.Equal(data[1:1+md5.Size], hash.Sum(nil)) && string(data[1+md5.Size:]) == s.username
+		s.authResult(id, string(data[1+md5.Size:]), ok, pppCHAP)
+
+	case pppIPCP:
+		if code != cpConfigureRequest {
+			return
+		}
+		options, err := decodeOptions(data)
+		if err != nil {
+			s.t.Errorf("bad IPCP options: %x", data)
+			return
+		}
+		assigned := map[byte][]byte{
+			ipcpOptionAddress: {10, 0, 0, 2},
+			ipcpOptionDNS1:    {8, 8, 8, 8},
+			ipcpOptionDNS2:    {8, 8, 4, 4},
+		}
+		var naks []cpOption
+		for _, opt := range options {
+			if want, ok := assigned[opt.typ]; ok && !bytes.Equal(opt.data, want) {
+				naks = append(naks, cpOption{opt.typ, want})
+			}
+		}
+		if len(naks) > 0 {
+			s.mu.Lock()
+			s.naked = true
+			s.mu.Unlock()
+			s.send(pppIPCP, encodeCP(cpConfigureNak, id, encodeOptions(naks)))
+			return
+		}
+		s.send(pppIPCP, encodeCP(cpConfigureAck, id, data))
+	}
+}
+
+// authResult отвечает на аутентификацию и после успеха начинает IPCP
+func (s *pppPeer) authResult(id byte, user string, ok bool, protocol uint16) {
+	s.mu.Lock()
+	s.authUser, s.authOK = user, ok
+	s.mu.Unlock()
+
+	success, failure := byte(2), byte(3)
+	if protocol == pppCHAP {
+		success, failure = 3, 4
+	}
+	if !ok {
+		s.send(protocol, encodeCP(failure, id, []byte("denied")))
+		return
+	}
+	s.send(protocol, encodeCP(success, id, nil))
+	s.send(pppIPCP, encodeCP(cpConfigureRequest, 1, encodeOptions([]cpOption{
+		{ipcpOptionAddress, []byte{10, 0, 0, 1}},
+	})))
+}
+
+// dialTestPPP устанавливает соединение с тестовым собеседником
+func dialTestPPP(t *testing.T, auth uint16, password string) (*pppPeer, *PPP, error) {
+	peer, conn := newPPPPeer(t, auth, "user", "secret")
+	p, err := NewPPP(conn, PPPConfig{
+		Username:     "user",
+		Password:     password,
+		Timeout:      5 * time.Second,
+		EchoInterval: -1,
+	})
+	return peer, p, err
+}
+
+func TestPPPNegotiation(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		auth uint16
+	}{
+		{"PAP", pppPAP},
+		{"CHAP", pppCHAP},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			peer, p, err := dialTestPPP(t, tc.auth, "secret")
+			if err != nil {
+				t.Fatalf("NewPPP: %v", err)
+			}
+
+			peer.mu.Lock()
+			authUser, authOK, naked := peer.authUser, peer.authOK, peer.naked
+			peer.mu.Unlock()
+			if !authOK || authUser != "user" {
+				t.Errorf("authentication: ok=%v user=%q", authOK, authUser)
+			}
+			if !naked {
+				t.Error("IPCP addresses were not negotiated via Configure-Nak")
+			}
+			if got := p.LocalIP().String(); got != "10.0.0.2" {
+				t.Errorf("LocalIP = %s, want 10.0.0.2", got)
+			}
+			if got := p.PeerIP().String(); got != "10.0.0.1" {
+				t.Errorf("PeerIP = %s, want 10.0.0.1", got)
+			}
+			if dns := p.DNS(); len(dns) != 2 || dns[0].String() != "8.8.8.8" || dns[1].String() != "8.8.4.4" {
+				t.Errorf("DNS = %v", dns)
+			}
+			if p.LocalIPv6() != nil {
+				t.Error("LocalIPv6 is set without IPv6CP")
+			}
+
+			// IP-пакеты в обе стороны
+			packet := []byte{0x45, 0x00, 0x00, 0x14, 0x7E, 0x7D, 0x01}
+			if err := p.WritePacket(packet); err != nil {
+				t.Fatalf("WritePacket: %v", err)
+			}
+			select {
+			case got := <-peer.packets:
+				if !bytes.Equal(got, packet) {
+					t.Errorf("peer got %x, want %x", got, packet)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("peer did not receive packet")
+			}
+			if err := p.WritePacket([]byte{0x60, 0}); !errors.Is(err, ErrPacketDropped) {
+				t.Errorf("IPv6 without IPv6CP: %v, want ErrPacketDropped", err)
+			}
+
+			peer.send(pppIPv4, packet)
+			buf := make([]byte, 1500)
+			n, err := p.ReadPacket(buf)
+			if err != nil || !bytes.Equal(buf[:n], packet) {
+				t.Errorf("ReadPacket = %x, %v", buf[:n], err)
+			}
+
+			// Close отправляет Terminate-Request и дожидается Terminate-Ack
+			start := time.Now()
+			if err := p.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed >= cpRestartTimer {
+				t.Errorf("Close waited %v for Terminate-Ack", elapsed)
+			}
+			peer.mu.Lock()
+			terminated := peer.terminate
+			peer.mu.Unlock()
+			if !terminated {
+				t.Error("peer did not receive LCP Terminate-Request")
+			}
+			if !errors.Is(p.Err(), ErrPPPClosed) {
+				t.Errorf("Err = %v, want ErrPPPClosed", p.Err())
+			}
+			if _, err := p.ReadPacket(buf); !errors.Is(err, ErrPPPClosed) {
+				t.Errorf("ReadPacket after Close: %v", err)
+			}
+		})
+	}
+}
+
+func TestPPPAuthFailure(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		auth uint16
+	}{
+		{"PAP", pppPAP},
+		{"CHAP", pppCHAP},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := dialTestPPP(t, tc.auth, "wrong")
+			if !errors.Is(err, ErrPPPAuth) {
+				t.Fatalf("NewPPP = %v, want ErrPPPAuth", err)
+			}
+		})
+	}
+}
+
+func TestPPPFCS(t *testing.T) {
+	// Контрольное значение FCS-16 (CRC-16/X-25) для "123456789"
+	if got := ^fcs16(fcsInit, []byte("123456789")); got != 0x906E {
+		t.Errorf("FCS = %#04x, want 0x906e", got)
+	}
+}
+
+func TestPPPFrameRoundTrip(t *testing.T) {
+	info := make([]byte, 256)
+	for i := range info {
+		info[i] = byte(i)
+	}
+	frame := encodeFrame(pppIPv4, info)
+
+	if frame[0] != hdlcFlag || frame[len(frame)-1] != hdlcFlag {
+		t.Fatalf("frame is not delimited by flags: %x", frame)
+	}
+	for i, b := range frame[1 : len(frame)-1] {
+		if b == hdlcFlag || b < 0x20 {
+			t.Fatalf("unescaped byte %#02x at %d", b, i+1)
+		}
+	}
+
+	// Кадр, разрезанный на части, между мусором
+	stream := append([]byte("\r\nGARBAGE"), frame...)
+	stream = append(stream, frame...)
+	var reader frameReader
+	var frames [][]byte
+	for i := 0; i < len(stream); i += 7 {
+		end := min(i+7, len(stream))
+		frames = append(frames, reader.feed(stream[i:end])...)
+	}
+	// Первый "кадр" - мусор до флага, он не проходит проверку FCS
+	var decoded int
+	for _, raw := range frames {
+		protocol, got, err := decodeFrame(raw)
+		if err != nil {
+			continue
+		}
+		decoded++
+		if protocol != pppIPv4 || !bytes.Equal(got, info) {
+			t.Errorf("decoded protocol %#04x info %x", protocol, got)
+		}
+	}
+	if decoded != 2 {
+		t.Errorf("decoded %d frames, want 2", decoded)
+	}
+}
+
+func TestPPPFrameErrors(t *testing.T) {
+	frame := encodeFrame(pppLCP, encodeCP(lcpEchoRequest, 1, []byte{1, 2, 3, 4}))
+	var reader frameReader
+	raw := reader.feed(frame)
+	if len(raw) != 1 {
+		t.Fatalf("got %d frames, want 1", len(raw))
+	}
+	corrupted := append([]byte(nil), raw[0]...)
+	corrupted[len(corrupted)/2] ^= 0x01
+	if _, _, err := decodeFrame(corrupted); !errors.Is(err, errBadFrame) {
+		t.Errorf("corrupted frame: %v, want errBadFrame", err)
+	}
+	if _, _, err := decodeFrame([]byte{0xFF, 0x03}); !errors.Is(err, errBadFrame) {
+		t.Errorf("short frame: %v, want errBadFrame", err)
+	}
+
+	// Кадр с ACFC и PFC: без адреса/управления, протокол одним байтом
+	compressed := []byte{0x21, 0x45, 0x00}
+	fcs := ^fcs16(fcsInit, compressed)
+	compressed = append(compressed, byte(fcs), byte(fcs>>8))
+	protocol, info, err := decodeFrame(compressed)
+	if err != nil || protocol != pppIPv4 || !bytes.Equal(info, []byte{0x45, 0x00}) {
+		t.Errorf("compressed frame = %#04x %x %v", protocol, info, err)
+	}
+}
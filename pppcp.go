@@ -0,0 +1,326 @@
+package gsm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Коды пакетов управляющих протоколов PPP (RFC 1661)
+const (
+	cpConfigureRequest = 1
+	cpConfigureAck     = 2
+	cpConfigureNak     = 3
+	cpConfigureReject  = 4
+	cpTerminateRequest = 5
+	cpTerminateAck     = 6
+	cpCodeReject       = 7
+	lcpProtocolReject  = 8
+	lcpEchoRequest     = 9
+	lcpEchoReply       = 10
+	lcpDiscardRequest  = 11
+)
+
+const (
+	cpRestartTimer  = 3 * time.Second // интервал повтора Configure-Request
+	cpMaxConfigure  = 10              // попыток Configure-Request до отказа
+	cpMaxNakRetries = 5               // Configure-Nak подряд до Configure-Reject
+)
+
+// cpOption опция Configure-Request
+type cpOption struct {
+	typ  byte
+	data []byte
+}
+
+// cpVerdict решение по опции собеседника
+type cpVerdict int
+
+const (
+	cpAck cpVerdict = iota
+	cpNak
+	cpReject
+)
+
+// encodeCP формирует пакет управляющего протокола
+func encodeCP(code, id byte, data []byte) []byte {
+	packet := make([]byte, 4, 4+len(data))
+	packet[0], packet[1] = code, id
+	binary.BigEndian.PutUint16(packet[2:], uint16(4+len(data)))
+	return append(packet, data...)
+}
+
+// decodeCP разбирает пакет управляющего протокола
+func decodeCP(packet []byte) (code, id byte, data []byte, err error) {
+	if len(packet) < 4 {
+		return 0, 0, nil, errBadFrame
+	}
+	length := int(binary.BigEndian.Uint16(packet[2:]))
+	if length < 4 || length > len(packet) {
+		return 0, 0, nil, errBadFrame
+	}
+	return packet[0], packet[1], packet[4:length], nil
+}
+
+// encodeOptions кодирует список опций
+func encodeOptions(options []cpOption) []byte {
+	var data []byte
+	for _, opt := range options {
+		data = append(data, opt.typ, byte(2+len(opt.data)))
+		data = append(data, opt.data...)
+	}
+	return data
+}
+
+// decodeOptions разбирает список опций
+func decodeOptions(data []byte) ([]cpOption, error) {
+	var options []cpOption
+	for len(data) > 0 {
+		if len(data) < 2 || int(data[1]) < 2 || int(data[1]) > len(data) {
+			return nil, errBadFrame
+		}
+		options = append(options, cpOption{typ: data[0], data: data[2:data[1]]})
+		data = data[data[1]:]
+	}
+	return options, nil
+}
+
+// controlProtocol согласование опций LCP, IPCP или IPv6CP (упрощенный автомат RFC 1661:
+// слой открыт, когда подтверждены и наш запрос, и запрос собеседника)
+type controlProtocol struct {
+	link     *PPP
+	protocol uint16
+	name     string
+
+	// request возвращает текущие собственные опции
+	request func() []cpOption
+	// nak принимает значение, предложенное собеседником
+	nak func(cpOption)
+	// reject убирает опцию, которую собеседник не поддерживает
+	reject func(cpOption)
+	// check проверяет опцию собеседника; для cpNak возвращает предлагаемое значение
+	check func(cpOption) (cpVerdict, []byte)
+	// extra обрабатывает коды, специфичные для протокола (LCP Echo и т.д.)
+	extra func(code, id byte, data []byte)
+	// up вызывается один раз при открытии слоя
+	up func()
+
+	mu          sync.Mutex
+	id          byte
+	requestID   byte
+	ackSent     bool
+	ackReceived bool
+	naks        int
+	opened      bool
+	stopped     bool
+	terminated  chan struct{}
+}
+
+// newControlProtocol создает протокол для канала
+func newControlProtocol(link *PPP, protocol uint16, name string) *controlProtocol {
+	return &controlProtocol{
+		link:       link,
+		protocol:   protocol,
+		name:       name,
+		terminated: make(chan struct{}),
+	}
+}
+
+// start отправляет Configure-Request и повторяет его, пока собеседник не подтвердит
+func (cp *controlProtocol) start() {
+	cp.sendRequest()
+	go func() {
+		ticker := time.NewTicker(cpRestartTimer)
+		defer ticker.Stop()
+		for attempt := 1; ; attempt++ {
+			select {
+			case <-cp.link.done:
+				return
+			case <-ticker.C:
+			}
+
+			cp.mu.Lock()
+			done := cp.ackReceived || cp.stopped
+			cp.mu.Unlock()
+			if done {
+				return
+			}
+			if attempt >= cpMaxConfigure {
+				cp.link.fail(fmt.Errorf("%s negotiation timeout", cp.name))
+				return
+			}
+			cp.sendRequest()
+		}
+	}()
+}
+
+// stop прекращает согласование (собеседник отверг протокол)
+func (cp *controlProtocol) stop() {
+	cp.mu.Lock()
+	cp.stopped = true
+	cp.mu.Unlock()
+}
+
+// isOpened проверяет, что слой открыт
+func (cp *controlProtocol) isOpened() bool {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.opened
+}
+
+// sendRequest отправляет Configure-Request с новым идентификатором
+func (cp *controlProtocol) sendRequest() {
+	cp.mu.Lock()
+	cp.id++
+	cp.requestID = cp.id
+	cp.ackReceived = false
+	id := cp.id
+	cp.mu.Unlock()
+	cp.send(cpConfigureRequest, id, encodeOptions(cp.request()))
+}
+
+// send отправляет пакет протокола
+func (cp *controlProtocol) send(code, id byte, data []byte) {
+	cp.link.writeFrame(cp.protocol, encodeCP(code, id, data))
+}
+
+// nextID возвращает идентификатор для пакетов вне согласования (Echo, Terminate)
+func (cp *controlProtocol) nextID() byte {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.id++
+	return cp.id
+}
+
+// terminate отправляет Terminate-Request и ждет Terminate-Ack
+func (cp *controlProtocol) terminate(timeout time.Duration) {
+	cp.send(cpTerminateRequest, cp.nextID(), nil)
+	select {
+	case <-cp.terminated:
+	case <-cp.link.done:
+	case <-time.After(timeout):
+	}
+}
+
+// handle обрабатывает входящий пакет протокола
+func (cp *controlProtocol) handle(packet []byte) {
+	code, id, data, err := decodeCP(packet)
+	if err != nil {
+		return
+	}
+
+	switch code {
+	case cpConfigureRequest:
+		cp.handleRequest(id, data)
+
+	case cpConfigureAck:
+		cp.mu.Lock()
+		if id == cp.requestID {
+			cp.ackReceived = true
+		}
+		cp.mu.Unlock()
+		cp.checkOpened()
+
+	case cpConfigureNak, cpConfigureReject:
+		cp.mu.Lock()
+		current := id == cp.requestID
+		cp.mu.Unlock()
+		options, err := decodeOptions(data)
+		if !current || err != nil {
+			return
+		}
+		for _, opt := range options {
+			if code == cpConfigureNak {
+				cp.nak(opt)
+			} else {
+				cp.reject(opt)
+			}
+		}
+		cp.sendRequest()
+
+	case cpTerminateRequest:
+		cp.send(cpTerminateAck, id, nil)
+		cp.link.fail(fmt.Errorf("%w: %s terminated by peer", ErrPPPClosed, cp.name))
+
+	case cpTerminateAck:
+		select {
+		case <-cp.terminated:
+		default:
+			close(cp.terminated)
+		}
+
+	case cpCodeReject:
+		// Собеседник не понял наш пакет - для используемого набора кодов не критично
+
+	default:
+		if cp.extra != nil {
+			cp.extra(code, id, data)
+		} else {
+			cp.send(cpCodeReject, cp.nextID(), packet)
+		}
+	}
+}
+
+// handleRequest отвечает на Configure-Request собеседника
+func (cp *controlProtocol) handleRequest(id byte, data []byte) {
+	options, err := decodeOptions(data)
+	if err != nil {
+		return
+	}
+
+	var naks, nakked, rejects []cpOption
+	for _, opt := range options {
+		verdict, suggestion := cp.check(opt)
+		switch verdict {
+		case cpNak:
+			naks = append(naks, cpOption{typ: opt.typ, data: suggestion})
+			nakked = append(nakked, opt)
+		case cpReject:
+			rejects = append(rejects, opt)
+		}
+	}
+
+	cp.mu.Lock()
+	if len(rejects) == 0 && len(naks) > 0 {
+		// Собеседник не соглашается с нашим значением - перестаем его предлагать
+		cp.naks++
+		if cp.naks > cpMaxNakRetries {
+			rejects, naks = nakked, nil
+		}
+	}
+	cp.mu.Unlock()
+
+	switch {
+	case len(rejects) > 0:
+		cp.send(cpConfigureReject, id, encodeOptions(rejects))
+	case len(naks) > 0:
+		cp.send(cpConfigureNak, id, encodeOptions(naks))
+	default:
+		cp.send(cpConfigureAck, id, data)
+		cp.mu.Lock()
+		reopen := cp.opened
+		cp.ackSent = true
+		cp.naks = 0
+		cp.mu.Unlock()
+		if reopen {
+			// Повторное согласование по инициативе собеседника
+			cp.sendRequest()
+			return
+		}
+		cp.checkOpened()
+	}
+}
+
+// checkOpened открывает слой, когда обе стороны подтвердили опции
+func (cp *controlProtocol) checkOpened() {
+	cp.mu.Lock()
+	open := cp.ackSent && cp.ackReceived && !cp.opened
+	if open {
+		cp.opened = true
+	}
+	cp.mu.Unlock()
+	if open && cp.up != nil {
+		go cp.up()
+	}
+}
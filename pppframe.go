@@ -0,0 +1,133 @@
+package gsm
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Номера протоколов PPP
+const (
+	pppIPv4   uint16 = 0x0021
+	pppIPv6   uint16 = 0x0057
+	pppIPCP   uint16 = 0x8021
+	pppIPv6CP uint16 = 0x8057
+	pppLCP    uint16 = 0xC021
+	pppPAP    uint16 = 0xC023
+	pppCHAP   uint16 = 0xC223
+)
+
+const (
+	hdlcFlag   = 0x7E
+	hdlcEscape = 0x7D
+	hdlcXOR    = 0x20
+	fcsInit    = 0xFFFF
+	fcsGood    = 0xF0B8 // остаток FCS-16 для кадра без ошибок (RFC 1662)
+
+	maxFrameSize = 65536 // предел размера собираемого кадра
+)
+
+// fcsTable таблица FCS-16 (полином x^16 + x^12 + x^5 + 1, RFC 1662)
+var fcsTable = func() [256]uint16 {
+	var table [256]uint16
+	for b := 0; b < 256; b++ {
+		v := uint16(b)
+		for i := 0; i < 8; i++ {
+			if v&1 != 0 {
+				v = v>>1 ^ 0x8408
+			} else {
+				v >>= 1
+			}
+		}
+		table[b] = v
+	}
+	return table
+}()
+
+// fcs16 вычисляет FCS-16 по данным
+func fcs16(fcs uint16, data []byte) uint16 {
+	for _, b := range data {
+		fcs = fcs>>8 ^ fcsTable[(fcs^uint16(b))&0xFF]
+	}
+	return fcs
+}
+
+// encodeFrame формирует HDLC-подобный кадр (RFC 1662) с полями адреса и управления.
+// Экранируются все управляющие символы - это допустимо при любом ACCM.
+func encodeFrame(protocol uint16, info []byte) []byte {
+	raw := make([]byte, 0, len(info)+6)
+	raw = append(raw, 0xFF, 0x03)
+	raw = binary.BigEndian.AppendUint16(raw, protocol)
+	raw = append(raw, info...)
+	fcs := ^fcs16(fcsInit, raw)
+	raw = append(raw, byte(fcs), byte(fcs>>8))
+
+	frame := make([]byte, 0, len(raw)*2+2)
+	frame = append(frame, hdlcFlag)
+	for _, b := range raw {
+		if b < 0x20 || b == hdlcFlag || b == hdlcEscape {
+			frame = append(frame, hdlcEscape, b^hdlcXOR)
+		} else {
+			frame = append(frame, b)
+		}
+	}
+	return append(frame, hdlcFlag)
+}
+
+// errBadFrame кадр с неверной контрольной суммой или заголовком
+var errBadFrame = errors.New("bad PPP frame")
+
+// decodeFrame проверяет FCS и возвращает протокол и данные кадра (без флагов, уже без экранирования).
+// Поля адреса/управления и протокол могут быть сжаты (ACFC, PFC).
+func decodeFrame(raw []byte) (uint16, []byte, error) {
+	if len(raw) < 4 || fcs16(fcsInit, raw) != fcsGood {
+		return 0, nil, errBadFrame
+	}
+	data := raw[:len(raw)-2]
+	if len(data) >= 2 && data[0] == 0xFF && data[1] == 0x03 {
+		data = data[2:]
+	}
+	if len(data) == 0 {
+		return 0, nil, errBadFrame
+	}
+	// Сжатое поле протокола - один нечетный байт
+	if data[0]&1 == 1 {
+		return uint16(data[0]), data[1:], nil
+	}
+	if len(data) < 2 {
+		return 0, nil, errBadFrame
+	}
+	return binary.BigEndian.Uint16(data), data[2:], nil
+}
+
+// frameReader собирает кадры из потока байт
+type frameReader struct {
+	buf     []byte
+	escaped bool
+}
+
+// feed обрабатывает прочитанные байты и возвращает завершенные кадры (без флагов и экранирования)
+func (r *frameReader) feed(data []byte) [][]byte {
+	var frames [][]byte
+	for _, b := range data {
+		switch {
+		case b == hdlcFlag:
+			if len(r.buf) > 0 && !r.escaped {
+				frames = append(frames, r.buf)
+			}
+			r.buf = nil
+			r.escaped = false
+		case b == hdlcEscape:
+			r.escaped = true
+		case r.escaped:
+			r.buf = append(r.buf, b^hdlcXOR)
+			r.escaped = false
+		default:
+			r.buf = append(r.buf, b)
+		}
+		// Поток без флагов (мусор или текст модема) не должен расти бесконечно
+		if len(r.buf) > maxFrameSize {
+			r.buf = r.buf[:0]
+		}
+	}
+	return frames
+}
@@ -0,0 +1,412 @@
+package qmi
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// Сообщения сервиса CTL
+const (
+	ctlGetVersionInfo   = 0x0021
+	ctlGetClientID      = 0x0022
+	ctlReleaseClientID  = 0x0023
+	broadcastClientID   = 0xFF
+	defaultTimeout      = 10 * time.Second
+	probeAttempts       = 5
+	releaseTimeout      = time.Second
+	maxEventQueueLength = 100
+)
+
+// ErrClosed клиент закрыт или устройство отключено
+var ErrClosed = errors.New("QMI client closed")
+
+// Config параметры клиента QMI
+type Config struct {
+	Device  string        // Устройство управления ("/dev/cdc-wdm0")
+	Timeout time.Duration // Тайм-аут запроса (по умолчанию 10 секунд)
+}
+
+// Client клиент QMI. Идентификаторы клиентов сервисов выделяются при первом обращении
+// к сервису и освобождаются в Close.
+type Client struct {
+	rw      io.ReadWriteCloser
+	timeout time.Duration
+
+	writeMu sync.Mutex
+	allocMu sync.Mutex // Сериализует выделение идентификаторов клиентов
+
+	mu          sync.Mutex
+	clients     map[Service]uint8
+	pending     map[transactionKey]chan *Message
+	indications []*indicationHandler
+	nextTx      uint16
+	nextCTLTx   uint8
+	smsStorage  uint8
+	events      chan gsm.Event
+	eventsOnce  sync.Once
+	eventsErr   error
+
+	done      chan struct{}
+	err       error
+	closeOnce sync.Once
+}
+
+// transactionKey связывает ответ с ожидающим запросом
+type transactionKey struct {
+	service     Service
+	client      uint8
+	transaction uint16
+}
+
+// indicationHandler обработчик уведомлений сервиса
+type indicationHandler struct {
+	service Service
+	id      uint16
+	handle  func(*Message)
+}
+
+// Open открывает устройство управления QMI и проверяет, что модем отвечает
+func Open(config Config) (*Client, error) {
+	if config.Device == "" {
+		config.Device = "/dev/cdc-wdm0"
+	}
+	f, err := os.OpenFile(config.Device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", config.Device, err)
+	}
+	c, err := NewClient(f, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", config.Device, err)
+	}
+	return c, nil
+}
+
+// NewClient создает клиент поверх произвольного канала QMUX (например, записи обмена
+// или прокси). Канал закрывается вместе с клиентом.
+func NewClient(rw io.ReadWriteCloser, config Config) (*Client, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	c := &Client{
+		rw:         rw,
+		timeout:    config.Timeout,
+		clients:    make(map[Service]uint8),
+		pending:    make(map[transactionKey]chan *Message),
+		smsStorage: storageUIM,
+		events:     make(chan gsm.Event, maxEventQueueLength),
+		done:       make(chan struct{}),
+	}
+	go c.readLoop()
+
+	if err := c.probe(); err != nil {
+		c.shutdown(err)
+		rw.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// probe ждет готовности модема: сразу после подключения первые запросы могут теряться
+func (c *Client) probe() error {
+	var err error
+	for i := 0; i < probeAttempts; i++ {
+		req := &Message{Service: ServiceCTL, ID: ctlGetVersionInfo}
+		if _, err = c.requestTimeout(req, time.Second); err == nil || errors.Is(err, ErrClosed) {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("modem does not respond to QMI: %w", err)
+	}
+	return nil
+}
+
+// Request отправляет запрос и ждет ответ. Для сервисов, кроме CTL, при req.Client == 0
+// используется общий идентификатор клиента. Ошибка из TLV результата возвращается
+// вместе с ответом, в котором могут быть дополнительные причины отказа.
+func (c *Client) Request(req *Message) (*Message, error) {
+	return c.requestTimeout(req, c.timeout)
+}
+
+// requestTimeout отправляет запрос с указанным тайм-аутом
+func (c *Client) requestTimeout(req *Message, timeout time.Duration) (*Message, error) {
+	if req.Service != ServiceCTL && req.Client == 0 {
+		client, err := c.clientID(req.Service)
+		if err != nil {
+			return nil, err
+		}
+		req.Client = client
+	}
+	req.Type = MessageRequest
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	if req.Service == ServiceCTL {
+		// У CTL номер транзакции занимает один октет
+		c.nextCTLTx++
+		if c.nextCTLTx == 0 {
+			c.nextCTLTx = 1
+		}
+		req.Transaction = uint16(c.nextCTLTx)
+	} else {
+		c.nextTx++
+		if c.nextTx == 0 {
+			c.nextTx = 1
+		}
+		req.Transaction = c.nextTx
+	}
+	key := transactionKey{req.Service, req.Client, req.Transaction}
+	ch := make(chan *Message, 1)
+	c.pending[key] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, key)
+		c.mu.Unlock()
+	}()
+
+	c.writeMu.Lock()
+	_, err := c.rw.Write(req.Marshal())
+	c.writeMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to send %s request 0x%04X: %w", req.Service, req.ID, err)
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case resp := <-ch:
+		return resp, resp.Err()
+	case <-c.done:
+		return nil, c.Err()
+	case <-timer.C:
+		return nil, fmt.Errorf("%s request 0x%04X timed out", req.Service, req.ID)
+	}
+}
+
+// call отправляет запрос общему клиенту сервиса
+func (c *Client) call(service Service, id uint16, tlvs ...TLV) (*Message, error) {
+	return c.Request(&Message{Service: service, ID: id, TLVs: tlvs})
+}
+
+// clientID возвращает общий идентификатор клиента сервиса, выделяя его при первом обращении
+func (c *Client) clientID(service Service) (uint8, error) {
+	c.allocMu.Lock()
+	defer c.allocMu.Unlock()
+
+	c.mu.Lock()
+	client, ok := c.clients[service]
+	c.mu.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	client, err := c.allocateClient(service)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.clients[service] = client
+	c.mu.Unlock()
+	return client, nil
+}
+
+// allocateClient выделяет новый идентификатор клиента сервиса (CTL Get Client ID)
+func (c *Client) allocateClient(service Service) (uint8, error) {
+	resp, err := c.Request(&Message{
+		Service: ServiceCTL,
+		ID:      ctlGetClientID,
+		TLVs:    []TLV{{Type: 0x01, Value: u8(uint8(service))}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate %s client: %w", service, err)
+	}
+	value, ok := resp.TLV(0x01)
+	if !ok || len(value) < 2 {
+		return 0, fmt.Errorf("failed to allocate %s client: %w", service, ErrMalformed)
+	}
+	return value[1], nil
+}
+
+// releaseClient освобождает идентификатор клиента сервиса (CTL Release Client ID)
+func (c *Client) releaseClient(service Service, client uint8) error {
+	_, err := c.requestTimeout(&Message{
+		Service: ServiceCTL,
+		ID:      ctlReleaseClientID,
+		TLVs:    []TLV{{Type: 0x01, Value: []byte{uint8(service), client}}},
+	}, releaseTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to release %s client %d: %w", service, client, err)
+	}
+	return nil
+}
+
+// onIndication регистрирует обработчик уведомлений и возвращает функцию отмены.
+// Обработчик вызывается из горутины чтения и не должен отправлять запросы.
+func (c *Client) onIndication(service Service, id uint16, handle func(*Message)) func() {
+	h := &indicationHandler{service, id, handle}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.indications = append(c.indications, h)
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, registered := range c.indications {
+			if registered == h {
+				c.indications = append(c.indications[:i], c.indications[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// readLoop читает сообщения QMUX и распределяет ответы и уведомления
+func (c *Client) readLoop() {
+	r := bufio.NewReader(c.rw)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+		if b != qmuxInterface {
+			// Рассинхронизация потока - ищем начало следующего кадра
+			continue
+		}
+		header := []byte{b, 0, 0}
+		if _, err := io.ReadFull(r, header[1:]); err != nil {
+			c.shutdown(err)
+			return
+		}
+		frame := make([]byte, 1+int(binary.LittleEndian.Uint16(header[1:])))
+		copy(frame, header)
+		if _, err := io.ReadFull(r, frame[3:]); err != nil {
+			c.shutdown(err)
+			return
+		}
+		msg, err := Unmarshal(frame)
+		if err != nil {
+			continue
+		}
+		c.dispatch(msg)
+	}
+}
+
+// dispatch передает ответ ожидающему запросу или уведомление обработчикам
+func (c *Client) dispatch(msg *Message) {
+	c.mu.Lock()
+	if msg.Type == MessageResponse {
+		ch, ok := c.pending[transactionKey{msg.Service, msg.Client, msg.Transaction}]
+		c.mu.Unlock()
+		if ok {
+			// Канал не закрывается при завершении работы; повторный ответ с тем же
+			// номером транзакции отбрасывается
+			select {
+			case ch <- msg:
+			default:
+			}
+		}
+		return
+	}
+	if msg.Type != MessageIndication {
+		c.mu.Unlock()
+		return
+	}
+	// Адресата (свой клиент или широковещательное 0xFF) проверяет обработчик
+	var handlers []func(*Message)
+	for _, h := range c.indications {
+		if h.service == msg.Service && h.id == msg.ID {
+			handlers = append(handlers, h.handle)
+		}
+	}
+	c.mu.Unlock()
+	for _, handle := range handlers {
+		handle(msg)
+	}
+}
+
+// shutdown завершает ожидающие запросы ошибкой: они ждут закрытия c.done
+func (c *Client) shutdown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	if errors.Is(err, ErrClosed) || errors.Is(err, io.EOF) || errors.Is(err, os.ErrClosed) {
+		c.err = ErrClosed
+	} else {
+		c.err = fmt.Errorf("%w: %w", ErrClosed, err)
+	}
+	close(c.done)
+}
+
+// emitEvent отправляет событие в канал без блокировки
+func (c *Client) emitEvent(event gsm.Event) {
+	select {
+	case c.events <- event:
+	default:
+		// Канал полон, пропускаем событие
+	}
+}
+
+// GetEventChannel возвращает канал событий: EventNewSMS и EventNetworkChange
+func (c *Client) GetEventChannel() (<-chan gsm.Event, error) {
+	if err := c.enableEvents(); err != nil {
+		return nil, err
+	}
+	return c.events, nil
+}
+
+// Done закрывается, когда устройство отключено или клиент закрыт
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err возвращает причину завершения работы клиента
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close освобождает идентификаторы клиентов и закрывает устройство
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		clients := make(map[Service]uint8, len(c.clients))
+		for service, client := range c.clients {
+			clients[service] = client
+		}
+		c.mu.Unlock()
+
+		for service, client := range clients {
+			c.releaseClient(service, client)
+		}
+		c.shutdown(ErrClosed)
+		err = c.rw.Close()
+	})
+	return err
+}
+
+var _ gsm.Device = (*Client)(nil)
+
+// addressed проверяет, что уведомление адресовано общему клиенту сервиса или всем клиентам
+func (c *Client) addressed(msg *Message) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	client, ok := c.clients[msg.Service]
+	return msg.Client == broadcastClientID || ok && msg.Client == client
+}
@@ -0,0 +1,358 @@
+package qmi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// step шаг записи обмена: запрос клиента и ответ модема
+type step struct {
+	service Service
+	id      uint16
+	req     []TLV      // Ожидаемые TLV запроса (nil - не проверяются)
+	resp    []TLV      // TLV ответа, кроме результата
+	code    ErrorCode  // Ошибка в TLV результата
+	silent  bool       // Модем не отвечает
+	twice   bool       // Модем повторяет ответ
+	after   []*Message // Уведомления после ответа
+}
+
+// fakeModem модем на другом конце net.Pipe: проигрывает запись обмена, а запросы CTL
+// (версии, выделение и освобождение идентификаторов клиентов) обслуживает сам
+type fakeModem struct {
+	t    *testing.T
+	conn net.Conn
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	steps   []step
+	clients map[Service]uint8
+	nextID  uint8
+	release map[Service][]uint8
+	silent  chan *Message
+}
+
+// newFakeModem создает модем и клиента поверх него
+func newFakeModem(t *testing.T, steps ...step) (*fakeModem, *Client) {
+	t.Helper()
+	local, remote := net.Pipe()
+	f := &fakeModem{
+		t:       t,
+		conn:    remote,
+		steps:   steps,
+		clients: make(map[Service]uint8),
+		nextID:  4,
+		release: make(map[Service][]uint8),
+		silent:  make(chan *Message, 4),
+	}
+	go f.serve()
+
+	c, err := NewClient(local, Config{Timeout: 2 * time.Second})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+		remote.Close()
+	})
+	return f, c
+}
+
+// expect добавляет шаги в запись обмена
+func (f *fakeModem) expect(steps ...step) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.steps = append(f.steps, steps...)
+}
+
+// done проверяет, что запись обмена проиграна целиком
+func (f *fakeModem) done() {
+	f.t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.steps {
+		f.t.Errorf("request %s 0x%04X was not sent", s.service, s.id)
+	}
+}
+
+// client возвращает идентификатор, выделенный клиенту сервиса
+func (f *fakeModem) client(service Service) uint8 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.clients[service]
+}
+
+// send отправляет сообщение модема клиенту
+func (f *fakeModem) send(msg *Message) {
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+	f.conn.Write(msg.Marshal())
+}
+
+// reply отправляет ответ на запрос
+func (f *fakeModem) reply(req *Message, code ErrorCode, tlvs ...TLV) {
+	result := u16(0)
+	if code != 0 {
+		result = u16(1)
+	}
+	f.send(&Message{
+		Service:     req.Service,
+		Client:      req.Client,
+		Type:        MessageResponse,
+		Transaction: req.Transaction,
+		ID:          req.ID,
+		TLVs:        append([]TLV{{Type: tlvResult, Value: append(result, u16(uint16(code))...)}}, tlvs...),
+	})
+}
+
+// serve читает запросы клиента и отвечает на них
+func (f *fakeModem) serve() {
+	header := make([]byte, 3)
+	for {
+		if _, err := io.ReadFull(f.conn, header); err != nil {
+			return
+		}
+		frame := make([]byte, 1+int(binary.LittleEndian.Uint16(header[1:])))
+		copy(frame, header)
+		if _, err := io.ReadFull(f.conn, frame[3:]); err != nil {
+			return
+		}
+		req, err := Unmarshal(frame)
+		if err != nil {
+			f.t.Errorf("client sent malformed frame %x: %v", frame, err)
+			continue
+		}
+		if req.Type != MessageRequest {
+			f.t.Errorf("client sent message of type %d", req.Type)
+			continue
+		}
+		if req.Service == ServiceCTL {
+			f.serveCTL(req)
+			continue
+		}
+		f.replay(req)
+	}
+}
+
+// serveCTL отвечает на запросы CTL
+func (f *fakeModem) serveCTL(req *Message) {
+	switch req.ID {
+	case ctlGetVersionInfo:
+		// Список сервисов: количество, затем сервис и версия major/minor
+		f.reply(req, 0, TLV{Type: 0x01, Value: []byte{2, 0x00, 1, 0, 0x02, 1, 0}})
+	case ctlGetClientID:
+		value, _ := req.TLV(0x01)
+		service := Service(value[0])
+		f.mu.Lock()
+		f.nextID++
+		id := f.nextID
+		if _, ok := f.clients[service]; !ok {
+			f.clients[service] = id
+		}
+		f.mu.Unlock()
+		f.reply(req, 0, TLV{Type: 0x01, Value: []byte{byte(service), id}})
+	case ctlReleaseClientID:
+		value, _ := req.TLV(0x01)
+		f.mu.Lock()
+		f.release[Service(value[0])] = append(f.release[Service(value[0])], value[1])
+		f.mu.Unlock()
+		f.reply(req, 0, TLV{Type: 0x01, Value: value})
+	default:
+		f.reply(req, ErrorNotSupported)
+	}
+}
+
+// replay сверяет запрос со следующим шагом записи и отвечает
+func (f *fakeModem) replay(req *Message) {
+	f.mu.Lock()
+	if len(f.steps) == 0 {
+		f.mu.Unlock()
+		f.t.Errorf("unexpected request %s 0x%04X", req.Service, req.ID)
+		f.reply(req, ErrorNotSupported)
+		return
+	}
+	s := f.steps[0]
+	f.steps = f.steps[1:]
+	f.mu.Unlock()
+
+	if req.Service != s.service || req.ID != s.id {
+		f.t.Errorf("request %s 0x%04X, want %s 0x%04X", req.Service, req.ID, s.service, s.id)
+		f.reply(req, ErrorNotSupported)
+		return
+	}
+	if s.req != nil && !equalTLVs(req.TLVs, s.req) {
+		f.t.Errorf("request %s 0x%04X TLVs %v, want %v", req.Service, req.ID, req.TLVs, s.req)
+	}
+	if s.silent {
+		f.silent <- req
+		return
+	}
+	f.reply(req, s.code, s.resp...)
+	if s.twice {
+		f.reply(req, s.code, s.resp...)
+	}
+	for _, ind := range s.after {
+		f.send(ind)
+	}
+}
+
+// equalTLVs сравнивает списки TLV
+func equalTLVs(a, b []TLV) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Type != b[i].Type || !bytes.Equal(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// indication создает уведомление сервиса
+func indication(service Service, client uint8, id uint16, tlvs ...TLV) *Message {
+	return &Message{Service: service, Client: client, Type: MessageIndication, ID: id, TLVs: tlvs}
+}
+
+func TestUnmarshalRecordedFrame(t *testing.T) {
+	// Ответ DMS Get Manufacturer: клиент 1, транзакция 1, "QUALCOMM"
+	frame, _ := hex.DecodeString("011e0080020102010021001200" + "02040000000000" + "0108005155414c434f4d4d")
+	msg, err := Unmarshal(frame)
+	if err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if msg.Service != ServiceDMS || msg.Client != 1 || msg.Type != MessageResponse ||
+		msg.Transaction != 1 || msg.ID != dmsGetManufacturer {
+		t.Errorf("header = %+v", msg)
+	}
+	if err := msg.Err(); err != nil {
+		t.Errorf("Err = %v", err)
+	}
+	if value, _ := msg.TLV(0x01); string(value) != "QUALCOMM" {
+		t.Errorf("TLV 0x01 = %q", value)
+	}
+
+	// Кодирование отличается только флагами QMUX (0x00 - отправитель клиент)
+	encoded := msg.Marshal()
+	encoded[3] = 0x80
+	if !bytes.Equal(encoded, frame) {
+		t.Errorf("Marshal = %x, want %x", encoded, frame)
+	}
+
+	for _, bad := range []string{"", "02", "011f0080020102010021001200", "0110008002010201002100ff00020400000000"} {
+		b, _ := hex.DecodeString(bad)
+		if _, err := Unmarshal(b); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Unmarshal(%s) = %v, want ErrMalformed", bad, err)
+		}
+	}
+}
+
+func TestClientIDsAndClose(t *testing.T) {
+	f, c := newFakeModem(t,
+		step{service: ServiceDMS, id: dmsGetModel, resp: []TLV{{Type: 0x01, Value: []byte("EC25")}}},
+		step{service: ServiceNAS, id: nasGetSignalStrength, resp: []TLV{{Type: 0x01, Value: []byte{0xBF, 0x08}}}},
+		step{service: ServiceDMS, id: dmsGetRevision, resp: []TLV{{Type: 0x01, Value: []byte("EC25EFAR06A06M4G")}}},
+	)
+	if model, err := c.GetModel(); err != nil || model != "EC25" {
+		t.Fatalf("GetModel = %q, %v", model, err)
+	}
+	signal, err := c.GetSignalQuality()
+	if err != nil || signal.RSSI != 24 {
+		t.Fatalf("GetSignalQuality = %+v, %v", signal, err)
+	}
+	// Идентификатор клиента выделяется один раз на сервис
+	if revision, err := c.GetRevision(); err != nil || revision != "EC25EFAR06A06M4G" {
+		t.Fatalf("GetRevision = %q, %v", revision, err)
+	}
+	f.done()
+
+	dms, nas := f.client(ServiceDMS), f.client(ServiceNAS)
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	f.mu.Lock()
+	released := f.release
+	f.mu.Unlock()
+	if len(released) != 2 || len(released[ServiceDMS]) != 1 || released[ServiceDMS][0] != dms ||
+		len(released[ServiceNAS]) != 1 || released[ServiceNAS][0] != nas {
+		t.Errorf("released clients %v, want DMS %d and NAS %d", released, dms, nas)
+	}
+
+	select {
+	case <-c.Done():
+	default:
+		t.Error("Done is not closed after Close")
+	}
+	if !errors.Is(c.Err(), ErrClosed) {
+		t.Errorf("Err = %v, want ErrClosed", c.Err())
+	}
+	if _, err := c.GetModel(); !errors.Is(err, ErrClosed) {
+		t.Errorf("request after Close: %v, want ErrClosed", err)
+	}
+}
+
+func TestErrorResult(t *testing.T) {
+	// Код без названия в errorNames
+	const code ErrorCode = 0x0010
+	f, c := newFakeModem(t,
+		step{service: ServiceDMS, id: dmsGetMSISDN, code: code},
+	)
+	_, err := c.GetSIMNumber()
+	if !IsError(err, code) {
+		t.Errorf("GetSIMNumber = %v, want QMI error 0x%04X", err, uint16(code))
+	}
+	f.done()
+}
+
+func TestDuplicateResponse(t *testing.T) {
+	// Повторный ответ не должен блокировать горутину чтения
+	f, c := newFakeModem(t,
+		step{service: ServiceDMS, id: dmsGetModel, resp: []TLV{{Type: 0x01, Value: []byte("EC25")}}, twice: true},
+		step{service: ServiceDMS, id: dmsGetModel, resp: []TLV{{Type: 0x01, Value: []byte("EC21")}}},
+	)
+	if model, err := c.GetModel(); err != nil || model != "EC25" {
+		t.Fatalf("GetModel = %q, %v", model, err)
+	}
+	if model, err := c.GetModel(); err != nil || model != "EC21" {
+		t.Fatalf("second GetModel = %q, %v", model, err)
+	}
+	f.done()
+}
+
+func TestDisconnectWithPendingRequests(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		f, c := newFakeModem(t,
+			step{service: ServiceDMS, id: dmsGetModel, silent: true},
+		)
+		result := make(chan error, 1)
+		go func() {
+			_, err := c.GetModel()
+			result <- err
+		}()
+
+		req := <-f.silent
+		// Ответ и отключение устройства одновременно: запрос завершается без паники
+		go f.reply(req, 0, TLV{Type: 0x01, Value: []byte("EC25")})
+		f.conn.Close()
+
+		select {
+		case err := <-result:
+			if err != nil && !errors.Is(err, ErrClosed) {
+				t.Fatalf("GetModel = %v, want nil or ErrClosed", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("request was not completed after disconnect")
+		}
+		<-c.Done()
+		if !errors.Is(c.Err(), ErrClosed) {
+			t.Errorf("Err = %v, want ErrClosed", c.Err())
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package qmi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Сообщения сервиса DMS
+const (
+	dmsGetMSISDN       = 0x0020
+	dmsGetManufacturer = 0x0021
+	dmsGetModel        = 0x0022
+	dmsGetRevision     = 0x0023
+	dmsGetIDs          = 0x0025
+	dmsUIMGetIMSI      = 0x0043
+)
+
+// dmsString запрашивает строковый параметр устройства из TLV 0x01
+func (c *Client) dmsString(id uint16, name string) (string, error) {
+	resp, err := c.call(ServiceDMS, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s: %w", name, err)
+	}
+	value, ok := resp.TLV(0x01)
+	if !ok {
+		return "", fmt.Errorf("failed to get %s: %w", name, ErrMalformed)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// GetManufacturer возвращает производителя модема
+func (c *Client) GetManufacturer() (string, error) {
+	return c.dmsString(dmsGetManufacturer, "manufacturer")
+}
+
+// GetModel возвращает модель модема
+func (c *Client) GetModel() (string, error) {
+	return c.dmsString(dmsGetModel, "model")
+}
+
+// GetRevision возвращает версию прошивки
+func (c *Client) GetRevision() (string, error) {
+	return c.dmsString(dmsGetRevision, "revision")
+}
+
+// GetSIMNumber возвращает номер телефона (MSISDN), записанный на SIM-карте
+func (c *Client) GetSIMNumber() (string, error) {
+	return c.dmsString(dmsGetMSISDN, "MSISDN")
+}
+
+// GetIMEI возвращает IMEI модема
+func (c *Client) GetIMEI() (string, error) {
+	resp, err := c.call(ServiceDMS, dmsGetIDs)
+	if err != nil {
+		return "", fmt.Errorf("failed to get IMEI: %w", err)
+	}
+	// 0x10 - ESN, 0x11 - IMEI, 0x12 - MEID
+	imei, ok := resp.TLV(0x11)
+	if !ok || len(imei) == 0 {
+		return "", fmt.Errorf("failed to get IMEI: modem has no IMEI")
+	}
+	return string(imei), nil
+}
+
+// GetIMSI возвращает IMSI SIM-карты. Новые модемы не поддерживают DMS UIM Get IMSI -
+// тогда IMSI читается из EF IMSI через сервис UIM.
+func (c *Client) GetIMSI() (string, error) {
+	resp, err := c.call(ServiceDMS, dmsUIMGetIMSI)
+	if err == nil {
+		if imsi, ok := resp.TLV(0x01); ok && len(imsi) >= 6 {
+			return string(imsi), nil
+		}
+	}
+	imsi, uimErr := c.readIMSI()
+	if uimErr != nil {
+		return "", fmt.Errorf("failed to get IMSI: %w", uimErr)
+	}
+	return imsi, nil
+}
@@ -0,0 +1,30 @@
+package qmi
+
+import "testing"
+
+func TestDMSTranscript(t *testing.T) {
+	f, c := newFakeModem(t,
+		step{service: ServiceDMS, id: dmsGetManufacturer, resp: []TLV{{Type: 0x01, Value: []byte("QUALCOMM INCORPORATED ")}}},
+		step{service: ServiceDMS, id: dmsGetIDs, resp: []TLV{
+			{Type: 0x10, Value: []byte("0")},
+			{Type: 0x11, Value: []byte("861234567890123")},
+		}},
+		// Новые прошивки не поддерживают DMS UIM Get IMSI - IMSI читается через UIM
+		step{service: ServiceDMS, id: dmsUIMGetIMSI, code: ErrorNotSupported},
+	)
+
+	if manufacturer, err := c.GetManufacturer(); err != nil || manufacturer != "QUALCOMM INCORPORATED" {
+		t.Errorf("GetManufacturer = %q, %v", manufacturer, err)
+	}
+	if imei, err := c.GetIMEI(); err != nil || imei != "861234567890123" {
+		t.Errorf("GetIMEI = %q, %v", imei, err)
+	}
+
+	f.expect(step{service: ServiceUIM, id: uimReadTransparent, resp: []TLV{
+		{Type: 0x11, Value: append(u16(9), 0x08, 0x29, 0x05, 0x10, 0x32, 0x54, 0x76, 0x98, 0x10)},
+	}})
+	if imsi, err := c.GetIMSI(); err != nil || imsi != "250012345678901" {
+		t.Errorf("GetIMSI = %q, %v", imsi, err)
+	}
+	f.done()
+}
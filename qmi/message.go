@@ -0,0 +1,320 @@
+// Package qmi реализует клиент протокола Qualcomm MSM Interface для модемов, управляемых
+// через символьное устройство /dev/cdc-wdmN (драйвер qmi_wwan). Поддерживаются сервисы
+// CTL, DMS, NAS, WDS, WMS и UIM; Client реализует интерфейс gsm.Device.
+package qmi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Service идентификатор сервиса QMI
+type Service uint8
+
+const (
+	ServiceCTL Service = 0x00 // Управление: выделение идентификаторов клиентов
+	ServiceWDS Service = 0x01 // Wireless Data Service: сессии передачи данных
+	ServiceDMS Service = 0x02 // Device Management: информация об устройстве
+	ServiceNAS Service = 0x03 // Network Access: регистрация и сигнал
+	ServiceWMS Service = 0x05 // Wireless Messaging: SMS
+	ServiceUIM Service = 0x0B // User Identity Module: SIM-карта
+)
+
+// String возвращает название сервиса
+func (s Service) String() string {
+	switch s {
+	case ServiceCTL:
+		return "CTL"
+	case ServiceWDS:
+		return "WDS"
+	case ServiceDMS:
+		return "DMS"
+	case ServiceNAS:
+		return "NAS"
+	case ServiceWMS:
+		return "WMS"
+	case ServiceUIM:
+		return "UIM"
+	default:
+		return fmt.Sprintf("service 0x%02X", uint8(s))
+	}
+}
+
+// MessageType тип сообщения в заголовке SDU
+type MessageType uint8
+
+const (
+	MessageRequest    MessageType = 0 // Запрос клиента
+	MessageResponse   MessageType = 1 // Ответ на запрос
+	MessageIndication MessageType = 2 // Уведомление от модема
+)
+
+const (
+	qmuxInterface  = 0x01 // Тип интерфейса QMUX
+	qmuxHeaderSize = 6    // Тип интерфейса, длина, флаги, сервис, клиент
+	ctlHeaderSize  = 6    // Флаги, транзакция (1 октет), ID сообщения, длина TLV
+	sduHeaderSize  = 7    // Флаги, транзакция (2 октета), ID сообщения, длина TLV
+	tlvResult      = 0x02 // TLV результата в каждом ответе
+)
+
+// ErrMalformed сообщение QMUX не удалось разобрать
+var ErrMalformed = errors.New("malformed QMI message")
+
+// TLV элемент данных сообщения
+type TLV struct {
+	Type  uint8
+	Value []byte
+}
+
+// Message сообщение QMI
+type Message struct {
+	Service     Service
+	Client      uint8       // Идентификатор клиента (0 для CTL, 0xFF - широковещательные уведомления)
+	Type        MessageType // Запрос, ответ или уведомление
+	Transaction uint16      // Номер транзакции (для CTL - один октет)
+	ID          uint16      // Идентификатор сообщения внутри сервиса
+	TLVs        []TLV
+}
+
+// TLV возвращает значение элемента данных с указанным типом
+func (m *Message) TLV(t uint8) ([]byte, bool) {
+	for _, tlv := range m.TLVs {
+		if tlv.Type == t {
+			return tlv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Add добавляет элемент данных и возвращает сообщение для цепочки вызовов
+func (m *Message) Add(t uint8, value []byte) *Message {
+	m.TLVs = append(m.TLVs, TLV{Type: t, Value: value})
+	return m
+}
+
+// Err возвращает ошибку из TLV результата ответа (nil при успехе)
+func (m *Message) Err() error {
+	value, ok := m.TLV(tlvResult)
+	if !ok || len(value) < 4 {
+		return fmt.Errorf("%w: %s message 0x%04X without result", ErrMalformed, m.Service, m.ID)
+	}
+	if binary.LittleEndian.Uint16(value) == 0 {
+		return nil
+	}
+	return &Error{Service: m.Service, Message: m.ID, Code: ErrorCode(binary.LittleEndian.Uint16(value[2:]))}
+}
+
+// Marshal кодирует сообщение в кадр QMUX
+func (m *Message) Marshal() []byte {
+	headerSize := sduHeaderSize
+	if m.Service == ServiceCTL {
+		headerSize = ctlHeaderSize
+	}
+	payload := 0
+	for _, tlv := range m.TLVs {
+		payload += 3 + len(tlv.Value)
+	}
+
+	b := make([]byte, 0, qmuxHeaderSize+headerSize+payload)
+	b = append(b, qmuxInterface)
+	b = binary.LittleEndian.AppendUint16(b, uint16(qmuxHeaderSize-1+headerSize+payload))
+	b = append(b, 0x00, byte(m.Service), m.Client) // Флаги 0x00 - отправитель клиент
+
+	if m.Service == ServiceCTL {
+		b = append(b, byte(m.Type), byte(m.Transaction))
+	} else {
+		// В сервисах флаги: 0x02 - ответ, 0x04 - уведомление
+		b = append(b, byte(m.Type)<<1)
+		b = binary.LittleEndian.AppendUint16(b, m.Transaction)
+	}
+	b = binary.LittleEndian.AppendUint16(b, m.ID)
+	b = binary.LittleEndian.AppendUint16(b, uint16(payload))
+	for _, tlv := range m.TLVs {
+		b = append(b, tlv.Type)
+		b = binary.LittleEndian.AppendUint16(b, uint16(len(tlv.Value)))
+		b = append(b, tlv.Value...)
+	}
+	return b
+}
+
+// Unmarshal разбирает кадр QMUX
+func Unmarshal(b []byte) (*Message, error) {
+	if len(b) < qmuxHeaderSize || b[0] != qmuxInterface {
+		return nil, fmt.Errorf("%w: bad QMUX header", ErrMalformed)
+	}
+	if length := int(binary.LittleEndian.Uint16(b[1:])); length+1 != len(b) {
+		return nil, fmt.Errorf("%w: QMUX length %d, frame %d", ErrMalformed, length, len(b)-1)
+	}
+
+	m := &Message{Service: Service(b[4]), Client: b[5]}
+	sdu := b[qmuxHeaderSize:]
+	if m.Service == ServiceCTL {
+		if len(sdu) < ctlHeaderSize {
+			return nil, fmt.Errorf("%w: short CTL header", ErrMalformed)
+		}
+		m.Type = MessageType(sdu[0] & 0x03)
+		m.Transaction = uint16(sdu[1])
+		sdu = sdu[2:]
+	} else {
+		if len(sdu) < sduHeaderSize {
+			return nil, fmt.Errorf("%w: short %s header", ErrMalformed, m.Service)
+		}
+		m.Type = MessageType(sdu[0]>>1) & 0x03
+		m.Transaction = binary.LittleEndian.Uint16(sdu[1:])
+		sdu = sdu[3:]
+	}
+	m.ID = binary.LittleEndian.Uint16(sdu)
+	payload := sdu[4:]
+	if int(binary.LittleEndian.Uint16(sdu[2:])) != len(payload) {
+		return nil, fmt.Errorf("%w: TLV length mismatch", ErrMalformed)
+	}
+
+	for len(payload) > 0 {
+		if len(payload) < 3 {
+			return nil, fmt.Errorf("%w: truncated TLV", ErrMalformed)
+		}
+		n := int(binary.LittleEndian.Uint16(payload[1:]))
+		if 3+n > len(payload) {
+			return nil, fmt.Errorf("%w: TLV 0x%02X overflows message", ErrMalformed, payload[0])
+		}
+		m.TLVs = append(m.TLVs, TLV{Type: payload[0], Value: payload[3 : 3+n]})
+		payload = payload[3+n:]
+	}
+	return m, nil
+}
+
+// ErrorCode код ошибки QMI из TLV результата
+type ErrorCode uint16
+
+const (
+	ErrorMalformedMessage     ErrorCode = 0x0001
+	ErrorNoMemory             ErrorCode = 0x0002
+	ErrorInternal             ErrorCode = 0x0003
+	ErrorAborted              ErrorCode = 0x0004
+	ErrorClientIDsExhausted   ErrorCode = 0x0005
+	ErrorInvalidClientID      ErrorCode = 0x0007
+	ErrorInvalidHandle        ErrorCode = 0x0009
+	ErrorIncorrectPIN         ErrorCode = 0x000C
+	ErrorNoNetworkFound       ErrorCode = 0x000D
+	ErrorCallFailed           ErrorCode = 0x000E
+	ErrorOutOfCall            ErrorCode = 0x000F
+	ErrorMissingArgument      ErrorCode = 0x0011
+	ErrorNoEffect             ErrorCode = 0x001A
+	ErrorAuthenticationFailed ErrorCode = 0x0022
+	ErrorPINBlocked           ErrorCode = 0x0023
+	ErrorPINPermBlocked       ErrorCode = 0x0024
+	ErrorUIMUninitialized     ErrorCode = 0x0025
+	ErrorInvalidArgument      ErrorCode = 0x0030
+	ErrorInvalidIndex         ErrorCode = 0x0031
+	ErrorNoEntry              ErrorCode = 0x0032
+	ErrorDeviceStorageFull    ErrorCode = 0x0033
+	ErrorDeviceNotReady       ErrorCode = 0x0034
+	ErrorNetworkNotReady      ErrorCode = 0x0035
+	ErrorNotSupported         ErrorCode = 0x005E
+)
+
+// errorNames названия известных кодов ошибок
+var errorNames = map[ErrorCode]string{
+	ErrorMalformedMessage:     "malformed message",
+	ErrorNoMemory:             "no memory",
+	ErrorInternal:             "internal error",
+	ErrorAborted:              "aborted",
+	ErrorClientIDsExhausted:   "client IDs exhausted",
+	ErrorInvalidClientID:      "invalid client ID",
+	ErrorInvalidHandle:        "invalid handle",
+	ErrorIncorrectPIN:         "incorrect PIN",
+	ErrorNoNetworkFound:       "no network found",
+	ErrorCallFailed:           "call failed",
+	ErrorOutOfCall:            "out of call",
+	ErrorMissingArgument:      "missing argument",
+	ErrorNoEffect:             "no effect",
+	ErrorAuthenticationFailed: "authentication failed",
+	ErrorPINBlocked:           "PIN blocked",
+	ErrorPINPermBlocked:       "PIN permanently blocked",
+	ErrorUIMUninitialized:     "UIM uninitialized",
+	ErrorInvalidArgument:      "invalid argument",
+	ErrorInvalidIndex:         "invalid index",
+	ErrorNoEntry:              "no entry",
+	ErrorDeviceStorageFull:    "device storage full",
+	ErrorDeviceNotReady:       "device not ready",
+	ErrorNetworkNotReady:      "network not ready",
+	ErrorNotSupported:         "not supported",
+}
+
+// Error ошибка, возвращенная модемом в ответе на запрос
+type Error struct {
+	Service Service
+	Message uint16
+	Code    ErrorCode
+}
+
+// Error возвращает текст ошибки
+func (e *Error) Error() string {
+	name, ok := errorNames[e.Code]
+	if !ok {
+		name = fmt.Sprintf("error 0x%04X", uint16(e.Code))
+	}
+	return fmt.Sprintf("QMI %s message 0x%04X: %s", e.Service, e.Message, name)
+}
+
+// IsError проверяет, что err - ошибка QMI с указанным кодом
+func IsError(err error, code ErrorCode) bool {
+	var qerr *Error
+	return errors.As(err, &qerr) && qerr.Code == code
+}
+
+// u8 кодирует значение TLV из одного октета
+func u8(v uint8) []byte {
+	return []byte{v}
+}
+
+// u16 кодирует значение TLV uint16 (little-endian)
+func u16(v uint16) []byte {
+	return binary.LittleEndian.AppendUint16(nil, v)
+}
+
+// u32 кодирует значение TLV uint32 (little-endian)
+func u32(v uint32) []byte {
+	return binary.LittleEndian.AppendUint32(nil, v)
+}
+
+// tlvReader последовательно читает поля значения TLV; при выходе за границу
+// возвращает нули и запоминает ошибку
+type tlvReader struct {
+	data []byte
+	bad  bool
+}
+
+// u8 читает один октет
+func (r *tlvReader) u8() uint8 {
+	b := r.bytes(1)
+	return b[0]
+}
+
+// u16 читает uint16
+func (r *tlvReader) u16() uint16 {
+	return binary.LittleEndian.Uint16(r.bytes(2))
+}
+
+// u32 читает uint32
+func (r *tlvReader) u32() uint32 {
+	return binary.LittleEndian.Uint32(r.bytes(4))
+}
+
+// bytes читает n октетов
+func (r *tlvReader) bytes(n int) []byte {
+	if n > len(r.data) {
+		r.bad = true
+		r.data = nil
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+// ok проверяет, что все поля прочитаны без выхода за границу
+func (r *tlvReader) ok() bool {
+	return !r.bad
+}
@@ -0,0 +1,246 @@
+package qmi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// Сообщения сервиса NAS
+const (
+	nasRegisterIndications = 0x0003
+	nasGetSignalStrength   = 0x0020
+	nasGetServingSystem    = 0x0024
+	nasServingSystemInd    = 0x0024
+	nasGetSignalInfo       = 0x004F
+)
+
+// Состояния регистрации в ответе Get Serving System
+const (
+	nasNotRegistered       = 0
+	nasRegistered          = 1
+	nasSearching           = 2
+	nasRegistrationDenied  = 3
+	nasRoamingIndicatorOff = 1
+)
+
+// radioNoService радиоинтерфейс QMI "нет обслуживания"
+const radioNoService = 0x00
+
+// servingSystem разобранный ответ или уведомление Serving System
+type servingSystem struct {
+	status   gsm.NetworkStatus
+	operator *gsm.OperatorInfo
+}
+
+// getServingSystem запрашивает состояние регистрации
+func (c *Client) getServingSystem() (*servingSystem, error) {
+	resp, err := c.call(ServiceNAS, nasGetServingSystem)
+	if err != nil {
+		return nil, err
+	}
+	return parseServingSystem(resp)
+}
+
+// parseServingSystem разбирает TLV регистрации, роуминга и текущей сети
+func parseServingSystem(msg *Message) (*servingSystem, error) {
+	value, ok := msg.TLV(0x01)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	r := &tlvReader{data: value}
+	state := r.u8()
+	r.bytes(3)           // Подключение CS, PS и тип выбранной сети
+	r.bytes(int(r.u8())) // Список радиоинтерфейсов
+	if !r.ok() {
+		return nil, ErrMalformed
+	}
+
+	ss := &servingSystem{}
+	roaming := false
+	if v, ok := msg.TLV(0x10); ok && len(v) > 0 {
+		roaming = v[0] != nasRoamingIndicatorOff
+	}
+	switch state {
+	case nasNotRegistered:
+		ss.status = gsm.NetworkNotRegistered
+	case nasRegistered:
+		ss.status = gsm.NetworkRegisteredHome
+		if roaming {
+			ss.status = gsm.NetworkRegisteredRoaming
+		}
+	case nasSearching:
+		ss.status = gsm.NetworkSearching
+	case nasRegistrationDenied:
+		ss.status = gsm.NetworkRegistrationDenied
+	default:
+		ss.status = gsm.NetworkUnknown
+	}
+
+	if v, ok := msg.TLV(0x12); ok {
+		r := &tlvReader{data: v}
+		mcc, mnc := r.u16(), r.u16()
+		name := r.bytes(int(r.u8()))
+		if r.ok() {
+			// TLV 0x1B уточняет, что MNC трехзначный (в том числе с ведущим нулем)
+			mncFormat := "%02d"
+			if mnc > 99 {
+				mncFormat = "%03d"
+			}
+			if v, ok := msg.TLV(0x1B); ok && len(v) >= 5 && v[4] != 0 {
+				mncFormat = "%03d"
+			}
+			op := &gsm.OperatorInfo{
+				Status:   "2",
+				LongName: string(name),
+				Numeric:  fmt.Sprintf("%03d"+mncFormat, mcc, mnc),
+			}
+			enrichOperator(op)
+			ss.operator = op
+		}
+	}
+	return ss, nil
+}
+
+// enrichOperator дополняет информацию об операторе страной и названиями из справочника
+func enrichOperator(op *gsm.OperatorInfo) {
+	plmn, ok := gsm.LookupOperator(op.Numeric)
+	op.Country, op.CountryISO = plmn.Country, plmn.ISO
+	if !ok {
+		return
+	}
+	op.Brand = plmn.Brand
+	if op.LongName == "" {
+		op.LongName = plmn.Brand
+	}
+	if op.ShortName == "" {
+		op.ShortName = plmn.Brand
+	}
+}
+
+// GetNetworkStatus возвращает статус регистрации в сети
+func (c *Client) GetNetworkStatus() (gsm.NetworkStatus, error) {
+	ss, err := c.getServingSystem()
+	if err != nil {
+		return gsm.NetworkUnknown, fmt.Errorf("failed to get network status: %w", err)
+	}
+	return ss.status, nil
+}
+
+// GetCurrentOperator возвращает текущего оператора
+func (c *Client) GetCurrentOperator() (*gsm.OperatorInfo, error) {
+	ss, err := c.getServingSystem()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current operator: %w", err)
+	}
+	if ss.operator == nil {
+		return nil, fmt.Errorf("no operator found: modem is not registered")
+	}
+	return ss.operator, nil
+}
+
+// GetSignalQuality возвращает уровень сигнала в шкале AT+CSQ (BER не сообщается - 99)
+func (c *Client) GetSignalQuality() (*gsm.SignalQuality, error) {
+	resp, err := c.call(ServiceNAS, nasGetSignalStrength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signal quality: %w", err)
+	}
+	value, ok := resp.TLV(0x01)
+	if !ok || len(value) < 2 {
+		return nil, fmt.Errorf("failed to get signal quality: %w", ErrMalformed)
+	}
+	signal := &gsm.SignalQuality{RSSI: 99, BER: 99}
+	if value[1] != radioNoService {
+		signal.RSSI = gsm.DBmToCSQ(float64(int8(value[0])))
+	}
+	return signal, nil
+}
+
+// GetSignalReport возвращает подробные метрики сигнала (NAS Get Signal Info)
+func (c *Client) GetSignalReport() (*gsm.SignalReport, error) {
+	resp, err := c.call(ServiceNAS, nasGetSignalInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get signal report: %w", err)
+	}
+	report := parseSignalInfo(resp)
+	if report == nil {
+		return nil, fmt.Errorf("failed to get signal report: no service")
+	}
+	report.Normalize()
+	return report, nil
+}
+
+// parseSignalInfo разбирает метрики технологии с наилучшим доступом: 5G, LTE, UMTS, GSM
+func parseSignalInfo(msg *Message) *gsm.SignalReport {
+	report := gsm.NewSignalReport("QMI")
+	found := false
+
+	if v, ok := msg.TLV(0x12); ok && len(v) >= 1 {
+		report.AccessTech = gsm.AccessTechGSM
+		report.RSSI = float64(int8(v[0]))
+		found = true
+	}
+	if v, ok := msg.TLV(0x13); ok && len(v) >= 3 {
+		// RSSI дБм, Ec/Io в -0.5 дБ
+		report.AccessTech = gsm.AccessTechUMTS
+		report.RSSI = float64(int8(v[0]))
+		report.EcIo = -float64(binary.LittleEndian.Uint16(v[1:])) / 2
+		found = true
+	}
+	if v, ok := msg.TLV(0x14); ok && len(v) >= 6 {
+		// RSSI дБм, RSRQ дБ, RSRP дБм, SNR в 0.1 дБ
+		report.AccessTech = gsm.AccessTechLTE
+		report.RSSI = float64(int8(v[0]))
+		report.RSRQ = float64(int8(v[1]))
+		report.RSRP = float64(int16(binary.LittleEndian.Uint16(v[2:])))
+		report.SINR = float64(int16(binary.LittleEndian.Uint16(v[4:]))) / 10
+		found = true
+	}
+	if v, ok := msg.TLV(0x17); ok && len(v) >= 4 {
+		// 5G NR: RSRP дБм, SNR в 0.1 дБ; RSRQ - в TLV 0x18
+		if report.AccessTech == gsm.AccessTechLTE {
+			report.AccessTech = gsm.AccessTechENDC
+		} else {
+			report.AccessTech = gsm.AccessTechNR5GC
+		}
+		report.RSRP = float64(int16(binary.LittleEndian.Uint16(v)))
+		report.SINR = float64(int16(binary.LittleEndian.Uint16(v[2:]))) / 10
+		report.RSRQ = math.NaN()
+		if q, ok := msg.TLV(0x18); ok && len(q) >= 2 {
+			report.RSRQ = float64(int16(binary.LittleEndian.Uint16(q)))
+		}
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return report
+}
+
+// enableNetworkEvents подписывается на уведомления Serving System
+func (c *Client) enableNetworkEvents() error {
+	c.onIndication(ServiceNAS, nasServingSystemInd, func(msg *Message) {
+		if !c.addressed(msg) {
+			return
+		}
+		ss, err := parseServingSystem(msg)
+		if err != nil {
+			return
+		}
+		data := map[string]interface{}{
+			"status": ss.status,
+		}
+		if ss.operator != nil {
+			data["operator"] = ss.operator
+		}
+		c.emitEvent(gsm.Event{Type: gsm.EventNetworkChange, Timestamp: time.Now(), Data: data})
+	})
+	// TLV 0x13 - уведомления Serving System
+	if _, err := c.call(ServiceNAS, nasRegisterIndications, TLV{Type: 0x13, Value: u8(1)}); err != nil {
+		return fmt.Errorf("failed to register NAS indications: %w", err)
+	}
+	return nil
+}
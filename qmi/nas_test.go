@@ -0,0 +1,83 @@
+package qmi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// servingSystemTLVs TLV Serving System: зарегистрирован в LTE, сеть 250-01 "MTS"
+func servingSystemTLVs(state uint8, roaming uint8) []TLV {
+	network := append(append(u16(250), u16(1)...), 3)
+	return []TLV{
+		{Type: 0x01, Value: []byte{state, 1, 1, 1, 1, 0x08}},
+		{Type: 0x10, Value: u8(roaming)},
+		{Type: 0x12, Value: append(network, "MTS"...)},
+	}
+}
+
+func TestNASTranscript(t *testing.T) {
+	f, c := newFakeModem(t,
+		step{service: ServiceNAS, id: nasGetServingSystem, resp: servingSystemTLVs(nasRegistered, nasRoamingIndicatorOff)},
+		step{service: ServiceNAS, id: nasGetServingSystem, resp: servingSystemTLVs(nasRegistered, 0)},
+		step{service: ServiceNAS, id: nasGetSignalInfo, resp: []TLV{
+			// LTE: RSSI -65 дБм, RSRQ -10 дБ, RSRP -95 дБм, SNR 12.5 дБ
+			{Type: 0x14, Value: append(append([]byte{0xBF, 0xF6}, u16(uint16(0xFFA1))...), u16(125)...)},
+		}},
+	)
+
+	operator, err := c.GetCurrentOperator()
+	if err != nil {
+		t.Fatalf("GetCurrentOperator: %v", err)
+	}
+	if operator.Numeric != "25001" || operator.LongName != "MTS" || operator.CountryISO == "" {
+		t.Errorf("operator = %+v", operator)
+	}
+	if status, err := c.GetNetworkStatus(); err != nil || status != gsm.NetworkRegisteredRoaming {
+		t.Errorf("GetNetworkStatus = %v, %v", status, err)
+	}
+
+	report, err := c.GetSignalReport()
+	if err != nil {
+		t.Fatalf("GetSignalReport: %v", err)
+	}
+	if report.AccessTech != gsm.AccessTechLTE || report.RSRP != -95 || report.RSRQ != -10 ||
+		report.RSSI != -65 || report.SINR != 12.5 {
+		t.Errorf("report = %+v", report)
+	}
+	f.done()
+}
+
+func TestNASIndications(t *testing.T) {
+	f, c := newFakeModem(t,
+		step{service: ServiceWMS, id: wmsSetEventReport, req: []TLV{{Type: 0x10, Value: u8(1)}}},
+		step{service: ServiceNAS, id: nasRegisterIndications, req: []TLV{{Type: 0x13, Value: u8(1)}}},
+	)
+	events, err := c.GetEventChannel()
+	if err != nil {
+		t.Fatalf("GetEventChannel: %v", err)
+	}
+	f.done()
+
+	// Уведомление чужому клиенту пропускается, общему клиенту NAS - доставляется
+	f.send(indication(ServiceNAS, f.client(ServiceNAS)+1, nasServingSystemInd, servingSystemTLVs(nasSearching, 1)...))
+	f.send(indication(ServiceNAS, f.client(ServiceNAS), nasServingSystemInd, servingSystemTLVs(nasRegistered, 1)...))
+
+	select {
+	case event := <-events:
+		if event.Type != gsm.EventNetworkChange || event.Data["status"] != gsm.NetworkRegisteredHome {
+			t.Errorf("event = %+v", event)
+		}
+		if op, ok := event.Data["operator"].(*gsm.OperatorInfo); !ok || op.Numeric != "25001" {
+			t.Errorf("event operator = %+v", event.Data["operator"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no EventNetworkChange")
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %+v", event)
+	default:
+	}
+}
@@ -0,0 +1,173 @@
+package qmi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// Сообщения сервиса UIM
+const (
+	uimReadTransparent = 0x0020
+	uimVerifyPIN       = 0x0026
+	uimGetCardStatus   = 0x002F
+)
+
+// Типы сессий UIM
+const (
+	uimSessionPrimaryGW = 0x00 // Основное 3GPP приложение (USIM/SIM)
+	uimSessionCardSlot1 = 0x06 // Прямой доступ к файлам карты в слоте 1
+)
+
+// Состояния приложения SIM в ответе Get Card Status
+const (
+	uimAppPIN1Required = 2
+	uimAppPUK1Required = 3
+	uimAppPIN1Blocked  = 5
+	uimAppReady        = 7
+)
+
+// ErrSIMNotInserted SIM-карта отсутствует или неисправна
+var ErrSIMNotInserted = errors.New("SIM not inserted")
+
+// GetSIMStatus возвращает статус PIN основного 3GPP приложения SIM-карты
+func (c *Client) GetSIMStatus() (gsm.PinStatus, error) {
+	resp, err := c.call(ServiceUIM, uimGetCardStatus)
+	if err != nil {
+		return "", fmt.Errorf("failed to get SIM status: %w", err)
+	}
+	value, ok := resp.TLV(0x10)
+	if !ok {
+		return "", fmt.Errorf("failed to get SIM status: %w", ErrMalformed)
+	}
+	state, err := parseCardStatus(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to get SIM status: %w", err)
+	}
+	return state, nil
+}
+
+// parseCardStatus находит основное 3GPP приложение и переводит его состояние в PinStatus
+func parseCardStatus(value []byte) (gsm.PinStatus, error) {
+	r := &tlvReader{data: value}
+	primary := r.u16() // Старший октет - номер карты, младший - номер приложения
+	r.bytes(6)         // Индексы 1X и вторичных приложений
+	cards := int(r.u8())
+
+	for card := 0; card < cards && r.ok(); card++ {
+		cardState := r.u8()
+		r.bytes(4) // Состояние UPIN, попытки UPIN/UPUK, код ошибки
+		apps := int(r.u8())
+		for app := 0; app < apps && r.ok(); app++ {
+			r.u8() // Тип приложения
+			appState := r.u8()
+			r.bytes(4) // Персонализация
+			r.bytes(int(r.u8()))
+			r.bytes(7) // Состояния и счетчики PIN1/PIN2
+			if uint16(card)<<8|uint16(app) != primary {
+				continue
+			}
+			if cardState != 1 {
+				return "", ErrSIMNotInserted
+			}
+			switch appState {
+			case uimAppReady:
+				return gsm.PinReady, nil
+			case uimAppPIN1Required:
+				return gsm.PinRequired, nil
+			case uimAppPUK1Required, uimAppPIN1Blocked:
+				return gsm.PukRequired, nil
+			default:
+				return "", fmt.Errorf("SIM application is not ready (state %d)", appState)
+			}
+		}
+		if card == int(primary>>8) && cardState != 1 {
+			return "", ErrSIMNotInserted
+		}
+	}
+	if !r.ok() {
+		return "", ErrMalformed
+	}
+	return "", ErrSIMNotInserted
+}
+
+// EnterPIN вводит PIN1 основного 3GPP приложения
+func (c *Client) EnterPIN(pin string) error {
+	pinTLV := append([]byte{0x01, byte(len(pin))}, pin...)
+	_, err := c.call(ServiceUIM, uimVerifyPIN,
+		TLV{Type: 0x01, Value: []byte{uimSessionPrimaryGW, 0}},
+		TLV{Type: 0x02, Value: pinTLV},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to enter PIN: %w", err)
+	}
+	return nil
+}
+
+// readTransparent читает элементарный файл SIM-карты целиком
+func (c *Client) readTransparent(session uint8, file uint16, path []uint16) ([]byte, error) {
+	fileTLV := append(u16(file), byte(len(path)*2))
+	for _, p := range path {
+		fileTLV = append(fileTLV, u16(p)...)
+	}
+	resp, err := c.call(ServiceUIM, uimReadTransparent,
+		TLV{Type: 0x01, Value: []byte{session, 0}},
+		TLV{Type: 0x02, Value: fileTLV},
+		TLV{Type: 0x03, Value: append(u16(0), u16(0)...)}, // Смещение 0, длина 0 - весь файл
+	)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := resp.TLV(0x11)
+	if !ok {
+		return nil, ErrMalformed
+	}
+	r := &tlvReader{data: value}
+	data := r.bytes(int(r.u16()))
+	if !r.ok() {
+		return nil, ErrMalformed
+	}
+	return data, nil
+}
+
+// GetICCID возвращает ICCID SIM-карты (EF ICCID 2FE2)
+func (c *Client) GetICCID() (string, error) {
+	data, err := c.readTransparent(uimSessionCardSlot1, 0x2FE2, []uint16{0x3F00})
+	if err != nil {
+		return "", fmt.Errorf("failed to get ICCID: %w", err)
+	}
+	return swappedBCD(data), nil
+}
+
+// readIMSI читает IMSI из EF IMSI (6F07 в DF GSM/ADF USIM)
+func (c *Client) readIMSI() (string, error) {
+	data, err := c.readTransparent(uimSessionPrimaryGW, 0x6F07, []uint16{0x3F00, 0x7FFF})
+	if err != nil {
+		return "", err
+	}
+	// Первый октет - длина, младший полубайт второго - признак четности
+	if len(data) < 2 || int(data[0]) > len(data)-1 {
+		return "", fmt.Errorf("unexpected EF IMSI contents % X", data)
+	}
+	digits := swappedBCD(data[1 : 1+data[0]])
+	if len(digits) < 2 {
+		return "", fmt.Errorf("unexpected EF IMSI contents % X", data)
+	}
+	return digits[1:], nil
+}
+
+// swappedBCD декодирует цифры, записанные полуоктетами младшим полубайтом вперед
+func swappedBCD(data []byte) string {
+	var result strings.Builder
+	for _, b := range data {
+		for _, nibble := range []byte{b & 0x0F, b >> 4} {
+			if nibble > 9 {
+				return result.String()
+			}
+			result.WriteByte('0' + nibble)
+		}
+	}
+	return result.String()
+}
@@ -0,0 +1,266 @@
+package qmi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// Сообщения сервиса WDS
+const (
+	wdsStartNetwork         = 0x0020
+	wdsStopNetwork          = 0x0021
+	wdsPacketServiceStatus  = 0x0022
+	wdsGetCurrentSettings   = 0x002D
+	wdsSetIPFamily          = 0x004D
+	wdsConnectionDisconnect = 1
+	defaultDataTimeout      = 60 * time.Second
+)
+
+// Запрашиваемые параметры Get Current Settings: APN, DNS, адрес, шлюз, MTU, семейство
+const wdsRequestedSettings = 0x0008 | 0x0010 | 0x0100 | 0x0200 | 0x2000 | 0x8000
+
+// ErrDataDisconnected сеть разорвала сессию передачи данных
+var ErrDataDisconnected = errors.New("data session disconnected")
+
+// DataConfig параметры сессии передачи данных
+type DataConfig struct {
+	APN      string        // Точка доступа (пусто - из профиля)
+	Username string        // Имя пользователя
+	Password string        // Пароль
+	Auth     gsm.APNAuth   // Тип аутентификации
+	Type     gsm.PDPType   // IP (по умолчанию), IPV6 или IPV4V6 - по сессии WDS на семейство
+	Profile  int           // Номер 3GPP профиля (0 - не использовать)
+	Timeout  time.Duration // Тайм-аут подключения (по умолчанию 60 секунд)
+}
+
+// DataSession активная сессия передачи данных. Трафик идет через сетевой интерфейс
+// qmi_wwan (wwan0), адреса для него возвращает Settings.
+type DataSession struct {
+	c       *Client
+	profile int
+	legs    []*dataLeg
+
+	done      chan struct{}
+	mu        sync.Mutex
+	err       error
+	closeOnce sync.Once
+}
+
+// dataLeg подключение одного семейства адресов с собственным клиентом WDS
+type dataLeg struct {
+	family uint8
+	client uint8
+	handle uint32
+	cancel func()
+}
+
+// StartData подключает сессию передачи данных (WDS Start Network)
+func (c *Client) StartData(config DataConfig) (*DataSession, error) {
+	if config.Timeout <= 0 {
+		config.Timeout = defaultDataTimeout
+	}
+	var families []uint8
+	switch config.Type {
+	case "", gsm.PDPTypeIP:
+		families = []uint8{4}
+	case gsm.PDPTypeIPv6:
+		families = []uint8{6}
+	case gsm.PDPTypeIPv4v6:
+		families = []uint8{4, 6}
+	default:
+		return nil, fmt.Errorf("unsupported PDP type %q", config.Type)
+	}
+
+	s := &DataSession{c: c, profile: config.Profile, done: make(chan struct{})}
+	for _, family := range families {
+		leg, err := s.start(config, family)
+		if err != nil {
+			s.Stop()
+			return nil, fmt.Errorf("failed to start IPv%d data session: %w", family, err)
+		}
+		s.legs = append(s.legs, leg)
+	}
+	return s, nil
+}
+
+// start выделяет клиента WDS и подключает одно семейство адресов
+func (s *DataSession) start(config DataConfig, family uint8) (*dataLeg, error) {
+	client, err := s.c.allocateClient(ServiceWDS)
+	if err != nil {
+		return nil, err
+	}
+	leg := &dataLeg{family: family, client: client}
+	leg.cancel = s.c.onIndication(ServiceWDS, wdsPacketServiceStatus, func(msg *Message) {
+		if msg.Client != client {
+			return
+		}
+		if v, ok := msg.TLV(0x01); ok && len(v) > 0 && v[0] == wdsConnectionDisconnect {
+			s.finish(fmt.Errorf("%w%s", ErrDataDisconnected, callEndReason(msg)))
+		}
+	})
+
+	fail := func(err error) (*dataLeg, error) {
+		leg.cancel()
+		s.c.releaseClient(ServiceWDS, client)
+		return nil, err
+	}
+
+	if family == 6 {
+		req := &Message{Service: ServiceWDS, Client: client, ID: wdsSetIPFamily}
+		if _, err := s.c.Request(req.Add(0x01, u8(family))); err != nil {
+			return fail(fmt.Errorf("failed to set IP family: %w", err))
+		}
+	}
+
+	req := &Message{Service: ServiceWDS, Client: client, ID: wdsStartNetwork}
+	if config.APN != "" {
+		req.Add(0x14, []byte(config.APN))
+	}
+	if config.Auth != gsm.APNAuthNone {
+		// Битовая маска: 1 - PAP, 2 - CHAP
+		req.Add(0x16, u8(uint8(config.Auth)))
+	}
+	if config.Username != "" {
+		req.Add(0x17, []byte(config.Username))
+	}
+	if config.Password != "" {
+		req.Add(0x18, []byte(config.Password))
+	}
+	req.Add(0x19, u8(family))
+	if config.Profile > 0 {
+		req.Add(0x31, u8(uint8(config.Profile)))
+	}
+
+	resp, err := s.c.requestTimeout(req, config.Timeout)
+	if err != nil {
+		if resp != nil {
+			err = fmt.Errorf("%w%s", err, callEndReason(resp))
+		}
+		return fail(err)
+	}
+	value, ok := resp.TLV(0x01)
+	if !ok || len(value) < 4 {
+		return fail(ErrMalformed)
+	}
+	leg.handle = binary.LittleEndian.Uint32(value)
+	return leg, nil
+}
+
+// callEndReason форматирует причину завершения вызова из TLV 0x10/0x11
+func callEndReason(msg *Message) string {
+	if v, ok := msg.TLV(0x11); ok && len(v) >= 4 {
+		return fmt.Sprintf(" (call end reason type %d, reason %d)",
+			binary.LittleEndian.Uint16(v), binary.LittleEndian.Uint16(v[2:]))
+	}
+	if v, ok := msg.TLV(0x10); ok && len(v) >= 2 {
+		return fmt.Sprintf(" (call end reason %d)", binary.LittleEndian.Uint16(v))
+	}
+	return ""
+}
+
+// Settings возвращает адреса, шлюзы, DNS и MTU сессии (по записи на семейство адресов)
+func (s *DataSession) Settings() ([]gsm.PDPDynamicParams, error) {
+	var params []gsm.PDPDynamicParams
+	for _, leg := range s.legs {
+		req := &Message{Service: ServiceWDS, Client: leg.client, ID: wdsGetCurrentSettings}
+		resp, err := s.c.Request(req.Add(0x10, u32(wdsRequestedSettings)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get IPv%d settings: %w", leg.family, err)
+		}
+		p := parseCurrentSettings(resp)
+		p.CID = s.profile
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// parseCurrentSettings разбирает ответ Get Current Settings
+func parseCurrentSettings(msg *Message) gsm.PDPDynamicParams {
+	var p gsm.PDPDynamicParams
+	if v, ok := msg.TLV(0x14); ok {
+		p.APN = string(v)
+	}
+	if v, ok := msg.TLV(0x1E); ok {
+		p.Address = ipv4(v)
+	}
+	if v, ok := msg.TLV(0x21); ok && len(v) >= 4 {
+		p.Mask = net.IPMask(ipv4(v))
+	}
+	if v, ok := msg.TLV(0x20); ok {
+		p.Gateway = ipv4(v)
+	}
+	for _, t := range []uint8{0x15, 0x16} {
+		if v, ok := msg.TLV(t); ok {
+			p.DNS = append(p.DNS, ipv4(v))
+		}
+	}
+	if v, ok := msg.TLV(0x25); ok && len(v) >= 17 {
+		p.Address = net.IP(v[:16])
+		p.Mask = net.CIDRMask(int(v[16]), 128)
+	}
+	if v, ok := msg.TLV(0x26); ok && len(v) >= 16 {
+		p.Gateway = net.IP(v[:16])
+	}
+	for _, t := range []uint8{0x27, 0x28} {
+		if v, ok := msg.TLV(t); ok && len(v) >= 16 {
+			p.DNS = append(p.DNS, net.IP(v[:16]))
+		}
+	}
+	if v, ok := msg.TLV(0x29); ok && len(v) >= 4 {
+		p.MTU = int(binary.LittleEndian.Uint32(v))
+	}
+	return p
+}
+
+// ipv4 переводит адрес IPv4 из uint32 little-endian
+func ipv4(v []byte) net.IP {
+	if len(v) < 4 {
+		return nil
+	}
+	return net.IPv4(v[3], v[2], v[1], v[0]).To4()
+}
+
+// finish завершает сессию с указанной причиной
+func (s *DataSession) finish(err error) {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// Done закрывается, когда сеть разорвала сессию или вызван Stop
+func (s *DataSession) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err возвращает причину завершения сессии
+func (s *DataSession) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Stop отключает сессию (WDS Stop Network) и освобождает клиентов WDS
+func (s *DataSession) Stop() error {
+	var firstErr error
+	for _, leg := range s.legs {
+		leg.cancel()
+		req := &Message{Service: ServiceWDS, Client: leg.client, ID: wdsStopNetwork}
+		if _, err := s.c.Request(req.Add(0x01, u32(leg.handle))); err != nil && firstErr == nil &&
+			!IsError(err, ErrorNoEffect) {
+			firstErr = fmt.Errorf("failed to stop IPv%d data session: %w", leg.family, err)
+		}
+		s.c.releaseClient(ServiceWDS, leg.client)
+	}
+	s.legs = nil
+	s.finish(ErrDataDisconnected)
+	return firstErr
+}
@@ -0,0 +1,91 @@
+package qmi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWDSTranscript(t *testing.T) {
+	f, c := newFakeModem(t,
+		step{service: ServiceWDS, id: wdsStartNetwork,
+			req:  []TLV{{Type: 0x14, Value: []byte("internet")}, {Type: 0x19, Value: u8(4)}},
+			resp: []TLV{{Type: 0x01, Value: u32(0x12345678)}}},
+		step{service: ServiceWDS, id: wdsGetCurrentSettings,
+			req: []TLV{{Type: 0x10, Value: u32(wdsRequestedSettings)}},
+			resp: []TLV{
+				{Type: 0x14, Value: []byte("internet")},
+				{Type: 0x15, Value: []byte{8, 8, 8, 8}},
+				{Type: 0x1E, Value: []byte{2, 0, 0, 10}},
+				{Type: 0x20, Value: []byte{1, 0, 0, 10}},
+				{Type: 0x21, Value: []byte{0, 255, 255, 255}},
+				{Type: 0x29, Value: u32(1430)},
+			}},
+	)
+
+	session, err := c.StartData(DataConfig{APN: "internet"})
+	if err != nil {
+		t.Fatalf("StartData: %v", err)
+	}
+	settings, err := session.Settings()
+	if err != nil || len(settings) != 1 {
+		t.Fatalf("Settings = %+v, %v", settings, err)
+	}
+	p := settings[0]
+	if p.APN != "internet" || p.Address.String() != "10.0.0.2" || p.Gateway.String() != "10.0.0.1" ||
+		p.Mask.String() != "ffffff00" || len(p.DNS) != 1 || p.DNS[0].String() != "8.8.8.8" || p.MTU != 1430 {
+		t.Errorf("settings = %+v", p)
+	}
+	f.done()
+
+	// Сеть разрывает сессию: Packet Service Status со статусом "отключено" и причиной
+	wds := f.client(ServiceWDS)
+	f.send(indication(ServiceWDS, wds, wdsPacketServiceStatus,
+		TLV{Type: 0x01, Value: []byte{wdsConnectionDisconnect, 0}},
+		TLV{Type: 0x10, Value: u16(2)},
+	))
+	select {
+	case <-session.Done():
+	case <-time.After(time.Second):
+		t.Fatal("session is not finished after disconnect indication")
+	}
+	if err := session.Err(); !errors.Is(err, ErrDataDisconnected) {
+		t.Errorf("Err = %v, want ErrDataDisconnected", err)
+	}
+
+	// Сессия уже разорвана: Stop Network отвечает No Effect, это не ошибка
+	f.expect(step{service: ServiceWDS, id: wdsStopNetwork,
+		req: []TLV{{Type: 0x01, Value: u32(0x12345678)}}, code: ErrorNoEffect})
+	if err := session.Stop(); err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+	f.done()
+
+	f.mu.Lock()
+	released := f.release[ServiceWDS]
+	f.mu.Unlock()
+	if len(released) != 1 || released[0] != wds {
+		t.Errorf("released WDS clients %v, want %d", released, wds)
+	}
+}
+
+func TestWDSStartFailure(t *testing.T) {
+	f, c := newFakeModem(t,
+		step{service: ServiceWDS, id: wdsStartNetwork, code: ErrorCallFailed,
+			resp: []TLV{{Type: 0x11, Value: append(u16(3), u16(2001)...)}}},
+	)
+	_, err := c.StartData(DataConfig{})
+	if !IsError(err, ErrorCallFailed) || !strings.Contains(err.Error(), "reason 2001") {
+		t.Fatalf("StartData = %v, want call failed with end reason", err)
+	}
+	f.done()
+
+	// Клиент WDS освобожден после неудачного подключения
+	f.mu.Lock()
+	released := len(f.release[ServiceWDS])
+	f.mu.Unlock()
+	if released != 1 {
+		t.Errorf("released %d WDS clients, want 1", released)
+	}
+}
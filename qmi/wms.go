@@ -0,0 +1,240 @@
+package qmi
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+// Сообщения сервиса WMS
+const (
+	wmsSetEventReport = 0x0001
+	wmsEventReportInd = 0x0001
+	wmsRawSend        = 0x0020
+	wmsRawRead        = 0x0022
+	wmsModifyTag      = 0x0023
+	wmsDelete         = 0x0024
+	wmsListMessages   = 0x0031
+)
+
+// Хранилища сообщений WMS
+const (
+	storageUIM = 0x00 // SIM-карта
+	storageNV  = 0x01 // Память модема
+)
+
+// Метки сообщений WMS
+const (
+	tagMTRead    = 0x00
+	tagMTNotRead = 0x01
+	tagMOSent    = 0x02
+	tagMONotSent = 0x03
+)
+
+const (
+	wmsFormatGWPP = 0x06 // Формат PDU GSM/WCDMA point-to-point
+	wmsModeGW     = 0x01 // Режим сообщений GSM/WCDMA
+	sendTimeout   = 60 * time.Second
+)
+
+// smsStatuses статусы сообщений в формате AT+CMGL по меткам WMS
+var smsStatuses = map[uint8]string{
+	tagMTRead:    "REC READ",
+	tagMTNotRead: "REC UNREAD",
+	tagMOSent:    "STO SENT",
+	tagMONotSent: "STO UNSENT",
+}
+
+// SetSMSStorage выбирает хранилище для чтения, списка и удаления SMS:
+// gsm.StorageSIM (по умолчанию) или gsm.StoragePhone
+func (c *Client) SetSMSStorage(storage gsm.SMSStorage) error {
+	var value uint8
+	switch storage {
+	case gsm.StorageSIM:
+		value = storageUIM
+	case gsm.StoragePhone:
+		value = storageNV
+	default:
+		return fmt.Errorf("unsupported SMS storage %q", storage)
+	}
+	c.mu.Lock()
+	c.smsStorage = value
+	c.mu.Unlock()
+	return nil
+}
+
+// storage возвращает выбранное хранилище SMS
+func (c *Client) storage() uint8 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.smsStorage
+}
+
+// SendSMS отправляет SMS сообщение
+func (c *Client) SendSMS(number, text string) error {
+	pdu, err := gsm.EncodeSMSSubmitPDU(number, text)
+	if err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	raw := append([]byte{wmsFormatGWPP}, u16(uint16(len(pdu)))...)
+	req := &Message{Service: ServiceWMS, ID: wmsRawSend}
+	req.Add(0x01, append(raw, pdu...))
+	if _, err := c.requestTimeout(req, sendTimeout); err != nil {
+		return fmt.Errorf("failed to send SMS: %w", err)
+	}
+	return nil
+}
+
+// ReadSMS читает SMS по индексу в выбранном хранилище
+func (c *Client) ReadSMS(index int) (*gsm.SMS, error) {
+	resp, err := c.call(ServiceWMS, wmsRawRead,
+		TLV{Type: 0x01, Value: append([]byte{c.storage()}, u32(uint32(index))...)},
+		TLV{Type: 0x10, Value: u8(wmsModeGW)},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SMS %d: %w", index, err)
+	}
+	value, ok := resp.TLV(0x01)
+	if !ok {
+		return nil, fmt.Errorf("failed to read SMS %d: %w", index, ErrMalformed)
+	}
+	r := &tlvReader{data: value}
+	tag := r.u8()
+	r.u8() // Формат
+	pdu := r.bytes(int(r.u16()))
+	if !r.ok() {
+		return nil, fmt.Errorf("failed to read SMS %d: %w", index, ErrMalformed)
+	}
+
+	sms, err := gsm.DecodeSMSPDU(pdu)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SMS %d: %w", index, err)
+	}
+	sms.Index = index
+	sms.Status = smsStatuses[tag]
+	return sms, nil
+}
+
+// ListSMS возвращает сообщения с указанным статусом AT+CMGL ("REC UNREAD", "ALL" или "")
+func (c *Client) ListSMS(status string) ([]*gsm.SMS, error) {
+	req := &Message{Service: ServiceWMS, ID: wmsListMessages}
+	req.Add(0x01, u8(c.storage()))
+	if status != "" && status != "ALL" {
+		tag, ok := smsTag(status)
+		if !ok {
+			return nil, fmt.Errorf("unsupported SMS status %q", status)
+		}
+		req.Add(0x11, u8(tag))
+	}
+	req.Add(0x12, u8(wmsModeGW))
+
+	resp, err := c.Request(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SMS: %w", err)
+	}
+	value, ok := resp.TLV(0x01)
+	if !ok {
+		return nil, fmt.Errorf("failed to list SMS: %w", ErrMalformed)
+	}
+	r := &tlvReader{data: value}
+	count := int(r.u32())
+	indexes := make([]int, 0, count)
+	for i := 0; i < count && r.ok(); i++ {
+		indexes = append(indexes, int(r.u32()))
+		r.u8() // Метка
+	}
+	if !r.ok() {
+		return nil, fmt.Errorf("failed to list SMS: %w", ErrMalformed)
+	}
+
+	messages := make([]*gsm.SMS, 0, len(indexes))
+	for _, index := range indexes {
+		sms, err := c.ReadSMS(index)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, sms)
+	}
+	return messages, nil
+}
+
+// smsTag переводит статус AT+CMGL в метку WMS
+func smsTag(status string) (uint8, bool) {
+	for tag, s := range smsStatuses {
+		if s == status {
+			return tag, true
+		}
+	}
+	return 0, false
+}
+
+// MarkSMSAsRead помечает входящее сообщение как прочитанное
+func (c *Client) MarkSMSAsRead(index int) error {
+	value := append([]byte{c.storage()}, u32(uint32(index))...)
+	_, err := c.call(ServiceWMS, wmsModifyTag,
+		TLV{Type: 0x01, Value: append(value, tagMTRead)},
+		TLV{Type: 0x10, Value: u8(wmsModeGW)},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark SMS %d as read: %w", index, err)
+	}
+	return nil
+}
+
+// DeleteSMS удаляет SMS по индексу
+func (c *Client) DeleteSMS(index int) error {
+	_, err := c.call(ServiceWMS, wmsDelete,
+		TLV{Type: 0x01, Value: u8(c.storage())},
+		TLV{Type: 0x10, Value: u32(uint32(index))},
+		TLV{Type: 0x12, Value: u8(wmsModeGW)},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete SMS %d: %w", index, err)
+	}
+	return nil
+}
+
+// enableSMSEvents включает уведомления о новых сообщениях
+func (c *Client) enableSMSEvents() error {
+	c.onIndication(ServiceWMS, wmsEventReportInd, func(msg *Message) {
+		if !c.addressed(msg) {
+			return
+		}
+		value, ok := msg.TLV(0x10)
+		if !ok {
+			return
+		}
+		r := &tlvReader{data: value}
+		storage := r.u8()
+		index := r.u32()
+		if !r.ok() {
+			return
+		}
+		name := gsm.StorageSIM
+		if storage == storageNV {
+			name = gsm.StoragePhone
+		}
+		c.emitEvent(gsm.Event{
+			Type:      gsm.EventNewSMS,
+			Timestamp: time.Now(),
+			Data:      map[string]interface{}{"storage": string(name), "index": int(index)},
+		})
+	})
+	if _, err := c.call(ServiceWMS, wmsSetEventReport, TLV{Type: 0x10, Value: u8(1)}); err != nil {
+		return fmt.Errorf("failed to enable SMS indications: %w", err)
+	}
+	return nil
+}
+
+// enableEvents один раз подписывается на уведомления WMS и NAS
+func (c *Client) enableEvents() error {
+	c.eventsOnce.Do(func() {
+		if err := c.enableSMSEvents(); err != nil {
+			c.eventsErr = err
+			return
+		}
+		c.eventsErr = c.enableNetworkEvents()
+	})
+	return c.eventsErr
+}
@@ -0,0 +1,68 @@
+package qmi
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/veryevilzed/gsm"
+)
+
+func TestWMSTranscript(t *testing.T) {
+	// SMS-DELIVER "hellohello" от 27838890001
+	pdu, _ := hex.DecodeString("07917283010010F5040BC87238880900F10000993092516195800AE8329BFD4697D9EC37")
+	list := append(u32(1), append(u32(3), tagMTNotRead)...)
+	message := append([]byte{tagMTNotRead, wmsFormatGWPP}, append(u16(uint16(len(pdu))), pdu...)...)
+
+	f, c := newFakeModem(t,
+		step{service: ServiceWMS, id: wmsListMessages,
+			req:  []TLV{{Type: 0x01, Value: u8(storageUIM)}, {Type: 0x11, Value: u8(tagMTNotRead)}, {Type: 0x12, Value: u8(wmsModeGW)}},
+			resp: []TLV{{Type: 0x01, Value: list}}},
+		step{service: ServiceWMS, id: wmsRawRead,
+			req:  []TLV{{Type: 0x01, Value: append([]byte{storageUIM}, u32(3)...)}, {Type: 0x10, Value: u8(wmsModeGW)}},
+			resp: []TLV{{Type: 0x01, Value: message}}},
+		step{service: ServiceWMS, id: wmsDelete,
+			req: []TLV{{Type: 0x01, Value: u8(storageNV)}, {Type: 0x10, Value: u32(3)}, {Type: 0x12, Value: u8(wmsModeGW)}}},
+	)
+
+	messages, err := c.ListSMS("REC UNREAD")
+	if err != nil {
+		t.Fatalf("ListSMS: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Index != 3 || messages[0].Status != "REC UNREAD" ||
+		messages[0].Sender != "27838890001" || messages[0].Text != "hellohello" {
+		t.Errorf("messages = %+v", messages)
+	}
+
+	if err := c.SetSMSStorage(gsm.StoragePhone); err != nil {
+		t.Fatalf("SetSMSStorage: %v", err)
+	}
+	if err := c.DeleteSMS(3); err != nil {
+		t.Errorf("DeleteSMS: %v", err)
+	}
+	f.done()
+}
+
+func TestWMSIndications(t *testing.T) {
+	f, c := newFakeModem(t,
+		step{service: ServiceWMS, id: wmsSetEventReport},
+		step{service: ServiceNAS, id: nasRegisterIndications},
+	)
+	events, err := c.GetEventChannel()
+	if err != nil {
+		t.Fatalf("GetEventChannel: %v", err)
+	}
+	f.done()
+
+	// Event Report приходит широковещательно: хранилище и индекс в TLV 0x10
+	f.send(indication(ServiceWMS, broadcastClientID, wmsEventReportInd, TLV{Type: 0x10, Value: append(u8(storageNV), u32(7)...)}))
+
+	select {
+	case event := <-events:
+		if event.Type != gsm.EventNewSMS || event.Data["storage"] != string(gsm.StoragePhone) || event.Data["index"] != 7 {
+			t.Errorf("event = %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no EventNewSMS")
+	}
+}
@@ -0,0 +1,223 @@
+package gsm
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryTimeout - время ожидания ответа для Query и QueryAll. Для быстрых команд используйте
+// QueryTimeout и QueryAllTimeout с коротким тайм-аутом.
+const queryTimeout = 30 * time.Second
+
+// ErrNoResponse возвращается, если в ответе нет строки с ожидаемым префиксом
+var ErrNoResponse = errors.New("no information response")
+
+// Query выполняет команду и разбирает первую строку ответа с префиксом в структуру T.
+//
+// Поля структуры связываются с позицией значения тегом `at`:
+//
+//	type StorageInfo struct {
+//		Storage string `at:"0"`
+//		Used    int    `at:"1"`
+//		Total   int    `at:"2"`
+//		LAC     int64  `at:"3,hex"`
+//	}
+//
+//	info, err := gsm.Query[StorageInfo](modem, "AT+CPMS?", "+CPMS")
+//
+// Поддерживаются строки, целые числа (опция hex - шестнадцатеричная запись), bool ("0"/"1"),
+// числа с плавающей точкой, именованные типы на их основе (перечисления), типы с
+// encoding.TextUnmarshaler, срезы (значение-список в скобках) и вложенные структуры (список
+// в скобках разбирается по тем же правилам). Пустые и отсутствующие поля пропускаются.
+func Query[T any](m *Modem, cmd, prefix string) (T, error) {
+	return QueryTimeout[T](m, cmd, prefix, queryTimeout)
+}
+
+// QueryTimeout выполняет Query с указанным временем ожидания ответа
+func QueryTimeout[T any](m *Modem, cmd, prefix string, timeout time.Duration) (T, error) {
+	var result T
+	resp, err := m.execCommand(cmd, timeout)
+	if err != nil {
+		return result, fmt.Errorf("failed to execute %s: %w", cmd, err)
+	}
+	items, err := ParseInfo[T](resp, prefix)
+	if err != nil {
+		return result, err
+	}
+	if len(items) == 0 {
+		return result, fmt.Errorf("%w: %s", ErrNoResponse, infoPrefix(prefix))
+	}
+	return items[0], nil
+}
+
+// QueryAll выполняет команду и разбирает все строки ответа с префиксом (многострочные ответы)
+func QueryAll[T any](m *Modem, cmd, prefix string) ([]T, error) {
+	return QueryAllTimeout[T](m, cmd, prefix, queryTimeout)
+}
+
+// QueryAllTimeout выполняет QueryAll с указанным временем ожидания ответа
+func QueryAllTimeout[T any](m *Modem, cmd, prefix string, timeout time.Duration) ([]T, error) {
+	resp, err := m.execCommand(cmd, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute %s: %w", cmd, err)
+	}
+	return ParseInfo[T](resp, prefix)
+}
+
+// ParseInfo разбирает все строки ответа с префиксом в срез структур T (см. Query)
+func ParseInfo[T any](response, prefix string) ([]T, error) {
+	prefix = infoPrefix(prefix)
+	var items []T
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+		fields, err := ParseFields(line[len(prefix):])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s response: %w", prefix, err)
+		}
+		var item T
+		if err := UnmarshalFields(fields, &item); err != nil {
+			return nil, fmt.Errorf("failed to parse %s response: %w", prefix, err)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// UnmarshalFields заполняет структуру по указателю v значениями полей согласно тегам `at`
+func UnmarshalFields(fields []Field, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected pointer to struct, got %T", v)
+	}
+	return unmarshalStruct(fields, rv.Elem())
+}
+
+// infoPrefix дополняет префикс двоеточием ("+CPMS" -> "+CPMS:")
+func infoPrefix(prefix string) string {
+	if strings.HasSuffix(prefix, ":") {
+		return prefix
+	}
+	return prefix + ":"
+}
+
+// unmarshalStruct заполняет поля структуры по тегам `at`
+func unmarshalStruct(fields []Field, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag, ok := sf.Tag.Lookup("at")
+		if !ok || tag == "-" || !sf.IsExported() {
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		index, err := strconv.Atoi(opts[0])
+		if err != nil || index < 0 {
+			return fmt.Errorf("invalid at tag %q on field %s", tag, sf.Name)
+		}
+		hex := false
+		for _, opt := range opts[1:] {
+			switch opt {
+			case "hex":
+				hex = true
+			default:
+				return fmt.Errorf("unknown at tag option %q on field %s", opt, sf.Name)
+			}
+		}
+
+		field := fieldAt(fields, index)
+		if field.IsEmpty() {
+			continue
+		}
+		if err := setField(rv.Field(i), field, hex); err != nil {
+			return fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+	}
+	return nil
+}
+
+// setField преобразует значение поля ответа в тип поля структуры
+func setField(v reflect.Value, field Field, hex bool) error {
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(field.Value))
+		}
+	}
+
+	base, number := 10, field.Value
+	if hex {
+		base = 16
+		number = strings.TrimPrefix(strings.TrimPrefix(number, "0x"), "0X")
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if field.IsList {
+			v.SetString(field.String())
+		} else {
+			v.SetString(field.Value)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(number, base, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", field.Value)
+		}
+		v.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(number, base, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", field.Value)
+		}
+		v.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(field.Value, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", field.Value)
+		}
+		v.SetFloat(f)
+
+	case reflect.Bool:
+		switch field.Value {
+		case "0":
+			v.SetBool(false)
+		case "1":
+			v.SetBool(true)
+		default:
+			return fmt.Errorf("invalid boolean %q", field.Value)
+		}
+
+	case reflect.Slice:
+		items := field.List
+		if !field.IsList {
+			items = []Field{field}
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setField(slice.Index(i), item, hex); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+
+	case reflect.Struct:
+		if !field.IsList {
+			return fmt.Errorf("expected list for struct, got %q", field.Value)
+		}
+		return unmarshalStruct(field.List, v)
+
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package gsm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testMode именованный тип-перечисление
+type testMode int
+
+// testLevel тип с encoding.TextUnmarshaler
+type testLevel struct {
+	name string
+}
+
+func (l *testLevel) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		return errors.New("empty level")
+	}
+	l.name = strings.ToLower(string(text))
+	return nil
+}
+
+// testCell вложенная структура
+type testCell struct {
+	MCC int    `at:"0"`
+	MNC string `at:"1"`
+}
+
+// testInfo структура со всеми поддерживаемыми видами полей
+type testInfo struct {
+	Name     string    `at:"0"`
+	Count    int       `at:"1"`
+	LAC      int64     `at:"2,hex"`
+	CellID   uint32    `at:"3,hex"`
+	Enabled  bool      `at:"4"`
+	Ratio    float64   `at:"5"`
+	Mode     testMode  `at:"6"`
+	Level    testLevel `at:"7"`
+	Bands    []int     `at:"8"`
+	Cell     testCell  `at:"9"`
+	Raw      string    `at:"10"`
+	Missing  int       `at:"20"`
+	Skipped  int       `at:"-"`
+	internal int       `at:"1"`
+}
+
+func TestUnmarshalFields(t *testing.T) {
+	fields, err := ParseFields(`"SM",12,"1A2B",0x0FF,1,0.5,3,"HIGH",(1,3,7),(250,"01"),(0-4)`)
+	if err != nil {
+		t.Fatalf("ParseFields: %v", err)
+	}
+	info := testInfo{Missing: -1, Skipped: -1}
+	if err := UnmarshalFields(fields, &info); err != nil {
+		t.Fatalf("UnmarshalFields: %v", err)
+	}
+	want := testInfo{
+		Name:    "SM",
+		Count:   12,
+		LAC:     0x1A2B,
+		CellID:  0xFF,
+		Enabled: true,
+		Ratio:   0.5,
+		Mode:    3,
+		Level:   testLevel{name: "high"},
+		Bands:   []int{1, 3, 7},
+		Cell:    testCell{MCC: 250, MNC: "01"},
+		Raw:     "(0-4)",
+		// Отсутствующее поле и поле с тегом "-" не изменяются
+		Missing: -1,
+		Skipped: -1,
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("UnmarshalFields = %+v, want %+v", info, want)
+	}
+}
+
+func TestUnmarshalFieldsEmpty(t *testing.T) {
+	// Пустые поля пропускаются, пустая строка в кавычках - нет
+	fields, _ := ParseFields(`"",,`)
+	info := testInfo{Name: "old", Count: 7, LAC: 5}
+	if err := UnmarshalFields(fields, &info); err != nil {
+		t.Fatalf("UnmarshalFields: %v", err)
+	}
+	if info.Name != "" || info.Count != 7 || info.LAC != 5 {
+		t.Errorf("UnmarshalFields = %+v", info)
+	}
+	// Одиночное значение вместо списка становится срезом из одного элемента
+	var bands struct {
+		Bands []int `at:"0"`
+	}
+	if err := UnmarshalFields([]Field{v("3")}, &bands); err != nil || !reflect.DeepEqual(bands.Bands, []int{3}) {
+		t.Errorf("UnmarshalFields = %v, %v", bands.Bands, err)
+	}
+}
+
+func TestUnmarshalFieldsTarget(t *testing.T) {
+	fields := []Field{v("1")}
+	var info testInfo
+	var nilInfo *testInfo
+	var count int
+	for _, target := range []interface{}{info, nilInfo, &count, nil} {
+		if err := UnmarshalFields(fields, target); err == nil {
+			t.Errorf("UnmarshalFields(%T) succeeded", target)
+		}
+	}
+	if err := UnmarshalFields(fields, &info); err != nil || info.Count != 0 || info.Name != "1" {
+		t.Errorf("UnmarshalFields(&info) = %+v, %v", info, err)
+	}
+}
+
+func TestUnmarshalFieldsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []Field
+		target interface{}
+	}{
+		{"invalid int", []Field{v("x")}, &struct {
+			N int `at:"0"`
+		}{}},
+		{"int overflow", []Field{v("300")}, &struct {
+			N int8 `at:"0"`
+		}{}},
+		{"negative uint", []Field{v("-1")}, &struct {
+			N uint `at:"0"`
+		}{}},
+		{"invalid hex", []Field{v("XYZ")}, &struct {
+			N int `at:"0,hex"`
+		}{}},
+		{"invalid bool", []Field{v("2")}, &struct {
+			B bool `at:"0"`
+		}{}},
+		{"invalid float", []Field{v("1,5")}, &struct {
+			F float64 `at:"0"`
+		}{}},
+		{"invalid slice item", []Field{list(v("1"), v("x"))}, &struct {
+			S []int `at:"0"`
+		}{}},
+		{"struct from value", []Field{v("1")}, &struct {
+			C testCell `at:"0"`
+		}{}},
+		{"text unmarshaler error", []Field{q("")}, &struct {
+			L testLevel `at:"0"`
+		}{}},
+		{"unsupported type", []Field{v("1")}, &struct {
+			M map[string]int `at:"0"`
+		}{}},
+		{"invalid index", []Field{v("1")}, &struct {
+			N int `at:"first"`
+		}{}},
+		{"empty index", []Field{v("1")}, &struct {
+			N int `at:""`
+		}{}},
+		{"negative index", []Field{v("1")}, &struct {
+			N int `at:"-1"`
+		}{}},
+		{"unknown option", []Field{v("1")}, &struct {
+			N int `at:"0,octal"`
+		}{}},
+	}
+	for _, tt := range tests {
+		if err := UnmarshalFields(tt.fields, tt.target); err == nil {
+			t.Errorf("%s: UnmarshalFields succeeded: %+v", tt.name, tt.target)
+		}
+	}
+}
+
+func TestParseInfo(t *testing.T) {
+	type storage struct {
+		Storage string `at:"0"`
+		Used    int    `at:"1"`
+		Total   int    `at:"2"`
+	}
+	resp := "+CPMS: \"SM\",3,20\r\n+CPMS: \"ME\",0,100\r\n+CMTI: \"SM\",4\r\n\r\nOK"
+	items, err := ParseInfo[storage](resp, "+CPMS")
+	if err != nil {
+		t.Fatalf("ParseInfo: %v", err)
+	}
+	want := []storage{{"SM", 3, 20}, {"ME", 0, 100}}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("ParseInfo = %+v, want %+v", items, want)
+	}
+	if items, err := ParseInfo[storage](resp, "+CPMS:"); err != nil || len(items) != 2 {
+		t.Errorf("ParseInfo with colon = %+v, %v", items, err)
+	}
+	if items, err := ParseInfo[storage]("OK", "+CPMS"); err != nil || len(items) != 0 {
+		t.Errorf("ParseInfo without lines = %+v, %v", items, err)
+	}
+	if _, err := ParseInfo[storage]("+CPMS: \"SM\",x,20\r\nOK", "+CPMS"); err == nil {
+		t.Error("ParseInfo accepted invalid integer")
+	}
+	if _, err := ParseInfo[storage]("+CPMS: \"SM,3\r\nOK", "+CPMS"); err == nil {
+		t.Error("ParseInfo accepted unterminated string")
+	}
+}
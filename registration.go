@@ -0,0 +1,308 @@
+package gsm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RegistrationDomain домен регистрации (семейство команд +CREG/+CGREG/+CEREG/+C5GREG)
+type RegistrationDomain string
+
+const (
+	RegistrationCS   RegistrationDomain = "CREG"   // Коммутация каналов (голос, SMS)
+	RegistrationGPRS RegistrationDomain = "CGREG"  // Пакетная сеть GPRS/UMTS
+	RegistrationEPS  RegistrationDomain = "CEREG"  // Пакетная сеть LTE (EPS), в т.ч. Cat-M и NB-IoT
+	Registration5GS  RegistrationDomain = "C5GREG" // Пакетная сеть 5G (5GS)
+)
+
+// registrationDomains домены в порядке опроса
+var registrationDomains = []RegistrationDomain{RegistrationCS, RegistrationGPRS, RegistrationEPS, Registration5GS}
+
+// AccessTechnology технология радиодоступа (<AcT> из 27.007)
+type AccessTechnology int
+
+const (
+	AccessTechUnknown    AccessTechnology = -1 // Не сообщается модемом
+	AccessTechGSM        AccessTechnology = 0  // GSM
+	AccessTechGSMCompact AccessTechnology = 1  // GSM Compact
+	AccessTechUMTS       AccessTechnology = 2  // UTRAN
+	AccessTechEDGE       AccessTechnology = 3  // GSM с EGPRS
+	AccessTechHSDPA      AccessTechnology = 4  // UTRAN с HSDPA
+	AccessTechHSUPA      AccessTechnology = 5  // UTRAN с HSUPA
+	AccessTechHSPA       AccessTechnology = 6  // UTRAN с HSDPA и HSUPA
+	AccessTechLTE        AccessTechnology = 7  // E-UTRAN
+	AccessTechECGSMIoT   AccessTechnology = 8  // EC-GSM-IoT
+	AccessTechNBIoT      AccessTechnology = 9  // E-UTRAN NB-S1 (NB-IoT)
+	AccessTechLTE5GC     AccessTechnology = 10 // E-UTRA, подключенный к 5GCN
+	AccessTechNR5GC      AccessTechnology = 11 // NR, подключенный к 5GCN (SA)
+	AccessTechNGRAN      AccessTechnology = 12 // NG-RAN
+	AccessTechENDC       AccessTechnology = 13 // E-UTRA-NR dual connectivity (NSA)
+
+	// AccessTechLTECatM - LTE Cat-M1 (eMTC); BG96, SARA-R4 и другие модули сообщают его в +CEREG значением 8
+	AccessTechLTECatM AccessTechnology = 100
+)
+
+// String возвращает название технологии доступа
+func (a AccessTechnology) String() string {
+	switch a {
+	case AccessTechGSM, AccessTechGSMCompact:
+		return "GSM"
+	case AccessTechEDGE:
+		return "EDGE"
+	case AccessTechUMTS:
+		return "UMTS"
+	case AccessTechHSDPA, AccessTechHSUPA, AccessTechHSPA:
+		return "HSPA"
+	case AccessTechLTE, AccessTechLTE5GC:
+		return "LTE"
+	case AccessTechLTECatM:
+		return "LTE Cat-M"
+	case AccessTechNBIoT:
+		return "NB-IoT"
+	case AccessTechECGSMIoT:
+		return "EC-GSM-IoT"
+	case AccessTechNR5GC, AccessTechNGRAN:
+		return "NR"
+	case AccessTechENDC:
+		return "LTE+NR"
+	default:
+		return "Unknown"
+	}
+}
+
+// RegistrationInfo состояние регистрации в одном домене
+type RegistrationInfo struct {
+	Domain        RegistrationDomain // Семейство команды
+	Status        NetworkStatus      // Статус регистрации
+	AreaCode      int64              // LAC (GSM/UMTS) или TAC (LTE/5G), -1 если не сообщается
+	CellID        int64              // Полный идентификатор соты (28 бит для LTE, 36 бит для NR), -1 если не сообщается
+	AccessTech    AccessTechnology   // Технология доступа
+	RAC           int                // Routing Area Code (только +CGREG), -1 если не сообщается
+	CauseType     int                // Тип причины отказа (0 - 24.008/24.301, 1 - vendor), -1 если не сообщается
+	RejectCause   int                // Причина отказа в регистрации, -1 если не сообщается
+	ActiveTime    time.Duration      // PSM: таймер активности T3324, 0 если не сообщается или отключен
+	PeriodicTimer time.Duration      // PSM: периодическое обновление T3412/T3312, 0 если не сообщается или отключен
+}
+
+// Registered проверяет, зарегистрирован ли модем в сети
+func (r *RegistrationInfo) Registered() bool {
+	return r.Status.Registered()
+}
+
+// Registered проверяет, означает ли статус регистрацию в сети (домашней или в роуминге)
+func (s NetworkStatus) Registered() bool {
+	switch s {
+	case NetworkRegisteredHome, NetworkRegisteredRoaming,
+		NetworkRegisteredSMSOnlyHome, NetworkRegisteredSMSOnlyRoaming,
+		NetworkRegisteredCSFBNotPreferredHome, NetworkRegisteredCSFBNotPreferredRoaming:
+		return true
+	default:
+		return false
+	}
+}
+
+// GetRegistration возвращает состояние регистрации в домене (AT+CREG?, AT+CGREG?, AT+CEREG?, AT+C5GREG?).
+// LAC и Cell ID сообщаются только после SetRegistrationReporting с mode >= 2.
+func (m *Modem) GetRegistration(domain RegistrationDomain) (*RegistrationInfo, error) {
+	cmd := "AT+" + string(domain) + "?"
+	resp, err := m.execCommand(cmd, time.Second*2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s registration: %w", domain, err)
+	}
+
+	prefix := "+" + string(domain) + ":"
+	for _, fields := range parseInfoLines(resp, prefix) {
+		if len(fields) >= 2 {
+			return parseRegistration(domain, fields[1:]), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected response format: %s", resp)
+}
+
+// GetRegistrations возвращает состояние регистрации во всех доменах, поддерживаемых модемом
+func (m *Modem) GetRegistrations() ([]RegistrationInfo, error) {
+	var result []RegistrationInfo
+	var lastErr error
+	for _, domain := range registrationDomains {
+		info, err := m.GetRegistration(domain)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		result = append(result, *info)
+	}
+	if len(result) == 0 {
+		return nil, lastErr
+	}
+	return result, nil
+}
+
+// SetRegistrationReporting устанавливает режим уведомлений о регистрации (<n>):
+// 0 - выключены, 1 - только статус, 2 - статус и местоположение, 3 - с причиной отказа,
+// 4 и 5 - дополнительно таймеры PSM (только +CGREG/+CEREG)
+func (m *Modem) SetRegistrationReporting(domain RegistrationDomain, mode int) error {
+	cmd := fmt.Sprintf("AT+%s=%d", domain, mode)
+	if _, err := m.execCommand(cmd, time.Second*2); err != nil {
+		return fmt.Errorf("failed to set %s reporting: %w", domain, err)
+	}
+	return nil
+}
+
+// bestRegistration возвращает статус регистрации с учетом всех доменов: LTE-only SIM-карта
+// не регистрируется в CS домене, но работает через EPS
+func (m *Modem) bestRegistration(domains ...RegistrationDomain) (NetworkStatus, error) {
+	status := NetworkUnknown
+	var firstErr error
+	found := false
+	for _, domain := range domains {
+		info, err := m.GetRegistration(domain)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if info.Registered() {
+			return info.Status, nil
+		}
+		if !found {
+			status = info.Status
+			found = true
+		}
+	}
+	if !found {
+		return NetworkUnknown, firstErr
+	}
+	return status, nil
+}
+
+// parseRegistration разбирает поля после <n> (ответ на запрос) или всю строку URC
+func parseRegistration(domain RegistrationDomain, fields []Field) *RegistrationInfo {
+	info := &RegistrationInfo{
+		Domain:      domain,
+		Status:      NetworkStatus(fieldAt(fields, 0).IntOr(int(NetworkUnknown))),
+		AreaCode:    -1,
+		CellID:      -1,
+		AccessTech:  AccessTechUnknown,
+		RAC:         -1,
+		CauseType:   -1,
+		RejectCause: -1,
+	}
+
+	if v, err := fieldAt(fields, 1).Hex(); err == nil {
+		info.AreaCode = v
+	}
+	if v, err := fieldAt(fields, 2).Hex(); err == nil {
+		info.CellID = v
+	}
+	if v, err := fieldAt(fields, 3).Int(); err == nil {
+		info.AccessTech = AccessTechnology(v)
+		if domain == RegistrationEPS && v == int(AccessTechECGSMIoT) {
+			info.AccessTech = AccessTechLTECatM
+		}
+	}
+
+	// Хвост строки различается по семействам
+	var rest []Field
+	if len(fields) > 4 {
+		rest = fields[4:]
+	}
+	switch domain {
+	case RegistrationGPRS:
+		// [<rac>][,[<cause_type>],[<reject_cause>][,[<Active-Time>],[<Periodic-RAU>],[<GPRS-READY-timer>]]]
+		if v, err := fieldAt(rest, 0).Hex(); err == nil {
+			info.RAC = int(v)
+		}
+		if len(rest) > 0 {
+			rest = rest[1:]
+		}
+	case Registration5GS:
+		// [<Allowed_NSSAI_length>],[<Allowed_NSSAI>][,<cause_type>,<reject_cause>]
+		if len(rest) >= 2 {
+			rest = rest[2:]
+		} else {
+			rest = nil
+		}
+	}
+
+	info.CauseType = fieldAt(rest, 0).IntOr(-1)
+	info.RejectCause = fieldAt(rest, 1).IntOr(-1)
+	if domain == RegistrationGPRS || domain == RegistrationEPS {
+		info.ActiveTime = decodeGPRSTimer2(fieldAt(rest, 2).Value)
+		info.PeriodicTimer = decodeGPRSTimer3(fieldAt(rest, 3).Value)
+	}
+	return info
+}
+
+// registrationURC определяет домен и поля URC +CREG/+CGREG/+CEREG/+C5GREG
+func registrationURC(line string) (RegistrationDomain, []Field, bool) {
+	for _, domain := range registrationDomains {
+		fields, ok := parseInfoLine(line, "+"+string(domain)+":")
+		if !ok || len(fields) == 0 {
+			continue
+		}
+		// Ответ на запрос начинается с <n>: +CREG: 2,1,"1A2B","00C3D4E5",7.
+		// В URC первым идет <stat>, а за ним сразу <lac> в кавычках: +CREG: 1,"1A2B","00C3D4E5",7
+		if len(fields) >= 2 && !fields[1].Quoted && !fields[1].IsEmpty() {
+			fields = fields[1:]
+		}
+		return domain, fields, true
+	}
+	return "", nil, false
+}
+
+// decodeGPRSTimer2 декодирует таймер T3324 (GPRS Timer 2, 24.008 10.5.7.4) из строки битов "00100100"
+func decodeGPRSTimer2(bits string) time.Duration {
+	unit, value, ok := decodeTimerBits(bits)
+	if !ok {
+		return 0
+	}
+	switch unit {
+	case 0:
+		return time.Duration(value) * 2 * time.Second
+	case 1:
+		return time.Duration(value) * time.Minute
+	case 2:
+		return time.Duration(value) * 6 * time.Minute
+	default:
+		// 111 - таймер отключен
+		return 0
+	}
+}
+
+// decodeGPRSTimer3 декодирует таймер T3412/T3312 (GPRS Timer 3, 24.008 10.5.7.4a) из строки битов
+func decodeGPRSTimer3(bits string) time.Duration {
+	unit, value, ok := decodeTimerBits(bits)
+	if !ok {
+		return 0
+	}
+	multipliers := []time.Duration{
+		10 * time.Minute,
+		time.Hour,
+		10 * time.Hour,
+		2 * time.Second,
+		30 * time.Second,
+		time.Minute,
+		320 * time.Hour,
+	}
+	if unit >= len(multipliers) {
+		// 111 - таймер отключен
+		return 0
+	}
+	return time.Duration(value) * multipliers[unit]
+}
+
+// decodeTimerBits разбирает байт таймера: 3 старших бита - единица измерения, 5 младших - значение
+func decodeTimerBits(bits string) (unit, value int, ok bool) {
+	bits = strings.TrimSpace(bits)
+	if len(bits) != 8 {
+		return 0, 0, false
+	}
+	b, err := strconv.ParseUint(bits, 2, 8)
+	if err != nil {
+		return 0, 0, false
+	}
+	return int(b >> 5), int(b & 0x1F), true
+}
@@ -0,0 +1,401 @@
+package gsm
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignalReport подробные метрики сигнала обслуживающей соты.
+// Значения, которые модем не сообщает, равны NaN (проверка - math.IsNaN).
+type SignalReport struct {
+	Source     string           // Команда-источник: "CESQ", "HCSQ", "QCSQ", "CPSI", "CSQ"
+	AccessTech AccessTechnology // Технология доступа (GSM, UMTS, LTE, NR)
+	RSSI       float64          // Мощность принимаемого сигнала, дБм
+	RSCP       float64          // UMTS: мощность пилотного канала, дБм
+	EcIo       float64          // UMTS: отношение Ec/Io, дБ
+	RSRP       float64          // LTE/NR: мощность опорного сигнала, дБм
+	RSRQ       float64          // LTE/NR: качество опорного сигнала, дБ
+	SINR       float64          // LTE/NR: отношение сигнал/шум, дБ
+	Quality    int              // Нормализованное качество 0-100%
+	Bars       int              // Количество "палочек" 0-5
+}
+
+// NewSignalReport создает отчет без значений
+func NewSignalReport(source string) *SignalReport {
+	nan := math.NaN()
+	return &SignalReport{
+		Source:     source,
+		AccessTech: AccessTechUnknown,
+		RSSI:       nan,
+		RSCP:       nan,
+		EcIo:       nan,
+		RSRP:       nan,
+		RSRQ:       nan,
+		SINR:       nan,
+	}
+}
+
+// hasMetrics проверяет, что модем сообщил хотя бы одно значение
+func (r *SignalReport) hasMetrics() bool {
+	for _, v := range []float64{r.RSSI, r.RSCP, r.RSRP, r.RSRQ, r.SINR} {
+		if !math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalize вычисляет качество и количество палочек по основной метрике технологии
+func (r *SignalReport) Normalize() {
+	switch {
+	case !math.IsNaN(r.RSRP):
+		// LTE/NR: -140 дБм - нет связи, -80 дБм и выше - отлично
+		r.Quality = scaleQuality(r.RSRP, -140, -80)
+	case !math.IsNaN(r.RSCP):
+		// UMTS: -120..-60 дБм
+		r.Quality = scaleQuality(r.RSCP, -120, -60)
+	case !math.IsNaN(r.RSSI):
+		// GSM: -113..-51 дБм (диапазон CSQ)
+		r.Quality = scaleQuality(r.RSSI, -113, -51)
+	default:
+		r.Quality = 0
+	}
+	r.Bars = (r.Quality + 19) / 20
+}
+
+// scaleQuality переводит значение из диапазона [min, max] в проценты
+func scaleQuality(value, min, max float64) int {
+	q := int(math.Round((value - min) * 100 / (max - min)))
+	if q < 0 {
+		return 0
+	}
+	if q > 100 {
+		return 100
+	}
+	return q
+}
+
+// CSQToDBm переводит RSSI из AT+CSQ (0-31) в дБм; false для 99 (неизвестно)
+func CSQToDBm(rssi int) (int, bool) {
+	if rssi < 0 || rssi > 31 {
+		return 0, false
+	}
+	return -113 + 2*rssi, true
+}
+
+// DBmToCSQ переводит мощность сигнала в дБм в шкалу AT+CSQ (0-31)
+func DBmToCSQ(dbm float64) int {
+	csq := int(math.Round((dbm + 113) / 2))
+	if csq < 0 {
+		return 0
+	}
+	if csq > 31 {
+		return 31
+	}
+	return csq
+}
+
+// DBm возвращает уровень сигнала в дБм; false, если модем не знает уровень (99)
+func (s *SignalQuality) DBm() (int, bool) {
+	return CSQToDBm(s.RSSI)
+}
+
+// GetSignalReport возвращает подробные метрики сигнала. Используются vendor-команды
+// (Huawei ^HCSQ, Quectel +QCSQ, SIMCom +CPSI), затем AT+CESQ и в последнюю очередь AT+CSQ.
+func (m *Modem) GetSignalReport() (*SignalReport, error) {
+	var sources []func() (*SignalReport, error)
+	switch m.Vendor() {
+	case VendorHuawei:
+		sources = append(sources, m.querySignal("AT^HCSQ?", "^HCSQ:", parseHCSQ))
+	case VendorQuectel:
+		sources = append(sources, m.querySignal("AT+QCSQ", "+QCSQ:", parseQCSQ))
+	case VendorSIMCom:
+		sources = append(sources, m.querySignal("AT+CPSI?", "+CPSI:", parseCPSI))
+	}
+	sources = append(sources, m.querySignal("AT+CESQ", "+CESQ:", parseCESQ), m.csqSignalReport)
+
+	var lastErr error
+	for _, source := range sources {
+		report, err := source()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if report.hasMetrics() {
+			report.Normalize()
+			return report, nil
+		}
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("signal level is unknown")
+	}
+	return nil, fmt.Errorf("failed to get signal report: %w", lastErr)
+}
+
+// querySignal возвращает источник метрик: команду и парсер ее ответа
+func (m *Modem) querySignal(cmd, prefix string, parse func([]Field) *SignalReport) func() (*SignalReport, error) {
+	return func() (*SignalReport, error) {
+		resp, err := m.execCommand(cmd, time.Second*2)
+		if err != nil {
+			return nil, err
+		}
+		for _, fields := range parseInfoLines(resp, prefix) {
+			if report := parse(fields); report != nil {
+				return report, nil
+			}
+		}
+		return nil, fmt.Errorf("unexpected response format: %s", resp)
+	}
+}
+
+// csqSignalReport строит отчет по AT+CSQ
+func (m *Modem) csqSignalReport() (*SignalReport, error) {
+	signal, err := m.GetSignalQuality()
+	if err != nil {
+		return nil, err
+	}
+	report := NewSignalReport("CSQ")
+	if dbm, ok := signal.DBm(); ok {
+		report.RSSI = float64(dbm)
+	}
+	return report, nil
+}
+
+// parseCESQ разбирает +CESQ: <rxlev>,<ber>,<rscp>,<ecno>,<rsrq>,<rsrp>[,<ss_rsrq>,<ss_rsrp>,<ss_sinr>]
+func parseCESQ(fields []Field) *SignalReport {
+	if len(fields) < 6 {
+		return nil
+	}
+	report := NewSignalReport("CESQ")
+
+	// 27.007 8.69: 99/255 - значение неизвестно
+	if v := fields[0].IntOr(99); v <= 63 {
+		report.RSSI = float64(v - 111)
+		report.AccessTech = AccessTechGSM
+	}
+	if v := fields[2].IntOr(255); v <= 96 {
+		report.RSCP = float64(v - 121)
+		report.AccessTech = AccessTechUMTS
+	}
+	if v := fields[3].IntOr(255); v <= 49 {
+		report.EcIo = float64(v)*0.5 - 24.5
+	}
+	if v := fields[4].IntOr(255); v <= 34 {
+		report.RSRQ = float64(v)*0.5 - 20
+	}
+	if v := fields[5].IntOr(255); v <= 97 {
+		report.RSRP = float64(v - 141)
+		report.AccessTech = AccessTechLTE
+	}
+
+	// 5G NR (27.007 Rel-15)
+	if v := fieldAt(fields, 7).IntOr(255); v <= 127 {
+		if report.AccessTech == AccessTechLTE {
+			report.AccessTech = AccessTechENDC
+		} else {
+			report.AccessTech = AccessTechNR5GC
+			report.RSRP = float64(v - 157)
+			if q := fieldAt(fields, 6).IntOr(255); q <= 127 {
+				report.RSRQ = float64(q)*0.5 - 43.5
+			}
+		}
+		if s := fieldAt(fields, 8).IntOr(255); s <= 127 {
+			report.SINR = float64(s)*0.5 - 23.5
+		}
+	}
+	return report
+}
+
+// parseHCSQ разбирает Huawei ^HCSQ: "LTE",<rssi>,<rsrp>,<sinr>,<rsrq> / "WCDMA",<rssi>,<rscp>,<ecio> / "GSM",<rssi>
+func parseHCSQ(fields []Field) *SignalReport {
+	if len(fields) == 0 {
+		return nil
+	}
+	report := NewSignalReport("HCSQ")
+	value := func(i, max int) (float64, bool) {
+		v := fieldAt(fields, i).IntOr(255)
+		return float64(v), v <= max
+	}
+
+	if v, ok := value(1, 96); ok {
+		report.RSSI = v - 121
+	}
+	switch strings.ToUpper(fields[0].Value) {
+	case "GSM":
+		report.AccessTech = AccessTechGSM
+	case "WCDMA", "TD-SCDMA":
+		report.AccessTech = AccessTechUMTS
+		if v, ok := value(2, 96); ok {
+			report.RSCP = v - 121
+		}
+		if v, ok := value(3, 65); ok {
+			report.EcIo = v*0.5 - 32.5
+		}
+	case "LTE":
+		report.AccessTech = AccessTechLTE
+		if v, ok := value(2, 97); ok {
+			report.RSRP = v - 141
+		}
+		if v, ok := value(3, 251); ok {
+			report.SINR = v*0.2 - 20.2
+		}
+		if v, ok := value(4, 34); ok {
+			report.RSRQ = v*0.5 - 20
+		}
+	case "NR":
+		// ^HCSQ: "NR",<ss_rsrp>,<ss_sinr>,<ss_rsrq>
+		report.AccessTech = AccessTechNR5GC
+		report.RSSI = math.NaN()
+		if v, ok := value(1, 126); ok {
+			report.RSRP = v - 157
+		}
+		if v, ok := value(2, 127); ok {
+			report.SINR = v*0.5 - 23.5
+		}
+		if v, ok := value(3, 127); ok {
+			report.RSRQ = v*0.5 - 43.5
+		}
+	default:
+		// "NOSERVICE"
+		return report
+	}
+	return report
+}
+
+// parseQCSQ разбирает Quectel +QCSQ: <sysmode>,... (значения уже в дБм/дБ)
+func parseQCSQ(fields []Field) *SignalReport {
+	if len(fields) == 0 {
+		return nil
+	}
+	report := NewSignalReport("QCSQ")
+	value := func(i int) (float64, bool) {
+		v, err := strconv.ParseFloat(fieldAt(fields, i).Value, 64)
+		return v, err == nil
+	}
+
+	switch strings.ToUpper(fields[0].Value) {
+	case "GSM":
+		// "GSM",<gsm_rssi>
+		report.AccessTech = AccessTechGSM
+		if v, ok := value(1); ok {
+			report.RSSI = v
+		}
+	case "WCDMA", "TDSCDMA":
+		// "WCDMA",<wcdma_rssi>,<wcdma_rscp>,<wcdma_ecio>
+		report.AccessTech = AccessTechUMTS
+		if v, ok := value(1); ok {
+			report.RSSI = v
+		}
+		if v, ok := value(2); ok {
+			report.RSCP = v
+		}
+		if v, ok := value(3); ok {
+			report.EcIo = v
+		}
+	case "LTE", "CAT-M", "CAT-NB":
+		// "LTE",<lte_rssi>,<lte_rsrp>,<lte_sinr>,<lte_rsrq>; SINR в единицах 1/5 дБ со смещением -20
+		report.AccessTech = AccessTechLTE
+		switch strings.ToUpper(fields[0].Value) {
+		case "CAT-M":
+			report.AccessTech = AccessTechLTECatM
+		case "CAT-NB":
+			report.AccessTech = AccessTechNBIoT
+		}
+		if v, ok := value(1); ok {
+			report.RSSI = v
+		}
+		if v, ok := value(2); ok {
+			report.RSRP = v
+		}
+		if v, ok := value(3); ok {
+			report.SINR = v/5 - 20
+		}
+		if v, ok := value(4); ok {
+			report.RSRQ = v
+		}
+	case "NR5G":
+		// "NR5G",<nr5g_rsrp>,<nr5g_sinr>,<nr5g_rsrq>
+		report.AccessTech = AccessTechNR5GC
+		if v, ok := value(1); ok {
+			report.RSRP = v
+		}
+		if v, ok := value(2); ok {
+			report.SINR = v
+		}
+		if v, ok := value(3); ok {
+			report.RSRQ = v
+		}
+	}
+	return report
+}
+
+// parseCPSI разбирает SIMCom +CPSI: <mode>,<status>,... (LTE/NR - значения в десятых долях)
+func parseCPSI(fields []Field) *SignalReport {
+	if len(fields) < 2 {
+		return nil
+	}
+	report := NewSignalReport("CPSI")
+	value := func(i int, scale float64) (float64, bool) {
+		v, err := strconv.ParseFloat(fieldAt(fields, i).Value, 64)
+		return v / scale, err == nil
+	}
+
+	switch mode := strings.ToUpper(fields[0].Value); {
+	case mode == "GSM":
+		// GSM,Online,250-02,0x1A2B,12401,27 EGSM 900,-64,2110,42-42
+		report.AccessTech = AccessTechGSM
+		if v, ok := value(6, 1); ok {
+			report.RSSI = v
+		}
+	case mode == "WCDMA":
+		// WCDMA,Online,<MCC-MNC>,<LAC>,<CellID>,<Band>,<UARFCN>,<PSC>,<Ecio>,<RSCP>,<Qual>,<RxLev>,<TXPWR>
+		report.AccessTech = AccessTechUMTS
+		if v, ok := value(8, 1); ok {
+			report.EcIo = v
+		}
+		if v, ok := value(9, 1); ok {
+			report.RSCP = v
+		}
+		if v, ok := value(11, 1); ok {
+			report.RSSI = v
+		}
+	case mode == "LTE" || mode == "CAT-M" || mode == "NB-IOT":
+		// LTE,Online,<MCC-MNC>,<TAC>,<SCellID>,<PCellID>,<Band>,<earfcn>,<dlbw>,<ulbw>,<RSRQ>,<RSRP>,<RSSI>,<RSSNR>
+		report.AccessTech = AccessTechLTE
+		if mode == "CAT-M" {
+			report.AccessTech = AccessTechLTECatM
+		} else if mode == "NB-IOT" {
+			report.AccessTech = AccessTechNBIoT
+		}
+		if v, ok := value(10, 10); ok {
+			report.RSRQ = v
+		}
+		if v, ok := value(11, 10); ok {
+			report.RSRP = v
+		}
+		if v, ok := value(12, 10); ok {
+			report.RSSI = v
+		}
+		if v, ok := value(13, 1); ok {
+			report.SINR = v
+		}
+	case strings.HasPrefix(mode, "NR5G"):
+		// NR5G_SA,Online,<MCC-MNC>,<TAC>,<SCellID>,<PCellID>,<Band>,<ARFCN>,<RSRP>,<RSRQ>,<SINR>
+		report.AccessTech = AccessTechNR5GC
+		if v, ok := value(8, 10); ok {
+			report.RSRP = v
+		}
+		if v, ok := value(9, 10); ok {
+			report.RSRQ = v
+		}
+		if v, ok := value(10, 10); ok {
+			report.SINR = v
+		}
+	default:
+		// NO SERVICE
+	}
+	return report
+}
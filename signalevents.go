@@ -0,0 +1,309 @@
+package gsm
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSignalThreshold    = 5.0              // дБ - минимальное изменение уровня для события
+	defaultSignalPollInterval = 30 * time.Second // период опроса, если модем не присылает URC
+)
+
+// signalMonitor отслеживает уровень сигнала и подавляет незначительные изменения
+type signalMonitor struct {
+	mu        sync.Mutex
+	threshold float64
+	interval  time.Duration
+	cievIndex int  // Номер индикатора "signal" в +CIND (0 - не найден)
+	autoCSQ   bool // SIMCom AT+AUTOCSQ: +CSQ приходит как URC
+
+	reported   bool
+	lastBars   int
+	lastDBm    float64
+	lastTech   AccessTechnology
+	lastLevels map[string]float64 // Последнее учтенное значение по метрике (RSRP, RSCP, RSSI)
+}
+
+// newSignalMonitor создает монитор с настройками по умолчанию
+func newSignalMonitor() *signalMonitor {
+	return &signalMonitor{
+		threshold:  defaultSignalThreshold,
+		interval:   defaultSignalPollInterval,
+		lastDBm:    math.NaN(),
+		lastTech:   AccessTechUnknown,
+		lastLevels: make(map[string]float64),
+	}
+}
+
+// SetSignalMonitor задает порог изменения уровня сигнала в дБ для EventSignalChange и период
+// опроса для модемов без уведомлений об уровне сигнала (применяется при StartEventListener)
+func (m *Modem) SetSignalMonitor(threshold float64, pollInterval time.Duration) {
+	m.signal.mu.Lock()
+	defer m.signal.mu.Unlock()
+	if threshold > 0 {
+		m.signal.threshold = threshold
+	}
+	if pollInterval > 0 {
+		m.signal.interval = pollInterval
+	}
+}
+
+// enableSignalEvents включает уведомления об уровне сигнала; false - модем их не поддерживает.
+// Вызывается из StartEventListener под m.mu.
+func (m *Modem) enableSignalEvents() bool {
+	ok := func(cmd string) bool {
+		resp, err := m.sendCommand(cmd, time.Second)
+		return err == nil && findErrorLine(resp) == "" && strings.Contains(resp, "OK")
+	}
+
+	m.signal.mu.Lock()
+	m.signal.reported = false
+	m.signal.lastLevels = make(map[string]float64)
+	m.signal.autoCSQ = false
+	m.signal.cievIndex = 0
+	m.signal.mu.Unlock()
+
+	switch m.Vendor() {
+	case VendorHuawei:
+		// ^RSSI и ^HCSQ
+		if ok("AT^CURC=1") {
+			return true
+		}
+	case VendorQuectel:
+		// +QIND: "csq",<rssi>,<ber>
+		if ok(`AT+QINDCFG="csq",1,0`) {
+			return true
+		}
+	case VendorSIMCom:
+		// +CSQ: <rssi>,<ber> при изменении уровня
+		if ok("AT+AUTOCSQ=1,1") {
+			m.signal.mu.Lock()
+			m.signal.autoCSQ = true
+			m.signal.mu.Unlock()
+			return true
+		}
+	}
+
+	// 27.007: индикатор "signal" из AT+CIND и уведомления +CIEV через AT+CMER
+	resp, err := m.sendCommand("AT+CIND=?", time.Second)
+	if err != nil {
+		return false
+	}
+	index := 0
+	for _, fields := range parseInfoLines(resp, "+CIND:") {
+		for i, field := range fields {
+			if field.IsList && strings.EqualFold(fieldAt(field.List, 0).Value, "signal") {
+				index = i + 1
+			}
+		}
+	}
+	if index == 0 || !ok("AT+CMER=3,0,0,1") {
+		return false
+	}
+	m.signal.mu.Lock()
+	m.signal.cievIndex = index
+	m.signal.mu.Unlock()
+	return true
+}
+
+// disableSignalEvents выключает уведомления об уровне сигнала (под m.mu)
+func (m *Modem) disableSignalEvents() {
+	switch m.Vendor() {
+	case VendorQuectel:
+		m.sendCommand(`AT+QINDCFG="csq",0,0`, time.Second)
+	case VendorSIMCom:
+		m.sendCommand("AT+AUTOCSQ=0,0", time.Second)
+	}
+
+	m.signal.mu.Lock()
+	ciev := m.signal.cievIndex
+	m.signal.mu.Unlock()
+	if ciev > 0 {
+		m.sendCommand("AT+CMER=0,0,0,0", time.Second)
+	}
+}
+
+// signalPollLoop опрашивает уровень сигнала, если модем не присылает уведомления
+func (m *Modem) signalPollLoop(stop chan struct{}) {
+	m.signal.mu.Lock()
+	interval := m.signal.interval
+	m.signal.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if report, err := m.GetSignalReport(); err == nil {
+			m.reportSignal(report)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleSignalLine обрабатывает URC об уровне сигнала
+func (m *Modem) handleSignalLine(line string) bool {
+	report, ok := m.signal.parse(line)
+	if !ok {
+		return false
+	}
+	if report != nil {
+		m.reportSignal(report)
+	}
+	return true
+}
+
+// parse разбирает URC: +CIEV, ^RSSI, ^HCSQ, +QIND: "csq", +CSQ (SIMCom AUTOCSQ).
+// Возвращает nil-отчет для строк, которые относятся к сигналу, но не несут значения.
+func (s *signalMonitor) parse(line string) (*SignalReport, bool) {
+	s.mu.Lock()
+	ciev, autoCSQ := s.cievIndex, s.autoCSQ
+	s.mu.Unlock()
+
+	csqReport := func(source string, rssi Field) *SignalReport {
+		report := NewSignalReport(source)
+		if dbm, ok := CSQToDBm(rssi.IntOr(99)); ok {
+			report.RSSI = float64(dbm)
+		}
+		return report
+	}
+
+	switch {
+	case strings.HasPrefix(line, "+CIEV:"):
+		// +CIEV: <ind>,<value> (некоторые модемы присылают имя индикатора: +CIEV: "signal",3)
+		fields, _ := parseInfoLine(line, "+CIEV:")
+		ind := fieldAt(fields, 0)
+		if !strings.EqualFold(ind.Value, "signal") && (ciev == 0 || ind.IntOr(-1) != ciev) {
+			return nil, false
+		}
+		report := NewSignalReport("CIEV")
+		report.Bars = fieldAt(fields, 1).IntOr(0)
+		report.Quality = report.Bars * 20
+		return report, true
+
+	case strings.HasPrefix(line, "^RSSI:"):
+		// Huawei: ^RSSI: <rssi> в шкале CSQ
+		fields, _ := parseInfoLine(line, "^RSSI:")
+		report := csqReport("RSSI", fieldAt(fields, 0))
+		report.Normalize()
+		return report, true
+
+	case strings.HasPrefix(line, "^HCSQ:"):
+		fields, _ := parseInfoLine(line, "^HCSQ:")
+		report := parseHCSQ(fields)
+		if report == nil {
+			return nil, true
+		}
+		report.Normalize()
+		return report, true
+
+	case strings.HasPrefix(line, "+QIND:"):
+		// Quectel: +QIND: "csq",<rssi>,<ber>
+		fields, _ := parseInfoLine(line, "+QIND:")
+		if !strings.EqualFold(fieldAt(fields, 0).Value, "csq") {
+			return nil, false
+		}
+		report := csqReport("QIND", fieldAt(fields, 1))
+		report.Normalize()
+		return report, true
+
+	case autoCSQ && strings.HasPrefix(line, "+CSQ:"):
+		fields, _ := parseInfoLine(line, "+CSQ:")
+		report := csqReport("CSQ", fieldAt(fields, 0))
+		report.Normalize()
+		return report, true
+	}
+	return nil, false
+}
+
+// reportSignal отправляет EventSignalChange, если изменение превышает порог
+func (m *Modem) reportSignal(report *SignalReport) {
+	level, metric := report.level()
+	if cm := m.ConnectivityMonitor(); cm != nil {
+		cm.observeSignal(level, report.Bars)
+	}
+	previous, changed := m.signal.update(metric, level, report.Bars, report.AccessTech)
+	if !changed {
+		return
+	}
+
+	m.emitEvent(Event{
+		Type:      EventSignalChange,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"dbm":         level,
+			"previousDbm": previous,
+			"bars":        report.Bars,
+			"quality":     report.Quality,
+			"accessTech":  report.AccessTech,
+			"source":      report.Source,
+			"report":      report,
+		},
+	})
+}
+
+// update запоминает новое значение и решает, нужно ли событие. Изменения меньше порога
+// (в том числе переход "палочки" на границе) подавляются, чтобы уровень не дребезжал.
+// Значение сравнивается с прошлым значением той же метрики: Huawei присылает вперемешку
+// ^RSSI (RSSI) и ^HCSQ (RSRP), которые различаются на 20-30 дБ.
+func (s *signalMonitor) update(metric string, dbm float64, bars int, tech AccessTechnology) (previous float64, changed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous = s.lastDBm
+	last, seen := s.lastLevels[metric]
+	if seen {
+		previous = last
+	}
+	switch {
+	case !s.reported:
+		changed = true
+	case math.IsNaN(dbm) != math.IsNaN(s.lastDBm):
+		// Появление или пропадание сигнала
+		changed = true
+	case tech != AccessTechUnknown && s.lastTech != AccessTechUnknown && tech != s.lastTech:
+		// Смена технологии: уровни RSSI/RSCP/RSRP несравнимы
+		changed = true
+	case math.IsNaN(dbm):
+		// Известны только "палочки" (+CIEV)
+		changed = bars != s.lastBars
+	case !seen:
+		// Первое значение этой метрики сравнивать не с чем
+		changed = false
+	default:
+		changed = math.Abs(dbm-last) >= s.threshold
+	}
+
+	if tech != AccessTechUnknown && s.lastTech != AccessTechUnknown && tech != s.lastTech {
+		// После смены технологии прежние значения метрик несравнимы
+		s.lastLevels = make(map[string]float64)
+	}
+	if (changed || !seen) && !math.IsNaN(dbm) {
+		s.lastLevels[metric] = dbm
+	}
+	if changed {
+		s.reported = true
+		s.lastDBm = dbm
+		s.lastBars = bars
+		s.lastTech = tech
+	}
+	return previous, changed
+}
+
+// level возвращает основную метрику уровня для технологии (RSRP, RSCP или RSSI) и ее имя,
+// NaN если уровень неизвестен
+func (r *SignalReport) level() (float64, string) {
+	switch {
+	case !math.IsNaN(r.RSRP):
+		return r.RSRP, "RSRP"
+	case !math.IsNaN(r.RSCP):
+		return r.RSCP, "RSCP"
+	default:
+		return r.RSSI, "RSSI"
+	}
+}
@@ -0,0 +1,141 @@
+package gsm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Идентификаторы элементарных файлов SIM (3GPP TS 51.011 / 31.102)
+const (
+	SIMFileICCID = 0x2FE2 // Идентификатор карты (ICCID)
+	SIMFileAD    = 0x6FAD // Administrative Data (длина MNC)
+	SIMFileSPN   = 0x6F46 // Service Provider Name
+	SIMFileGID1  = 0x6F3E // Group Identifier Level 1 (идентификатор MVNO)
+)
+
+// SIMIdentity данные SIM-карты, определяющие оператора и MVNO
+type SIMIdentity struct {
+	IMSI string // International Mobile Subscriber Identity
+	MCC  string // Код страны из IMSI
+	MNC  string // Код сети из IMSI (2 или 3 цифры)
+	SPN  string // Service Provider Name (пусто, если не записано на SIM)
+	GID1 string // Group Identifier Level 1 в hex (пусто, если не записано на SIM)
+}
+
+// Numeric возвращает код домашней сети MCC+MNC
+func (s SIMIdentity) Numeric() string {
+	return s.MCC + s.MNC
+}
+
+// ReadSIMFile читает прозрачный файл SIM командой AT+CRSM (READ BINARY).
+// length 0 - весь файл (поддерживается не всеми модемами).
+func (m *Modem) ReadSIMFile(fileID, length int) ([]byte, error) {
+	cmd := fmt.Sprintf("AT+CRSM=176,%d,0,0,%d", fileID, length)
+	resp, err := m.execCommand(cmd, time.Second*3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SIM file %04X: %w", fileID, err)
+	}
+
+	// +CRSM: <sw1>,<sw2>[,<response>]
+	for _, fields := range parseInfoLines(resp, "+CRSM:") {
+		sw1, sw2 := fieldAt(fields, 0).IntOr(0), fieldAt(fields, 1).IntOr(0)
+		// 0x90 - успешно, 0x91 - успешно с дополнительными данными от SIM
+		if sw1 != 0x90 && sw1 != 0x91 {
+			return nil, fmt.Errorf("failed to read SIM file %04X: status %02X%02X", fileID, sw1, sw2)
+		}
+		data, err := hex.DecodeString(fieldAt(fields, 2).Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SIM file %04X: %w", fileID, err)
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("unexpected response format: %s", resp)
+}
+
+// GetICCID возвращает серийный номер SIM-карты (EF_ICCID). В отличие от IMSI он не меняется
+// при перепрограммировании профиля и подходит для учета по картам.
+func (m *Modem) GetICCID() (string, error) {
+	data, err := m.ReadSIMFile(SIMFileICCID, 10)
+	if err != nil {
+		return "", err
+	}
+	// Цифры хранятся в BCD с переставленными полубайтами, заполнитель F
+	return decodeSemiOctets(data, 20), nil
+}
+
+// GetSPN возвращает название сервис-провайдера (EF_SPN), по нему различаются MVNO
+func (m *Modem) GetSPN() (string, error) {
+	data, err := m.ReadSIMFile(SIMFileSPN, 17)
+	if err != nil {
+		return "", err
+	}
+	return decodeSPN(data), nil
+}
+
+// decodeSPN разбирает EF_SPN: байт условий отображения и имя в GSM 03.38 или UCS2 (0x80)
+func decodeSPN(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	name := trimPadding(data[1:])
+	if len(name) > 0 && name[0] == 0x80 {
+		text := name[1:]
+		decoded, err := DecodeUCS2(hex.EncodeToString(text[:len(text)/2*2]))
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(decoded)
+	}
+	return strings.TrimSpace(DecodeGSM7(name))
+}
+
+// GetGID1 возвращает Group Identifier Level 1 (EF_GID1) в hex без заполнителя FF
+func (m *Modem) GetGID1() (string, error) {
+	data, err := m.ReadSIMFile(SIMFileGID1, 0)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(trimPadding(data))), nil
+}
+
+// trimPadding отбрасывает заполнитель 0xFF в конце данных файла SIM
+func trimPadding(data []byte) []byte {
+	for len(data) > 0 && data[len(data)-1] == 0xFF {
+		data = data[:len(data)-1]
+	}
+	return data
+}
+
+// GetSIMIdentity читает IMSI, SPN и GID1. Длина MNC берется из EF_AD, а если файл недоступен -
+// определяется по справочнику операторов. SPN и GID1 необязательны и при ошибке остаются пустыми.
+func (m *Modem) GetSIMIdentity() (*SIMIdentity, error) {
+	imsi, err := m.GetIMSI()
+	if err != nil {
+		return nil, err
+	}
+
+	id := &SIMIdentity{IMSI: imsi, MCC: imsi[:3]}
+	mncLength := 0
+	if ad, err := m.ReadSIMFile(SIMFileAD, 4); err == nil && len(ad) >= 4 {
+		if n := int(ad[3] & 0x0F); n == 2 || n == 3 {
+			mncLength = n
+		}
+	}
+	if mncLength == 0 {
+		mncLength = 2
+		if _, ok := LookupOperator(imsi[:6]); ok {
+			mncLength = 3
+		}
+	}
+	id.MNC = imsi[3 : 3+mncLength]
+
+	if spn, err := m.GetSPN(); err == nil {
+		id.SPN = spn
+	}
+	if gid1, err := m.GetGID1(); err == nil {
+		id.GID1 = gid1
+	}
+	return id, nil
+}
@@ -28,6 +28,19 @@ const (
 	StorageStatus    SMSStorage = "SR" // Status report - отчеты о доставке
 )
 
+// SMSStorageInfo состояние хранилищ SMS (ответ AT+CPMS?)
+type SMSStorageInfo struct {
+	ReadStorage    SMSStorage `at:"0"` // Хранилище для чтения и удаления
+	ReadUsed       int        `at:"1"` // Занято сообщений
+	ReadTotal      int        `at:"2"` // Всего мест
+	WriteStorage   SMSStorage `at:"3"` // Хранилище для записи и отправки
+	WriteUsed      int        `at:"4"`
+	WriteTotal     int        `at:"5"`
+	ReceiveStorage SMSStorage `at:"6"` // Хранилище для входящих сообщений
+	ReceiveUsed    int        `at:"7"`
+	ReceiveTotal   int        `at:"8"`
+}
+
 // SendSMS отправляет SMS сообщение
 func (m *Modem) SendSMS(number, text string) error {
 	// Устанавливаем текстовый режим
@@ -46,8 +59,8 @@ func (m *Modem) SendSMS(number, text string) error {
 
 	if needsUCS2 {
 		// Устанавливаем UCS2 кодировку
-		if _, err := m.SendCommand("AT+CSCS=\"UCS2\"", time.Second); err != nil {
-			return fmt.Errorf("failed to set UCS2 encoding: %w", err)
+		if err := m.SetCharset("UCS2"); err != nil {
+			return err
 		}
 
 		// Кодируем номер в UCS2
@@ -56,8 +69,8 @@ func (m *Modem) SendSMS(number, text string) error {
 		text = EncodeUCS2(text)
 	} else {
 		// Устанавливаем GSM кодировку для ASCII
-		if _, err := m.SendCommand("AT+CSCS=\"GSM\"", time.Second); err != nil {
-			return fmt.Errorf("failed to set GSM encoding: %w", err)
+		if err := m.SetCharset("GSM"); err != nil {
+			return err
 		}
 	}
 
@@ -94,6 +107,7 @@ func (m *Modem) SendSMS(number, text string) error {
 
 	// Возвращаем кодировку обратно на GSM
 	m.sendCommand("AT+CSCS=\"GSM\"", time.Second)
+	m.rememberCharset("GSM")
 
 	return nil
 }
@@ -227,34 +241,13 @@ func (m *Modem) SetSMSStorage(readStorage, writeStorage, receiveStorage SMSStora
 }
 
 // GetSMSStorageInfo возвращает информацию о хранилище SMS
-func (m *Modem) GetSMSStorageInfo() (map[string]string, error) {
-	resp, err := m.SendCommand("AT+CPMS?", time.Second*2)
+func (m *Modem) GetSMSStorageInfo() (*SMSStorageInfo, error) {
+	// Парсим ответ вида +CPMS: "SM",10,20,"SM",10,20,"SM",10,20
+	info, err := QueryTimeout[SMSStorageInfo](m, "AT+CPMS?", "+CPMS", time.Second*2)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get SMS storage info: %w", err)
 	}
-
-	info := make(map[string]string)
-
-	// Парсим ответ вида +CPMS: "SM",10,20,"SM",10,20,"SM",10,20
-	if strings.Contains(resp, "+CPMS:") {
-		parts := strings.Split(resp, ":")
-		if len(parts) >= 2 {
-			values := strings.Split(strings.TrimSpace(parts[1]), ",")
-			if len(values) >= 9 {
-				info["ReadStorage"] = strings.Trim(values[0], "\"")
-				info["ReadUsed"] = strings.TrimSpace(values[1])
-				info["ReadTotal"] = strings.TrimSpace(values[2])
-				info["WriteStorage"] = strings.Trim(values[3], "\"")
-				info["WriteUsed"] = strings.TrimSpace(values[4])
-				info["WriteTotal"] = strings.TrimSpace(values[5])
-				info["ReceiveStorage"] = strings.Trim(values[6], "\"")
-				info["ReceiveUsed"] = strings.TrimSpace(values[7])
-				info["ReceiveTotal"] = strings.TrimSpace(values[8])
-			}
-		}
-	}
-
-	return info, nil
+	return &info, nil
 }
 
 // SetNewSMSIndication устанавливает индикацию новых SMS
@@ -282,21 +275,12 @@ func parseSMS(response string, index int) (*SMS, error) {
 		line = strings.TrimSpace(line)
 
 		// Ищем заголовок сообщения
-		if strings.HasPrefix(line, "+CMGR:") {
-			// +CMGR: "REC UNREAD","+79991234567","","20/01/01,12:00:00+12"
-			parts := strings.Split(line[6:], ",")
-			if len(parts) >= 4 {
-				sms.Status = strings.Trim(parts[0], " \"")
-				sms.Sender = strings.Trim(parts[1], " \"")
-
-				// Парсим время если есть
-				if len(parts) >= 4 {
-					timeStr := strings.Trim(parts[3], " \"")
-					if len(parts) >= 5 {
-						timeStr += "," + strings.Trim(parts[4], " \"")
-					}
-					sms.Time = parseGSMTime(timeStr)
-				}
+		// +CMGR: "REC UNREAD","+79991234567","","20/01/01,12:00:00+12"
+		if fields, ok := parseInfoLine(line, "+CMGR:"); ok {
+			if len(fields) >= 4 {
+				sms.Status = fields[0].Value
+				sms.Sender = fields[1].Value
+				sms.Time = parseGSMTime(fields[3].Value)
 			}
 
 			// Текст сообщения обычно на следующей строке
@@ -325,32 +309,23 @@ func parseSMSList(response string) ([]*SMS, error) {
 		line := strings.TrimSpace(lines[i])
 
 		// Ищем заголовки сообщений
-		if strings.HasPrefix(line, "+CMGL:") {
-			// +CMGL: 1,"REC UNREAD","+79991234567","","20/01/01,12:00:00+12"
-			parts := strings.Split(line[6:], ",")
-			if len(parts) >= 2 {
-				indexStr := strings.TrimSpace(parts[0])
-				index, err := strconv.Atoi(indexStr)
+		// +CMGL: 1,"REC UNREAD","+79991234567","","20/01/01,12:00:00+12"
+		if fields, ok := parseInfoLine(line, "+CMGL:"); ok {
+			if len(fields) >= 2 {
+				index, err := fields[0].Int()
 				if err != nil {
 					continue
 				}
 
 				sms := &SMS{
 					Index:  index,
-					Status: strings.Trim(parts[1], " \""),
-				}
-
-				if len(parts) >= 3 {
-					sms.Sender = strings.Trim(parts[2], " \"")
+					Status: fields[1].Value,
+					Sender: fieldAt(fields, 2).Value,
 				}
 
 				// Парсим время если есть
-				if len(parts) >= 5 {
-					timeStr := strings.Trim(parts[4], " \"")
-					if len(parts) >= 6 {
-						timeStr += "," + strings.Trim(parts[5], " \"")
-					}
-					sms.Time = parseGSMTime(timeStr)
+				if len(fields) >= 5 {
+					sms.Time = parseGSMTime(fields[4].Value)
 				}
 
 				// Текст сообщения на следующей строке
@@ -0,0 +1,288 @@
+package gsm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Типы сообщений TP-MTI (3GPP TS 23.040)
+const (
+	pduTypeDeliver = 0x00
+	pduTypeSubmit  = 0x01
+)
+
+// Кодировки пользовательских данных (TP-DCS)
+const (
+	pduAlphabetGSM7 = 0
+	pduAlphabet8Bit = 1
+	pduAlphabetUCS2 = 2
+)
+
+// ErrInvalidPDU PDU сообщения поврежден или имеет неподдерживаемый формат
+var ErrInvalidPDU = errors.New("invalid SMS PDU")
+
+// EncodeSMSSubmitPDU кодирует одиночное SMS-SUBMIT с адресом SMSC по умолчанию (первый октет 00).
+// Текст в алфавите GSM 03.38 кодируется 7-битно (до 160 символов), остальной - UCS2 (до 70).
+func EncodeSMSSubmitPDU(number, text string) ([]byte, error) {
+	pdu := []byte{
+		0x00,          // Длина адреса SMSC: использовать SMSC из настроек SIM
+		pduTypeSubmit, // SMS-SUBMIT без срока действия
+		0x00,          // TP-MR: номер назначит модем
+	}
+	addr, err := encodePDUAddress(number)
+	if err != nil {
+		return nil, err
+	}
+	pdu = append(pdu, addr...)
+	pdu = append(pdu, 0x00) // TP-PID
+
+	if septets, err := EncodeGSM7(text); err == nil {
+		if len(septets) > 160 {
+			return nil, fmt.Errorf("text too long for single SMS: %d septets", len(septets))
+		}
+		pdu = append(pdu, 0x00, byte(len(septets)))
+		return append(pdu, PackSeptets(septets)...), nil
+	}
+
+	units := utf16.Encode([]rune(text))
+	if len(units) > 70 {
+		return nil, fmt.Errorf("text too long for single SMS: %d UCS2 characters", len(units))
+	}
+	pdu = append(pdu, 0x08, byte(len(units)*2))
+	for _, u := range units {
+		pdu = append(pdu, byte(u>>8), byte(u))
+	}
+	return pdu, nil
+}
+
+// encodePDUAddress кодирует номер в поле адреса (длина в цифрах, тип номера, полуоктеты).
+// Номер может содержать только цифры и "+" в начале, не длиннее 20 цифр.
+func encodePDUAddress(number string) ([]byte, error) {
+	toa := byte(0x81) // Национальный формат
+	digits := number
+	if strings.HasPrefix(digits, "+") {
+		toa = 0x91 // Международный формат
+		digits = digits[1:]
+	}
+	if digits == "" || len(digits) > 20 || strings.Trim(digits, "0123456789") != "" {
+		return nil, fmt.Errorf("invalid phone number %q", number)
+	}
+	addr := []byte{byte(len(digits)), toa}
+	for i := 0; i < len(digits); i += 2 {
+		lo := digits[i] - '0'
+		hi := byte(0x0F)
+		if i+1 < len(digits) {
+			hi = digits[i+1] - '0'
+		}
+		addr = append(addr, hi<<4|lo)
+	}
+	return addr, nil
+}
+
+// DecodeSMSPDU разбирает PDU SMS-DELIVER (входящее) или SMS-SUBMIT (исходящее из памяти)
+// вместе с адресом SMSC. Index и Status заполняет вызывающий.
+// Для частей длинного сообщения возвращается текст только этой части.
+func DecodeSMSPDU(pdu []byte) (*SMS, error) {
+	r := pduReader{data: pdu}
+	r.skip(int(r.byte())) // Адрес SMSC
+	first := r.byte()
+
+	sms := &SMS{}
+	switch first & 0x03 {
+	case pduTypeDeliver:
+		sms.Sender = r.address()
+	case pduTypeSubmit:
+		r.byte() // TP-MR
+		sms.Receiver = r.address()
+	default:
+		return nil, fmt.Errorf("%w: unsupported message type %d", ErrInvalidPDU, first&0x03)
+	}
+	r.byte() // TP-PID
+	dcs := r.byte()
+
+	if first&0x03 == pduTypeDeliver {
+		sms.Time = r.timestamp()
+	} else {
+		// TP-VP: 0 - нет, 2 - относительный (1 октет), 1 и 3 - 7 октетов
+		switch (first >> 3) & 0x03 {
+		case 2:
+			r.skip(1)
+		case 1, 3:
+			r.skip(7)
+		}
+	}
+
+	length := int(r.byte())
+	ud := r.rest()
+	if r.err != nil {
+		return nil, r.err
+	}
+	text, err := decodeUserData(ud, length, pduAlphabet(dcs), first&0x40 != 0)
+	if err != nil {
+		return nil, err
+	}
+	sms.Text = text
+	return sms, nil
+}
+
+// pduAlphabet определяет кодировку пользовательских данных по TP-DCS
+func pduAlphabet(dcs byte) int {
+	switch {
+	case dcs&0xC0 == 0x00:
+		return int(dcs>>2) & 0x03
+	case dcs&0xF0 == 0xF0:
+		return int(dcs>>2) & 0x01
+	case dcs&0xF0 == 0xE0:
+		return pduAlphabetUCS2
+	default:
+		return pduAlphabetGSM7
+	}
+}
+
+// decodeUserData декодирует TP-UD, пропуская заголовок UDH
+func decodeUserData(ud []byte, length, alphabet int, hasUDH bool) (string, error) {
+	headerLen := 0
+	if hasUDH {
+		if len(ud) == 0 || int(ud[0])+1 > len(ud) {
+			return "", fmt.Errorf("%w: truncated user data header", ErrInvalidPDU)
+		}
+		headerLen = int(ud[0]) + 1
+	}
+
+	switch alphabet {
+	case pduAlphabetGSM7:
+		septets := UnpackSeptets(ud)
+		if length > len(septets) {
+			return "", fmt.Errorf("%w: user data shorter than %d septets", ErrInvalidPDU, length)
+		}
+		// Заголовок дополняется битами заполнения до границы септета
+		skip := (headerLen*8 + 6) / 7
+		if skip > length {
+			return "", fmt.Errorf("%w: user data header longer than message", ErrInvalidPDU)
+		}
+		return DecodeGSM7(septets[skip:length]), nil
+	case pduAlphabetUCS2:
+		if length > len(ud) || headerLen > length {
+			return "", fmt.Errorf("%w: user data shorter than %d octets", ErrInvalidPDU, length)
+		}
+		data := ud[headerLen:length]
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+		}
+		return string(utf16.Decode(units)), nil
+	default:
+		if length > len(ud) || headerLen > length {
+			return "", fmt.Errorf("%w: user data shorter than %d octets", ErrInvalidPDU, length)
+		}
+		return string(ud[headerLen:length]), nil
+	}
+}
+
+// pduReader последовательно читает поля PDU, запоминая первую ошибку
+type pduReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// byte читает один октет
+func (r *pduReader) byte() byte {
+	if r.pos >= len(r.data) {
+		r.fail()
+		return 0
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+// bytes читает n октетов
+func (r *pduReader) bytes(n int) []byte {
+	if r.pos+n > len(r.data) {
+		r.fail()
+		return make([]byte, n)
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// skip пропускает n октетов
+func (r *pduReader) skip(n int) {
+	r.bytes(n)
+}
+
+// rest возвращает непрочитанный остаток
+func (r *pduReader) rest() []byte {
+	if r.pos >= len(r.data) {
+		return nil
+	}
+	return r.data[r.pos:]
+}
+
+// fail фиксирует выход за границу PDU
+func (r *pduReader) fail() {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: truncated at offset %d", ErrInvalidPDU, r.pos)
+	}
+	r.pos = len(r.data)
+}
+
+// address читает поле адреса: номер в полуоктетах или буквенно-цифровое имя отправителя
+func (r *pduReader) address() string {
+	digits := int(r.byte())
+	toa := r.byte()
+	data := r.bytes((digits + 1) / 2)
+
+	if toa&0x70 == 0x50 {
+		// Буквенно-цифровой адрес: digits - количество полуоктетов 7-битного текста
+		septets := UnpackSeptets(data)
+		if n := digits * 4 / 7; n < len(septets) {
+			septets = septets[:n]
+		}
+		return DecodeGSM7(septets)
+	}
+
+	var number strings.Builder
+	if toa&0x70 == 0x10 {
+		number.WriteByte('+')
+	}
+	number.WriteString(decodeSemiOctets(data, digits))
+	return number.String()
+}
+
+// timestamp читает TP-SCTS: год, месяц, день, часы, минуты, секунды и пояс в четвертях часа
+func (r *pduReader) timestamp() time.Time {
+	data := r.bytes(7)
+	v := make([]int, 6)
+	for i := range v {
+		v[i] = int(data[i]&0x0F)*10 + int(data[i]>>4)
+	}
+	tz := int(data[6]&0x07)*10 + int(data[6]>>4)
+	if data[6]&0x08 != 0 {
+		tz = -tz
+	}
+	return time.Date(2000+v[0], time.Month(v[1]), v[2], v[3], v[4], v[5], 0,
+		time.FixedZone("", tz*15*60))
+}
+
+// decodeSemiOctets декодирует цифры, записанные полуоктетами младшим полубайтом вперед
+func decodeSemiOctets(data []byte, digits int) string {
+	const alphabet = "0123456789*#abc"
+	var result strings.Builder
+	for i := 0; i < digits && i/2 < len(data); i++ {
+		nibble := data[i/2] & 0x0F
+		if i%2 == 1 {
+			nibble = data[i/2] >> 4
+		}
+		if nibble == 0x0F {
+			break
+		}
+		result.WriteByte(alphabet[nibble])
+	}
+	return result.String()
+}
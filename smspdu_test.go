@@ -0,0 +1,139 @@
+package gsm
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mustHex декодирует шестнадцатеричную строку PDU
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("invalid hex %q: %v", s, err)
+	}
+	return data
+}
+
+func TestEncodeSMSSubmitPDU(t *testing.T) {
+	tests := []struct {
+		number string
+		text   string
+		pdu    string
+	}{
+		{"+79991234567", "Hello", "0001000B919799214365F70000" + "05C8329BFD06"},
+		// Нечетное число цифр дополняется F, национальный формат - тип 81
+		{"900", "hellohello", "000100038109F000000A" + "E8329BFD4697D9EC37"},
+		{"+79991234567", "Привет", "0001000B919799214365F70008" + "0C041F04400438043204350442"},
+	}
+	for _, tt := range tests {
+		pdu, err := EncodeSMSSubmitPDU(tt.number, tt.text)
+		if err != nil {
+			t.Errorf("EncodeSMSSubmitPDU(%q, %q): %v", tt.number, tt.text, err)
+			continue
+		}
+		if got := strings.ToUpper(hex.EncodeToString(pdu)); got != tt.pdu {
+			t.Errorf("EncodeSMSSubmitPDU(%q, %q) = %s, want %s", tt.number, tt.text, got, tt.pdu)
+		}
+
+		// Исходящее сообщение разбирается обратно
+		sms, err := DecodeSMSPDU(pdu)
+		if err != nil {
+			t.Errorf("DecodeSMSPDU(%X): %v", pdu, err)
+			continue
+		}
+		if sms.Receiver != tt.number || sms.Text != tt.text {
+			t.Errorf("DecodeSMSPDU(%X) = %q, %q", pdu, sms.Receiver, sms.Text)
+		}
+	}
+}
+
+func TestEncodeSMSSubmitPDUInvalid(t *testing.T) {
+	for _, number := range []string{"", "+", "+7999abc4567", "8 999 123 45 67", "*100#", "+123456789012345678901"} {
+		if pdu, err := EncodeSMSSubmitPDU(number, "Hi"); err == nil {
+			t.Errorf("EncodeSMSSubmitPDU(%q) = %X, want error", number, pdu)
+		}
+	}
+	if _, err := EncodeSMSSubmitPDU("+79991234567", strings.Repeat("a", 160)); err != nil {
+		t.Errorf("EncodeSMSSubmitPDU(160 septets): %v", err)
+	}
+	if _, err := EncodeSMSSubmitPDU("+79991234567", strings.Repeat("a", 161)); err == nil {
+		t.Error("EncodeSMSSubmitPDU accepted 161 septets")
+	}
+	if _, err := EncodeSMSSubmitPDU("+79991234567", strings.Repeat("я", 71)); err == nil {
+		t.Error("EncodeSMSSubmitPDU accepted 71 UCS2 characters")
+	}
+}
+
+func TestDecodeSMSPDU(t *testing.T) {
+	tests := []struct {
+		name   string
+		pdu    string
+		sender string
+		text   string
+		time   time.Time
+	}{
+		{
+			"deliver with SMSC", "07917283010010F5040BC87238880900F10000423092516195800AE8329BFD4697D9EC37",
+			"27838890001", "hellohello",
+			time.Date(2024, 3, 29, 15, 16, 59, 0, time.FixedZone("", 2*3600)),
+		},
+		{
+			// Буквенно-цифровой отправитель "Sale": 7 полуоктетов, тип D0
+			"alphanumeric sender", "000407D0D330BB0C0000425010210000000" + "5C8329BFD06",
+			"Sale", "Hello",
+			time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			// Часть 1 из 2 длинного сообщения: UDH 050003AA0201 и бит заполнения перед текстом
+			"GSM7 with UDH", "0044" + "0B919799214365F7" + "0000" + "42501021000000" + "09" + "050003AA02019069",
+			"+79991234567", "Hi",
+			time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			"UCS2 with UDH", "0044" + "0B919799214365F7" + "0008" + "42501021000009" + "0A" + "050003AA0202041F0440",
+			"+79991234567", "Пр",
+			time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("", -10*15*60)),
+		},
+		{
+			"8-bit", "0004" + "0B919799214365F7" + "0004" + "42501021000000" + "03" + "414243",
+			"+79991234567", "ABC",
+			time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		sms, err := DecodeSMSPDU(mustHex(t, tt.pdu))
+		if err != nil {
+			t.Errorf("%s: DecodeSMSPDU: %v", tt.name, err)
+			continue
+		}
+		if sms.Sender != tt.sender || sms.Text != tt.text || !sms.Time.Equal(tt.time) {
+			t.Errorf("%s: DecodeSMSPDU = %q, %q, %v, want %q, %q, %v",
+				tt.name, sms.Sender, sms.Text, sms.Time, tt.sender, tt.text, tt.time)
+		}
+		_, offset := sms.Time.Zone()
+		if _, want := tt.time.Zone(); offset != want {
+			t.Errorf("%s: time zone offset = %d, want %d", tt.name, offset, want)
+		}
+	}
+}
+
+func TestDecodeSMSPDUInvalid(t *testing.T) {
+	for _, pdu := range []string{
+		"",
+		"07917283010010",            // Адрес SMSC обрезан
+		"0004" + "0B919799214365",   // Адрес отправителя обрезан
+		"0002" + "0B919799214365F7", // SMS-STATUS-REPORT не поддерживается
+		"0004" + "0B919799214365F7" + "0000" + "425010",                                 // Время обрезано
+		"0004" + "0B919799214365F7" + "0000" + "42501021000000" + "0A" + "C8329BFD06",   // Текст короче длины
+		"0044" + "0B919799214365F7" + "0008" + "42501021000000" + "04" + "050003AA0202", // UDH длиннее текста
+		"0044" + "0B919799214365F7" + "0000" + "42501021000000" + "02" + "08",           // UDH обрезан
+	} {
+		if sms, err := DecodeSMSPDU(mustHex(t, pdu)); !errors.Is(err, ErrInvalidPDU) {
+			t.Errorf("DecodeSMSPDU(%s) = %+v, %v, want ErrInvalidPDU", pdu, sms, err)
+		}
+	}
+}
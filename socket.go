@@ -0,0 +1,739 @@
+package gsm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	socketPollInterval     = 200 * time.Millisecond // опрос входящих данных без уведомлений
+	socketStateInterval    = 2 * time.Second        // проверка состояния соединения при отсутствии данных
+	socketFallbackInterval = 10 * time.Second       // опрос при запущенном обработчике событий (на случай потерянного URC)
+	defaultDialTimeout     = 60 * time.Second
+)
+
+// errSocketBusy буфер отправки модема заполнен, отправку нужно повторить
+var errSocketBusy = errors.New("socket send buffer is full")
+
+// DialerConfig настройки встроенного TCP/IP стека модема
+type DialerConfig struct {
+	CID     int           // PDP контекст (по умолчанию 1)
+	APN     string        // Точка доступа, если контекст еще не настроен (SIM800: AT+CSTT)
+	Timeout time.Duration // Таймаут установки соединения (по умолчанию 60 секунд)
+}
+
+// Dialer открывает TCP и UDP сокеты через встроенный TCP/IP стек модема:
+// Quectel (AT+QIOPEN), SIMCom SIM800 (AT+CIPSTART) и u-blox (AT+USOCR).
+// Возвращаемые соединения реализуют net.Conn и net.PacketConn, поэтому подходят
+// для http.Transport.DialContext, MQTT клиентов и т.д.
+type Dialer struct {
+	modem  *Modem
+	stack  socketStack
+	config DialerConfig
+}
+
+// NewDialer активирует PDP контекст во встроенном стеке модема и возвращает Dialer
+func (m *Modem) NewDialer(config DialerConfig) (*Dialer, error) {
+	if config.CID <= 0 {
+		config.CID = 1
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultDialTimeout
+	}
+
+	var stack socketStack
+	switch m.Vendor() {
+	case VendorQuectel:
+		stack = &quectelStack{m: m}
+	case VendorSIMCom:
+		stack = &simcomStack{m: m}
+	case VendorUblox:
+		stack = &ubloxStack{m: m}
+	default:
+		return nil, ErrNotSupported
+	}
+
+	d := &Dialer{modem: m, stack: stack, config: config}
+	if err := stack.activate(config); err != nil {
+		return nil, fmt.Errorf("failed to activate IP stack: %w", err)
+	}
+	return d, nil
+}
+
+// Dial открывает соединение ("tcp", "tcp4", "udp", "udp4") с адресом "host:port"
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext открывает соединение с учетом срока контекста
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	var udp bool
+	switch network {
+	case "tcp", "tcp4":
+	case "udp", "udp4":
+		udp = true
+	default:
+		return nil, &net.OpError{Op: "dial", Net: network, Err: net.UnknownNetworkError(network)}
+	}
+
+	host, port, err := splitHostPort(address)
+	if err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+	}
+	timeout := d.config.Timeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+
+	c := d.newConn(network, udp, false)
+	c.remote = socketAddr(network, host, port)
+	if err := d.stack.open(c, host, port, 0, timeout); err != nil {
+		d.modem.sockets.remove(c)
+		return nil, &net.OpError{Op: "dial", Net: network, Addr: c.remote, Err: err}
+	}
+	d.modem.sockets.add(c)
+	return c, nil
+}
+
+// ListenPacket открывает UDP сокет для обмена с произвольными адресами (":port" - локальный порт).
+// SIM800 поддерживает только UDP соединения с одним адресом (Dial).
+func (d *Dialer) ListenPacket(network, address string) (net.PacketConn, error) {
+	if network != "udp" && network != "udp4" {
+		return nil, &net.OpError{Op: "listen", Net: network, Err: net.UnknownNetworkError(network)}
+	}
+	_, port, err := splitHostPort(address)
+	if err != nil {
+		return nil, &net.OpError{Op: "listen", Net: network, Err: err}
+	}
+
+	c := d.newConn(network, true, true)
+	c.local = &net.UDPAddr{IP: net.IPv4zero, Port: port}
+	if err := d.stack.open(c, "", 0, port, d.config.Timeout); err != nil {
+		d.modem.sockets.remove(c)
+		return nil, &net.OpError{Op: "listen", Net: network, Addr: c.local, Err: err}
+	}
+	d.modem.sockets.add(c)
+	return c, nil
+}
+
+// newConn создает соединение (идентификатор назначает стек при открытии)
+func (d *Dialer) newConn(network string, udp, listen bool) *socketConn {
+	c := &socketConn{
+		modem:   d.modem,
+		stack:   d.stack,
+		config:  d.config,
+		id:      -1,
+		network: network,
+		udp:     udp,
+		listen:  listen,
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if udp {
+		c.local = &net.UDPAddr{IP: net.IPv4zero}
+	} else {
+		c.local = &net.TCPAddr{IP: net.IPv4zero}
+	}
+	return c
+}
+
+// splitHostPort разбирает "host:port"
+func splitHostPort(address string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %q", portStr)
+	}
+	return host, port, nil
+}
+
+// hostAddr адрес, заданный именем хоста (модем разрешает его сам)
+type hostAddr struct {
+	network string
+	address string
+}
+
+func (a hostAddr) Network() string { return a.network }
+func (a hostAddr) String() string  { return a.address }
+
+// socketAddr возвращает адрес удаленной стороны
+func socketAddr(network, host string, port int) net.Addr {
+	if ip := net.ParseIP(host); ip != nil {
+		if strings.HasPrefix(network, "udp") {
+			return &net.UDPAddr{IP: ip, Port: port}
+		}
+		return &net.TCPAddr{IP: ip, Port: port}
+	}
+	return hostAddr{network: network, address: net.JoinHostPort(host, strconv.Itoa(port))}
+}
+
+// socketStack команды встроенного TCP/IP стека конкретного производителя
+type socketStack interface {
+	// activate поднимает PDP контекст для стека
+	activate(config DialerConfig) error
+	// open открывает сокет и назначает c.id (пустой host - UDP сокет без удаленного адреса)
+	open(c *socketConn, host string, port, localPort int, timeout time.Duration) error
+	// send отправляет данные (to - адрес получателя для UDP сокета без удаленного адреса)
+	send(c *socketConn, data []byte, to *net.UDPAddr) (int, error)
+	// recv читает до max байт (для UDP - одну датаграмму); пустой результат - данных нет
+	recv(c *socketConn, max int) ([]byte, *net.UDPAddr, error)
+	// alive проверяет, что соединение не закрыто удаленной стороной
+	alive(c *socketConn) (bool, error)
+	// close закрывает сокет
+	close(c *socketConn) error
+	// maxSend максимальный размер одной отправки
+	maxSend() int
+	// maxRecv максимальный размер одного чтения
+	maxRecv() int
+}
+
+// socketConn сокет встроенного стека, реализует net.Conn и net.PacketConn
+type socketConn struct {
+	modem   *Modem
+	stack   socketStack
+	config  DialerConfig
+	id      int
+	network string
+	udp     bool
+	listen  bool
+	local   net.Addr
+	remote  net.Addr
+
+	readMu     sync.Mutex
+	writeMu    sync.Mutex
+	deadlineMu sync.Mutex
+	readDL     time.Time
+	writeDL    time.Time
+
+	notify     chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	peerClosed atomic.Bool
+}
+
+// wake сообщает о входящих данных
+func (c *socketConn) wake() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// isClosed проверяет, что сокет закрыт локально
+func (c *socketConn) isClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// opError оборачивает ошибку в *net.OpError, как это делают сокеты стандартной библиотеки
+func (c *socketConn) opError(op string, err error) error {
+	if err == io.EOF {
+		return err
+	}
+	return &net.OpError{Op: op, Net: c.network, Source: c.local, Addr: c.remote, Err: err}
+}
+
+// Read читает данные соединения
+func (c *socketConn) Read(b []byte) (int, error) {
+	n, _, err := c.read(b)
+	return n, err
+}
+
+// ReadFrom читает датаграмму и адрес отправителя
+func (c *socketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, from, err := c.read(b)
+	if from == nil {
+		return n, c.remote, err
+	}
+	return n, from, err
+}
+
+// read ждет данные, опрашивая модем и реагируя на уведомления о приеме. При запущенном
+// обработчике событий прием и закрытие сообщаются URC, поэтому модем опрашивается редко.
+func (c *socketConn) read(b []byte) (int, *net.UDPAddr, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+	if len(b) == 0 {
+		return 0, nil, nil
+	}
+
+	lastCheck := time.Now()
+	for {
+		if c.isClosed() {
+			return 0, nil, c.opError("read", net.ErrClosed)
+		}
+		deadline := c.deadline(&c.readDL)
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			return 0, nil, c.opError("read", os.ErrDeadlineExceeded)
+		}
+
+		max := len(b)
+		if max > c.stack.maxRecv() {
+			max = c.stack.maxRecv()
+		}
+		data, from, err := c.stack.recv(c, max)
+		if err != nil {
+			return 0, nil, c.opError("read", err)
+		}
+		if len(data) > 0 {
+			return copy(b, data), from, nil
+		}
+		if c.peerClosed.Load() {
+			if c.udp {
+				return 0, nil, c.opError("read", net.ErrClosed)
+			}
+			return 0, nil, io.EOF
+		}
+
+		wait, check := socketPollInterval, socketStateInterval
+		if c.modem.IsEventListenerRunning() {
+			wait, check = socketFallbackInterval, socketFallbackInterval
+		}
+
+		// Без уведомлений закрытие соединения удаленной стороной видно только по состоянию сокета
+		if time.Since(lastCheck) >= check {
+			lastCheck = time.Now()
+			if alive, err := c.stack.alive(c); err == nil && !alive {
+				// Еще одно чтение забирает данные, пришедшие перед закрытием
+				c.peerClosed.Store(true)
+				continue
+			}
+		}
+
+		if !deadline.IsZero() && time.Until(deadline) < wait {
+			wait = time.Until(deadline)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-c.notify:
+		case <-c.done:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// Write отправляет данные, разбивая их на части допустимого размера
+func (c *socketConn) Write(b []byte) (int, error) {
+	return c.write(b, nil)
+}
+
+// WriteTo отправляет датаграмму по адресу
+func (c *socketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	to, ok := addr.(*net.UDPAddr)
+	if !ok || to.IP.To4() == nil {
+		return 0, c.opError("write", fmt.Errorf("unsupported address %v", addr))
+	}
+	if !c.listen {
+		return 0, c.opError("write", fmt.Errorf("socket is connected"))
+	}
+	return c.write(b, to)
+}
+
+// write отправляет данные с повтором при заполненном буфере модема
+func (c *socketConn) write(b []byte, to *net.UDPAddr) (int, error) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.listen && to == nil {
+		return 0, c.opError("write", fmt.Errorf("destination address required"))
+	}
+	if c.udp && len(b) > c.stack.maxSend() {
+		// Датаграмму нельзя разбивать
+		return 0, c.opError("write", fmt.Errorf("message too long: %d > %d", len(b), c.stack.maxSend()))
+	}
+
+	written := 0
+	for written < len(b) {
+		if c.isClosed() {
+			return written, c.opError("write", net.ErrClosed)
+		}
+		if deadline := c.deadline(&c.writeDL); !deadline.IsZero() && !time.Now().Before(deadline) {
+			return written, c.opError("write", os.ErrDeadlineExceeded)
+		}
+
+		end := written + c.stack.maxSend()
+		if end > len(b) {
+			end = len(b)
+		}
+		n, err := c.stack.send(c, b[written:end], to)
+		if errors.Is(err, errSocketBusy) {
+			time.Sleep(socketPollInterval)
+			continue
+		}
+		if err != nil {
+			return written, c.opError("write", err)
+		}
+		written += n
+	}
+	return written, nil
+}
+
+// Close закрывает сокет
+func (c *socketConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.modem.sockets.remove(c)
+		// Сокет, закрытый удаленной стороной, модем может уже не знать
+		if e := c.stack.close(c); e != nil && !c.peerClosed.Load() {
+			err = c.opError("close", e)
+		}
+	})
+	return err
+}
+
+// LocalAddr возвращает локальный адрес (для TCP модем его не сообщает)
+func (c *socketConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// RemoteAddr возвращает адрес удаленной стороны
+func (c *socketConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// SetDeadline задает срок для чтения и записи
+func (c *socketConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+// SetReadDeadline задает срок для чтения
+func (c *socketConn) SetReadDeadline(t time.Time) error {
+	c.deadlineMu.Lock()
+	c.readDL = t
+	c.deadlineMu.Unlock()
+	c.wake()
+	return nil
+}
+
+// SetWriteDeadline задает срок для записи
+func (c *socketConn) SetWriteDeadline(t time.Time) error {
+	c.deadlineMu.Lock()
+	c.writeDL = t
+	c.deadlineMu.Unlock()
+	return nil
+}
+
+// deadline возвращает текущий срок
+func (c *socketConn) deadline(t *time.Time) time.Time {
+	c.deadlineMu.Lock()
+	defer c.deadlineMu.Unlock()
+	return *t
+}
+
+// socketManager открытые сокеты модема и обработка их URC
+type socketManager struct {
+	mu      sync.Mutex
+	conns   map[int]*socketConn
+	results map[int]chan string
+}
+
+// newSocketManager создает пустую таблицу сокетов
+func newSocketManager() *socketManager {
+	return &socketManager{
+		conns:   make(map[int]*socketConn),
+		results: make(map[int]chan string),
+	}
+}
+
+// reserve назначает сокету свободный идентификатор 0..max-1 (для стеков, где его выбирает хост)
+func (sm *socketManager) reserve(c *socketConn, max int) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for id := 0; id < max; id++ {
+		if _, used := sm.conns[id]; !used {
+			c.id = id
+			sm.conns[id] = c
+			return nil
+		}
+	}
+	return fmt.Errorf("no free sockets")
+}
+
+// add регистрирует открытый сокет
+func (sm *socketManager) add(c *socketConn) {
+	sm.mu.Lock()
+	sm.conns[c.id] = c
+	sm.mu.Unlock()
+}
+
+// remove удаляет сокет из таблицы
+func (sm *socketManager) remove(c *socketConn) {
+	sm.mu.Lock()
+	if sm.conns[c.id] == c {
+		delete(sm.conns, c.id)
+	}
+	sm.mu.Unlock()
+}
+
+// expect подготавливает ожидание результата открытия сокета
+func (sm *socketManager) expect(id int) chan string {
+	ch := make(chan string, 1)
+	sm.mu.Lock()
+	sm.results[id] = ch
+	sm.mu.Unlock()
+	return ch
+}
+
+// cancel отменяет ожидание результата открытия сокета, если результат не пришел
+func (sm *socketManager) cancel(id int, ch chan string) {
+	sm.mu.Lock()
+	if sm.results[id] == ch {
+		delete(sm.results, id)
+	}
+	sm.mu.Unlock()
+}
+
+// scan обрабатывает URC сокетов, попавшие в ответ на команду
+func (sm *socketManager) scan(response string) {
+	for _, line := range strings.Split(response, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			sm.handleURC(line)
+		}
+	}
+}
+
+// handleURC обрабатывает уведомления сокетов, возвращает true если строка им принадлежит:
+// Quectel +QIURC/+QIOPEN, SIMCom +CIPRXGET: 1/"<n>, CLOSED"/"<n>, CONNECT OK", u-blox +UUSORD/+UUSORF/+UUSOCL
+func (sm *socketManager) handleURC(line string) bool {
+	id, kind, ok := parseSocketURC(line)
+	if !ok {
+		return false
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	switch kind {
+	case "deact":
+		for _, c := range sm.conns {
+			c.peerClosed.Store(true)
+			c.wake()
+		}
+	case "recv":
+		if c := sm.conns[id]; c != nil {
+			c.wake()
+		}
+	case "closed":
+		if c := sm.conns[id]; c != nil {
+			c.peerClosed.Store(true)
+			c.wake()
+		}
+	case "":
+	default:
+		// Результат открытия сокета
+		if ch := sm.results[id]; ch != nil {
+			delete(sm.results, id)
+			ch <- kind
+		}
+	}
+	return true
+}
+
+// parseSocketURC разбирает URC сокета: kind "recv", "closed", "deact", результат открытия
+// ("0" или код ошибки, "CONNECT OK") или "" для прочих строк стека
+func parseSocketURC(line string) (int, string, bool) {
+	switch {
+	case strings.HasPrefix(line, "+QIURC:"):
+		// +QIURC: "recv",<connectID> / +QIURC: "closed",<connectID> / +QIURC: "pdpdeact",<contextID>
+		fields, _ := parseInfoLine(line, "+QIURC:")
+		switch fieldAt(fields, 0).Value {
+		case "recv", "closed":
+			return fieldAt(fields, 1).IntOr(-1), fieldAt(fields, 0).Value, true
+		case "pdpdeact":
+			return -1, "deact", true
+		}
+		return -1, "", true
+	case strings.HasPrefix(line, "+QIOPEN:"):
+		// +QIOPEN: <connectID>,<err>
+		fields, _ := parseInfoLine(line, "+QIOPEN:")
+		return fieldAt(fields, 0).IntOr(-1), fieldAt(fields, 1).Value, true
+	case strings.HasPrefix(line, "+CIPRXGET: 1,"):
+		fields, _ := parseInfoLine(line, "+CIPRXGET:")
+		return fieldAt(fields, 1).IntOr(-1), "recv", true
+	case strings.HasPrefix(line, "+UUSORD:") || strings.HasPrefix(line, "+UUSORF:"):
+		// +UUSORD: <socket>,<length>
+		fields, _ := parseInfoLine(line, line[:len("+UUSORD:")])
+		return fieldAt(fields, 0).IntOr(-1), "recv", true
+	case strings.HasPrefix(line, "+UUSOCL:"):
+		fields, _ := parseInfoLine(line, "+UUSOCL:")
+		return fieldAt(fields, 0).IntOr(-1), "closed", true
+	case line == "+PDP: DEACT" || strings.HasPrefix(line, "+UUPSDD:"):
+		return -1, "deact", true
+	}
+
+	// SIM800 в режиме нескольких соединений: "<n>, CLOSED", "<n>, CONNECT OK"
+	if idx := strings.Index(line, ", "); idx > 0 {
+		if id, err := strconv.Atoi(line[:idx]); err == nil {
+			switch status := line[idx+2:]; status {
+			case "CLOSED":
+				return id, "closed", true
+			case "CONNECT OK", "CONNECT FAIL", "ALREADY CONNECT":
+				return id, status, true
+			case "SEND OK", "SEND FAIL", "CLOSE OK":
+				return id, "", true
+			}
+		}
+	}
+	return -1, "", false
+}
+
+// commandUntil отправляет команду и читает ответ до одной из финальных строк
+func (m *Modem) commandUntil(cmd string, finals []string, timeout time.Duration) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dataMode {
+		return "", ErrDataMode
+	}
+
+	m.flushPort()
+	if _, err := m.port.Write([]byte(cmd + "\r\n")); err != nil {
+		return "", fmt.Errorf("failed to write command: %w", err)
+	}
+	resp, err := m.readUntil(finals, timeout)
+	debugResponse(cmd, resp)
+	m.sockets.scan(resp)
+	return resp, err
+}
+
+// sendWithPrompt отправляет команду, ждет приглашение (">") и передает данные
+func (m *Modem) sendWithPrompt(cmd, prompt string, data []byte, finals []string, timeout time.Duration) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dataMode {
+		return "", ErrDataMode
+	}
+
+	m.flushPort()
+	if _, err := m.port.Write([]byte(cmd + "\r\n")); err != nil {
+		return "", fmt.Errorf("failed to write command: %w", err)
+	}
+	if resp, err := m.readUntil([]string{prompt}, time.Second*5); err != nil {
+		debugResponse(cmd, resp)
+		return resp, err
+	}
+	if _, err := m.port.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write data: %w", err)
+	}
+
+	resp, err := m.readUntil(finals, timeout)
+	debugResponse(cmd, resp)
+	m.sockets.scan(resp)
+	return resp, err
+}
+
+// readUntil читает порт до одной из финальных строк или ошибки (под m.mu)
+func (m *Modem) readUntil(finals []string, timeout time.Duration) (string, error) {
+	var response []byte
+	buf := make([]byte, 1024)
+	for start := time.Now(); time.Since(start) < timeout; {
+		n, err := m.readPort(buf)
+		if err != nil || n == 0 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		response = append(response, buf[:n]...)
+
+		resp := string(response)
+		for _, final := range finals {
+			if strings.Contains(resp, final) {
+				return resp, nil
+			}
+		}
+		if line := findErrorLine(resp); line != "" && strings.HasSuffix(resp, "\n") {
+			return resp, fmt.Errorf("%w: %s", ErrCommandFailed, line)
+		}
+	}
+	return string(response), fmt.Errorf("timeout waiting for response")
+}
+
+// readData отправляет команду чтения и возвращает поля заголовка и двоичные данные ответа
+// вида "<prefix> ...,<length>,...\r\n<data>\r\nOK". Данные могут содержать любые байты,
+// поэтому читается ровно <length> байт.
+func (m *Modem) readData(cmd, prefix string, lengthIndex int, timeout time.Duration) ([]Field, []byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.dataMode {
+		return nil, nil, ErrDataMode
+	}
+
+	m.flushPort()
+	if _, err := m.port.Write([]byte(cmd + "\r\n")); err != nil {
+		return nil, nil, fmt.Errorf("failed to write command: %w", err)
+	}
+
+	var response []byte
+	buf := make([]byte, 2048)
+	for start := time.Now(); time.Since(start) < timeout; {
+		n, err := m.readPort(buf)
+		if err != nil || n == 0 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		response = append(response, buf[:n]...)
+
+		fields, header, data, ok := splitDataResponse(response, prefix, lengthIndex)
+		if header == nil {
+			resp := string(response)
+			if line := findErrorLine(resp); line != "" && strings.HasSuffix(resp, "\n") {
+				debugResponse(cmd, resp)
+				return nil, nil, fmt.Errorf("%w: %s", ErrCommandFailed, line)
+			}
+			if strings.Contains(resp, "\r\nOK\r\n") {
+				// Ответ без заголовка - данных нет
+				m.sockets.scan(resp)
+				return nil, nil, nil
+			}
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		debugResponse(cmd, string(header)+fmt.Sprintf("<%d bytes>", len(data)))
+		m.sockets.scan(string(header))
+		return fields, data, nil
+	}
+	return nil, nil, fmt.Errorf("timeout waiting for %s", prefix)
+}
+
+// splitDataResponse разбирает ответ "<prefix> ...,<length>,...\r\n<data>\r\nOK": header - все
+// до начала данных (URC перед заголовком и сама строка заголовка), nil - заголовка еще нет;
+// ok - данные и финальный OK получены полностью
+func splitDataResponse(response []byte, prefix string, lengthIndex int) (fields []Field, header, data []byte, ok bool) {
+	idx := bytes.Index(response, []byte(prefix))
+	if idx < 0 {
+		return nil, nil, nil, false
+	}
+	eol := bytes.Index(response[idx:], []byte("\r\n"))
+	if eol < 0 {
+		return nil, nil, nil, false
+	}
+	fields, _ = parseInfoLine(string(response[idx:idx+eol]), prefix)
+	length := fieldAt(fields, lengthIndex).IntOr(0)
+	dataStart := idx + eol + 2
+	if len(response) < dataStart+length || !bytes.Contains(response[dataStart+length:], []byte("OK\r\n")) {
+		return fields, response[:dataStart], nil, false
+	}
+	return fields, response[:dataStart], response[dataStart : dataStart+length], true
+}
@@ -0,0 +1,200 @@
+package gsm
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseSocketURC(t *testing.T) {
+	tests := []struct {
+		line string
+		id   int
+		kind string
+		ok   bool
+	}{
+		{`+QIURC: "recv",3`, 3, "recv", true},
+		{`+QIURC: "closed",0`, 0, "closed", true},
+		{`+QIURC: "pdpdeact",1`, -1, "deact", true},
+		{`+QIURC: "incoming full"`, -1, "", true},
+		{`+QIOPEN: 2,0`, 2, "0", true},
+		{`+QIOPEN: 2,565`, 2, "565", true},
+		{`+CIPRXGET: 1,4`, 4, "recv", true},
+		{`+UUSORD: 1,32`, 1, "recv", true},
+		{`+UUSORF: 2,16`, 2, "recv", true},
+		{`+UUSOCL: 1`, 1, "closed", true},
+		{`+PDP: DEACT`, -1, "deact", true},
+		{`+UUPSDD: 0`, -1, "deact", true},
+		{`1, CONNECT OK`, 1, "CONNECT OK", true},
+		{`2, CONNECT FAIL`, 2, "CONNECT FAIL", true},
+		{`0, ALREADY CONNECT`, 0, "ALREADY CONNECT", true},
+		{`5, CLOSED`, 5, "closed", true},
+		{`3, SEND OK`, 3, "", true},
+		// Ответ на чтение и чужие URC стеку не принадлежат
+		{`+CIPRXGET: 2,1,10,0`, -1, "", false},
+		{`+QIRD: 10`, -1, "", false},
+		{`+CMTI: "SM",1`, -1, "", false},
+		{`Balance, 100`, -1, "", false},
+	}
+	for _, tt := range tests {
+		id, kind, ok := parseSocketURC(tt.line)
+		if id != tt.id || kind != tt.kind || ok != tt.ok {
+			t.Errorf("parseSocketURC(%q) = %d, %q, %v, want %d, %q, %v", tt.line, id, kind, ok, tt.id, tt.kind, tt.ok)
+		}
+	}
+}
+
+func TestSplitDataResponse(t *testing.T) {
+	// Двоичные данные с CR LF, "OK" и префиксом внутри
+	payload := []byte("\x00\r\nOK\r\n+QIRD: 5\xff")
+	response := append([]byte("\r\n+QIURC: \"recv\",0\r\n+QIRD: 16\r\n"), payload...)
+	response = append(response, "\r\n\r\nOK\r\n"...)
+
+	fields, header, data, ok := splitDataResponse(response, "+QIRD:", 0)
+	if !ok || !bytes.Equal(data, payload) {
+		t.Fatalf("splitDataResponse = %q, %v, want %q", data, ok, payload)
+	}
+	if fieldAt(fields, 0).IntOr(-1) != len(payload) {
+		t.Errorf("fields = %v", fields)
+	}
+	if string(header) != "\r\n+QIURC: \"recv\",0\r\n+QIRD: 16\r\n" {
+		t.Errorf("header = %q", header)
+	}
+
+	// Ответ приходит частями: данные считаются полученными только с финальным OK
+	for i := 0; i < len(response); i++ {
+		if _, _, _, ok := splitDataResponse(response[:i], "+QIRD:", 0); ok {
+			t.Fatalf("splitDataResponse accepted partial response of %d bytes", i)
+		}
+	}
+
+	// SIMCom: длина в поле 1, UDP адрес Quectel - в полях после длины
+	response = []byte("\r\n+CIPRXGET: 2,1,3,0\r\nabc\r\nOK\r\n")
+	if _, _, data, ok := splitDataResponse(response, "+CIPRXGET: 2,", 1); !ok || string(data) != "abc" {
+		t.Errorf("splitDataResponse(CIPRXGET) = %q, %v", data, ok)
+	}
+	response = []byte("\r\n+QIRD: 2,\"10.0.0.1\",5683\r\nhi\r\n\r\nOK\r\n")
+	fields, _, data, ok = splitDataResponse(response, "+QIRD:", 0)
+	if !ok || string(data) != "hi" || fieldAt(fields, 1).Value != "10.0.0.1" || fieldAt(fields, 2).IntOr(0) != 5683 {
+		t.Errorf("splitDataResponse(UDP) = %v, %q, %v", fields, data, ok)
+	}
+
+	// Пустой буфер и ответ без заголовка
+	if _, _, data, ok := splitDataResponse([]byte("\r\n+QIRD: 0\r\n\r\nOK\r\n"), "+QIRD:", 0); !ok || len(data) != 0 {
+		t.Errorf("splitDataResponse(empty) = %q, %v", data, ok)
+	}
+	if _, header, _, _ := splitDataResponse([]byte("\r\nOK\r\n"), "+QIRD:", 0); header != nil {
+		t.Errorf("header without prefix = %q", header)
+	}
+}
+
+func TestSocketManagerResults(t *testing.T) {
+	sm := newSocketManager()
+	ch := sm.expect(2)
+	if !sm.handleURC("+QIOPEN: 2,0") {
+		t.Fatal("handleURC rejected +QIOPEN")
+	}
+	if result := <-ch; result != "0" {
+		t.Errorf("result = %q", result)
+	}
+
+	// Неудачное открытие не оставляет ожидание в таблице
+	ch = sm.expect(3)
+	sm.cancel(3, ch)
+	if len(sm.results) != 0 {
+		t.Errorf("results after cancel = %v", sm.results)
+	}
+	// Отмена старого ожидания не трогает новое для того же идентификатора
+	old := sm.expect(4)
+	current := sm.expect(4)
+	sm.cancel(4, old)
+	if sm.results[4] != current {
+		t.Error("cancel removed another waiter")
+	}
+}
+
+// fakeStack стек, данные которого появляются по команде теста
+type fakeStack struct {
+	mu     sync.Mutex
+	data   []byte
+	recvs  int
+	alives int
+}
+
+func (s *fakeStack) activate(DialerConfig) error { return nil }
+func (s *fakeStack) open(*socketConn, string, int, int, time.Duration) error {
+	return nil
+}
+func (s *fakeStack) send(_ *socketConn, data []byte, _ *net.UDPAddr) (int, error) {
+	return len(data), nil
+}
+func (s *fakeStack) recv(_ *socketConn, max int) ([]byte, *net.UDPAddr, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recvs++
+	data := s.data
+	s.data = nil
+	return data, nil, nil
+}
+func (s *fakeStack) alive(*socketConn) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alives++
+	return true, nil
+}
+func (s *fakeStack) close(*socketConn) error { return nil }
+func (s *fakeStack) maxSend() int            { return 512 }
+func (s *fakeStack) maxRecv() int            { return 512 }
+
+func TestSocketReadWaitsForURC(t *testing.T) {
+	stack := &fakeStack{}
+	modem := &Modem{eventsEnabled: true, sockets: newSocketManager()}
+	d := &Dialer{modem: modem, stack: stack}
+	c := d.newConn("tcp", false, false)
+	c.id = 0
+	modem.sockets.add(c)
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		stack.mu.Lock()
+		stack.data = []byte("hello")
+		stack.mu.Unlock()
+		modem.sockets.handleURC(`+QIURC: "recv",0`)
+	}()
+
+	buf := make([]byte, 16)
+	start := time.Now()
+	n, err := c.Read(buf)
+	if err != nil || string(buf[:n]) != "hello" {
+		t.Fatalf("Read = %q, %v", buf[:n], err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Read returned after %v, URC did not wake it", elapsed)
+	}
+	// При URC модем не опрашивается каждые socketPollInterval
+	stack.mu.Lock()
+	recvs, alives := stack.recvs, stack.alives
+	stack.mu.Unlock()
+	if recvs != 2 || alives != 0 {
+		t.Errorf("recv called %d times, alive %d times, want 2 and 0", recvs, alives)
+	}
+}
+
+func TestSocketReadDeadline(t *testing.T) {
+	stack := &fakeStack{}
+	modem := &Modem{eventsEnabled: true, sockets: newSocketManager()}
+	d := &Dialer{modem: modem, stack: stack}
+	c := d.newConn("tcp", false, false)
+	c.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+	start := time.Now()
+	_, err := c.Read(make([]byte, 16))
+	if ne, ok := err.(net.Error); !ok || !ne.Timeout() {
+		t.Fatalf("Read error = %v, want timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("deadline exceeded after %v", elapsed)
+	}
+}
@@ -0,0 +1,443 @@
+package gsm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// quectelStack TCP/IP команды Quectel (BG96, EC2x, EG9x): AT+QIOPEN в режиме буфера
+// (данные читаются AT+QIRD по уведомлению +QIURC: "recv")
+type quectelStack struct {
+	m   *Modem
+	cid int
+}
+
+func (s *quectelStack) activate(config DialerConfig) error {
+	s.cid = config.CID
+
+	// +QIACT: <contextID>,<context_state>,<context_type>[,<IP_address>]
+	if resp, err := s.m.execCommand("AT+QIACT?", time.Second*5); err == nil {
+		for _, fields := range parseInfoLines(resp, "+QIACT:") {
+			if fieldAt(fields, 0).IntOr(-1) == s.cid && fieldAt(fields, 1).Value == "1" {
+				return nil
+			}
+		}
+	}
+
+	if config.APN != "" {
+		cmd := fmt.Sprintf(`AT+QICSGP=%d,1,"%s","","",0`, s.cid, config.APN)
+		if _, err := s.m.execCommand(cmd, time.Second*5); err != nil {
+			return fmt.Errorf("failed to set APN: %w", err)
+		}
+	}
+	if _, err := s.m.execCommand(fmt.Sprintf("AT+QIACT=%d", s.cid), activateTimeout); err != nil {
+		return fmt.Errorf("failed to activate context %d: %w", s.cid, err)
+	}
+	return nil
+}
+
+func (s *quectelStack) open(c *socketConn, host string, port, localPort int, timeout time.Duration) error {
+	if err := s.m.sockets.reserve(c, 12); err != nil {
+		return err
+	}
+
+	var cmd string
+	switch {
+	case !c.udp:
+		cmd = fmt.Sprintf(`AT+QIOPEN=%d,%d,"TCP","%s",%d,0,0`, s.cid, c.id, host, port)
+	case c.listen:
+		if localPort == 0 {
+			// Для "UDP SERVICE" локальный порт обязателен
+			localPort = 40000 + c.id
+			c.local = &net.UDPAddr{IP: net.IPv4zero, Port: localPort}
+		}
+		cmd = fmt.Sprintf(`AT+QIOPEN=%d,%d,"UDP SERVICE","127.0.0.1",0,%d,0`, s.cid, c.id, localPort)
+	default:
+		cmd = fmt.Sprintf(`AT+QIOPEN=%d,%d,"UDP","%s",%d,0,0`, s.cid, c.id, host, port)
+	}
+
+	ch := s.m.sockets.expect(c.id)
+	defer s.m.sockets.cancel(c.id, ch)
+	resp, err := s.m.execCommand(cmd, time.Second*5)
+	if err != nil {
+		return fmt.Errorf("failed to open socket: %w", err)
+	}
+	s.m.sockets.scan(resp)
+
+	// +QIOPEN: <connectID>,<err>
+	result, err := s.m.awaitURC(ch, "+QIOPEN:", timeout, s.m.sockets.handleURC)
+	if err == nil && result != "0" {
+		err = fmt.Errorf("failed to open socket: error %s", result)
+	}
+	if err != nil {
+		// Сокет нужно закрыть и после неудачного открытия
+		s.m.execCommand(fmt.Sprintf("AT+QICLOSE=%d,1", c.id), time.Second*5)
+		return err
+	}
+	return nil
+}
+
+func (s *quectelStack) send(c *socketConn, data []byte, to *net.UDPAddr) (int, error) {
+	cmd := fmt.Sprintf("AT+QISEND=%d,%d", c.id, len(data))
+	if to != nil {
+		cmd += fmt.Sprintf(`,"%s",%d`, to.IP, to.Port)
+	}
+	resp, err := s.m.sendWithPrompt(cmd, ">", data, []string{"SEND OK", "SEND FAIL"}, time.Second*30)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send: %w", err)
+	}
+	if strings.Contains(resp, "SEND FAIL") {
+		return 0, errSocketBusy
+	}
+	return len(data), nil
+}
+
+func (s *quectelStack) recv(c *socketConn, max int) ([]byte, *net.UDPAddr, error) {
+	// +QIRD: <read_actual_length>[,"<remoteIP>",<remote_port>]
+	fields, data, err := s.m.readData(fmt.Sprintf("AT+QIRD=%d,%d", c.id, max), "+QIRD:", 0, time.Second*10)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read: %w", err)
+	}
+	if c.listen && len(data) > 0 {
+		from := &net.UDPAddr{IP: net.ParseIP(fieldAt(fields, 1).Value), Port: fieldAt(fields, 2).IntOr(0)}
+		return data, from, nil
+	}
+	return data, nil, nil
+}
+
+func (s *quectelStack) alive(c *socketConn) (bool, error) {
+	resp, err := s.m.execCommand(fmt.Sprintf("AT+QISTATE=1,%d", c.id), time.Second*5)
+	if err != nil {
+		return false, err
+	}
+	// +QISTATE: <connectID>,"<service_type>","<IP>",<remote_port>,<local_port>,<socket_state>,...
+	// <socket_state>: 2 - соединение установлено, 3 - слушает (UDP SERVICE), 4 - закрывается
+	for _, fields := range parseInfoLines(resp, "+QISTATE:") {
+		if fieldAt(fields, 0).IntOr(-1) == c.id {
+			state := fieldAt(fields, 5).IntOr(-1)
+			return state == 2 || state == 3, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *quectelStack) close(c *socketConn) error {
+	_, err := s.m.execCommand(fmt.Sprintf("AT+QICLOSE=%d,10", c.id), time.Second*15)
+	return err
+}
+
+func (s *quectelStack) maxSend() int { return 1460 }
+func (s *quectelStack) maxRecv() int { return 1500 }
+
+// simcomStates состояния AT+CIPSTATUS (SIM800)
+var simcomStates = []string{
+	"STATE: IP INITIAL", "STATE: IP START", "STATE: IP CONFIG", "STATE: IP GPRSACT",
+	"STATE: IP STATUS", "STATE: IP PROCESSING", "STATE: PDP DEACT",
+}
+
+// simcomStack TCP/IP команды SIMCom SIM800/SIM900: AT+CIPSTART в режиме нескольких соединений
+// (AT+CIPMUX=1) с ручным приемом (AT+CIPRXGET=1)
+type simcomStack struct {
+	m *Modem
+}
+
+func (s *simcomStack) activate(config DialerConfig) error {
+	resp, err := s.m.commandUntil("AT+CIPSTATUS", simcomStates, time.Second*5)
+	if err != nil {
+		return fmt.Errorf("failed to get IP state: %w", err)
+	}
+	state := ""
+	for _, candidate := range simcomStates {
+		if strings.Contains(resp, candidate) {
+			state = strings.TrimPrefix(candidate, "STATE: ")
+		}
+	}
+
+	// Режим нескольких соединений задается только в IP INITIAL
+	if state != "IP INITIAL" && state != "PDP DEACT" {
+		if resp, err := s.m.execCommand("AT+CIPMUX?", time.Second); err == nil && strings.Contains(resp, "+CIPMUX: 0") {
+			state = "PDP DEACT"
+		}
+	}
+
+	switch state {
+	case "PDP DEACT":
+		if _, err := s.m.commandUntil("AT+CIPSHUT", []string{"SHUT OK"}, time.Second*65); err != nil {
+			return fmt.Errorf("failed to reset IP stack: %w", err)
+		}
+		fallthrough
+	case "IP INITIAL":
+		if _, err := s.m.execCommand("AT+CIPMUX=1", time.Second); err != nil {
+			return fmt.Errorf("failed to enable multiple connections: %w", err)
+		}
+		if _, err := s.m.execCommand("AT+CIPRXGET=1", time.Second); err != nil {
+			return fmt.Errorf("failed to enable manual receive: %w", err)
+		}
+		apn := config.APN
+		if apn == "" {
+			if contexts, err := s.m.GetPDPContexts(); err == nil {
+				for _, ctx := range contexts {
+					if ctx.CID == config.CID {
+						apn = ctx.APN
+					}
+				}
+			}
+		}
+		if _, err := s.m.execCommand(fmt.Sprintf(`AT+CSTT="%s"`, apn), time.Second*5); err != nil {
+			return fmt.Errorf("failed to set APN: %w", err)
+		}
+		fallthrough
+	case "IP START":
+		if _, err := s.m.execCommand("AT+CIICR", time.Second*85); err != nil {
+			return fmt.Errorf("failed to bring up wireless connection: %w", err)
+		}
+		fallthrough
+	case "IP GPRSACT":
+		// Ответ - только адрес без OK; команда переводит стек в IP STATUS
+		s.m.SendCommand("AT+CIFSR", time.Second*2)
+	}
+	return nil
+}
+
+func (s *simcomStack) open(c *socketConn, host string, port, localPort int, timeout time.Duration) error {
+	if c.listen {
+		return ErrNotSupported
+	}
+	if err := s.m.sockets.reserve(c, 6); err != nil {
+		return err
+	}
+
+	protocol := "TCP"
+	if c.udp {
+		protocol = "UDP"
+	}
+	ch := s.m.sockets.expect(c.id)
+	defer s.m.sockets.cancel(c.id, ch)
+	resp, err := s.m.execCommand(fmt.Sprintf(`AT+CIPSTART=%d,"%s","%s",%d`, c.id, protocol, host, port), time.Second*5)
+	if err != nil {
+		return fmt.Errorf("failed to open socket: %w", err)
+	}
+	s.m.sockets.scan(resp)
+
+	// <n>, CONNECT OK / <n>, CONNECT FAIL / <n>, ALREADY CONNECT
+	result, err := s.m.awaitURC(ch, fmt.Sprintf("%d, ", c.id), timeout, s.m.sockets.handleURC)
+	if err == nil && result != "CONNECT OK" && result != "ALREADY CONNECT" {
+		err = fmt.Errorf("failed to open socket: %s", result)
+	}
+	if err != nil {
+		s.m.commandUntil(fmt.Sprintf("AT+CIPCLOSE=%d,1", c.id), []string{"CLOSE OK"}, time.Second*5)
+		return err
+	}
+	return nil
+}
+
+func (s *simcomStack) send(c *socketConn, data []byte, to *net.UDPAddr) (int, error) {
+	cmd := fmt.Sprintf("AT+CIPSEND=%d,%d", c.id, len(data))
+	resp, err := s.m.sendWithPrompt(cmd, ">", data, []string{"SEND OK", "SEND FAIL"}, time.Second*30)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send: %w", err)
+	}
+	if strings.Contains(resp, "SEND FAIL") {
+		return 0, fmt.Errorf("failed to send: SEND FAIL")
+	}
+	return len(data), nil
+}
+
+func (s *simcomStack) recv(c *socketConn, max int) ([]byte, *net.UDPAddr, error) {
+	// +CIPRXGET: 2,<id>,<reqlength>,<cnflength>
+	cmd := fmt.Sprintf("AT+CIPRXGET=2,%d,%d", c.id, max)
+	_, data, err := s.m.readData(cmd, "+CIPRXGET: 2,", 1, time.Second*10)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read: %w", err)
+	}
+	return data, nil, nil
+}
+
+func (s *simcomStack) alive(c *socketConn) (bool, error) {
+	resp, err := s.m.execCommand(fmt.Sprintf("AT+CIPSTATUS=%d", c.id), time.Second*5)
+	if err != nil {
+		return false, err
+	}
+	// +CIPSTATUS: <n>,<bearer>,"TCP","<IP>","<port>","<state>"
+	for _, fields := range parseInfoLines(resp, "+CIPSTATUS:") {
+		if fieldAt(fields, 0).IntOr(-1) == c.id {
+			state := fieldAt(fields, 5).Value
+			return state == "CONNECTED" || state == "CONNECTING", nil
+		}
+	}
+	return false, nil
+}
+
+func (s *simcomStack) close(c *socketConn) error {
+	_, err := s.m.commandUntil(fmt.Sprintf("AT+CIPCLOSE=%d,1", c.id), []string{"CLOSE OK"}, time.Second*10)
+	return err
+}
+
+func (s *simcomStack) maxSend() int { return 1024 }
+func (s *simcomStack) maxRecv() int { return 1460 }
+
+// ubloxStack TCP/IP команды u-blox (SARA, LARA, TOBY): AT+USOCR с данными в шестнадцатеричном
+// виде (AT+UDCONF=1,1), поэтому приглашение "@" и двоичный ответ не используются
+type ubloxStack struct {
+	m *Modem
+}
+
+func (s *ubloxStack) activate(config DialerConfig) error {
+	if _, err := s.m.execCommand("AT+UDCONF=1,1", time.Second*5); err != nil {
+		return fmt.Errorf("failed to enable hex mode: %w", err)
+	}
+	if config.APN == "" {
+		// LTE модули используют контекст, активированный при регистрации
+		return nil
+	}
+
+	// +UPSND: 0,8,<status>
+	if resp, err := s.m.execCommand("AT+UPSND=0,8", time.Second*5); err == nil {
+		for _, fields := range parseInfoLines(resp, "+UPSND:") {
+			if fieldAt(fields, 2).Value == "1" {
+				return nil
+			}
+		}
+	}
+	if _, err := s.m.execCommand(fmt.Sprintf(`AT+UPSD=0,1,"%s"`, config.APN), time.Second*5); err != nil {
+		return fmt.Errorf("failed to set APN: %w", err)
+	}
+	if _, err := s.m.execCommand("AT+UPSDA=0,3", activateTimeout); err != nil {
+		return fmt.Errorf("failed to activate packet data profile: %w", err)
+	}
+	return nil
+}
+
+func (s *ubloxStack) open(c *socketConn, host string, port, localPort int, timeout time.Duration) error {
+	cmd := "AT+USOCR=6"
+	if c.udp {
+		cmd = "AT+USOCR=17"
+		if localPort > 0 {
+			cmd += fmt.Sprintf(",%d", localPort)
+		}
+	}
+	resp, err := s.m.execCommand(cmd, time.Second*5)
+	if err != nil {
+		return fmt.Errorf("failed to create socket: %w", err)
+	}
+	c.id = -1
+	for _, fields := range parseInfoLines(resp, "+USOCR:") {
+		c.id = fieldAt(fields, 0).IntOr(-1)
+	}
+	if c.id < 0 {
+		return fmt.Errorf("failed to create socket: %w: +USOCR:", ErrNoResponse)
+	}
+	if host == "" {
+		return nil
+	}
+
+	if err := s.connect(c, host, port, timeout); err != nil {
+		s.m.execCommand(fmt.Sprintf("AT+USOCL=%d", c.id), time.Second*10)
+		return err
+	}
+	return nil
+}
+
+// connect разрешает имя хоста (AT+UDNSRN) и соединяет сокет (AT+USOCO)
+func (s *ubloxStack) connect(c *socketConn, host string, port int, timeout time.Duration) error {
+	ip := host
+	if net.ParseIP(host) == nil {
+		resp, err := s.m.execCommand(fmt.Sprintf(`AT+UDNSRN=0,"%s"`, host), time.Second*70)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", host, err)
+		}
+		ip = ""
+		for _, fields := range parseInfoLines(resp, "+UDNSRN:") {
+			ip = fieldAt(fields, 0).Value
+		}
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("failed to resolve %s: %w: +UDNSRN:", host, ErrNoResponse)
+		}
+	}
+	if _, err := s.m.execCommand(fmt.Sprintf(`AT+USOCO=%d,"%s",%d`, c.id, ip, port), timeout); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	return nil
+}
+
+func (s *ubloxStack) send(c *socketConn, data []byte, to *net.UDPAddr) (int, error) {
+	payload := strings.ToUpper(hex.EncodeToString(data))
+	cmd := fmt.Sprintf(`AT+USOWR=%d,%d,"%s"`, c.id, len(data), payload)
+	prefix := "+USOWR:"
+	if to != nil {
+		cmd = fmt.Sprintf(`AT+USOST=%d,"%s",%d,%d,"%s"`, c.id, to.IP, to.Port, len(data), payload)
+		prefix = "+USOST:"
+	}
+	resp, err := s.m.execCommand(cmd, time.Second*30)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send: %w", err)
+	}
+
+	// +USOWR: <socket>,<length>
+	for _, fields := range parseInfoLines(resp, prefix) {
+		if n := fieldAt(fields, 1).IntOr(0); n > 0 {
+			return n, nil
+		}
+	}
+	return 0, errSocketBusy
+}
+
+func (s *ubloxStack) recv(c *socketConn, max int) ([]byte, *net.UDPAddr, error) {
+	if c.udp {
+		// +USORF: <socket>,"<remote_ip>",<remote_port>,<length>,"<data>"
+		resp, err := s.m.execCommand(fmt.Sprintf("AT+USORF=%d,%d", c.id, max), time.Second*10)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read: %w", err)
+		}
+		for _, fields := range parseInfoLines(resp, "+USORF:") {
+			data, err := hex.DecodeString(fieldAt(fields, 4).Value)
+			if err != nil || len(data) == 0 {
+				continue
+			}
+			from := &net.UDPAddr{IP: net.ParseIP(fieldAt(fields, 1).Value), Port: fieldAt(fields, 2).IntOr(0)}
+			return data, from, nil
+		}
+		return nil, nil, nil
+	}
+
+	// +USORD: <socket>,<length>,"<data>"
+	resp, err := s.m.execCommand(fmt.Sprintf("AT+USORD=%d,%d", c.id, max), time.Second*10)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read: %w", err)
+	}
+	for _, fields := range parseInfoLines(resp, "+USORD:") {
+		data, err := hex.DecodeString(fieldAt(fields, 2).Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read: invalid data: %w", err)
+		}
+		return data, nil, nil
+	}
+	return nil, nil, nil
+}
+
+func (s *ubloxStack) alive(c *socketConn) (bool, error) {
+	if c.udp {
+		return true, nil
+	}
+	resp, err := s.m.execCommand(fmt.Sprintf("AT+USOCTL=%d,10", c.id), time.Second*5)
+	if err != nil {
+		return false, err
+	}
+	// +USOCTL: <socket>,10,<tcp_status>: 0 - CLOSED, 1..6 - LISTEN..FIN_WAIT_2, 7 и выше - CLOSE_WAIT и закрытие
+	for _, fields := range parseInfoLines(resp, "+USOCTL:") {
+		state := fieldAt(fields, 2).IntOr(0)
+		return state >= 1 && state <= 6, nil
+	}
+	return false, nil
+}
+
+func (s *ubloxStack) close(c *socketConn) error {
+	_, err := s.m.execCommand(fmt.Sprintf("AT+USOCL=%d", c.id), time.Second*10)
+	return err
+}
+
+func (s *ubloxStack) maxSend() int { return 512 }
+func (s *ubloxStack) maxRecv() int { return 512 }
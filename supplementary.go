@@ -0,0 +1,320 @@
+package gsm
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ForwardingReason причина переадресации (<reason> в AT+CCFC)
+type ForwardingReason int
+
+const (
+	ForwardUnconditional  ForwardingReason = iota // 0 - безусловная
+	ForwardBusy                                   // 1 - при занятости
+	ForwardNoReply                                // 2 - при отсутствии ответа
+	ForwardNotReachable                           // 3 - при недоступности
+	ForwardAll                                    // 4 - все переадресации
+	ForwardAllConditional                         // 5 - все условные переадресации
+)
+
+// ServiceClass класс услуги (битовая маска <class> из 27.007)
+type ServiceClass int
+
+const (
+	ClassVoice      ServiceClass = 1   // Голос
+	ClassData       ServiceClass = 2   // Данные
+	ClassFax        ServiceClass = 4   // Факс
+	ClassSMS        ServiceClass = 8   // SMS
+	ClassDataSync   ServiceClass = 16  // Синхронные данные
+	ClassDataAsync  ServiceClass = 32  // Асинхронные данные
+	ClassPacket     ServiceClass = 64  // Пакетный доступ
+	ClassPAD        ServiceClass = 128 // Доступ к PAD
+	ClassDefault    ServiceClass = 7   // Голос, данные и факс (значение по умолчанию в 27.007)
+	ClassAllClasses ServiceClass = 255 // Все классы
+)
+
+// Facility код услуги для AT+CLCK/AT+CPWD
+type Facility string
+
+const (
+	BarAllOutgoing              Facility = "AO" // BAOC - запрет всех исходящих
+	BarOutgoingInternational    Facility = "OI" // BOIC - запрет исходящих международных
+	BarOutgoingInternationalExH Facility = "OX" // BOIC-exHC - запрет международных, кроме домашней страны
+	BarAllIncoming              Facility = "AI" // BAIC - запрет всех входящих
+	BarIncomingRoaming          Facility = "IR" // BIC-Roam - запрет входящих в роуминге
+	BarAll                      Facility = "AB" // Все запреты (только снятие)
+	BarAllOutgoingServices      Facility = "AG" // Все запреты исходящих (только снятие)
+	BarAllIncomingServices      Facility = "AC" // Все запреты входящих (только снятие)
+	FacilitySIMPIN              Facility = "SC" // PIN SIM-карты
+	FacilitySIMPIN2             Facility = "P2" // PIN2 SIM-карты
+)
+
+// CLIRMode режим идентификации при исходящих вызовах (<n> в AT+CLIR)
+type CLIRMode int
+
+const (
+	CLIRDefault     CLIRMode = iota // 0 - по подписке
+	CLIRInvocation                  // 1 - скрывать свой номер
+	CLIRSuppression                 // 2 - показывать свой номер
+)
+
+// CLIRProvisioning статус услуги CLIR в сети (<m> в +CLIR)
+type CLIRProvisioning int
+
+const (
+	CLIRNotProvisioned      CLIRProvisioning = iota // 0 - услуга не подключена
+	CLIRPermanent                                   // 1 - постоянное скрытие номера
+	CLIRUnknown                                     // 2 - неизвестно (нет связи с сетью)
+	CLIRTemporaryRestricted                         // 3 - временно, по умолчанию скрыт
+	CLIRTemporaryAllowed                            // 4 - временно, по умолчанию показан
+)
+
+// ForwardingRule правило переадресации из ответа +CCFC
+type ForwardingRule struct {
+	Reason      ForwardingReason // Причина переадресации
+	Active      bool             // Активна ли переадресация
+	Class       ServiceClass     // Класс услуги
+	Number      string           // Номер, на который выполняется переадресация
+	NumberType  int              // Тип адреса: 129=национальный, 145=международный
+	NoReplyTime int              // Время до переадресации в секундах (для ForwardNoReply)
+}
+
+// ServiceStatus статус услуги для класса из ответов +CLCK/+CCWA
+type ServiceStatus struct {
+	Active bool         // Активна ли услуга
+	Class  ServiceClass // Класс услуги
+}
+
+// numberType возвращает тип адреса для номера
+func numberType(number string) int {
+	if strings.HasPrefix(number, "+") {
+		return 145
+	}
+	return 129
+}
+
+// SetCallForwarding регистрирует переадресацию на номер (noReplyTime учитывается только для ForwardNoReply)
+func (m *Modem) SetCallForwarding(reason ForwardingReason, class ServiceClass, number string, noReplyTime int) error {
+	cmd := fmt.Sprintf("AT+CCFC=%d,3,\"%s\",%d,%d", reason, number, numberType(number), class)
+	if reason == ForwardNoReply && noReplyTime > 0 {
+		cmd += fmt.Sprintf(",,,%d", noReplyTime)
+	}
+	if _, err := m.execCommand(cmd, time.Second*30); err != nil {
+		return fmt.Errorf("failed to set call forwarding: %w", err)
+	}
+	return nil
+}
+
+// EnableCallForwarding включает или выключает ранее зарегистрированную переадресацию
+func (m *Modem) EnableCallForwarding(reason ForwardingReason, class ServiceClass, enable bool) error {
+	mode := 0
+	if enable {
+		mode = 1
+	}
+	cmd := fmt.Sprintf("AT+CCFC=%d,%d,,,%d", reason, mode, class)
+	if _, err := m.execCommand(cmd, time.Second*30); err != nil {
+		return fmt.Errorf("failed to change call forwarding: %w", err)
+	}
+	return nil
+}
+
+// EraseCallForwarding удаляет переадресацию
+func (m *Modem) EraseCallForwarding(reason ForwardingReason, class ServiceClass) error {
+	cmd := fmt.Sprintf("AT+CCFC=%d,4,,,%d", reason, class)
+	if _, err := m.execCommand(cmd, time.Second*30); err != nil {
+		return fmt.Errorf("failed to erase call forwarding: %w", err)
+	}
+	return nil
+}
+
+// QueryCallForwarding запрашивает у сети состояние переадресации
+func (m *Modem) QueryCallForwarding(reason ForwardingReason, class ServiceClass) ([]ForwardingRule, error) {
+	cmd := fmt.Sprintf("AT+CCFC=%d,2,,,%d", reason, class)
+	resp, err := m.execCommand(cmd, time.Second*30)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query call forwarding: %w", err)
+	}
+
+	var rules []ForwardingRule
+	// +CCFC: 1,1,"+79991234567",145,,,20
+	for _, fields := range parseInfoLines(resp, "+CCFC:") {
+		if len(fields) < 2 {
+			continue
+		}
+		rules = append(rules, ForwardingRule{
+			Reason:      reason,
+			Active:      fields[0].Value == "1",
+			Class:       ServiceClass(fields[1].IntOr(0)),
+			Number:      fieldAt(fields, 2).Value,
+			NumberType:  fieldAt(fields, 3).IntOr(0),
+			NoReplyTime: fieldAt(fields, 6).IntOr(0),
+		})
+	}
+	return rules, nil
+}
+
+// SetCallBarring включает или выключает запрет вызовов (password - пароль запрета, обычно "0000")
+func (m *Modem) SetCallBarring(facility Facility, enable bool, password string, class ServiceClass) error {
+	mode := 0
+	if enable {
+		mode = 1
+	}
+	cmd := fmt.Sprintf("AT+CLCK=\"%s\",%d,\"%s\",%d", facility, mode, password, class)
+	if _, err := m.execCommand(cmd, time.Second*30); err != nil {
+		return fmt.Errorf("failed to set call barring: %w", err)
+	}
+	return nil
+}
+
+// QueryCallBarring запрашивает у сети состояние запрета вызовов
+func (m *Modem) QueryCallBarring(facility Facility, class ServiceClass) ([]ServiceStatus, error) {
+	cmd := fmt.Sprintf("AT+CLCK=\"%s\",2,,%d", facility, class)
+	resp, err := m.execCommand(cmd, time.Second*30)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query call barring: %w", err)
+	}
+	return parseServiceStatus(resp, "+CLCK:"), nil
+}
+
+// ChangePassword меняет пароль запрета вызовов или PIN (AT+CPWD)
+func (m *Modem) ChangePassword(facility Facility, oldPassword, newPassword string) error {
+	cmd := fmt.Sprintf("AT+CPWD=\"%s\",\"%s\",\"%s\"", facility, oldPassword, newPassword)
+	if _, err := m.execCommand(cmd, time.Second*30); err != nil {
+		return fmt.Errorf("failed to change password: %w", err)
+	}
+	return nil
+}
+
+// SetCLIR устанавливает режим показа своего номера при исходящих вызовах
+func (m *Modem) SetCLIR(mode CLIRMode) error {
+	if _, err := m.execCommand(fmt.Sprintf("AT+CLIR=%d", mode), time.Second*5); err != nil {
+		return fmt.Errorf("failed to set CLIR: %w", err)
+	}
+	return nil
+}
+
+// GetCLIR возвращает режим CLIR и статус услуги в сети
+func (m *Modem) GetCLIR() (CLIRMode, CLIRProvisioning, error) {
+	resp, err := m.execCommand("AT+CLIR?", time.Second*30)
+	if err != nil {
+		return CLIRDefault, CLIRUnknown, fmt.Errorf("failed to get CLIR: %w", err)
+	}
+
+	// +CLIR: 0,4
+	for _, fields := range parseInfoLines(resp, "+CLIR:") {
+		if len(fields) >= 2 {
+			return CLIRMode(fields[0].IntOr(0)), CLIRProvisioning(fields[1].IntOr(int(CLIRUnknown))), nil
+		}
+	}
+	return CLIRDefault, CLIRUnknown, fmt.Errorf("unexpected response format: %s", resp)
+}
+
+// QueryCLIP проверяет, подключена ли в сети услуга определения номера
+func (m *Modem) QueryCLIP() (bool, error) {
+	resp, err := m.execCommand("AT+CLIP?", time.Second*30)
+	if err != nil {
+		return false, fmt.Errorf("failed to query CLIP: %w", err)
+	}
+
+	// +CLIP: 1,1 - второй параметр: 0=не подключена, 1=подключена, 2=неизвестно
+	values, err := parseATResponseValues(resp, "+CLIP:")
+	if err != nil || len(values) < 2 {
+		return false, fmt.Errorf("unexpected response format: %s", resp)
+	}
+	return values[1] == "1", nil
+}
+
+// SetCallWaitingClass включает или выключает в сети ожидание вызова для классов услуг
+func (m *Modem) SetCallWaitingClass(enable bool, class ServiceClass) error {
+	mode := 0
+	if enable {
+		mode = 1
+	}
+	cmd := fmt.Sprintf("AT+CCWA=1,%d,%d", mode, class)
+	if _, err := m.execCommand(cmd, time.Second*30); err != nil {
+		return fmt.Errorf("failed to set call waiting: %w", err)
+	}
+	return nil
+}
+
+// QueryCallWaiting запрашивает у сети состояние ожидания вызова
+func (m *Modem) QueryCallWaiting(class ServiceClass) ([]ServiceStatus, error) {
+	cmd := fmt.Sprintf("AT+CCWA=1,2,%d", class)
+	resp, err := m.execCommand(cmd, time.Second*30)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query call waiting: %w", err)
+	}
+	return parseServiceStatus(resp, "+CCWA:"), nil
+}
+
+// parseServiceStatus парсит строки вида +CLCK: <status>,<class>
+func parseServiceStatus(response, prefix string) []ServiceStatus {
+	var result []ServiceStatus
+	for _, fields := range parseInfoLines(response, prefix) {
+		status, err := fieldAt(fields, 0).Int()
+		if err != nil {
+			continue
+		}
+		result = append(result, ServiceStatus{
+			Active: status == 1,
+			Class:  ServiceClass(fieldAt(fields, 1).IntOr(0)),
+		})
+	}
+	return result
+}
+
+// Helper functions for supplementary service notifications
+func mapCSSICode(code int) string {
+	switch code {
+	case 0:
+		return "unconditional call forwarding is active"
+	case 1:
+		return "some conditional call forwarding is active"
+	case 2:
+		return "call has been forwarded"
+	case 3:
+		return "call is waiting"
+	case 4:
+		return "closed user group call"
+	case 5:
+		return "outgoing calls are barred"
+	case 6:
+		return "incoming calls are barred"
+	case 7:
+		return "CLIR suppression rejected"
+	case 8:
+		return "call has been deflected"
+	default:
+		return "unknown"
+	}
+}
+
+func mapCSSUCode(code int) string {
+	switch code {
+	case 0:
+		return "forwarded call"
+	case 1:
+		return "closed user group call"
+	case 2:
+		return "call has been put on hold"
+	case 3:
+		return "call has been retrieved"
+	case 4:
+		return "multiparty call entered"
+	case 5:
+		return "held call released"
+	case 6:
+		return "forward check SS message received"
+	case 7:
+		return "call is being connected by explicit call transfer"
+	case 8:
+		return "call has been connected by explicit call transfer"
+	case 9:
+		return "deflected call"
+	case 10:
+		return "additional incoming call forwarded"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,311 @@
+package gsm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field одно значение информационного ответа 27.007 (+CMD: <field>,<field>,...)
+type Field struct {
+	Value  string  // Значение без кавычек (для списка - пусто)
+	Quoted bool    // Значение было строкой в кавычках
+	IsList bool    // Значение было списком в скобках
+	List   []Field // Элементы списка
+}
+
+// IsEmpty проверяет, что поле пропущено (,,)
+func (f Field) IsEmpty() bool {
+	return !f.Quoted && !f.IsList && f.Value == ""
+}
+
+// Int возвращает значение поля как целое число
+func (f Field) Int() (int, error) {
+	return strconv.Atoi(f.Value)
+}
+
+// IntOr возвращает значение поля как целое число или def, если поле пустое или не число
+func (f Field) IntOr(def int) int {
+	if v, err := strconv.Atoi(f.Value); err == nil {
+		return v
+	}
+	return def
+}
+
+// Hex возвращает значение поля, записанное в шестнадцатеричном виде ("1A2B" или "0x1A2B")
+func (f Field) Hex() (int64, error) {
+	value := strings.TrimPrefix(strings.TrimPrefix(f.Value, "0x"), "0X")
+	return strconv.ParseInt(value, 16, 64)
+}
+
+// Range разбирает диапазон вида "0-4", одиночное число считается диапазоном из одного значения
+func (f Field) Range() (min, max int, ok bool) {
+	parts := strings.SplitN(f.Value, "-", 2)
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, false
+	}
+	if len(parts) == 1 {
+		return min, min, true
+	}
+	max, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil {
+		return 0, 0, false
+	}
+	return min, max, true
+}
+
+// String возвращает значение в виде, близком к исходному
+func (f Field) String() string {
+	if f.IsList {
+		items := make([]string, len(f.List))
+		for i, item := range f.List {
+			items[i] = item.String()
+		}
+		return "(" + strings.Join(items, ",") + ")"
+	}
+	if f.Quoted {
+		return strconv.Quote(f.Value)
+	}
+	return f.Value
+}
+
+// fieldAt возвращает поле по индексу или пустое поле
+func fieldAt(fields []Field, i int) Field {
+	if i < len(fields) {
+		return fields[i]
+	}
+	return Field{}
+}
+
+// ParseFields разбирает значения информационного ответа: строки в кавычках (с экранированием
+// \" и \hh), вложенные списки в скобках, пустые поля и диапазоны
+func ParseFields(data string) ([]Field, error) {
+	t := &fieldTokenizer{data: data}
+	fields, err := t.parseList(0)
+	if err != nil {
+		return nil, err
+	}
+	if t.pos < len(t.data) {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.data[t.pos], t.pos)
+	}
+	return fields, nil
+}
+
+// fieldTokenizer состояние разбора
+type fieldTokenizer struct {
+	data string
+	pos  int
+}
+
+// parseList разбирает поля до конца строки (depth=0) или до закрывающей скобки
+func (t *fieldTokenizer) parseList(depth int) ([]Field, error) {
+	var fields []Field
+	for {
+		field, err := t.parseField(depth)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+
+		t.skipSpaces()
+		if t.pos >= len(t.data) {
+			if depth > 0 {
+				return nil, fmt.Errorf("unterminated list")
+			}
+			return fields, nil
+		}
+
+		switch t.data[t.pos] {
+		case ',':
+			t.pos++
+		case ')':
+			if depth == 0 {
+				return nil, fmt.Errorf("unexpected ')' at position %d", t.pos)
+			}
+			return fields, nil
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d", t.data[t.pos], t.pos)
+		}
+	}
+}
+
+// parseField разбирает одно поле
+func (t *fieldTokenizer) parseField(depth int) (Field, error) {
+	t.skipSpaces()
+	if t.pos >= len(t.data) {
+		return Field{}, nil
+	}
+
+	switch t.data[t.pos] {
+	case '"':
+		value, err := t.parseQuoted()
+		if err != nil {
+			return Field{}, err
+		}
+		return Field{Value: value, Quoted: true}, nil
+
+	case '(':
+		t.pos++
+		t.skipSpaces()
+		field := Field{IsList: true}
+		if t.pos < len(t.data) && t.data[t.pos] == ')' {
+			// Пустой список ()
+			t.pos++
+			return field, nil
+		}
+		list, err := t.parseList(depth + 1)
+		if err != nil {
+			return Field{}, err
+		}
+		t.pos++ // ')'
+		field.List = list
+		return field, nil
+
+	default:
+		start := t.pos
+		for t.pos < len(t.data) && t.data[t.pos] != ',' && t.data[t.pos] != ')' {
+			t.pos++
+		}
+		return Field{Value: strings.TrimSpace(t.data[start:t.pos])}, nil
+	}
+}
+
+// parseQuoted разбирает строку в кавычках
+func (t *fieldTokenizer) parseQuoted() (string, error) {
+	t.pos++ // открывающая кавычка
+	var value strings.Builder
+	for t.pos < len(t.data) {
+		c := t.data[t.pos]
+		switch {
+		case c == '"':
+			t.pos++
+			return value.String(), nil
+
+		case c == '\\' && t.pos+1 < len(t.data):
+			next := t.data[t.pos+1]
+			if next == '"' || next == '\\' {
+				value.WriteByte(next)
+				t.pos += 2
+				continue
+			}
+			// V.250: \hh - символ с шестнадцатеричным кодом
+			if t.pos+2 < len(t.data) {
+				if code, err := strconv.ParseUint(t.data[t.pos+1:t.pos+3], 16, 8); err == nil {
+					value.WriteByte(byte(code))
+					t.pos += 3
+					continue
+				}
+			}
+			value.WriteByte(c)
+			t.pos++
+
+		default:
+			value.WriteByte(c)
+			t.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// skipSpaces пропускает пробелы
+func (t *fieldTokenizer) skipSpaces() {
+	for t.pos < len(t.data) && (t.data[t.pos] == ' ' || t.data[t.pos] == '\t') {
+		t.pos++
+	}
+}
+
+// parseFieldsLoose разбирает строку, не поддающуюся ParseFields (например, кавычки без
+// экранирования в имени отправителя SMS): строка в кавычках заканчивается на кавычке,
+// за которой следует запятая или конец строки
+func parseFieldsLoose(data string) []Field {
+	var fields []Field
+	for {
+		data = strings.TrimLeft(data, " \t")
+		var field Field
+		switch {
+		case strings.HasPrefix(data, "\""):
+			end := looseQuoteEnd(data)
+			field = Field{Value: data[1:end], Quoted: true}
+			data = data[min(end+1, len(data)):]
+
+		case strings.HasPrefix(data, "("):
+			end := looseListEnd(data)
+			field = Field{IsList: true, List: parseFieldsLoose(data[1:end])}
+			data = data[min(end+1, len(data)):]
+
+		default:
+			end := strings.IndexByte(data, ',')
+			if end == -1 {
+				end = len(data)
+			}
+			field = Field{Value: strings.TrimSpace(data[:end])}
+			data = data[end:]
+		}
+		fields = append(fields, field)
+
+		data = strings.TrimLeft(data, " \t")
+		if !strings.HasPrefix(data, ",") {
+			return fields
+		}
+		data = data[1:]
+	}
+}
+
+// looseQuoteEnd возвращает позицию закрывающей кавычки: первой, за которой идет запятая
+// или конец строки (без нее - конец строки)
+func looseQuoteEnd(data string) int {
+	for i := 1; i < len(data); i++ {
+		if data[i] != '"' {
+			continue
+		}
+		if rest := strings.TrimLeft(data[i+1:], " \t"); rest == "" || rest[0] == ',' || rest[0] == ')' {
+			return i
+		}
+	}
+	return len(data)
+}
+
+// looseListEnd возвращает позицию парной закрывающей скобки (без нее - конец строки)
+func looseListEnd(data string) int {
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			i += looseQuoteEnd(data[i:])
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(data)
+}
+
+// parseInfoLine разбирает строку ответа с префиксом (+CMD:), возвращает false если префикса нет.
+// Строка, которую не удается разобрать строго, разбирается parseFieldsLoose.
+func parseInfoLine(line, prefix string) ([]Field, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, prefix) {
+		return nil, false
+	}
+	fields, err := ParseFields(line[len(prefix):])
+	if err != nil {
+		return parseFieldsLoose(line[len(prefix):]), true
+	}
+	return fields, true
+}
+
+// parseInfoLines разбирает все строки ответа с префиксом
+func parseInfoLines(response, prefix string) [][]Field {
+	var result [][]Field
+	for _, line := range strings.Split(response, "\n") {
+		if fields, ok := parseInfoLine(line, prefix); ok {
+			result = append(result, fields)
+		}
+	}
+	return result
+}
@@ -0,0 +1,147 @@
+package gsm
+
+import (
+	"reflect"
+	"testing"
+)
+
+// q строковое поле в кавычках
+func q(value string) Field {
+	return Field{Value: value, Quoted: true}
+}
+
+// v поле без кавычек
+func v(value string) Field {
+	return Field{Value: value}
+}
+
+// list поле-список
+func list(items ...Field) Field {
+	return Field{IsList: true, List: items}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		data string
+		want []Field
+	}{
+		{` 1,"REC READ","+79991234567"`, []Field{v("1"), q("REC READ"), q("+79991234567")}},
+		{`"a,b",2`, []Field{q("a,b"), v("2")}},
+		// Экранирование \" и \\, а также \hh по V.250
+		{`"say \"hi\"","C:\\dir"`, []Field{q(`say "hi"`), q(`C:\dir`)}},
+		{`"\22quoted\22","2\2C3"`, []Field{q(`"quoted"`), q("2,3")}},
+		{`"\zz"`, []Field{q(`\zz`)}},
+		// Пустые поля
+		{`1,,3`, []Field{v("1"), v(""), v("3")}},
+		{`,`, []Field{v(""), v("")}},
+		{``, []Field{v("")}},
+		{`"",`, []Field{q(""), v("")}},
+		// Вложенные списки и диапазоны
+		{`(0-4),(0,1,2)`, []Field{list(v("0-4")), list(v("0"), v("1"), v("2"))}},
+		{`("GSM","UCS2"),()`, []Field{list(q("GSM"), q("UCS2")), list()}},
+		{`(1,(2,3)),4`, []Field{list(v("1"), list(v("2"), v("3"))), v("4")}},
+		{` ( 1 , "a" ) , 2 `, []Field{list(v("1"), q("a")), v("2")}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFields(tt.data)
+		if err != nil {
+			t.Errorf("ParseFields(%q): %v", tt.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFields(%q) = %v, want %v", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseFieldsMalformed(t *testing.T) {
+	for _, data := range []string{
+		`"unterminated`,
+		`(1,2`,
+		`1,2)`,
+		`"a"b`,
+		`"REC READ","Shop "Best"","",""`,
+		`((1)`,
+	} {
+		if fields, err := ParseFields(data); err == nil {
+			t.Errorf("ParseFields(%q) = %v, want error", data, fields)
+		}
+	}
+}
+
+func TestFieldConversions(t *testing.T) {
+	if n, err := v("42").Int(); err != nil || n != 42 {
+		t.Errorf("Int = %d, %v", n, err)
+	}
+	if n := v("x").IntOr(-1); n != -1 {
+		t.Errorf("IntOr = %d, want -1", n)
+	}
+	for _, value := range []string{"1A2B", "0x1A2B", "0X1a2b"} {
+		if n, err := v(value).Hex(); err != nil || n != 0x1A2B {
+			t.Errorf("Hex(%q) = %d, %v", value, n, err)
+		}
+	}
+	if lo, hi, ok := v("0-4").Range(); !ok || lo != 0 || hi != 4 {
+		t.Errorf("Range(0-4) = %d, %d, %v", lo, hi, ok)
+	}
+	if lo, hi, ok := v("7").Range(); !ok || lo != 7 || hi != 7 {
+		t.Errorf("Range(7) = %d, %d, %v", lo, hi, ok)
+	}
+	if _, _, ok := v("a-b").Range(); ok {
+		t.Error("Range(a-b) succeeded")
+	}
+	if s := list(v("1"), q("a")).String(); s != `(1,"a")` {
+		t.Errorf("String = %s", s)
+	}
+	if !v("").IsEmpty() || q("").IsEmpty() || list().IsEmpty() {
+		t.Error("IsEmpty mismatch")
+	}
+}
+
+func TestParseInfoLineLoose(t *testing.T) {
+	tests := []struct {
+		line string
+		want []Field
+	}{
+		// Кавычки внутри имени отправителя без экранирования
+		{`+CMGR: "REC READ","Shop "Best"","","24/05/01,12:00:00+12"`,
+			[]Field{q("REC READ"), q(`Shop "Best"`), q(""), q("24/05/01,12:00:00+12")}},
+		{`+CMGR: "REC READ", "A"B" , 1`, []Field{q("REC READ"), q(`A"B`), v("1")}},
+		{`+CMGR: "unterminated,1`, []Field{q("unterminated,1")}},
+		{`+CMGR: ("a"b"),2`, []Field{list(q(`a"b`)), v("2")}},
+	}
+	for _, tt := range tests {
+		got, ok := parseInfoLine(tt.line, "+CMGR:")
+		if !ok {
+			t.Errorf("parseInfoLine(%q) = false", tt.line)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseInfoLine(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+	if _, ok := parseInfoLine(`+CMGL: 1`, "+CMGR:"); ok {
+		t.Error("parseInfoLine accepted another prefix")
+	}
+}
+
+func TestParseSMSUnescapedQuotes(t *testing.T) {
+	resp := "+CMGR: \"REC READ\",\"Shop \"Best\"\",\"\",\"24/05/01,12:00:00+12\"\r\nSale today\r\n\r\nOK"
+	sms, err := parseSMS(resp, 3)
+	if err != nil {
+		t.Fatalf("parseSMS: %v", err)
+	}
+	if sms.Sender != `Shop "Best"` || sms.Text != "Sale today" || sms.Status != "REC READ" {
+		t.Errorf("sms = %+v", sms)
+	}
+
+	list := "+CMGL: 1,\"REC READ\",\"Shop \"Best\"\",\"\",\"24/05/01,12:00:00+12\"\r\nSale today\r\n" +
+		"+CMGL: 2,\"REC UNREAD\",\"+79991234567\",\"\",\"24/05/01,12:05:00+12\"\r\nHello\r\n\r\nOK"
+	messages, err := parseSMSList(list)
+	if err != nil {
+		t.Fatalf("parseSMSList: %v", err)
+	}
+	if len(messages) != 2 || messages[0].Sender != `Shop "Best"` || messages[1].Text != "Hello" {
+		t.Errorf("messages = %+v", messages)
+	}
+}
@@ -0,0 +1,158 @@
+//go:build linux
+
+package gsm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// TUN сетевой интерфейс Linux для IP-пакетов PPP соединения (требует CAP_NET_ADMIN)
+type TUN struct {
+	file *os.File
+	name string
+}
+
+// OpenTUN создает TUN интерфейс (name "" - имя выбирает ядро, например tun0)
+func OpenTUN(name string) (*TUN, error) {
+	fd, err := unix.Open("/dev/net/tun", unix.O_RDWR|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /dev/net/tun: %w", err)
+	}
+
+	ifr, err := unix.NewIfreq(name)
+	if err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to create TUN %q: %w", name, err)
+	}
+	// Пакеты без заголовка packet information - как в PPP
+	ifr.SetUint16(unix.IFF_TUN | unix.IFF_NO_PI)
+	if err := unix.IoctlIfreq(fd, unix.TUNSETIFF, ifr); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("failed to create TUN %q: %w", name, err)
+	}
+
+	return &TUN{file: os.NewFile(uintptr(fd), "/dev/net/tun"), name: ifr.Name()}, nil
+}
+
+// Name возвращает имя интерфейса
+func (t *TUN) Name() string {
+	return t.name
+}
+
+// Read читает IP-пакет, отправленный системой в интерфейс
+func (t *TUN) Read(b []byte) (int, error) {
+	return t.file.Read(b)
+}
+
+// Write передает IP-пакет системе
+func (t *TUN) Write(b []byte) (int, error) {
+	return t.file.Write(b)
+}
+
+// Close удаляет интерфейс
+func (t *TUN) Close() error {
+	return t.file.Close()
+}
+
+// ConfigurePointToPoint назначает интерфейсу адреса IPv4 (локальный и собеседника), MTU
+// и поднимает его. Маршруты и DNS настраиваются отдельно.
+func (t *TUN) ConfigurePointToPoint(local, peer net.IP, mtu int) error {
+	sock, err := unix.Socket(unix.AF_INET, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to configure %s: %w", t.name, err)
+	}
+	defer unix.Close(sock)
+
+	setAddr := func(req uint, ip net.IP) error {
+		ifr, err := unix.NewIfreq(t.name)
+		if err != nil {
+			return err
+		}
+		if err := ifr.SetInet4Addr(ip.To4()); err != nil {
+			return err
+		}
+		return unix.IoctlIfreq(sock, req, ifr)
+	}
+
+	if err := setAddr(unix.SIOCSIFADDR, local); err != nil {
+		return fmt.Errorf("failed to set %s address: %w", t.name, err)
+	}
+	if peer != nil {
+		if err := setAddr(unix.SIOCSIFDSTADDR, peer); err != nil {
+			return fmt.Errorf("failed to set %s peer address: %w", t.name, err)
+		}
+	}
+	if err := setAddr(unix.SIOCSIFNETMASK, net.IPv4bcast); err != nil {
+		return fmt.Errorf("failed to set %s netmask: %w", t.name, err)
+	}
+
+	ifr, err := unix.NewIfreq(t.name)
+	if err != nil {
+		return fmt.Errorf("failed to configure %s: %w", t.name, err)
+	}
+	if mtu > 0 {
+		ifr.SetUint32(uint32(mtu))
+		if err := unix.IoctlIfreq(sock, unix.SIOCSIFMTU, ifr); err != nil {
+			return fmt.Errorf("failed to set %s MTU: %w", t.name, err)
+		}
+	}
+
+	if err := unix.IoctlIfreq(sock, unix.SIOCGIFFLAGS, ifr); err != nil {
+		return fmt.Errorf("failed to get %s flags: %w", t.name, err)
+	}
+	ifr.SetUint16(ifr.Uint16() | unix.IFF_UP | unix.IFF_RUNNING | unix.IFF_POINTOPOINT)
+	if err := unix.IoctlIfreq(sock, unix.SIOCSIFFLAGS, ifr); err != nil {
+		return fmt.Errorf("failed to bring %s up: %w", t.name, err)
+	}
+	return nil
+}
+
+// Bridge передает пакеты между интерфейсом и каналом, пока одна из сторон не закроется.
+// Перед возвратом закрывает обе стороны.
+func (t *TUN) Bridge(link PacketLink) error {
+	errs := make(chan error, 2)
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, err := t.Read(buf)
+			if err != nil {
+				errs <- fmt.Errorf("failed to read %s: %w", t.name, err)
+				return
+			}
+			if err := link.WritePacket(buf[:n]); err != nil && !errors.Is(err, ErrPacketDropped) {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, err := link.ReadPacket(buf)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if _, err := t.Write(buf[:n]); err != nil {
+				errs <- fmt.Errorf("failed to write %s: %w", t.name, err)
+				return
+			}
+		}
+	}()
+
+	err := <-errs
+	t.Close()
+	link.Close()
+	<-errs
+	if errors.Is(err, ErrPPPClosed) || errors.Is(err, os.ErrClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,535 @@
+package gsm
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ussdTimeout - время ожидания ответа сети, если в контексте не задан дедлайн
+const ussdTimeout = 30 * time.Second
+
+// ErrUSSDSessionClosed возвращается при попытке ответить в завершенной USSD сессии
+var ErrUSSDSessionClosed = errors.New("USSD session is closed")
+
+// USSDStatus статус USSD ответа (<m> в +CUSD)
+type USSDStatus int
+
+const (
+	USSDDone           USSDStatus = iota // 0 - сессия завершена, действий не требуется
+	USSDActionRequired                   // 1 - требуется ответ пользователя (меню)
+	USSDTerminated                       // 2 - сессия прервана сетью
+	USSDOtherClient                      // 3 - ответ обработан другим клиентом
+	USSDNotSupported                     // 4 - операция не поддерживается
+	USSDTimeout                          // 5 - таймаут сети
+)
+
+// Alphabet кодировка данных, определяемая по DCS
+type Alphabet int
+
+const (
+	AlphabetGSM7 Alphabet = iota // 7-битный алфавит GSM 03.38
+	Alphabet8Bit                 // 8-битные данные
+	AlphabetUCS2                 // UCS2 (UTF-16BE)
+)
+
+// USSDResponse ответ сети на USSD запрос
+type USSDResponse struct {
+	Status  USSDStatus // Статус сессии
+	Message string     // Текст ответа (уже декодированный)
+	Raw     string     // Текст ответа в том виде, в котором его вернул модем
+	DCS     int        // Data Coding Scheme (-1, если не указан)
+}
+
+// USSDSession интерактивная USSD сессия (навигация по меню оператора)
+type USSDSession struct {
+	modem     *Modem
+	mu        sync.Mutex
+	responses chan USSDResponse
+	last      USSDResponse
+	closed    bool
+}
+
+// ussdRouter направляет ответы +CUSD в активную сессию
+type ussdRouter struct {
+	mu      sync.Mutex
+	session *USSDSession
+}
+
+// begin регистрирует новую сессию
+func (r *ussdRouter) begin(session *USSDSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.session != nil {
+		return fmt.Errorf("USSD session is already active")
+	}
+	r.session = session
+	return nil
+}
+
+// end снимает регистрацию сессии
+func (r *ussdRouter) end(session *USSDSession) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.session == session {
+		r.session = nil
+	}
+}
+
+// deliver передает ответ в активную сессию, возвращает false если сессии нет
+func (r *ussdRouter) deliver(resp USSDResponse) (*USSDSession, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.session == nil {
+		return nil, false
+	}
+	select {
+	case r.session.responses <- resp:
+	default:
+	}
+	return r.session, true
+}
+
+// adopt создает сессию для запроса, инициированного сетью
+func (r *ussdRouter) adopt(m *Modem, resp USSDResponse) *USSDSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session := newUSSDSession(m)
+	session.last = resp
+	if resp.Status == USSDActionRequired && r.session == nil {
+		r.session = session
+	} else {
+		session.closed = true
+	}
+	return session
+}
+
+// newUSSDSession создает объект сессии
+func newUSSDSession(m *Modem) *USSDSession {
+	return &USSDSession{
+		modem:     m,
+		responses: make(chan USSDResponse, 4),
+	}
+}
+
+// StartUSSD отправляет USSD запрос и возвращает сессию с первым ответом сети
+func (m *Modem) StartUSSD(ctx context.Context, code string) (*USSDSession, error) {
+	session := newUSSDSession(m)
+	if err := m.ussd.begin(session); err != nil {
+		return nil, err
+	}
+
+	// Устанавливаем кодировку для USSD, если пользователь не выбрал свою
+	if m.Charset() == "" {
+		if err := m.SetCharset("GSM"); err != nil {
+			m.ussd.end(session)
+			return nil, err
+		}
+	}
+
+	if _, err := session.send(ctx, code); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// SendUSSD отправляет USSD запрос и возвращает текст ответа.
+// Если сеть ожидает продолжения диалога, сессия закрывается.
+func (m *Modem) SendUSSD(code string) (string, error) {
+	session, err := m.StartUSSD(context.Background(), code)
+	if err != nil {
+		return "", err
+	}
+
+	resp := session.Response()
+	if resp.Status == USSDActionRequired {
+		session.Cancel()
+	}
+	return resp.Message, nil
+}
+
+// Response возвращает последний ответ сети
+func (s *USSDSession) Response() USSDResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// Status возвращает статус последнего ответа
+func (s *USSDSession) Status() USSDStatus {
+	return s.Response().Status
+}
+
+// Message возвращает текст последнего ответа
+func (s *USSDSession) Message() string {
+	return s.Response().Message
+}
+
+// Active проверяет, ожидает ли сеть продолжения диалога
+func (s *USSDSession) Active() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.closed && s.last.Status == USSDActionRequired
+}
+
+// Reply отправляет ответ пользователя (например, пункт меню "1") и ждет следующий ответ сети
+func (s *USSDSession) Reply(ctx context.Context, text string) (USSDResponse, error) {
+	if !s.Active() {
+		return s.Response(), ErrUSSDSessionClosed
+	}
+	return s.send(ctx, text)
+}
+
+// Cancel прерывает сессию (AT+CUSD=2)
+func (s *USSDSession) Cancel() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.last.Status = USSDTerminated
+	s.mu.Unlock()
+
+	s.modem.ussd.end(s)
+	if _, err := s.modem.execCommand("AT+CUSD=2", time.Second*5); err != nil {
+		return fmt.Errorf("failed to cancel USSD session: %w", err)
+	}
+	return nil
+}
+
+// send отправляет строку в сессию и ждет ответ сети
+func (s *USSDSession) send(ctx context.Context, text string) (USSDResponse, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ussdTimeout)
+		defer cancel()
+	}
+
+	// Сбрасываем ответы, оставшиеся от предыдущего шага
+	for len(s.responses) > 0 {
+		<-s.responses
+	}
+
+	payload, err := s.modem.encodeUSSD(text)
+	if err != nil {
+		s.close(USSDTerminated)
+		return USSDResponse{}, err
+	}
+
+	cmd := fmt.Sprintf("AT+CUSD=1,\"%s\",15", payload)
+	resp, err := s.modem.execCommand(cmd, time.Second*10)
+	if err != nil {
+		s.close(USSDTerminated)
+		return USSDResponse{}, fmt.Errorf("failed to send USSD: %w", err)
+	}
+
+	// Ответ мог прийти вместе с OK
+	if idx := strings.Index(resp, "+CUSD:"); idx != -1 {
+		if urc, ok := completeURC(resp[idx:]); ok {
+			if r, ok := parseCUSD(urc); ok {
+				return s.receive(s.modem.decodeUSSD(r)), nil
+			}
+		}
+	}
+
+	var r USSDResponse
+	if s.modem.IsEventListenerRunning() {
+		select {
+		case r = <-s.responses:
+		case <-ctx.Done():
+			s.Cancel()
+			return USSDResponse{Status: USSDTimeout}, fmt.Errorf("failed to get USSD response: %w", ctx.Err())
+		}
+	} else {
+		// Без обработчика событий читаем URC из порта сами
+		deadline, _ := ctx.Deadline()
+		line, err := s.modem.waitForURC("+CUSD:", time.Until(deadline))
+		if err != nil {
+			s.Cancel()
+			return USSDResponse{Status: USSDTimeout}, fmt.Errorf("failed to get USSD response: %w", err)
+		}
+		var ok bool
+		if r, ok = parseCUSD(line); !ok {
+			s.Cancel()
+			return USSDResponse{}, fmt.Errorf("invalid USSD response format: %s", line)
+		}
+		r = s.modem.decodeUSSD(r)
+	}
+
+	return s.receive(r), nil
+}
+
+// receive сохраняет ответ и закрывает сессию, если сеть не ждет продолжения
+func (s *USSDSession) receive(r USSDResponse) USSDResponse {
+	s.mu.Lock()
+	s.last = r
+	s.mu.Unlock()
+
+	if r.Status != USSDActionRequired {
+		s.close(r.Status)
+	}
+	return r
+}
+
+// close помечает сессию завершенной без отправки AT+CUSD=2
+func (s *USSDSession) close(status USSDStatus) {
+	s.mu.Lock()
+	s.closed = true
+	s.last.Status = status
+	s.mu.Unlock()
+
+	s.modem.ussd.end(s)
+}
+
+// handleUSSD обрабатывает +CUSD, пришедший через обработчик событий
+func (m *Modem) handleUSSD(line string) {
+	resp, ok := parseCUSD(line)
+	if !ok {
+		return
+	}
+	resp = m.decodeUSSD(resp)
+
+	event := Event{
+		Type:      EventUSSD,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"message": resp.Message,
+			"status":  resp.Status,
+			"dcs":     resp.DCS,
+		},
+	}
+
+	if _, ok := m.ussd.deliver(resp); ok {
+		event.Data["networkInitiated"] = false
+	} else {
+		// Запрос или уведомление, инициированные сетью
+		event.Data["networkInitiated"] = true
+		event.Data["session"] = m.ussd.adopt(m, resp)
+	}
+	m.emitEvent(event)
+}
+
+// parseCUSD парсит ответ +CUSD: <m>[,"<str>",<dcs>]
+func parseCUSD(data string) (USSDResponse, bool) {
+	data = strings.TrimSpace(data)
+	if !strings.HasPrefix(data, "+CUSD:") {
+		return USSDResponse{}, false
+	}
+	fields, err := ParseFields(data[len("+CUSD:"):])
+	if err != nil {
+		// Сеть может прислать текст с неэкранированными кавычками: текст - от первой
+		// до последней кавычки (разбор полей parseInfoLine здесь не подходит)
+		return parseCUSDLoose(data)
+	}
+
+	status, err := fieldAt(fields, 0).Int()
+	if err != nil {
+		return USSDResponse{}, false
+	}
+
+	resp := USSDResponse{Status: USSDStatus(status), DCS: fieldAt(fields, 2).IntOr(-1)}
+	resp.Raw = fieldAt(fields, 1).Value
+	resp.Message = resp.Raw
+	return resp, true
+}
+
+// parseCUSDLoose разбирает +CUSD, не поддающийся токенизатору: текстом считается все
+// между первой и последней кавычкой перед ",<dcs>"
+func parseCUSDLoose(data string) (USSDResponse, bool) {
+	data = strings.TrimSpace(data)
+	if !strings.HasPrefix(data, "+CUSD:") {
+		return USSDResponse{}, false
+	}
+	data = strings.TrimSpace(data[len("+CUSD:"):])
+
+	statusEnd := strings.IndexAny(data, ",\r\n")
+	if statusEnd == -1 {
+		statusEnd = len(data)
+	}
+	status, err := strconv.Atoi(strings.TrimSpace(data[:statusEnd]))
+	if err != nil {
+		return USSDResponse{}, false
+	}
+
+	resp := USSDResponse{Status: USSDStatus(status), DCS: -1}
+	first := strings.Index(data, "\"")
+	last := strings.LastIndex(data, "\"")
+	if first != -1 && last > first {
+		resp.Raw = data[first+1 : last]
+		resp.Message = resp.Raw
+		rest := strings.TrimPrefix(strings.TrimSpace(data[last+1:]), ",")
+		if dcs, err := strconv.Atoi(strings.TrimSpace(rest)); err == nil {
+			resp.DCS = dcs
+		}
+	}
+	return resp, true
+}
+
+// DCSAlphabet определяет кодировку по Data Coding Scheme для USSD/CBS (3GPP TS 23.038, раздел 5)
+func DCSAlphabet(dcs int) Alphabet {
+	switch {
+	case dcs == 0x11:
+		// UCS2 с указанием языка
+		return AlphabetUCS2
+	case dcs&0xC0 == 0x40 || dcs&0xF0 == 0x90:
+		// Общая схема кодирования: биты 3-2 задают алфавит
+		switch (dcs >> 2) & 0x03 {
+		case 1:
+			return Alphabet8Bit
+		case 2:
+			return AlphabetUCS2
+		}
+	case dcs&0xF0 == 0xF0:
+		if dcs&0x04 != 0 {
+			return Alphabet8Bit
+		}
+	}
+	return AlphabetGSM7
+}
+
+// SetUSSDPackedMode включает отправку и прием USSD в виде упакованного 7-битного hex
+// (включается автоматически для Huawei, если AT^USSDMODE не сообщает режим 0)
+func (m *Modem) SetUSSDPackedMode(enable bool) {
+	m.optMu.Lock()
+	defer m.optMu.Unlock()
+	m.ussdPacked = enable
+}
+
+// detectUSSDMode уточняет режим USSD модема Huawei по AT^USSDMODE: в режиме 0 модем сам
+// кодирует и декодирует текст, в режиме 1 (и на прошивках без команды) - упакованный hex
+func (m *Modem) detectUSSDMode() {
+	if m.Vendor() != VendorHuawei {
+		return
+	}
+	resp, err := m.execCommand("AT^USSDMODE?", time.Second*2)
+	if err != nil {
+		return
+	}
+	if packed, ok := parseUSSDMode(resp); ok {
+		m.SetUSSDPackedMode(packed)
+	}
+}
+
+// parseUSSDMode разбирает ответ ^USSDMODE: <mode>, возвращает true для режима упакованного hex
+func parseUSSDMode(resp string) (bool, bool) {
+	for _, fields := range parseInfoLines(resp, "^USSDMODE:") {
+		if mode, err := fieldAt(fields, 0).Int(); err == nil {
+			return mode != 0, true
+		}
+	}
+	return false, false
+}
+
+// usesPackedUSSD проверяет, нужен ли упакованный 7-битный hex для USSD
+func (m *Modem) usesPackedUSSD() bool {
+	m.optMu.Lock()
+	defer m.optMu.Unlock()
+	return m.ussdPacked
+}
+
+// encodeUSSD кодирует строку запроса с учетом кодировки TE и особенностей модема
+func (m *Modem) encodeUSSD(text string) (string, error) {
+	if m.usesPackedUSSD() {
+		packed, err := EncodeUSSDPacked(text)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode USSD: %w", err)
+		}
+		return packed, nil
+	}
+	if m.Charset() == "UCS2" {
+		return strings.ToUpper(EncodeUCS2(text)), nil
+	}
+	return text, nil
+}
+
+// decodeUSSD декодирует текст ответа по DCS и текущей кодировке TE
+func (m *Modem) decodeUSSD(resp USSDResponse) USSDResponse {
+	resp.Message = decodeUSSDText(resp.Raw, resp.DCS, m.Charset(), m.usesPackedUSSD())
+	return resp
+}
+
+// decodeUSSDText декодирует текст USSD: charset - значение AT+CSCS, packed - модем отдает
+// 7-битные данные упакованным hex
+func decodeUSSDText(text string, dcs int, charset string, packed bool) string {
+	if text == "" || !isHexString(text) {
+		// Модем уже вернул читаемый текст
+		return text
+	}
+
+	if dcs < 0 {
+		// DCS не указан - пробуем угадать
+		return DecodeGSMText(text)
+	}
+
+	switch DCSAlphabet(dcs) {
+	case AlphabetUCS2:
+		data := text
+		if dcs == 0x11 && len(data) >= 4 {
+			// Первые два октета - код языка в 7-битной кодировке
+			data = data[4:]
+		}
+		if decoded, err := DecodeUCS2(data); err == nil {
+			return decoded
+		}
+
+	case Alphabet8Bit:
+		if data, err := hex.DecodeString(text); err == nil {
+			if utf8.Valid(data) {
+				return string(data)
+			}
+			// Latin-1: каждый байт соответствует символу
+			runes := make([]rune, len(data))
+			for i, b := range data {
+				runes[i] = rune(b)
+			}
+			return string(runes)
+		}
+
+	default:
+		if packed {
+			if decoded, err := DecodeUSSDPacked(text); err == nil {
+				if dcs == 0x10 && len([]rune(decoded)) > 3 {
+					// Первые три символа - язык и CR
+					decoded = string([]rune(decoded)[3:])
+				}
+				return decoded
+			}
+		}
+		if charset == "UCS2" || charset == "HEX" {
+			// Модем сам декодировал 7-битные данные и перекодировал их в hex кодировку TE
+			if charset == "UCS2" {
+				if decoded, err := DecodeUCS2(text); err == nil {
+					return decoded
+				}
+			} else if data, err := hex.DecodeString(text); err == nil {
+				return DecodeGSM7(data)
+			}
+		}
+	}
+
+	return text
+}
+
+// isHexString проверяет, что строка непустая и состоит из четного числа hex символов
+func isHexString(s string) bool {
+	if s == "" || len(s)%2 != 0 {
+		return false
+	}
+	for _, c := range s {
+		if !((c >= '0' && c <= '9') || (c >= 'A' && c <= 'F') || (c >= 'a' && c <= 'f')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package gsm
+
+import "testing"
+
+func TestDCSAlphabet(t *testing.T) {
+	tests := map[int]Alphabet{
+		0x00: AlphabetGSM7,
+		0x0F: AlphabetGSM7,
+		0x10: AlphabetGSM7,
+		0x11: AlphabetUCS2,
+		0x44: Alphabet8Bit,
+		0x48: AlphabetUCS2,
+		0x94: Alphabet8Bit,
+		0x98: AlphabetUCS2,
+		0xF0: AlphabetGSM7,
+		0xF4: Alphabet8Bit,
+	}
+	for dcs, want := range tests {
+		if got := DCSAlphabet(dcs); got != want {
+			t.Errorf("DCSAlphabet(0x%02X) = %v, want %v", dcs, got, want)
+		}
+	}
+}
+
+func TestDecodeUSSDText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		dcs     int
+		charset string
+		packed  bool
+		want    string
+	}{
+		{"plain text", "Balance: 100.50 RUB", 15, "GSM", false, "Balance: 100.50 RUB"},
+		{"packed GSM7", "AA180C3602", 15, "GSM", true, "*100#"},
+		{"packed with CR padding", "31D98C56B3DD1A", 15, "GSM", true, "1234567"},
+		// Текстовый режим: четное число цифр - это текст, а не упакованные данные
+		{"digits in text mode", "100500", 15, "GSM", false, "100500"},
+		{"packed with language", "6577035966B3DF", 0x10, "GSM", true, "Hello"},
+		{"UCS2", "04110430043B0430043D0441", 0x48, "GSM", false, "Баланс"},
+		{"UCS2 with language", "72750411043004400020", 0x11, "GSM", false, "Бар "},
+		{"8-bit", "48656C6C6F", 0x44, "GSM", false, "Hello"},
+		{"8-bit Latin-1", "436166E9", 0x44, "GSM", false, "Café"},
+		{"GSM7 recoded to UCS2", "002A0031003000300023", 15, "UCS2", false, "*100#"},
+		{"GSM7 recoded to HEX", "2A31303023", 15, "HEX", false, "*100#"},
+	}
+	for _, tt := range tests {
+		if got := decodeUSSDText(tt.text, tt.dcs, tt.charset, tt.packed); got != tt.want {
+			t.Errorf("%s: decodeUSSDText(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseUSSDMode(t *testing.T) {
+	tests := []struct {
+		resp   string
+		packed bool
+		ok     bool
+	}{
+		{"^USSDMODE: 0\r\nOK", false, true},
+		{"^USSDMODE:1\r\nOK", true, true},
+		{"OK", false, false},
+		{"^USSDMODE: x\r\nOK", false, false},
+	}
+	for _, tt := range tests {
+		if packed, ok := parseUSSDMode(tt.resp); packed != tt.packed || ok != tt.ok {
+			t.Errorf("parseUSSDMode(%q) = %v, %v, want %v, %v", tt.resp, packed, ok, tt.packed, tt.ok)
+		}
+	}
+}
+
+func TestParseCUSD(t *testing.T) {
+	tests := []struct {
+		line   string
+		status USSDStatus
+		raw    string
+		dcs    int
+	}{
+		{`+CUSD: 0,"Balance: 100 RUB",15`, 0, "Balance: 100 RUB", 15},
+		{`+CUSD: 1,"Reply \"1\" to confirm",72`, 1, `Reply "1" to confirm`, 72},
+		{`+CUSD: 0,"He said "hi", ok",15`, 0, `He said "hi", ok`, 15},
+		{`+CUSD: 2`, 2, "", -1},
+	}
+	for _, tt := range tests {
+		resp, ok := parseCUSD(tt.line)
+		if !ok || resp.Status != tt.status || resp.Raw != tt.raw || resp.DCS != tt.dcs {
+			t.Errorf("parseCUSD(%q) = %+v, %v", tt.line, resp, ok)
+		}
+	}
+	if _, ok := parseCUSD("+CMTI: \"SM\",1"); ok {
+		t.Error("parseCUSD accepted another URC")
+	}
+}
@@ -0,0 +1,61 @@
+package gsm
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotSupported возвращается, если функция не поддерживается модемом
+var ErrNotSupported = errors.New("not supported by this modem")
+
+// Vendor производитель модема, определяет набор vendor-специфичных AT команд
+type Vendor string
+
+const (
+	VendorUnknown Vendor = ""        // Производитель не определен, используются только стандартные команды 27.007
+	VendorHuawei  Vendor = "huawei"  // Huawei (E173, E3372, ME909 и т.д.)
+	VendorQuectel Vendor = "quectel" // Quectel (EC25, EG25, M66, BG96 и т.д.)
+	VendorSIMCom  Vendor = "simcom"  // SIMCom (SIM800, SIM7600 и т.д.)
+	VendorUblox   Vendor = "ublox"   // u-blox (SARA, TOBY, LARA)
+	VendorZTE     Vendor = "zte"     // ZTE (MF823, MF831)
+	VendorSierra  Vendor = "sierra"  // Sierra Wireless (MC73xx, EM74xx)
+	VendorFibocom Vendor = "fibocom" // Fibocom (L850, L860)
+)
+
+// DetectVendor определяет производителя по ответу AT+CGMI
+func DetectVendor(manufacturer string) Vendor {
+	name := strings.ToLower(manufacturer)
+	switch {
+	case strings.Contains(name, "huawei"):
+		return VendorHuawei
+	case strings.Contains(name, "quectel"):
+		return VendorQuectel
+	case strings.Contains(name, "simcom") || strings.Contains(name, "sim tech"):
+		return VendorSIMCom
+	case strings.Contains(name, "u-blox") || strings.Contains(name, "ublox"):
+		return VendorUblox
+	case strings.Contains(name, "zte"):
+		return VendorZTE
+	case strings.Contains(name, "sierra"):
+		return VendorSierra
+	case strings.Contains(name, "fibocom"):
+		return VendorFibocom
+	default:
+		return VendorUnknown
+	}
+}
+
+// Vendor возвращает производителя модема, определенного при подключении
+func (m *Modem) Vendor() Vendor {
+	m.optMu.Lock()
+	defer m.optMu.Unlock()
+	return m.vendor
+}
+
+// SetVendor переопределяет производителя (если модем сообщает нестандартный AT+CGMI)
+func (m *Modem) SetVendor(vendor Vendor) {
+	m.optMu.Lock()
+	defer m.optMu.Unlock()
+	m.vendor = vendor
+	m.ussdPacked = vendor == VendorHuawei
+}